@@ -0,0 +1,179 @@
+// Command couponctl runs coupon operations from the command line, against
+// the same database and with the same services as the API.
+//
+//	couponctl list [cursor]
+//	couponctl get <coupon-id>
+//	couponctl delete <coupon-id>
+//	couponctl restore <coupon-id>
+//	couponctl submit <coupon-id>
+//	couponctl approve <coupon-id>
+//	couponctl reject <coupon-id> <reason>
+//	couponctl end <coupon-id>
+//	couponctl purge <retention-days>
+//	couponctl history <coupon-id> [cursor]
+//	couponctl examples [coupon-id]
+//	couponctl campaign-report <campaign-id>
+//	couponctl applicable < request.json
+//	couponctl preview < request.json
+//
+// The requests for applicable and preview have the same shape as the bodies
+// of POST /applicable-coupons and POST /coupons/preview. Results are printed
+// as JSON. Changes are recorded in the audit log as made by -actor, the user
+// running the command unless set. Approving and rejecting need -roles
+// approver.
+//
+// examples runs the examples of a coupon, or of every coupon, and exits with
+// status 1 when any of them fails, e.g. to check a change of the pricing code
+// before it is deployed.
+package main
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"monk-commerce-assignment/config"
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/services"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/db"
+	ulog "monk-commerce-assignment/utils/log"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 30*time.Second, "give up after this long")
+	actor := flag.String("actor", "couponctl:"+os.Getenv("USER"), "who changes are recorded as made by")
+	roles := flag.String("roles", "", "comma separated roles of the actor, e.g. approver")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: couponctl [-timeout 30s] [-actor name] [-roles approver] list [cursor] | get <id> | delete <id> | restore <id> | submit <id> | approve <id> | reject <id> <reason> | end <id> | purge <days> | history <id> [cursor] | examples [id] | campaign-report <id> | applicable < request.json | preview < request.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "dev"
+	}
+	file, err := os.Open(env + ".json")
+	if err != nil {
+		fail(err)
+	}
+	var cnf *config.Config
+	config.ParseJSON(file, &cnf)
+	config.Set(cnf)
+
+	err = db.Init(&db.Config{
+		URL: cnf.DatabaseURL,
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	parent, cancel := stdcontext.WithTimeout(stdcontext.Background(), *timeout)
+	defer cancel()
+	refID := uuid.New().String()
+	ctx := context.New(parent, refID, ulog.New(refID, cnf.AppName, cnf.LogLevel), db.New())
+	ctx.Actor = *actor
+	if *roles != "" {
+		ctx.Roles = strings.Split(*roles, ",")
+	}
+
+	coupons := services.NewCouponService(daos.NewCoupon(), daos.NewCustomer())
+
+	var result any
+	failed := false
+	switch command := flag.Arg(0); command {
+	case "list":
+		result, err = coupons.ListCoupons(ctx, dtos.CouponListQuery{Cursor: flag.Arg(1)})
+	case "get":
+		result, err = coupons.GetCouponById(ctx, argument())
+	case "delete":
+		err = coupons.DeleteCoupon(ctx, argument())
+		result = map[string]string{"message": "Coupon deleted successfully"}
+	case "restore":
+		result, err = coupons.RestoreCoupon(ctx, argument())
+	case "submit":
+		result, err = coupons.SubmitCoupon(ctx, argument())
+	case "approve":
+		result, err = coupons.ApproveCoupon(ctx, argument())
+	case "reject":
+		result, err = coupons.RejectCoupon(ctx, argument(), dtos.ReviewRequest{Reason: strings.Join(flag.Args()[2:], " ")})
+	case "end":
+		result, err = coupons.EndCoupon(ctx, argument())
+	case "purge":
+		var days int
+		days, err = strconv.Atoi(argument())
+		if err != nil || days < 0 {
+			fail(fmt.Errorf("invalid retention days %q", argument()))
+		}
+		var purged int
+		purged, err = coupons.PurgeDeletedCoupons(ctx, time.Duration(days)*24*time.Hour)
+		result = map[string]int{"purged": purged}
+	case "history":
+		result, err = coupons.GetCouponHistory(ctx, argument(), dtos.AuditQuery{Cursor: flag.Arg(2)})
+	case "examples":
+		var run *dtos.ExampleRun
+		if flag.NArg() > 1 {
+			run, err = coupons.RunCouponExamples(ctx, flag.Arg(1))
+		} else {
+			run, err = coupons.RunAllCouponExamples(ctx)
+		}
+		failed = err == nil && run.Failed > 0
+		result = run
+	case "campaign-report":
+		result, err = coupons.GetCampaignReport(ctx, argument())
+	case "applicable":
+		var request dtos.ApplicableCouponsRequest
+		err = json.NewDecoder(os.Stdin).Decode(&request)
+		if err != nil {
+			fail(err)
+		}
+		result, err = coupons.GetApplicableCoupons(ctx, request.Cart, request.Customer)
+	case "preview":
+		var request dtos.PreviewRequest
+		err = json.NewDecoder(os.Stdin).Decode(&request)
+		if err != nil {
+			fail(err)
+		}
+		result, err = coupons.PreviewCoupon(ctx, request)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown command:", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func argument() string {
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	return flag.Arg(1)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "couponctl:", err)
+	os.Exit(1)
+}
@@ -14,6 +14,24 @@ type Config struct {
 	Port        string `json:"port"`
 	DatabaseURL string `json:"database_url"`
 	LogLevel    string `json:"log_level"`
+	// How often every instance reloads all coupons, in case a change
+	// notification was missed. Defaults to 60 seconds.
+	CouponRefreshSeconds int `json:"coupon_refresh_seconds"`
+	// File the last known coupon snapshot is saved to for warm starts.
+	// Empty keeps the snapshot in memory only.
+	CouponSnapshotPath string `json:"coupon_snapshot_path"`
+	// Date the v1 API is switched off, sent in the Sunset header of v1
+	// responses, e.g. "Wed, 31 Dec 2025 23:59:59 GMT". Empty sends none.
+	V1SunsetDate string `json:"v1_sunset_date"`
+	// Locale of the coupon text served when none of the locales a client
+	// accepts has a translation. Defaults to "en".
+	DefaultLocale string `json:"default_locale"`
+	// Days deleted coupons are kept before they are purged, unless they were
+	// redeemed. Zero keeps them forever.
+	CouponRetentionDays int `json:"coupon_retention_days"`
+	// Key the gateway signs the identity headers of requests with. Requests
+	// that name an actor are rejected while it is empty.
+	IdentitySecret string `json:"identity_secret"`
 }
 
 func ParseJSON(r io.Reader, v any) error {
@@ -35,4 +53,7 @@ func Set(c *Config) {
 	if os.Getenv("DATABASE_URL") != "" {
 		conf.DatabaseURL = os.Getenv("DATABASE_URL")
 	}
+	if os.Getenv("IDENTITY_SECRET") != "" {
+		conf.IdentitySecret = os.Getenv("IDENTITY_SECRET")
+	}
 }
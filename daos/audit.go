@@ -0,0 +1,64 @@
+package daos
+
+import (
+	"time"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+)
+
+// AuditQuery selects a page of audit entries, newest first. Zero filters
+// match every entry.
+type AuditQuery struct {
+	CouponId  string
+	Actor     string
+	Action    string
+	RequestId string
+	// Entries made at or after Since and before Until
+	Since *time.Time
+	Until *time.Time
+	// ID of the last entry of the previous page
+	Before int64
+	Limit  int
+}
+
+func (c *Coupon) PersistCouponAuditEntry(ctx *context.Context, req *models.CouponAuditEntry) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) ListCouponAuditEntries(ctx *context.Context, query *AuditQuery) ([]*models.CouponAuditEntry, error) {
+	db := ctx.DB.Debug().Model(&models.CouponAuditEntry{})
+	if query.CouponId != "" {
+		db = db.Where("coupon_id = ?", query.CouponId)
+	}
+	if query.Actor != "" {
+		db = db.Where("actor = ?", query.Actor)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.RequestId != "" {
+		db = db.Where("request_id = ?", query.RequestId)
+	}
+	if query.Since != nil {
+		db = db.Where("created_at >= ?", *query.Since)
+	}
+	if query.Until != nil {
+		db = db.Where("created_at < ?", *query.Until)
+	}
+	if query.Before > 0 {
+		db = db.Where("id < ?", query.Before)
+	}
+
+	var entries []*models.CouponAuditEntry
+	err := db.Order("id DESC").Limit(query.Limit).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,198 @@
+package daos
+
+import (
+	"encoding/json"
+	"sync"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/google/uuid"
+)
+
+// CouponChangesChannel is the Postgres channel writers notify with a
+// CouponChange for every coupon they change
+const CouponChangesChannel = "coupon_changes"
+
+// InstanceID identifies this process in the coupon change notifications it
+// sends, so it can skip its own
+var InstanceID = uuid.New().String()
+
+// CouponChange is the payload of a notification on CouponChangesChannel
+type CouponChange struct {
+	// InstanceID of the process that made the change
+	Instance string `json:"instance"`
+	CouponID string `json:"coupon_id"`
+}
+
+// ParseCouponChange decodes the payload of a coupon change notification.
+// Instances that predate CouponChange send the bare coupon ID.
+func ParseCouponChange(payload string) CouponChange {
+	var change CouponChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil || change.CouponID == "" {
+		return CouponChange{CouponID: payload}
+	}
+	return change
+}
+
+// couponCache holds the rules of coupons read from the database. It is shared
+// by every request in the process.
+type couponCache struct {
+	mu sync.RWMutex
+	// Bumped on every invalidation, so a load that raced with an
+	// invalidation does not put stale rows back into the cache
+	generation uint64
+	// Set when rules holds every coupon in the database
+	complete bool
+	order    []string
+	rules    map[string]*models.CouponRules
+}
+
+var cache = &couponCache{
+	rules: make(map[string]*models.CouponRules),
+}
+
+// InvalidateCoupon drops a single coupon from the cache
+func InvalidateCoupon(couponId string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	cache.complete = false
+	delete(cache.rules, couponId)
+}
+
+// InvalidateCoupons drops every coupon from the cache
+func InvalidateCoupons() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	cache.complete = false
+	cache.order = nil
+	cache.rules = make(map[string]*models.CouponRules)
+}
+
+// CachedCoupon is a read-through cache in front of the coupon reads of a
+// repository. Writes go straight to the repository; writers are expected to call NotifyCouponChanged
+// in their transaction so every instance invalidates its cache.
+//
+// The models returned from the cache are shared and must not be modified.
+type CachedCoupon struct {
+	ICoupon
+}
+
+func NewCachedCoupon(coupons ICoupon) *CachedCoupon {
+	return &CachedCoupon{
+		ICoupon: coupons,
+	}
+}
+
+func (c *CachedCoupon) GetAllCoupons(ctx *context.Context) ([]*models.Coupon, error) {
+	cache.mu.RLock()
+	if cache.complete {
+		coupons := make([]*models.Coupon, 0, len(cache.order))
+		for _, id := range cache.order {
+			coupons = append(coupons, cache.rules[id].Coupon)
+		}
+		cache.mu.RUnlock()
+		return coupons, nil
+	}
+	generation := cache.generation
+	cache.mu.RUnlock()
+
+	coupons, err := c.ICoupon.GetAllCoupons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet, err := c.LoadRuleSet(ctx, coupons)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.generation == generation {
+		cache.complete = true
+		cache.order = ruleSet.IDs()
+		cache.rules = make(map[string]*models.CouponRules, len(ruleSet.Coupons))
+		for _, rules := range ruleSet.Coupons {
+			cache.rules[rules.Coupon.Id] = rules
+		}
+	}
+
+	return coupons, nil
+}
+
+func (c *CachedCoupon) GetCouponById(ctx *context.Context, id string) (*models.Coupon, error) {
+	cache.mu.RLock()
+	rules, ok := cache.rules[id]
+	cache.mu.RUnlock()
+	if ok {
+		return rules.Coupon, nil
+	}
+
+	return c.ICoupon.GetCouponById(ctx, id)
+}
+
+// LoadRuleSet returns the rules of the given coupons, loading the ones that
+// are not cached yet. Cached rules are only reused while the coupon row is
+// unchanged.
+func (c *CachedCoupon) LoadRuleSet(ctx *context.Context, coupons []*models.Coupon) (*models.RuleSet, error) {
+	couponRules := make([]*models.CouponRules, len(coupons))
+	var missing []*models.Coupon
+
+	cache.mu.RLock()
+	generation := cache.generation
+	for i, coupon := range coupons {
+		rules, ok := cache.rules[coupon.Id]
+		if ok && rules.Coupon.UpdatedAt.Equal(coupon.UpdatedAt) {
+			couponRules[i] = rules
+			continue
+		}
+		missing = append(missing, coupon)
+	}
+	cache.mu.RUnlock()
+
+	if len(missing) > 0 {
+		loaded, err := LoadRuleSet(ctx, c.ICoupon, missing)
+		if err != nil {
+			return nil, err
+		}
+		for i, coupon := range coupons {
+			if couponRules[i] == nil {
+				couponRules[i] = loaded.Get(coupon.Id)
+			}
+		}
+
+		cache.mu.Lock()
+		if cache.generation == generation {
+			for _, rules := range loaded.Coupons {
+				if _, ok := cache.rules[rules.Coupon.Id]; !ok && cache.complete {
+					// Only coupons already listed belong in a complete cache
+					continue
+				}
+				cache.rules[rules.Coupon.Id] = rules
+			}
+		}
+		cache.mu.Unlock()
+	}
+
+	return models.NewRuleSetFromRules(couponRules), nil
+}
+
+// NotifyCouponChanged queues a notification on CouponChangesChannel. Postgres
+// delivers it to every listening instance when the transaction commits, and
+// drops it if the transaction rolls back.
+func (c *Coupon) NotifyCouponChanged(ctx *context.Context, couponId string) error {
+	payload, err := json.Marshal(CouponChange{Instance: InstanceID, CouponID: couponId})
+	if err != nil {
+		return err
+	}
+	err = ctx.Transaction.Debug().Exec("SELECT pg_notify(?, ?)", CouponChangesChannel, string(payload)).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package daos
+
+import (
+	"encoding/json"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CampaignEventsChannel is the Postgres channel every budget threshold a
+// campaign crosses is announced on, as a JSON models.CampaignEvent
+const CampaignEventsChannel = "campaign_events"
+
+func (c *Coupon) PersistCampaign(ctx *context.Context, req *models.Campaign) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// PersistCampaignEvent records the event unless the campaign fired its
+// threshold already, and reports whether it did
+func (c *Coupon) PersistCampaignEvent(ctx *context.Context, req *models.CampaignEvent) (bool, error) {
+	result := ctx.Transaction.Debug().Clauses(clause.OnConflict{DoNothing: true}).Create(req)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (c *Coupon) GetCampaign(ctx *context.Context, id string) (*models.Campaign, error) {
+	var campaign models.Campaign
+	err := ctx.DB.Debug().Where("id = ?", id).First(&campaign).Error
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (c *Coupon) GetCampaigns(ctx *context.Context, ids []string) ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
+	err := ctx.DB.Debug().Where("id = ANY(?)", pq.StringArray(ids)).Find(&campaigns).Error
+	if err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ListCampaigns returns every campaign, in the order they were created
+func (c *Coupon) ListCampaigns(ctx *context.Context) ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
+	err := ctx.DB.Debug().Order("created_at, id").Find(&campaigns).Error
+	if err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ListCampaignEvents returns the thresholds a campaign crossed, lowest first
+func (c *Coupon) ListCampaignEvents(ctx *context.Context, campaignId string) ([]*models.CampaignEvent, error) {
+	var events []*models.CampaignEvent
+	err := ctx.DB.Debug().Where("campaign_id = ?", campaignId).Order("threshold").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListCampaignCoupons returns the coupons of a campaign that are not deleted,
+// the oldest first
+func (c *Coupon) ListCampaignCoupons(ctx *context.Context, campaignId string) ([]*models.Coupon, error) {
+	var coupons []*models.Coupon
+	err := ctx.DB.Debug().Where("campaign_id = ? AND deleted_at IS NULL", campaignId).Order("created_at, id").Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+// UpdateCampaign changes the name, dates and budget of a campaign, leaving
+// what was spent as it is. It returns gorm.ErrRecordNotFound if there is no
+// such campaign.
+func (c *Coupon) UpdateCampaign(ctx *context.Context, req *models.Campaign) error {
+	result := ctx.Transaction.Debug().Model(&models.Campaign{}).
+		Where("id = ?", req.Id).
+		Updates(map[string]interface{}{
+			"name":       req.Name,
+			"starts_at":  req.StartsAt,
+			"ends_at":    req.EndsAt,
+			"budget":     req.Budget,
+			"updated_at": req.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SpendCampaignBudget adds amount to what a campaign spent, as long as what
+// is left of its budget covers it, and returns the campaign as it is then.
+// The row lock taken by the update serializes concurrent redemptions, so
+// together they never spend more than the budget. It returns
+// gorm.ErrRecordNotFound if the budget does not cover amount or there is no
+// such campaign.
+func (c *Coupon) SpendCampaignBudget(ctx *context.Context, campaignId string, amount float64) (*models.Campaign, error) {
+	var campaign models.Campaign
+	result := ctx.Transaction.Debug().Model(&campaign).Clauses(clause.Returning{}).
+		Where("id = ? AND spent + ? <= budget", campaignId, amount).
+		Update("spent", gorm.Expr("spent + ?", amount))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &campaign, nil
+}
+
+// NotifyCampaignEvent queues a notification on CampaignEventsChannel, sent
+// when the transaction commits
+func (c *Coupon) NotifyCampaignEvent(ctx *context.Context, event *models.CampaignEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	err = ctx.Transaction.Debug().Exec("SELECT pg_notify(?, ?)", CampaignEventsChannel, string(payload)).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListCampaignSpend sums the redemptions that drew from a campaign by coupon
+func (c *Customer) ListCampaignSpend(ctx *context.Context, campaignId string) ([]*models.CampaignCouponSpend, error) {
+	var spend []*models.CampaignCouponSpend
+	err := ctx.DB.Debug().Model(&models.Redemption{}).
+		Select("coupon_id, COUNT(*) AS redemptions, COALESCE(SUM(discount), 0) AS discount").
+		Where("campaign_id = ?", campaignId).
+		Group("coupon_id").
+		Order("coupon_id").
+		Scan(&spend).Error
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
@@ -1,11 +1,18 @@
 package daos
 
 import (
+	"database/sql"
+	"time"
+
 	"monk-commerce-assignment/models"
 	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Coupon struct {
+	gormTransactor
 }
 
 func NewCoupon() ICoupon {
@@ -13,25 +20,100 @@ func NewCoupon() ICoupon {
 }
 
 type ICoupon interface {
+	Transactor
 	PersistCoupon(ctx *context.Context, req *models.Coupon) error
 	PersistCartWiseCoupon(ctx *context.Context, req *models.CartWiseCoupon) error
 	PersistProductWiseCoupon(ctx *context.Context, req *models.ProductWiseCoupon) error
 	PersistBxGyCoupon(ctx *context.Context, req *models.BxGyCoupon) error
 	PersistBxGyBuyCoupon(ctx *context.Context, req *models.BxGyBuyProduct) error
 	PersistBxGyGetCoupon(ctx *context.Context, req *models.BxGyGetProduct) error
+	PersistVolumePricingCoupon(ctx *context.Context, req *models.VolumePricingCoupon) error
+	PersistVolumePricingProduct(ctx *context.Context, req *models.VolumePricingProduct) error
+	PersistVolumePricingBand(ctx *context.Context, req *models.VolumePricingBand) error
+	PersistFixedPriceCoupon(ctx *context.Context, req *models.FixedPriceCoupon) error
+	PersistFixedPriceProduct(ctx *context.Context, req *models.FixedPriceProduct) error
+	PersistShippingCoupon(ctx *context.Context, req *models.ShippingCoupon) error
+	PersistShippingCouponMethod(ctx *context.Context, req *models.ShippingCouponMethod) error
+	PersistCouponEligibility(ctx *context.Context, req *models.CouponEligibility) error
+	PersistCouponEligibilitySegment(ctx *context.Context, req *models.CouponEligibilitySegment) error
+	PersistCouponTranslation(ctx *context.Context, req *models.CouponTranslation) error
+	PersistCouponTag(ctx *context.Context, req *models.CouponTag) error
+	PersistCouponAuditEntry(ctx *context.Context, req *models.CouponAuditEntry) error
+	PersistCouponRevision(ctx *context.Context, req *models.CouponRevision) error
+	PersistCouponExample(ctx *context.Context, req *models.CouponExample) error
+	PersistCampaign(ctx *context.Context, req *models.Campaign) error
+	PersistCampaignEvent(ctx *context.Context, req *models.CampaignEvent) (bool, error)
 	GetAllCoupons(ctx *context.Context) ([]*models.Coupon, error)
+	ListPurgeableCoupons(ctx *context.Context, deletedBefore time.Time, limit int) ([]*models.Coupon, error)
+	LockPurgeableCoupon(ctx *context.Context, couponId string, deletedBefore time.Time) error
+	LockCouponCampaign(ctx *context.Context, couponId string) (string, error)
+	ListCoupons(ctx *context.Context, query *CouponQuery) ([]*models.Coupon, error)
+	ListCouponAuditEntries(ctx *context.Context, query *AuditQuery) ([]*models.CouponAuditEntry, error)
 	GetCartWiseCoupon(ctx *context.Context, couponId string) (*models.CartWiseCoupon, error)
 	GetProductWiseCoupon(ctx *context.Context, couponId string) (*models.ProductWiseCoupon, error)
 	GetBxGyCoupon(ctx *context.Context, couponId string) (*models.BxGyCoupon, error)
 	GetBxGyBuyProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyBuyProduct, error)
 	GetBxGyGetProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyGetProduct, error)
+	GetVolumePricingCoupon(ctx *context.Context, couponId string) (*models.VolumePricingCoupon, error)
+	GetVolumePricingProducts(ctx *context.Context, couponId string) ([]*models.VolumePricingProduct, error)
+	GetVolumePricingBands(ctx *context.Context, couponId string) ([]*models.VolumePricingBand, error)
+	GetFixedPriceCoupon(ctx *context.Context, couponId string) (*models.FixedPriceCoupon, error)
+	GetFixedPriceProducts(ctx *context.Context, couponId string) ([]*models.FixedPriceProduct, error)
+	GetShippingCoupon(ctx *context.Context, couponId string) (*models.ShippingCoupon, error)
+	GetShippingCouponMethods(ctx *context.Context, couponId string) ([]*models.ShippingCouponMethod, error)
+	GetCouponEligibility(ctx *context.Context, couponId string) (*models.CouponEligibility, error)
+	GetCouponEligibilitySegments(ctx *context.Context, couponId string) ([]*models.CouponEligibilitySegment, error)
+	GetCartWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.CartWiseCoupon, error)
+	GetProductWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.ProductWiseCoupon, error)
+	GetBxGyCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyCoupon, error)
+	GetBxGyBuyProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyBuyProduct, error)
+	GetBxGyGetProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyGetProduct, error)
+	GetVolumePricingCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingCoupon, error)
+	GetVolumePricingProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingProduct, error)
+	GetVolumePricingBandsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingBand, error)
+	GetFixedPriceCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceCoupon, error)
+	GetFixedPriceProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceProduct, error)
+	GetShippingCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCoupon, error)
+	GetShippingCouponMethodsByCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCouponMethod, error)
+	GetCouponEligibilities(ctx *context.Context, couponIds []string) ([]*models.CouponEligibility, error)
+	GetCouponEligibilitySegmentsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponEligibilitySegment, error)
+	GetCouponTranslationsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTranslation, error)
+	GetCouponTagsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTag, error)
 	GetCouponById(ctx *context.Context, id string) (*models.Coupon, error)
+	GetPendingCouponRevision(ctx *context.Context, couponId string) (*models.CouponRevision, error)
+	ListCouponExamples(ctx *context.Context, couponId string) ([]*models.CouponExample, error)
+	GetCouponExample(ctx *context.Context, couponId string, exampleId string) (*models.CouponExample, error)
+	GetCampaign(ctx *context.Context, id string) (*models.Campaign, error)
+	GetCampaigns(ctx *context.Context, ids []string) ([]*models.Campaign, error)
+	ListCampaigns(ctx *context.Context) ([]*models.Campaign, error)
+	ListCampaignEvents(ctx *context.Context, campaignId string) ([]*models.CampaignEvent, error)
+	ListCampaignCoupons(ctx *context.Context, campaignId string) ([]*models.Coupon, error)
+	UpdateCoupon(ctx *context.Context, req *models.Coupon, status string) error
+	UpdateCouponRevision(ctx *context.Context, req *models.CouponRevision, status string) error
+	UpdateCouponExample(ctx *context.Context, req *models.CouponExample) error
+	UpdateCampaign(ctx *context.Context, req *models.Campaign) error
+	SpendCampaignBudget(ctx *context.Context, campaignId string, amount float64) (*models.Campaign, error)
+	UpdateCouponDeletedAt(ctx *context.Context, couponId string, deletedAt *time.Time, updatedAt time.Time) error
 	DeleteCoupon(ctx *context.Context, couponId string) error
 	DeleteCartWiseCoupon(ctx *context.Context, couponId string) error
 	DeleteProductWiseCoupon(ctx *context.Context, couponId string) error
 	DeleteBxGyCoupon(ctx *context.Context, couponId string) error
 	DeleteBxGyBuyProducts(ctx *context.Context, couponId string) error
 	DeleteBxGyGetProducts(ctx *context.Context, couponId string) error
+	DeleteVolumePricingCoupon(ctx *context.Context, couponId string) error
+	DeleteVolumePricingProducts(ctx *context.Context, couponId string) error
+	DeleteVolumePricingBands(ctx *context.Context, couponId string) error
+	DeleteFixedPriceCoupon(ctx *context.Context, couponId string) error
+	DeleteFixedPriceProducts(ctx *context.Context, couponId string) error
+	DeleteShippingCoupon(ctx *context.Context, couponId string) error
+	DeleteShippingCouponMethods(ctx *context.Context, couponId string) error
+	DeleteCouponEligibility(ctx *context.Context, couponId string) error
+	DeleteCouponEligibilitySegments(ctx *context.Context, couponId string) error
+	DeleteCouponTranslations(ctx *context.Context, couponId string) error
+	DeleteCouponTags(ctx *context.Context, couponId string) error
+	DeleteCouponExample(ctx *context.Context, couponId string, exampleId string) error
+	NotifyCouponChanged(ctx *context.Context, couponId string) error
+	NotifyCampaignEvent(ctx *context.Context, event *models.CampaignEvent) error
 }
 
 func (c *Coupon) PersistCoupon(ctx *context.Context, req *models.Coupon) error {
@@ -43,6 +125,14 @@ func (c *Coupon) PersistCoupon(ctx *context.Context, req *models.Coupon) error {
 	return nil
 }
 
+func (c *Coupon) PersistCouponRevision(ctx *context.Context, req *models.CouponRevision) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *Coupon) PersistCartWiseCoupon(ctx *context.Context, req *models.CartWiseCoupon) error {
 	err := ctx.Transaction.Debug().Create(req).Error
 	if err != nil {
@@ -88,9 +178,210 @@ func (c *Coupon) PersistBxGyGetCoupon(ctx *context.Context, req *models.BxGyGetP
 	return nil
 }
 
+func (c *Coupon) PersistVolumePricingCoupon(ctx *context.Context, req *models.VolumePricingCoupon) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistVolumePricingProduct(ctx *context.Context, req *models.VolumePricingProduct) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistVolumePricingBand(ctx *context.Context, req *models.VolumePricingBand) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistFixedPriceCoupon(ctx *context.Context, req *models.FixedPriceCoupon) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistFixedPriceProduct(ctx *context.Context, req *models.FixedPriceProduct) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistShippingCoupon(ctx *context.Context, req *models.ShippingCoupon) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistShippingCouponMethod(ctx *context.Context, req *models.ShippingCouponMethod) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistCouponEligibility(ctx *context.Context, req *models.CouponEligibility) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistCouponEligibilitySegment(ctx *context.Context, req *models.CouponEligibilitySegment) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistCouponTranslation(ctx *context.Context, req *models.CouponTranslation) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Coupon) PersistCouponTag(ctx *context.Context, req *models.CouponTag) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAllCoupons returns the coupons that are not deleted
 func (c *Coupon) GetAllCoupons(ctx *context.Context) ([]*models.Coupon, error) {
 	var coupons []*models.Coupon
-	err := ctx.DB.Debug().Find(&coupons).Error
+	err := ctx.DB.Debug().Where("deleted_at IS NULL").Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+// ListPurgeableCoupons returns coupons deleted before the given time that
+// were never redeemed, the ones deleted first first
+func (c *Coupon) ListPurgeableCoupons(ctx *context.Context, deletedBefore time.Time, limit int) ([]*models.Coupon, error) {
+	var coupons []*models.Coupon
+	err := ctx.DB.Debug().
+		Where("deleted_at < ?", deletedBefore).
+		Where("NOT EXISTS (SELECT 1 FROM redemptions WHERE redemptions.coupon_id = coupons.id)").
+		Order("deleted_at, id").Limit(limit).Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+// LockPurgeableCoupon locks a coupon for purging, it must run in a
+// transaction. It returns gorm.ErrRecordNotFound if the coupon is no longer
+// purgeable, e.g. because it was restored or redeemed in the meantime. The
+// lock holds off new redemptions until the transaction ends.
+func (c *Coupon) LockPurgeableCoupon(ctx *context.Context, couponId string, deletedBefore time.Time) error {
+	var coupon models.Coupon
+	err := ctx.Transaction.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND deleted_at < ?", couponId, deletedBefore).
+		Where("NOT EXISTS (SELECT 1 FROM redemptions WHERE redemptions.coupon_id = coupons.id)").
+		First(&coupon).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LockCouponCampaign returns the campaign of a coupon, empty if it is in
+// none, and locks the coupon so it stays in the campaign until the
+// transaction ends, it must run in a transaction
+func (c *Coupon) LockCouponCampaign(ctx *context.Context, couponId string) (string, error) {
+	var coupon models.Coupon
+	err := ctx.Transaction.Debug().Clauses(clause.Locking{Strength: "SHARE"}).
+		Select("id", "campaign_id").
+		Where("id = ?", couponId).
+		First(&coupon).Error
+	if err != nil {
+		return "", err
+	}
+	if coupon.CampaignID == nil {
+		return "", nil
+	}
+	return *coupon.CampaignID, nil
+}
+
+func (c *Coupon) ListCoupons(ctx *context.Context, query *CouponQuery) ([]*models.Coupon, error) {
+	db := ctx.DB.Debug().Model(&models.Coupon{})
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.Active != nil {
+		db = db.Where("is_active = ?", *query.Active)
+	}
+	switch query.State {
+	case models.CouponScheduled:
+		db = db.Where("starts_at > ?", query.Now)
+	case models.CouponLive:
+		db = db.Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", query.Now, query.Now)
+	case models.CouponExpired:
+		db = db.Where("ends_at <= ?", query.Now)
+	}
+	if query.ProductId != "" {
+		db = db.Where(`id IN (
+			SELECT coupon_id FROM product_wise_coupons WHERE product_id = @product
+			UNION SELECT bx_gy_coupon_id FROM bx_gy_buy_products WHERE product_id = @product
+			UNION SELECT bx_gy_coupon_id FROM bx_gy_get_products WHERE product_id = @product
+			UNION SELECT volume_pricing_coupon_id FROM volume_pricing_products WHERE product_id = @product
+			UNION SELECT fixed_price_coupon_id FROM fixed_price_products WHERE product_id = @product
+		)`, sql.Named("product", query.ProductId))
+	}
+	if query.CodePrefix != "" {
+		db = db.Where("code LIKE ?", escapeLike(query.CodePrefix)+"%")
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Deleted {
+		db = db.Where("deleted_at IS NOT NULL")
+	} else {
+		db = db.Where("deleted_at IS NULL")
+	}
+
+	order, after := "created_at, id", "(created_at, id) > (?, ?)"
+	if query.Descending {
+		order, after = "created_at DESC, id DESC", "(created_at, id) < (?, ?)"
+	}
+	if query.After != nil {
+		db = db.Where(after, query.After.CreatedAt, query.After.Id)
+	}
+
+	var coupons []*models.Coupon
+	err := db.Order(order).Limit(query.Limit).Find(&coupons).Error
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +417,7 @@ func (c *Coupon) GetBxGyCoupon(ctx *context.Context, couponId string) (*models.B
 
 func (c *Coupon) GetBxGyBuyProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyBuyProduct, error) {
 	var buyProducts []*models.BxGyBuyProduct
-	err := ctx.DB.Debug().Where("bxgy_coupon_id = ?", bxgyCouponId).Find(&buyProducts).Error
+	err := ctx.DB.Debug().Where("bx_gy_coupon_id = ?", bxgyCouponId).Find(&buyProducts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -135,13 +426,98 @@ func (c *Coupon) GetBxGyBuyProducts(ctx *context.Context, bxgyCouponId string) (
 
 func (c *Coupon) GetBxGyGetProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyGetProduct, error) {
 	var getProducts []*models.BxGyGetProduct
-	err := ctx.DB.Debug().Where("bxgy_coupon_id = ?", bxgyCouponId).Find(&getProducts).Error
+	err := ctx.DB.Debug().Where("bx_gy_coupon_id = ?", bxgyCouponId).Find(&getProducts).Error
 	if err != nil {
 		return nil, err
 	}
 	return getProducts, nil
 }
 
+func (c *Coupon) GetVolumePricingCoupon(ctx *context.Context, couponId string) (*models.VolumePricingCoupon, error) {
+	var volumeCoupon models.VolumePricingCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).First(&volumeCoupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &volumeCoupon, nil
+}
+
+func (c *Coupon) GetVolumePricingProducts(ctx *context.Context, couponId string) ([]*models.VolumePricingProduct, error) {
+	var products []*models.VolumePricingProduct
+	err := ctx.DB.Debug().Where("volume_pricing_coupon_id = ?", couponId).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (c *Coupon) GetVolumePricingBands(ctx *context.Context, couponId string) ([]*models.VolumePricingBand, error) {
+	var bands []*models.VolumePricingBand
+	err := ctx.DB.Debug().Where("volume_pricing_coupon_id = ?", couponId).Order("min_quantity").Find(&bands).Error
+	if err != nil {
+		return nil, err
+	}
+	return bands, nil
+}
+
+func (c *Coupon) GetFixedPriceCoupon(ctx *context.Context, couponId string) (*models.FixedPriceCoupon, error) {
+	var fixedPriceCoupon models.FixedPriceCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).First(&fixedPriceCoupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fixedPriceCoupon, nil
+}
+
+func (c *Coupon) GetFixedPriceProducts(ctx *context.Context, couponId string) ([]*models.FixedPriceProduct, error) {
+	var products []*models.FixedPriceProduct
+	err := ctx.DB.Debug().Where("fixed_price_coupon_id = ?", couponId).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (c *Coupon) GetShippingCoupon(ctx *context.Context, couponId string) (*models.ShippingCoupon, error) {
+	var shippingCoupon models.ShippingCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).First(&shippingCoupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shippingCoupon, nil
+}
+
+func (c *Coupon) GetShippingCouponMethods(ctx *context.Context, couponId string) ([]*models.ShippingCouponMethod, error) {
+	var methods []*models.ShippingCouponMethod
+	err := ctx.DB.Debug().Where("shipping_coupon_id = ?", couponId).Find(&methods).Error
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// GetCouponEligibility returns nil when the coupon has no eligibility conditions
+func (c *Coupon) GetCouponEligibility(ctx *context.Context, couponId string) (*models.CouponEligibility, error) {
+	var eligibilities []*models.CouponEligibility
+	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).Limit(1).Find(&eligibilities).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(eligibilities) == 0 {
+		return nil, nil
+	}
+	return eligibilities[0], nil
+}
+
+func (c *Coupon) GetCouponEligibilitySegments(ctx *context.Context, couponId string) ([]*models.CouponEligibilitySegment, error) {
+	var segments []*models.CouponEligibilitySegment
+	err := ctx.DB.Debug().Where("coupon_eligibility_id = ?", couponId).Find(&segments).Error
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
 func (c *Coupon) GetCouponById(ctx *context.Context, id string) (*models.Coupon, error) {
 	var coupon models.Coupon
 	err := ctx.DB.Debug().Where("id = ?", id).First(&coupon).Error
@@ -151,9 +527,96 @@ func (c *Coupon) GetCouponById(ctx *context.Context, id string) (*models.Coupon,
 	return &coupon, nil
 }
 
+// GetPendingCouponRevision returns the revision of a coupon waiting for
+// review, or nil if there is none
+func (c *Coupon) GetPendingCouponRevision(ctx *context.Context, couponId string) (*models.CouponRevision, error) {
+	var revisions []*models.CouponRevision
+	err := ctx.DB.Debug().Where("coupon_id = ? AND status = ?", couponId, models.StatusPendingApproval).
+		Limit(1).Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return revisions[0], nil
+}
+
+// UpdateCoupon writes the definition and the workflow fields of a coupon, if
+// it still has the given status. It returns gorm.ErrRecordNotFound if it does
+// not, e.g. because another request approved it in the meantime.
+func (c *Coupon) UpdateCoupon(ctx *context.Context, req *models.Coupon, status string) error {
+	result := ctx.Transaction.Debug().Model(&models.Coupon{}).
+		Where("id = ? AND status = ?", req.Id, status).
+		Updates(map[string]interface{}{
+			"type":             req.Type,
+			"code":             req.Code,
+			"is_active":        req.IsActive,
+			"condition":        req.Condition,
+			"metadata":         req.Metadata,
+			"starts_at":        req.StartsAt,
+			"ends_at":          req.EndsAt,
+			"updated_at":       req.UpdatedAt,
+			"status":           req.Status,
+			"edited_by":        req.EditedBy,
+			"submitted_by":     req.SubmittedBy,
+			"approved_by":      req.ApprovedBy,
+			"approved_at":      req.ApprovedAt,
+			"rejection_reason": req.RejectionReason,
+			"campaign_id":      req.CampaignID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateCouponRevision records the review of a revision, if it still has the
+// given status. It returns gorm.ErrRecordNotFound if it does not.
+func (c *Coupon) UpdateCouponRevision(ctx *context.Context, req *models.CouponRevision, status string) error {
+	result := ctx.Transaction.Debug().Model(&models.CouponRevision{}).
+		Where("id = ? AND status = ?", req.Id, status).
+		Updates(map[string]interface{}{
+			"status":      req.Status,
+			"reviewed_by": req.ReviewedBy,
+			"reason":      req.Reason,
+			"reviewed_at": req.ReviewedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateCouponDeletedAt deletes a coupon, or restores it when deletedAt is
+// nil. It returns gorm.ErrRecordNotFound if the coupon is already deleted, or
+// not deleted when restoring, e.g. because another request got there first.
+func (c *Coupon) UpdateCouponDeletedAt(ctx *context.Context, couponId string, deletedAt *time.Time, updatedAt time.Time) error {
+	query := ctx.Transaction.Debug().Model(&models.Coupon{}).Where("id = ?", couponId)
+	if deletedAt != nil {
+		query = query.Where("deleted_at IS NULL")
+	} else {
+		query = query.Where("deleted_at IS NOT NULL")
+	}
+	result := query.Updates(map[string]interface{}{"deleted_at": deletedAt, "updated_at": updatedAt})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (c *Coupon) DeleteCoupon(ctx *context.Context, couponId string) error {
 	// Delete the main coupon record
-	err := ctx.DB.Debug().Where("id = ?", couponId).Delete(&models.Coupon{}).Error
+	err := ctx.Transaction.Debug().Where("id = ?", couponId).Delete(&models.Coupon{}).Error
 	if err != nil {
 		return err
 	}
@@ -162,7 +625,7 @@ func (c *Coupon) DeleteCoupon(ctx *context.Context, couponId string) error {
 
 func (c *Coupon) DeleteCartWiseCoupon(ctx *context.Context, couponId string) error {
 	// Delete the cart-wise coupon entry
-	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).Delete(&models.CartWiseCoupon{}).Error
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.CartWiseCoupon{}).Error
 	if err != nil {
 		return err
 	}
@@ -171,7 +634,7 @@ func (c *Coupon) DeleteCartWiseCoupon(ctx *context.Context, couponId string) err
 
 func (c *Coupon) DeleteProductWiseCoupon(ctx *context.Context, couponId string) error {
 	// Delete the product-wise coupon entry
-	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).Delete(&models.ProductWiseCoupon{}).Error
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.ProductWiseCoupon{}).Error
 	if err != nil {
 		return err
 	}
@@ -180,7 +643,7 @@ func (c *Coupon) DeleteProductWiseCoupon(ctx *context.Context, couponId string)
 
 func (c *Coupon) DeleteBxGyCoupon(ctx *context.Context, couponId string) error {
 	// Delete the main BxGy coupon entry
-	err := ctx.DB.Debug().Where("coupon_id = ?", couponId).Delete(&models.BxGyCoupon{}).Error
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.BxGyCoupon{}).Error
 	if err != nil {
 		return err
 	}
@@ -189,7 +652,7 @@ func (c *Coupon) DeleteBxGyCoupon(ctx *context.Context, couponId string) error {
 
 func (c *Coupon) DeleteBxGyBuyProducts(ctx *context.Context, couponId string) error {
 	// Delete BxGy buy products related to the coupon
-	err := ctx.DB.Debug().Where("bxgy_coupon_id = ?", couponId).Delete(&models.BxGyBuyProduct{}).Error
+	err := ctx.Transaction.Debug().Where("bx_gy_coupon_id = ?", couponId).Delete(&models.BxGyBuyProduct{}).Error
 	if err != nil {
 		return err
 	}
@@ -198,7 +661,106 @@ func (c *Coupon) DeleteBxGyBuyProducts(ctx *context.Context, couponId string) er
 
 func (c *Coupon) DeleteBxGyGetProducts(ctx *context.Context, couponId string) error {
 	// Delete BxGy get products related to the coupon
-	err := ctx.DB.Debug().Where("bxgy_coupon_id = ?", couponId).Delete(&models.BxGyGetProduct{}).Error
+	err := ctx.Transaction.Debug().Where("bx_gy_coupon_id = ?", couponId).Delete(&models.BxGyGetProduct{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteVolumePricingCoupon(ctx *context.Context, couponId string) error {
+	// Delete the volume pricing coupon entry
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.VolumePricingCoupon{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteVolumePricingProducts(ctx *context.Context, couponId string) error {
+	// Delete the products covered by the volume pricing coupon
+	err := ctx.Transaction.Debug().Where("volume_pricing_coupon_id = ?", couponId).Delete(&models.VolumePricingProduct{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteVolumePricingBands(ctx *context.Context, couponId string) error {
+	// Delete the quantity bands of the volume pricing coupon
+	err := ctx.Transaction.Debug().Where("volume_pricing_coupon_id = ?", couponId).Delete(&models.VolumePricingBand{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteFixedPriceCoupon(ctx *context.Context, couponId string) error {
+	// Delete the fixed price coupon entry
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.FixedPriceCoupon{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteFixedPriceProducts(ctx *context.Context, couponId string) error {
+	// Delete the override prices of the fixed price coupon
+	err := ctx.Transaction.Debug().Where("fixed_price_coupon_id = ?", couponId).Delete(&models.FixedPriceProduct{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteShippingCoupon(ctx *context.Context, couponId string) error {
+	// Delete the shipping coupon entry
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.ShippingCoupon{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteShippingCouponMethods(ctx *context.Context, couponId string) error {
+	// Delete the shipping methods the coupon is restricted to
+	err := ctx.Transaction.Debug().Where("shipping_coupon_id = ?", couponId).Delete(&models.ShippingCouponMethod{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteCouponEligibility(ctx *context.Context, couponId string) error {
+	// Delete the eligibility conditions of the coupon
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.CouponEligibility{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteCouponEligibilitySegments(ctx *context.Context, couponId string) error {
+	// Delete the customer segments the coupon is restricted to
+	err := ctx.Transaction.Debug().Where("coupon_eligibility_id = ?", couponId).Delete(&models.CouponEligibilitySegment{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteCouponTranslations(ctx *context.Context, couponId string) error {
+	// Delete the display text of the coupon in every locale
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.CouponTranslation{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Coupon) DeleteCouponTags(ctx *context.Context, couponId string) error {
+	// Delete the tags of the coupon
+	err := ctx.Transaction.Debug().Where("coupon_id = ?", couponId).Delete(&models.CouponTag{}).Error
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,38 @@
+package daos
+
+import (
+	"strings"
+	"time"
+)
+
+// CouponQuery selects a page of coupons. Pages are ordered by (created_at, id)
+// and zero filters match every coupon.
+type CouponQuery struct {
+	Type   string
+	Active *bool
+	// One of models.CouponScheduled, CouponLive or CouponExpired, evaluated at Now
+	State     string
+	Now       time.Time
+	ProductId string
+	// Matches codes starting with the prefix, codes are stored in upper case
+	CodePrefix string
+	// One of the models.Status constants
+	Status string
+	// Selects deleted coupons instead of the others
+	Deleted    bool
+	Descending bool
+	// Position of the last coupon of the previous page
+	After *CouponCursor
+	Limit int
+}
+
+// CouponCursor is the position of a coupon in the listing order
+type CouponCursor struct {
+	CreatedAt time.Time
+	Id        string
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,72 @@
+package daos
+
+import (
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Customer struct {
+	gormTransactor
+}
+
+func NewCustomer() ICustomer {
+	return &Customer{}
+}
+
+type ICustomer interface {
+	Transactor
+	PersistCustomerOrder(ctx *context.Context, req *models.CustomerOrder) error
+	PersistRedemption(ctx *context.Context, req *models.Redemption) error
+	GetCustomerHistory(ctx *context.Context, customerId string) (*models.CustomerHistory, error)
+	ListCampaignSpend(ctx *context.Context, campaignId string) ([]*models.CampaignCouponSpend, error)
+}
+
+// PersistCustomerOrder records an order in the order history. An order is
+// kept once however many coupons are redeemed on it: recording it again adds
+// the discount to the one already recorded. The total and the coupon recorded
+// first are kept. It returns gorm.ErrRecordNotFound if the order is recorded
+// for another customer.
+func (c *Customer) PersistCustomerOrder(ctx *context.Context, req *models.CustomerOrder) error {
+	result := ctx.Transaction.Debug().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "order_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"discount":  gorm.Expr("customer_orders.discount + EXCLUDED.discount"),
+			"coupon_id": gorm.Expr("COALESCE(customer_orders.coupon_id, EXCLUDED.coupon_id)"),
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{gorm.Expr("customer_orders.customer_id = EXCLUDED.customer_id")}},
+	}).Create(req)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (c *Customer) PersistRedemption(ctx *context.Context, req *models.Redemption) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Customer) GetCustomerHistory(ctx *context.Context, customerId string) (*models.CustomerHistory, error) {
+	history := models.CustomerHistory{
+		CustomerID: customerId,
+	}
+	err := ctx.DB.Debug().Model(&models.CustomerOrder{}).
+		Select("COUNT(*) AS order_count, COALESCE(SUM(total), 0) AS lifetime_spend, MAX(created_at) AS last_order_at").
+		Where("customer_id = ?", customerId).
+		Scan(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
@@ -0,0 +1,74 @@
+package daos
+
+import (
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+)
+
+func (c *Coupon) PersistCouponExample(ctx *context.Context, req *models.CouponExample) error {
+	err := ctx.Transaction.Debug().Create(req).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListCouponExamples returns the examples of a coupon, or of every coupon
+// when couponId is empty, by coupon and name
+func (c *Coupon) ListCouponExamples(ctx *context.Context, couponId string) ([]*models.CouponExample, error) {
+	db := ctx.DB.Debug()
+	if couponId != "" {
+		db = db.Where("coupon_id = ?", couponId)
+	}
+
+	var examples []*models.CouponExample
+	err := db.Order("coupon_id, name").Find(&examples).Error
+	if err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
+
+func (c *Coupon) GetCouponExample(ctx *context.Context, couponId string, exampleId string) (*models.CouponExample, error) {
+	var example models.CouponExample
+	err := ctx.DB.Debug().Where("id = ? AND coupon_id = ?", exampleId, couponId).First(&example).Error
+	if err != nil {
+		return nil, err
+	}
+	return &example, nil
+}
+
+// UpdateCouponExample replaces the name, cart, customer and expected outcome
+// of an example. It returns gorm.ErrRecordNotFound if there is no such example.
+func (c *Coupon) UpdateCouponExample(ctx *context.Context, req *models.CouponExample) error {
+	result := ctx.Transaction.Debug().Model(&models.CouponExample{}).
+		Where("id = ? AND coupon_id = ?", req.Id, req.CouponID).
+		Updates(map[string]interface{}{
+			"name":       req.Name,
+			"cart":       req.Cart,
+			"customer":   req.Customer,
+			"expected":   req.Expected,
+			"updated_at": req.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteCouponExample returns gorm.ErrRecordNotFound if there is no such example
+func (c *Coupon) DeleteCouponExample(ctx *context.Context, couponId string, exampleId string) error {
+	result := ctx.Transaction.Debug().Where("id = ? AND coupon_id = ?", exampleId, couponId).Delete(&models.CouponExample{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
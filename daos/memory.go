@@ -0,0 +1,473 @@
+package daos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var errNoTransaction = errors.New("no transaction in progress")
+
+// memoryTable holds the rows of one table, grouped by the ID of the row they
+// belong to (a coupon for most tables). It mirrors the constraints of the
+// Postgres schema: a primary key within each group, a foreign key to the
+// parent table and ON DELETE CASCADE to the child tables.
+type memoryTable[T any] struct {
+	name string
+	rows map[string][]*T
+	// Identifies a row within its group, empty for tables with one row per group
+	key      func(row *T) string
+	parent   memoryParent
+	children []memoryChild
+}
+
+type memoryParent interface {
+	has(id string) bool
+}
+
+type memoryChild interface {
+	remove(id string) (undo func())
+}
+
+// memoryRows is a table of any row type, so the store can copy all of them
+type memoryRows interface {
+	tableName() string
+	// copyTo replaces the rows of the table of the same name in c
+	copyTo(c *MemoryStore)
+}
+
+// constraintError builds the error Postgres reports for a violated constraint,
+// so callers handle both storages the same way
+func constraintError(code pq.ErrorCode, table string, format string, args ...interface{}) error {
+	return &pq.Error{
+		Code:    code,
+		Table:   table,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func newMemoryTable[T any](name string, parent memoryParent, key func(row *T) string) *memoryTable[T] {
+	return &memoryTable[T]{
+		name:   name,
+		rows:   make(map[string][]*T),
+		key:    key,
+		parent: parent,
+	}
+}
+
+func (t *memoryTable[T]) tableName() string {
+	return t.name
+}
+
+// copyTo copies the groups of rows but shares the rows themselves, which is
+// safe because writes replace a row instead of changing it
+func (t *memoryTable[T]) copyTo(c *MemoryStore) {
+	table := c.tables[t.name].(*memoryTable[T])
+	table.rows = make(map[string][]*T, len(t.rows))
+	for id, rows := range t.rows {
+		table.rows[id] = slices.Clone(rows)
+	}
+}
+
+func (t *memoryTable[T]) has(id string) bool {
+	return len(t.rows[id]) > 0
+}
+
+func (t *memoryTable[T]) insert(id string, row *T) (func(), error) {
+	if t.parent != nil && !t.parent.has(id) {
+		return nil, constraintError("23503", t.name, "insert on table %q violates foreign key constraint: %s does not exist", t.name, id)
+	}
+
+	key := ""
+	if t.key != nil {
+		key = t.key(row)
+	}
+	for _, existing := range t.rows[id] {
+		if t.key == nil || t.key(existing) == key {
+			return nil, constraintError("23505", t.name, "duplicate key value violates unique constraint on table %q", t.name)
+		}
+	}
+
+	clone := *row
+	t.rows[id] = append(t.rows[id], &clone)
+	return func() {
+		rows := t.rows[id]
+		t.rows[id] = rows[:len(rows)-1]
+		if len(t.rows[id]) == 0 {
+			delete(t.rows, id)
+		}
+	}, nil
+}
+
+// update changes the row of a group with one row, nothing happens when there
+// is no such row
+func (t *memoryTable[T]) update(id string, fn func(row *T)) func() {
+	rows := t.rows[id]
+	if len(rows) == 0 {
+		return func() {}
+	}
+	previous := rows[0]
+	updated := *previous
+	fn(&updated)
+	rows[0] = &updated
+	return func() {
+		rows[0] = previous
+	}
+}
+
+func (t *memoryTable[T]) remove(id string) func() {
+	var undos []func()
+	for _, child := range t.children {
+		undos = append(undos, child.remove(id))
+	}
+
+	rows, ok := t.rows[id]
+	delete(t.rows, id)
+	return func() {
+		if ok {
+			t.rows[id] = rows
+		}
+		for i := len(undos) - 1; i >= 0; i-- {
+			undos[i]()
+		}
+	}
+}
+
+// get returns copies of the rows of a group, so callers cannot change the store
+func (t *memoryTable[T]) get(ids ...string) []*T {
+	var rows []*T
+	for _, id := range ids {
+		for _, row := range t.rows[id] {
+			clone := *row
+			rows = append(rows, &clone)
+		}
+	}
+	return rows
+}
+
+func (t *memoryTable[T]) first(id string) (*T, error) {
+	rows := t.get(id)
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return rows[0], nil
+}
+
+// memoryOp applies a single write to s and returns how to undo it. A write
+// made in a transaction is applied to the copy of the store of the
+// transaction first and to the store itself when it commits.
+type memoryOp func(s *MemoryStore) (undo func(), err error)
+
+// memoryTx is a transaction working on its own copy of the store
+type memoryTx struct {
+	store *MemoryStore
+	// The writes applied to the copy and how to undo them
+	ops   []memoryOp
+	undos []func()
+	// Number of writes when each savepoint was taken
+	savepoints map[string]int
+}
+
+// MemoryStore keeps coupons, their audit log, campaigns, customer orders and
+// redemptions in process memory. It implements ICoupon and ICustomer with the
+// same constraints as the Postgres schema and is safe for concurrent use.
+//
+// A transaction works on a copy of the store taken when it begins. Its writes
+// are applied to the copy right away, so a write that violates a constraint
+// fails when it is made and later reads of the transaction see it. When the
+// transaction commits, its writes are applied again to the store, atomically.
+// If one of them no longer applies because of a transaction committed in the
+// meantime, the commit fails with a serialization failure, which WithTx
+// retries. Reads outside a transaction see committed data and writes outside
+// a transaction are applied right away.
+type MemoryStore struct {
+	mu                  sync.RWMutex
+	coupons             *memoryTable[models.Coupon]
+	cartWise            *memoryTable[models.CartWiseCoupon]
+	productWise         *memoryTable[models.ProductWiseCoupon]
+	bxgy                *memoryTable[models.BxGyCoupon]
+	buyProducts         *memoryTable[models.BxGyBuyProduct]
+	getProducts         *memoryTable[models.BxGyGetProduct]
+	volume              *memoryTable[models.VolumePricingCoupon]
+	volumeProducts      *memoryTable[models.VolumePricingProduct]
+	volumeBands         *memoryTable[models.VolumePricingBand]
+	fixedPrice          *memoryTable[models.FixedPriceCoupon]
+	fixedPriceProducts  *memoryTable[models.FixedPriceProduct]
+	shipping            *memoryTable[models.ShippingCoupon]
+	shippingMethods     *memoryTable[models.ShippingCouponMethod]
+	eligibility         *memoryTable[models.CouponEligibility]
+	eligibilitySegments *memoryTable[models.CouponEligibilitySegment]
+	translations        *memoryTable[models.CouponTranslation]
+	tags                *memoryTable[models.CouponTag]
+	redemptions         *memoryTable[models.Redemption]
+	revisions           *memoryTable[models.CouponRevision]
+	examples            *memoryTable[models.CouponExample]
+	campaigns           *memoryTable[models.Campaign]
+	campaignEvents      *memoryTable[models.CampaignEvent]
+	// Customer orders are grouped by customer, order IDs are unique across
+	// customers and map to the customer the order is recorded for
+	customerOrders *memoryTable[models.CustomerOrder]
+	orderCustomers map[string]string
+	// Append-only, in ID order
+	auditLog []*models.CouponAuditEntry
+	auditSeq int64
+	// All the tables above by name
+	tables map[string]memoryRows
+
+	txMu sync.Mutex
+	txs  map[*context.Context]*memoryTx
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		orderCustomers: make(map[string]string),
+		txs:            make(map[*context.Context]*memoryTx),
+	}
+
+	s.coupons = newMemoryTable[models.Coupon]("coupons", nil, nil)
+	s.cartWise = newMemoryTable[models.CartWiseCoupon]("cart_wise_coupons", s.coupons, nil)
+	s.productWise = newMemoryTable[models.ProductWiseCoupon]("product_wise_coupons", s.coupons, nil)
+	s.bxgy = newMemoryTable[models.BxGyCoupon]("bx_gy_coupons", s.coupons, nil)
+	s.buyProducts = newMemoryTable("bx_gy_buy_products", s.bxgy, func(row *models.BxGyBuyProduct) string { return row.ProductID })
+	s.getProducts = newMemoryTable("bx_gy_get_products", s.bxgy, func(row *models.BxGyGetProduct) string { return row.ProductID })
+	s.volume = newMemoryTable[models.VolumePricingCoupon]("volume_pricing_coupons", s.coupons, nil)
+	s.volumeProducts = newMemoryTable("volume_pricing_products", s.volume, func(row *models.VolumePricingProduct) string { return row.ProductID })
+	s.volumeBands = newMemoryTable("volume_pricing_bands", s.volume, func(row *models.VolumePricingBand) string { return fmt.Sprint(row.MinQuantity) })
+	s.fixedPrice = newMemoryTable[models.FixedPriceCoupon]("fixed_price_coupons", s.coupons, nil)
+	s.fixedPriceProducts = newMemoryTable("fixed_price_products", s.fixedPrice, func(row *models.FixedPriceProduct) string { return row.ProductID })
+	s.shipping = newMemoryTable[models.ShippingCoupon]("shipping_coupons", s.coupons, nil)
+	s.shippingMethods = newMemoryTable("shipping_coupon_methods", s.shipping, func(row *models.ShippingCouponMethod) string { return row.Method })
+	s.eligibility = newMemoryTable[models.CouponEligibility]("coupon_eligibilities", s.coupons, nil)
+	s.eligibilitySegments = newMemoryTable("coupon_eligibility_segments", s.eligibility, func(row *models.CouponEligibilitySegment) string { return row.Segment })
+	s.translations = newMemoryTable("coupon_translations", s.coupons, func(row *models.CouponTranslation) string { return row.Locale })
+	s.tags = newMemoryTable("coupon_tags", s.coupons, func(row *models.CouponTag) string { return row.Tag })
+	s.redemptions = newMemoryTable("redemptions", s.coupons, func(row *models.Redemption) string { return row.OrderID })
+	s.revisions = newMemoryTable("coupon_revisions", s.coupons, func(row *models.CouponRevision) string { return row.Id })
+	s.examples = newMemoryTable("coupon_examples", s.coupons, func(row *models.CouponExample) string { return row.Id })
+	s.campaigns = newMemoryTable[models.Campaign]("campaigns", nil, nil)
+	s.campaignEvents = newMemoryTable("campaign_events", s.campaigns, func(row *models.CampaignEvent) string { return fmt.Sprint(row.Threshold) })
+	s.customerOrders = newMemoryTable[models.CustomerOrder]("customer_orders", nil, func(row *models.CustomerOrder) string { return row.OrderID })
+
+	s.coupons.children = []memoryChild{s.cartWise, s.productWise, s.bxgy, s.volume, s.fixedPrice, s.shipping, s.eligibility, s.translations, s.tags, s.redemptions, s.revisions, s.examples}
+	s.bxgy.children = []memoryChild{s.buyProducts, s.getProducts}
+	s.volume.children = []memoryChild{s.volumeProducts, s.volumeBands}
+	s.fixedPrice.children = []memoryChild{s.fixedPriceProducts}
+	s.shipping.children = []memoryChild{s.shippingMethods}
+	s.eligibility.children = []memoryChild{s.eligibilitySegments}
+	s.campaigns.children = []memoryChild{s.campaignEvents}
+
+	s.tables = make(map[string]memoryRows)
+	for _, table := range []memoryRows{s.coupons, s.cartWise, s.productWise, s.bxgy, s.buyProducts, s.getProducts, s.volume, s.volumeProducts, s.volumeBands, s.fixedPrice, s.fixedPriceProducts, s.shipping, s.shippingMethods, s.eligibility, s.eligibilitySegments, s.translations, s.tags, s.redemptions, s.revisions, s.examples, s.campaigns, s.campaignEvents, s.customerOrders} {
+		s.tables[table.tableName()] = table
+	}
+
+	return s
+}
+
+// clone copies the data of the store for a transaction, it must be called
+// with the store locked
+func (s *MemoryStore) clone() *MemoryStore {
+	c := NewMemoryStore()
+	for _, table := range s.tables {
+		table.copyTo(c)
+	}
+	c.orderCustomers = maps.Clone(s.orderCustomers)
+	// Appending to the audit log of the copy must not write to the one of the store
+	c.auditLog = slices.Clip(s.auditLog)
+	c.auditSeq = s.auditSeq
+	return c
+}
+
+// Begin starts a transaction on a copy of the store. Transactions are applied
+// to the store one at a time when they commit, and fail when they conflict
+// with one committed after they began, so every isolation level behaves as
+// serializable.
+func (s *MemoryStore) Begin(ctx *context.Context, isolation sql.IsolationLevel) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	if _, ok := s.txs[ctx]; ok {
+		return errors.New("transaction already in progress")
+	}
+	s.mu.RLock()
+	store := s.clone()
+	s.mu.RUnlock()
+	s.txs[ctx] = &memoryTx{
+		store:      store,
+		savepoints: make(map[string]int),
+	}
+	return nil
+}
+
+// Commit applies the writes of the transaction to the store. They all applied
+// to the copy of the transaction, so one that fails now conflicts with a
+// transaction committed since; the ones already applied are undone and the
+// commit fails with a serialization failure.
+func (s *MemoryStore) Commit(ctx *context.Context) error {
+	tx, err := s.endTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undos := make([]func(), 0, len(tx.ops))
+	for _, op := range tx.ops {
+		undo, err := op(s)
+		if err != nil {
+			for i := len(undos) - 1; i >= 0; i-- {
+				undos[i]()
+			}
+			return &pq.Error{
+				Code:    "40001",
+				Message: fmt.Sprintf("could not serialize access due to concurrent update: %v", err),
+			}
+		}
+		undos = append(undos, undo)
+	}
+	return nil
+}
+
+// Rollback drops the transaction with its copy of the store
+func (s *MemoryStore) Rollback(ctx *context.Context) error {
+	_, err := s.endTx(ctx)
+	return err
+}
+
+func (s *MemoryStore) InTransaction(ctx *context.Context) bool {
+	return s.tx(ctx) != nil
+}
+
+func (s *MemoryStore) Savepoint(ctx *context.Context, name string) error {
+	tx := s.tx(ctx)
+	if tx == nil {
+		return errNoTransaction
+	}
+
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	tx.savepoints[name] = len(tx.ops)
+	return nil
+}
+
+// RollbackTo undoes the writes made since the savepoint was taken and drops
+// the savepoints taken after it
+func (s *MemoryStore) RollbackTo(ctx *context.Context, name string) error {
+	tx := s.tx(ctx)
+	if tx == nil {
+		return errNoTransaction
+	}
+
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	n, ok := tx.savepoints[name]
+	if !ok {
+		return fmt.Errorf("savepoint %q does not exist", name)
+	}
+	for i := len(tx.undos) - 1; i >= n; i-- {
+		tx.undos[i]()
+	}
+	tx.ops, tx.undos = tx.ops[:n], tx.undos[:n]
+	for savepoint, at := range tx.savepoints {
+		if at > n {
+			delete(tx.savepoints, savepoint)
+		}
+	}
+	return nil
+}
+
+// tx returns the transaction of the context, nil when there is none
+func (s *MemoryStore) tx(ctx *context.Context) *memoryTx {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	return s.txs[ctx]
+}
+
+func (s *MemoryStore) endTx(ctx *context.Context) (*memoryTx, error) {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	tx, ok := s.txs[ctx]
+	if !ok {
+		return nil, errNoTransaction
+	}
+	delete(s.txs, ctx)
+	return tx, nil
+}
+
+// write applies op to the copy of the store of the transaction of the context
+// and keeps it to apply again when the transaction commits. Without a
+// transaction op is applied to the store right away.
+func (s *MemoryStore) write(ctx *context.Context, op memoryOp) error {
+	tx := s.tx(ctx)
+	if tx == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, err := op(s)
+		return err
+	}
+
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	undo, err := op(tx.store)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, op)
+	tx.undos = append(tx.undos, undo)
+	return nil
+}
+
+// insertOp and removeOp find the table by name in the store they are applied
+// to, which is not the one table belongs to in a transaction
+func insertOp[T any](table *memoryTable[T], id string, row *T) memoryOp {
+	clone := *row
+	name := table.name
+	return func(s *MemoryStore) (func(), error) {
+		return s.tables[name].(*memoryTable[T]).insert(id, &clone)
+	}
+}
+
+func removeOp[T any](table *memoryTable[T], id string) memoryOp {
+	name := table.name
+	return func(s *MemoryStore) (func(), error) {
+		return s.tables[name].(*memoryTable[T]).remove(id), nil
+	}
+}
+
+// read runs fn on the store, or on the copy of the transaction of the context
+// so the transaction sees its own writes, while holding its read lock
+func read[R any](ctx *context.Context, s *MemoryStore, fn func(s *MemoryStore) (R, error)) (R, error) {
+	if tx := s.tx(ctx); tx != nil {
+		s = tx.store
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s)
+}
+
+// sortCoupons orders coupons by creation time, the order they are listed in
+func sortCoupons(coupons []*models.Coupon) {
+	sort.SliceStable(coupons, func(i, j int) bool {
+		if coupons[i].CreatedAt.Equal(coupons[j].CreatedAt) {
+			return coupons[i].Id < coupons[j].Id
+		}
+		return coupons[i].CreatedAt.Before(coupons[j].CreatedAt)
+	})
+}
+
+var (
+	_ ICoupon   = (*MemoryStore)(nil)
+	_ ICustomer = (*MemoryStore)(nil)
+)
@@ -0,0 +1,51 @@
+package daos
+
+import (
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+)
+
+// The audit log of MemoryStore is a plain list in ID order. Like the Postgres
+// table it has no foreign key to coupons and entries are never removed.
+
+func (s *MemoryStore) PersistCouponAuditEntry(ctx *context.Context, req *models.CouponAuditEntry) error {
+	entry := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		s.auditSeq++
+		entry.Id = s.auditSeq
+		s.auditLog = append(s.auditLog, &entry)
+		return func() {
+			s.auditLog = s.auditLog[:len(s.auditLog)-1]
+			s.auditSeq--
+		}, nil
+	})
+}
+
+func (s *MemoryStore) ListCouponAuditEntries(ctx *context.Context, query *AuditQuery) ([]*models.CouponAuditEntry, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponAuditEntry, error) {
+		var entries []*models.CouponAuditEntry
+		for i := len(s.auditLog) - 1; i >= 0 && len(entries) < query.Limit; i-- {
+			entry := s.auditLog[i]
+			if !auditMatches(entry, query) {
+				continue
+			}
+			clone := *entry
+			entries = append(entries, &clone)
+		}
+		return entries, nil
+	})
+}
+
+func auditMatches(entry *models.CouponAuditEntry, query *AuditQuery) bool {
+	switch {
+	case query.CouponId != "" && entry.CouponID != query.CouponId,
+		query.Actor != "" && entry.Actor != query.Actor,
+		query.Action != "" && entry.Action != query.Action,
+		query.RequestId != "" && entry.RequestID != query.RequestId,
+		query.Since != nil && entry.CreatedAt.Before(*query.Since),
+		query.Until != nil && !entry.CreatedAt.Before(*query.Until),
+		query.Before > 0 && entry.Id >= query.Before:
+		return false
+	}
+	return true
+}
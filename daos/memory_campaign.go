@@ -0,0 +1,168 @@
+package daos
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+)
+
+func (s *MemoryStore) PersistCampaign(ctx *context.Context, req *models.Campaign) error {
+	return s.write(ctx, insertOp(s.campaigns, req.Id, req))
+}
+
+// PersistCampaignEvent skips thresholds the campaign fired already. When it
+// records the event, the transaction fails to commit if another one fired the
+// threshold meanwhile.
+func (s *MemoryStore) PersistCampaignEvent(ctx *context.Context, req *models.CampaignEvent) (bool, error) {
+	insert := insertOp(s.campaignEvents, req.CampaignID, req)
+	checked, recorded := false, false
+	err := s.write(ctx, func(s *MemoryStore) (func(), error) {
+		if !checked {
+			checked = true
+			recorded = !slices.ContainsFunc(s.campaignEvents.rows[req.CampaignID], func(event *models.CampaignEvent) bool {
+				return event.Threshold == req.Threshold
+			})
+		}
+		if !recorded {
+			return func() {}, nil
+		}
+		return insert(s)
+	})
+	return recorded && err == nil, err
+}
+
+func (s *MemoryStore) GetCampaign(ctx *context.Context, id string) (*models.Campaign, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.Campaign, error) {
+		return s.campaigns.first(id)
+	})
+}
+
+func (s *MemoryStore) GetCampaigns(ctx *context.Context, ids []string) ([]*models.Campaign, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Campaign, error) {
+		return s.campaigns.get(ids...), nil
+	})
+}
+
+func (s *MemoryStore) ListCampaigns(ctx *context.Context) ([]*models.Campaign, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Campaign, error) {
+		var campaigns []*models.Campaign
+		for id := range s.campaigns.rows {
+			campaigns = append(campaigns, s.campaigns.get(id)...)
+		}
+		sort.Slice(campaigns, func(i, j int) bool {
+			if campaigns[i].CreatedAt.Equal(campaigns[j].CreatedAt) {
+				return campaigns[i].Id < campaigns[j].Id
+			}
+			return campaigns[i].CreatedAt.Before(campaigns[j].CreatedAt)
+		})
+		return campaigns, nil
+	})
+}
+
+func (s *MemoryStore) ListCampaignEvents(ctx *context.Context, campaignId string) ([]*models.CampaignEvent, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CampaignEvent, error) {
+		events := s.campaignEvents.get(campaignId)
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Threshold < events[j].Threshold
+		})
+		return events, nil
+	})
+}
+
+func (s *MemoryStore) ListCampaignCoupons(ctx *context.Context, campaignId string) ([]*models.Coupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Coupon, error) {
+		var coupons []*models.Coupon
+		for id, rows := range s.coupons.rows {
+			coupon := rows[0]
+			if coupon.DeletedAt == nil && coupon.CampaignID != nil && *coupon.CampaignID == campaignId {
+				coupons = append(coupons, s.coupons.get(id)...)
+			}
+		}
+		sortCoupons(coupons)
+		return coupons, nil
+	})
+}
+
+func (s *MemoryStore) UpdateCampaign(ctx *context.Context, req *models.Campaign) error {
+	update := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		if !s.campaigns.has(update.Id) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return s.campaigns.update(update.Id, func(campaign *models.Campaign) {
+			campaign.Name = update.Name
+			campaign.StartsAt = update.StartsAt
+			campaign.EndsAt = update.EndsAt
+			campaign.Budget = update.Budget
+			campaign.UpdatedAt = update.UpdatedAt
+		}), nil
+	})
+}
+
+// SpendCampaignBudget checks the budget covers amount, and checks it again
+// when the transaction commits with the store locked, so concurrent
+// redemptions together never spend more than the budget. Amounts are
+// compared in cents, like the DECIMAL columns of Postgres.
+func (s *MemoryStore) SpendCampaignBudget(ctx *context.Context, campaignId string, amount float64) (*models.Campaign, error) {
+	var spent *models.Campaign
+	err := s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.campaigns.rows[campaignId]
+		if len(rows) == 0 || math.Round((rows[0].Spent+amount)*100) > math.Round(rows[0].Budget*100) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		undo := s.campaigns.update(campaignId, func(campaign *models.Campaign) {
+			campaign.Spent += amount
+		})
+		if spent == nil {
+			spent, _ = s.campaigns.first(campaignId)
+		}
+		return undo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spent, nil
+}
+
+// NotifyCampaignEvent has no one to notify, like NotifyCouponChanged
+func (s *MemoryStore) NotifyCampaignEvent(ctx *context.Context, event *models.CampaignEvent) error {
+	return nil
+}
+
+func (s *MemoryStore) ListCampaignSpend(ctx *context.Context, campaignId string) ([]*models.CampaignCouponSpend, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CampaignCouponSpend, error) {
+		byCoupon := make(map[string]*models.CampaignCouponSpend)
+		var spend []*models.CampaignCouponSpend
+		for couponId := range s.redemptions.rows {
+			for _, redemption := range s.redemptions.rows[couponId] {
+				if redemption.CampaignID == nil || *redemption.CampaignID != campaignId {
+					continue
+				}
+				coupon, ok := byCoupon[couponId]
+				if !ok {
+					coupon = &models.CampaignCouponSpend{CouponID: couponId}
+					byCoupon[couponId] = coupon
+					spend = append(spend, coupon)
+				}
+				coupon.Redemptions++
+				coupon.Discount += redemption.Discount
+			}
+		}
+		sort.Slice(spend, func(i, j int) bool {
+			return spend[i].CouponID < spend[j].CouponID
+		})
+		return spend, nil
+	})
+}
+
+// campaignMissing tells whether a coupon refers to a campaign that does not
+// exist, like the foreign key on coupons.campaign_id. It must be called with
+// the store locked.
+func (s *MemoryStore) campaignMissing(campaignId *string) bool {
+	return campaignId != nil && !s.campaigns.has(*campaignId)
+}
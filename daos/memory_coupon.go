@@ -0,0 +1,700 @@
+package daos
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+)
+
+// ICoupon on MemoryStore
+
+func (s *MemoryStore) PersistCoupon(ctx *context.Context, req *models.Coupon) error {
+	insert := insertOp(s.coupons, req.Id, req)
+	code, campaignId := req.Code, req.CampaignID
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		if s.campaignMissing(campaignId) {
+			return nil, constraintError("23503", "coupons", "insert on table %q violates foreign key constraint: campaign %s does not exist", "coupons", *campaignId)
+		}
+		// Codes that are set are unique, like the partial index on coupons.code
+		if code != "" {
+			for _, rows := range s.coupons.rows {
+				if rows[0].Code == code {
+					return nil, constraintError("23505", "coupons", "duplicate key value violates unique constraint %q", "idx_coupons_code")
+				}
+			}
+		}
+		return insert(s)
+	})
+}
+
+func (s *MemoryStore) PersistCouponRevision(ctx *context.Context, req *models.CouponRevision) error {
+	insert := insertOp(s.revisions, req.CouponID, req)
+	couponId := req.CouponID
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		// A coupon has one pending revision at most, like idx_coupon_revisions_pending
+		if s.pendingRevision(couponId) != nil {
+			return nil, constraintError("23505", "coupon_revisions", "duplicate key value violates unique constraint %q", "idx_coupon_revisions_pending")
+		}
+		return insert(s)
+	})
+}
+
+func (s *MemoryStore) PersistCartWiseCoupon(ctx *context.Context, req *models.CartWiseCoupon) error {
+	return s.write(ctx, insertOp(s.cartWise, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistProductWiseCoupon(ctx *context.Context, req *models.ProductWiseCoupon) error {
+	return s.write(ctx, insertOp(s.productWise, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistBxGyCoupon(ctx *context.Context, req *models.BxGyCoupon) error {
+	return s.write(ctx, insertOp(s.bxgy, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistBxGyBuyCoupon(ctx *context.Context, req *models.BxGyBuyProduct) error {
+	return s.write(ctx, insertOp(s.buyProducts, req.BxGyCouponID, req))
+}
+
+func (s *MemoryStore) PersistBxGyGetCoupon(ctx *context.Context, req *models.BxGyGetProduct) error {
+	return s.write(ctx, insertOp(s.getProducts, req.BxGyCouponID, req))
+}
+
+func (s *MemoryStore) PersistVolumePricingCoupon(ctx *context.Context, req *models.VolumePricingCoupon) error {
+	return s.write(ctx, insertOp(s.volume, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistVolumePricingProduct(ctx *context.Context, req *models.VolumePricingProduct) error {
+	return s.write(ctx, insertOp(s.volumeProducts, req.VolumePricingCouponID, req))
+}
+
+func (s *MemoryStore) PersistVolumePricingBand(ctx *context.Context, req *models.VolumePricingBand) error {
+	return s.write(ctx, insertOp(s.volumeBands, req.VolumePricingCouponID, req))
+}
+
+func (s *MemoryStore) PersistFixedPriceCoupon(ctx *context.Context, req *models.FixedPriceCoupon) error {
+	return s.write(ctx, insertOp(s.fixedPrice, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistFixedPriceProduct(ctx *context.Context, req *models.FixedPriceProduct) error {
+	return s.write(ctx, insertOp(s.fixedPriceProducts, req.FixedPriceCouponID, req))
+}
+
+func (s *MemoryStore) PersistShippingCoupon(ctx *context.Context, req *models.ShippingCoupon) error {
+	return s.write(ctx, insertOp(s.shipping, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistShippingCouponMethod(ctx *context.Context, req *models.ShippingCouponMethod) error {
+	return s.write(ctx, insertOp(s.shippingMethods, req.ShippingCouponID, req))
+}
+
+func (s *MemoryStore) PersistCouponEligibility(ctx *context.Context, req *models.CouponEligibility) error {
+	return s.write(ctx, insertOp(s.eligibility, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistCouponEligibilitySegment(ctx *context.Context, req *models.CouponEligibilitySegment) error {
+	return s.write(ctx, insertOp(s.eligibilitySegments, req.CouponEligibilityID, req))
+}
+
+func (s *MemoryStore) PersistCouponTranslation(ctx *context.Context, req *models.CouponTranslation) error {
+	return s.write(ctx, insertOp(s.translations, req.CouponID, req))
+}
+
+func (s *MemoryStore) PersistCouponTag(ctx *context.Context, req *models.CouponTag) error {
+	return s.write(ctx, insertOp(s.tags, req.CouponID, req))
+}
+
+func (s *MemoryStore) GetAllCoupons(ctx *context.Context) ([]*models.Coupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Coupon, error) {
+		var coupons []*models.Coupon
+		for id, rows := range s.coupons.rows {
+			if rows[0].DeletedAt == nil {
+				coupons = append(coupons, s.coupons.get(id)...)
+			}
+		}
+		sortCoupons(coupons)
+		return coupons, nil
+	})
+}
+
+func (s *MemoryStore) ListPurgeableCoupons(ctx *context.Context, deletedBefore time.Time, limit int) ([]*models.Coupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Coupon, error) {
+		var coupons []*models.Coupon
+		for id := range s.coupons.rows {
+			if s.purgeable(id, deletedBefore) {
+				coupons = append(coupons, s.coupons.get(id)...)
+			}
+		}
+		sort.Slice(coupons, func(i, j int) bool {
+			if coupons[i].DeletedAt.Equal(*coupons[j].DeletedAt) {
+				return coupons[i].Id < coupons[j].Id
+			}
+			return coupons[i].DeletedAt.Before(*coupons[j].DeletedAt)
+		})
+		if len(coupons) > limit {
+			coupons = coupons[:limit]
+		}
+		return coupons, nil
+	})
+}
+
+// LockPurgeableCoupon checks that the coupon is purgeable, and checks again
+// when the transaction commits, which happens under the write lock of the store
+func (s *MemoryStore) LockPurgeableCoupon(ctx *context.Context, couponId string, deletedBefore time.Time) error {
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		if !s.purgeable(couponId, deletedBefore) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return func() {}, nil
+	})
+}
+
+// LockCouponCampaign reads the campaign of a coupon, and checks the coupon is
+// still in it when the transaction commits
+func (s *MemoryStore) LockCouponCampaign(ctx *context.Context, couponId string) (string, error) {
+	checked, campaignId := false, ""
+	err := s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.coupons.rows[couponId]
+		if len(rows) == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+		current := ""
+		if rows[0].CampaignID != nil {
+			current = *rows[0].CampaignID
+		}
+		if checked && current != campaignId {
+			return nil, gorm.ErrRecordNotFound
+		}
+		checked, campaignId = true, current
+		return func() {}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return campaignId, nil
+}
+
+// purgeable tells whether a coupon was deleted before the given time and
+// never redeemed
+func (s *MemoryStore) purgeable(couponId string, deletedBefore time.Time) bool {
+	rows := s.coupons.rows[couponId]
+	if len(rows) == 0 || rows[0].DeletedAt == nil || !rows[0].DeletedAt.Before(deletedBefore) {
+		return false
+	}
+	return !s.redemptions.has(couponId)
+}
+
+func (s *MemoryStore) ListCoupons(ctx *context.Context, query *CouponQuery) ([]*models.Coupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.Coupon, error) {
+		var coupons []*models.Coupon
+		for id := range s.coupons.rows {
+			coupon := s.coupons.get(id)[0]
+			if s.matches(coupon, query) {
+				coupons = append(coupons, coupon)
+			}
+		}
+		sortCoupons(coupons)
+		if query.Descending {
+			slices.Reverse(coupons)
+		}
+		if len(coupons) > query.Limit {
+			coupons = coupons[:query.Limit]
+		}
+		return coupons, nil
+	})
+}
+
+// matches applies the filters and the cursor of a query to a coupon, the way
+// the Postgres query does
+func (s *MemoryStore) matches(coupon *models.Coupon, query *CouponQuery) bool {
+	if query.Type != "" && coupon.Type != query.Type {
+		return false
+	}
+	if query.Active != nil && coupon.IsActive != *query.Active {
+		return false
+	}
+	if query.State != "" && coupon.ValidityState(query.Now) != query.State {
+		return false
+	}
+	if query.ProductId != "" && !s.targetsProduct(coupon.Id, query.ProductId) {
+		return false
+	}
+	if !strings.HasPrefix(coupon.Code, query.CodePrefix) {
+		return false
+	}
+	if query.Status != "" && coupon.Status != query.Status {
+		return false
+	}
+	if query.Deleted != (coupon.DeletedAt != nil) {
+		return false
+	}
+	if after := query.After; after != nil {
+		cmp := coupon.CreatedAt.Compare(after.CreatedAt)
+		if cmp == 0 {
+			cmp = strings.Compare(coupon.Id, after.Id)
+		}
+		if (!query.Descending && cmp <= 0) || (query.Descending && cmp >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// targetsProduct tells whether a coupon names the product in its details
+func (s *MemoryStore) targetsProduct(couponId, productId string) bool {
+	for _, product := range s.productWise.get(couponId) {
+		if product.ProductID == productId {
+			return true
+		}
+	}
+	for _, product := range s.buyProducts.get(couponId) {
+		if product.ProductID == productId {
+			return true
+		}
+	}
+	for _, product := range s.getProducts.get(couponId) {
+		if product.ProductID == productId {
+			return true
+		}
+	}
+	for _, product := range s.volumeProducts.get(couponId) {
+		if product.ProductID == productId {
+			return true
+		}
+	}
+	for _, product := range s.fixedPriceProducts.get(couponId) {
+		if product.ProductID == productId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) GetCouponById(ctx *context.Context, id string) (*models.Coupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.Coupon, error) {
+		return s.coupons.first(id)
+	})
+}
+
+func (s *MemoryStore) GetCartWiseCoupon(ctx *context.Context, couponId string) (*models.CartWiseCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.CartWiseCoupon, error) {
+		return s.cartWise.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetProductWiseCoupon(ctx *context.Context, couponId string) (*models.ProductWiseCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.ProductWiseCoupon, error) {
+		return s.productWise.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetBxGyCoupon(ctx *context.Context, couponId string) (*models.BxGyCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.BxGyCoupon, error) {
+		return s.bxgy.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetBxGyBuyProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyBuyProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.BxGyBuyProduct, error) {
+		return s.buyProducts.get(bxgyCouponId), nil
+	})
+}
+
+func (s *MemoryStore) GetBxGyGetProducts(ctx *context.Context, bxgyCouponId string) ([]*models.BxGyGetProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.BxGyGetProduct, error) {
+		return s.getProducts.get(bxgyCouponId), nil
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingCoupon(ctx *context.Context, couponId string) (*models.VolumePricingCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.VolumePricingCoupon, error) {
+		return s.volume.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingProducts(ctx *context.Context, couponId string) ([]*models.VolumePricingProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.VolumePricingProduct, error) {
+		return s.volumeProducts.get(couponId), nil
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingBands(ctx *context.Context, couponId string) ([]*models.VolumePricingBand, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.VolumePricingBand, error) {
+		return s.volumeBands.get(couponId), nil
+	})
+}
+
+func (s *MemoryStore) GetFixedPriceCoupon(ctx *context.Context, couponId string) (*models.FixedPriceCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.FixedPriceCoupon, error) {
+		return s.fixedPrice.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetFixedPriceProducts(ctx *context.Context, couponId string) ([]*models.FixedPriceProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.FixedPriceProduct, error) {
+		return s.fixedPriceProducts.get(couponId), nil
+	})
+}
+
+func (s *MemoryStore) GetShippingCoupon(ctx *context.Context, couponId string) (*models.ShippingCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.ShippingCoupon, error) {
+		return s.shipping.first(couponId)
+	})
+}
+
+func (s *MemoryStore) GetShippingCouponMethods(ctx *context.Context, couponId string) ([]*models.ShippingCouponMethod, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.ShippingCouponMethod, error) {
+		return s.shippingMethods.get(couponId), nil
+	})
+}
+
+func (s *MemoryStore) GetCouponEligibility(ctx *context.Context, couponId string) (*models.CouponEligibility, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.CouponEligibility, error) {
+		eligibilities := s.eligibility.get(couponId)
+		if len(eligibilities) == 0 {
+			return nil, nil
+		}
+		return eligibilities[0], nil
+	})
+}
+
+func (s *MemoryStore) GetCouponEligibilitySegments(ctx *context.Context, couponId string) ([]*models.CouponEligibilitySegment, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponEligibilitySegment, error) {
+		return s.eligibilitySegments.get(couponId), nil
+	})
+}
+
+func (s *MemoryStore) GetCartWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.CartWiseCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CartWiseCoupon, error) {
+		return s.cartWise.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetProductWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.ProductWiseCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.ProductWiseCoupon, error) {
+		return s.productWise.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetBxGyCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.BxGyCoupon, error) {
+		return s.bxgy.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetBxGyBuyProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyBuyProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.BxGyBuyProduct, error) {
+		return s.buyProducts.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetBxGyGetProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyGetProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.BxGyGetProduct, error) {
+		return s.getProducts.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.VolumePricingCoupon, error) {
+		return s.volume.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.VolumePricingProduct, error) {
+		return s.volumeProducts.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetVolumePricingBandsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingBand, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.VolumePricingBand, error) {
+		return s.volumeBands.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetFixedPriceCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.FixedPriceCoupon, error) {
+		return s.fixedPrice.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetFixedPriceProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceProduct, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.FixedPriceProduct, error) {
+		return s.fixedPriceProducts.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetShippingCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCoupon, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.ShippingCoupon, error) {
+		return s.shipping.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetShippingCouponMethodsByCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCouponMethod, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.ShippingCouponMethod, error) {
+		return s.shippingMethods.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetCouponEligibilities(ctx *context.Context, couponIds []string) ([]*models.CouponEligibility, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponEligibility, error) {
+		return s.eligibility.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetCouponEligibilitySegmentsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponEligibilitySegment, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponEligibilitySegment, error) {
+		return s.eligibilitySegments.get(couponIds...), nil
+	})
+}
+
+func (s *MemoryStore) GetCouponTranslationsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTranslation, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponTranslation, error) {
+		translations := s.translations.get(couponIds...)
+		sort.Slice(translations, func(i, j int) bool { return translations[i].Locale < translations[j].Locale })
+		return translations, nil
+	})
+}
+
+func (s *MemoryStore) GetCouponTagsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTag, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponTag, error) {
+		tags := s.tags.get(couponIds...)
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+		return tags, nil
+	})
+}
+
+func (s *MemoryStore) GetPendingCouponRevision(ctx *context.Context, couponId string) (*models.CouponRevision, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.CouponRevision, error) {
+		revision := s.pendingRevision(couponId)
+		if revision == nil {
+			return nil, nil
+		}
+		clone := *revision
+		return &clone, nil
+	})
+}
+
+// pendingRevision returns the revision of a coupon waiting for review, it
+// must be called with the store locked
+func (s *MemoryStore) pendingRevision(couponId string) *models.CouponRevision {
+	for _, revision := range s.revisions.rows[couponId] {
+		if revision.Status == models.StatusPendingApproval {
+			return revision
+		}
+	}
+	return nil
+}
+
+// UpdateCoupon checks the status of the coupon, and checks it again when the
+// transaction commits, which happens under the write lock of the store
+func (s *MemoryStore) UpdateCoupon(ctx *context.Context, req *models.Coupon, status string) error {
+	update := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.coupons.rows[update.Id]
+		if len(rows) == 0 || rows[0].Status != status {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if s.campaignMissing(update.CampaignID) {
+			return nil, constraintError("23503", "coupons", "update on table %q violates foreign key constraint: campaign %s does not exist", "coupons", *update.CampaignID)
+		}
+		if update.Code != "" {
+			for id, rows := range s.coupons.rows {
+				if id != update.Id && rows[0].Code == update.Code {
+					return nil, constraintError("23505", "coupons", "duplicate key value violates unique constraint %q", "idx_coupons_code")
+				}
+			}
+		}
+		return s.coupons.update(update.Id, func(coupon *models.Coupon) {
+			// Like the Postgres update, the creation and deletion times are kept
+			createdAt, deletedAt := coupon.CreatedAt, coupon.DeletedAt
+			*coupon = update
+			coupon.CreatedAt, coupon.DeletedAt = createdAt, deletedAt
+		}), nil
+	})
+}
+
+func (s *MemoryStore) UpdateCouponRevision(ctx *context.Context, req *models.CouponRevision, status string) error {
+	update := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.revisions.rows[update.CouponID]
+		for i, revision := range rows {
+			if revision.Id != update.Id {
+				continue
+			}
+			if revision.Status != status {
+				break
+			}
+			reviewed := *revision
+			reviewed.Status = update.Status
+			reviewed.ReviewedBy = update.ReviewedBy
+			reviewed.Reason = update.Reason
+			reviewed.ReviewedAt = update.ReviewedAt
+			rows[i] = &reviewed
+			return func() {
+				rows[i] = revision
+			}, nil
+		}
+		return nil, gorm.ErrRecordNotFound
+	})
+}
+
+func (s *MemoryStore) UpdateCouponDeletedAt(ctx *context.Context, couponId string, deletedAt *time.Time, updatedAt time.Time) error {
+	if deletedAt != nil {
+		at := *deletedAt
+		deletedAt = &at
+	}
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.coupons.rows[couponId]
+		if len(rows) == 0 || (rows[0].DeletedAt == nil) == (deletedAt == nil) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return s.coupons.update(couponId, func(coupon *models.Coupon) {
+			coupon.DeletedAt = deletedAt
+			coupon.UpdatedAt = updatedAt
+		}), nil
+	})
+}
+
+func (s *MemoryStore) DeleteCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.coupons, couponId))
+}
+
+func (s *MemoryStore) DeleteCartWiseCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.cartWise, couponId))
+}
+
+func (s *MemoryStore) DeleteProductWiseCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.productWise, couponId))
+}
+
+func (s *MemoryStore) DeleteBxGyCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.bxgy, couponId))
+}
+
+func (s *MemoryStore) DeleteBxGyBuyProducts(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.buyProducts, couponId))
+}
+
+func (s *MemoryStore) DeleteBxGyGetProducts(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.getProducts, couponId))
+}
+
+func (s *MemoryStore) DeleteVolumePricingCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.volume, couponId))
+}
+
+func (s *MemoryStore) DeleteVolumePricingProducts(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.volumeProducts, couponId))
+}
+
+func (s *MemoryStore) DeleteVolumePricingBands(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.volumeBands, couponId))
+}
+
+func (s *MemoryStore) DeleteFixedPriceCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.fixedPrice, couponId))
+}
+
+func (s *MemoryStore) DeleteFixedPriceProducts(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.fixedPriceProducts, couponId))
+}
+
+func (s *MemoryStore) DeleteShippingCoupon(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.shipping, couponId))
+}
+
+func (s *MemoryStore) DeleteShippingCouponMethods(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.shippingMethods, couponId))
+}
+
+func (s *MemoryStore) DeleteCouponEligibility(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.eligibility, couponId))
+}
+
+func (s *MemoryStore) DeleteCouponEligibilitySegments(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.eligibilitySegments, couponId))
+}
+
+func (s *MemoryStore) DeleteCouponTranslations(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.translations, couponId))
+}
+
+func (s *MemoryStore) DeleteCouponTags(ctx *context.Context, couponId string) error {
+	return s.write(ctx, removeOp(s.tags, couponId))
+}
+
+// NotifyCouponChanged has no one to notify, an in-memory store is never
+// shared between instances
+func (s *MemoryStore) NotifyCouponChanged(ctx *context.Context, couponId string) error {
+	return nil
+}
+
+// ICustomer on MemoryStore
+
+// PersistCustomerOrder records an order, or adds to the order already
+// recorded with the same ID like the upsert of Customer does. It returns
+// gorm.ErrRecordNotFound if the order is recorded for another customer.
+func (s *MemoryStore) PersistCustomerOrder(ctx *context.Context, req *models.CustomerOrder) error {
+	order := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		customerId, ok := s.orderCustomers[order.OrderID]
+		if ok && customerId != order.CustomerID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if !ok {
+			undo, err := s.customerOrders.insert(order.CustomerID, &order)
+			if err != nil {
+				return nil, err
+			}
+			s.orderCustomers[order.OrderID] = order.CustomerID
+			return func() {
+				delete(s.orderCustomers, order.OrderID)
+				undo()
+			}, nil
+		}
+
+		rows := s.customerOrders.rows[customerId]
+		for i, recorded := range rows {
+			if recorded.OrderID != order.OrderID {
+				continue
+			}
+			updated := *recorded
+			updated.Discount += order.Discount
+			if updated.CouponID == nil {
+				updated.CouponID = order.CouponID
+			}
+			rows[i] = &updated
+			return func() {
+				rows[i] = recorded
+			}, nil
+		}
+		return nil, gorm.ErrRecordNotFound
+	})
+}
+
+func (s *MemoryStore) PersistRedemption(ctx *context.Context, req *models.Redemption) error {
+	return s.write(ctx, insertOp(s.redemptions, req.CouponID, req))
+}
+
+func (s *MemoryStore) GetCustomerHistory(ctx *context.Context, customerId string) (*models.CustomerHistory, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.CustomerHistory, error) {
+		history := models.CustomerHistory{
+			CustomerID: customerId,
+		}
+		for _, order := range s.customerOrders.get(customerId) {
+			history.OrderCount++
+			history.LifetimeSpend += order.Total
+			if history.LastOrderAt == nil || order.CreatedAt.After(*history.LastOrderAt) {
+				createdAt := order.CreatedAt
+				history.LastOrderAt = &createdAt
+			}
+		}
+		return &history, nil
+	})
+}
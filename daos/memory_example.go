@@ -0,0 +1,109 @@
+package daos
+
+import (
+	"sort"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"gorm.io/gorm"
+)
+
+func (s *MemoryStore) PersistCouponExample(ctx *context.Context, req *models.CouponExample) error {
+	insert := insertOp(s.examples, req.CouponID, req)
+	couponId, name := req.CouponID, req.Name
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		if s.exampleNamed(couponId, name, "") {
+			return nil, constraintError("23505", "coupon_examples", "duplicate key value violates unique constraint %q", "idx_coupon_examples_name")
+		}
+		return insert(s)
+	})
+}
+
+func (s *MemoryStore) ListCouponExamples(ctx *context.Context, couponId string) ([]*models.CouponExample, error) {
+	return read(ctx, s, func(s *MemoryStore) ([]*models.CouponExample, error) {
+		var examples []*models.CouponExample
+		if couponId != "" {
+			examples = s.examples.get(couponId)
+		} else {
+			for id := range s.examples.rows {
+				examples = append(examples, s.examples.get(id)...)
+			}
+		}
+		sort.Slice(examples, func(i, j int) bool {
+			if examples[i].CouponID != examples[j].CouponID {
+				return examples[i].CouponID < examples[j].CouponID
+			}
+			return examples[i].Name < examples[j].Name
+		})
+		return examples, nil
+	})
+}
+
+func (s *MemoryStore) GetCouponExample(ctx *context.Context, couponId string, exampleId string) (*models.CouponExample, error) {
+	return read(ctx, s, func(s *MemoryStore) (*models.CouponExample, error) {
+		for _, example := range s.examples.get(couponId) {
+			if example.Id == exampleId {
+				return example, nil
+			}
+		}
+		return nil, gorm.ErrRecordNotFound
+	})
+}
+
+func (s *MemoryStore) UpdateCouponExample(ctx *context.Context, req *models.CouponExample) error {
+	update := *req
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.examples.rows[update.CouponID]
+		for i, example := range rows {
+			if example.Id != update.Id {
+				continue
+			}
+			if s.exampleNamed(update.CouponID, update.Name, update.Id) {
+				return nil, constraintError("23505", "coupon_examples", "duplicate key value violates unique constraint %q", "idx_coupon_examples_name")
+			}
+			updated := *example
+			updated.Name = update.Name
+			updated.Cart = update.Cart
+			updated.Customer = update.Customer
+			updated.Expected = update.Expected
+			updated.UpdatedAt = update.UpdatedAt
+			rows[i] = &updated
+			return func() {
+				rows[i] = example
+			}, nil
+		}
+		return nil, gorm.ErrRecordNotFound
+	})
+}
+
+func (s *MemoryStore) DeleteCouponExample(ctx *context.Context, couponId string, exampleId string) error {
+	return s.write(ctx, func(s *MemoryStore) (func(), error) {
+		rows := s.examples.rows[couponId]
+		for i, example := range rows {
+			if example.Id != exampleId {
+				continue
+			}
+			s.examples.rows[couponId] = append(rows[:i:i], rows[i+1:]...)
+			if len(s.examples.rows[couponId]) == 0 {
+				delete(s.examples.rows, couponId)
+			}
+			return func() {
+				s.examples.rows[couponId] = rows
+			}, nil
+		}
+		return nil, gorm.ErrRecordNotFound
+	})
+}
+
+// exampleNamed tells whether another example of the coupon than exceptId has
+// the name, like idx_coupon_examples_name. It must be called with the store
+// locked.
+func (s *MemoryStore) exampleNamed(couponId string, name string, exceptId string) bool {
+	for _, example := range s.examples.rows[couponId] {
+		if example.Name == name && example.Id != exceptId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,409 @@
+package daos
+
+import (
+	stdcontext "context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/log"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+func testContext() *context.Context {
+	return context.New(stdcontext.Background(), "test", log.New("test", "test", log.LevelFatal), nil)
+}
+
+func beginTx(t *testing.T, s *MemoryStore) *context.Context {
+	t.Helper()
+	tx := testContext()
+	if err := s.Begin(tx, 0); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	return tx
+}
+
+func persistCampaign(t *testing.T, s *MemoryStore, ctx *context.Context, id string) {
+	t.Helper()
+	if err := s.PersistCampaign(ctx, &models.Campaign{Id: id, Budget: 100}); err != nil {
+		t.Fatalf("PersistCampaign(%s) error = %v", id, err)
+	}
+}
+
+// assertCampaigns checks which of the campaigns a, b and c ctx sees
+func assertCampaigns(t *testing.T, s *MemoryStore, ctx *context.Context, want ...string) {
+	t.Helper()
+	campaigns, err := s.GetCampaigns(ctx, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("GetCampaigns() error = %v", err)
+	}
+	var got []string
+	for _, campaign := range campaigns {
+		got = append(got, campaign.Id)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("campaigns = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("campaigns = %v, want %v", got, want)
+		}
+	}
+}
+
+func pqCode(err error) pq.ErrorCode {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code
+	}
+	return ""
+}
+
+func TestMemoryStoreTransactionSeesItsOwnWrites(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	tx := beginTx(t, s)
+
+	persistCampaign(t, s, tx, "a")
+	spent, err := s.SpendCampaignBudget(tx, "a", 40)
+	if err != nil {
+		t.Fatalf("SpendCampaignBudget() error = %v", err)
+	}
+	if spent.Spent != 40 {
+		t.Errorf("SpendCampaignBudget() spent = %v, want 40", spent.Spent)
+	}
+
+	campaign, err := s.GetCampaign(tx, "a")
+	if err != nil {
+		t.Fatalf("GetCampaign() in the transaction error = %v", err)
+	}
+	if campaign.Spent != 40 {
+		t.Errorf("spent in the transaction = %v, want 40", campaign.Spent)
+	}
+	// Others do not see the writes until they commit
+	if _, err := s.GetCampaign(ctx, "a"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetCampaign() outside the transaction error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	if err := s.Commit(tx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	campaign, err = s.GetCampaign(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetCampaign() after the commit error = %v", err)
+	}
+	if campaign.Spent != 40 {
+		t.Errorf("spent after the commit = %v, want 40", campaign.Spent)
+	}
+}
+
+func TestMemoryStoreWriteFailsWhenMade(t *testing.T) {
+	s := NewMemoryStore()
+	tx := beginTx(t, s)
+	persistCampaign(t, s, tx, "a")
+
+	err := s.PersistCampaign(tx, &models.Campaign{Id: "a"})
+	if code := pqCode(err); code != "23505" {
+		t.Errorf("PersistCampaign() of a duplicate error = %v, want a unique violation", err)
+	}
+	_, err = s.PersistCampaignEvent(tx, &models.CampaignEvent{CampaignID: "b", Threshold: 50})
+	if code := pqCode(err); code != "23503" {
+		t.Errorf("PersistCampaignEvent() of a missing campaign error = %v, want a foreign key violation", err)
+	}
+
+	// The failed writes are not part of the transaction
+	if err := s.Commit(tx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	assertCampaigns(t, s, testContext(), "a")
+}
+
+func TestMemoryStoreRollback(t *testing.T) {
+	s := NewMemoryStore()
+	persistCampaign(t, s, testContext(), "a")
+
+	tx := beginTx(t, s)
+	persistCampaign(t, s, tx, "b")
+	if _, err := s.SpendCampaignBudget(tx, "a", 10); err != nil {
+		t.Fatalf("SpendCampaignBudget() error = %v", err)
+	}
+	if err := s.Rollback(tx); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if s.InTransaction(tx) {
+		t.Error("InTransaction() = true after the rollback")
+	}
+	ctx := testContext()
+	assertCampaigns(t, s, ctx, "a")
+	campaign, err := s.GetCampaign(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetCampaign() error = %v", err)
+	}
+	if campaign.Spent != 0 {
+		t.Errorf("spent = %v, want 0", campaign.Spent)
+	}
+	if err := s.Commit(tx); !errors.Is(err, errNoTransaction) {
+		t.Errorf("Commit() after the rollback error = %v, want %v", err, errNoTransaction)
+	}
+}
+
+func TestMemoryStoreSavepoints(t *testing.T) {
+	s := NewMemoryStore()
+	tx := beginTx(t, s)
+
+	persistCampaign(t, s, tx, "a")
+	if err := s.Savepoint(tx, "sp_1"); err != nil {
+		t.Fatalf("Savepoint() error = %v", err)
+	}
+	persistCampaign(t, s, tx, "b")
+	if err := s.Savepoint(tx, "sp_2"); err != nil {
+		t.Fatalf("Savepoint() error = %v", err)
+	}
+	persistCampaign(t, s, tx, "c")
+
+	if err := s.RollbackTo(tx, "sp_1"); err != nil {
+		t.Fatalf("RollbackTo() error = %v", err)
+	}
+	assertCampaigns(t, s, tx, "a")
+
+	// Savepoints taken after it are dropped, the one rolled back to is kept
+	if err := s.RollbackTo(tx, "sp_2"); err == nil {
+		t.Error("RollbackTo() a dropped savepoint error = nil")
+	}
+	persistCampaign(t, s, tx, "b")
+	if err := s.RollbackTo(tx, "sp_1"); err != nil {
+		t.Fatalf("RollbackTo() again error = %v", err)
+	}
+	persistCampaign(t, s, tx, "c")
+
+	if err := s.Commit(tx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	assertCampaigns(t, s, testContext(), "a", "c")
+}
+
+func TestMemoryStoreCommitConflict(t *testing.T) {
+	s := NewMemoryStore()
+	first := beginTx(t, s)
+	second := beginTx(t, s)
+
+	persistCampaign(t, s, first, "a")
+	persistCampaign(t, s, second, "b")
+	persistCampaign(t, s, second, "a")
+
+	if err := s.Commit(first); err != nil {
+		t.Fatalf("Commit() of the first transaction error = %v", err)
+	}
+	err := s.Commit(second)
+	if !isRetryable(err) {
+		t.Fatalf("Commit() of the second transaction error = %v, want a serialization failure", err)
+	}
+	// None of the writes of the second transaction are applied
+	assertCampaigns(t, s, testContext(), "a")
+}
+
+func TestMemoryStoreWithTxRetriesConflicts(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	persistCampaign(t, s, ctx, "a")
+
+	attempts := 0
+	err := WithTx(ctx, s, func(tx *context.Context) error {
+		attempts++
+		campaign, err := s.GetCampaign(tx, "a")
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			// Another request spends the rest of the budget meanwhile
+			if _, err := s.SpendCampaignBudget(testContext(), "a", 100); err != nil {
+				return err
+			}
+		}
+		if campaign.Exhausted() {
+			return gorm.ErrRecordNotFound
+		}
+		_, err = s.SpendCampaignBudget(tx, "a", 10)
+		return err
+	})
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("WithTx() error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestMemoryStoreSpendCampaignBudget(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	persistCampaign(t, s, ctx, "a")
+
+	tests := []struct {
+		amount  float64
+		wantErr error
+		spent   float64
+	}{
+		{60, nil, 60},
+		{50, gorm.ErrRecordNotFound, 60},
+		{39.99, nil, 99.99},
+		{0.01, nil, 100},
+		{0.01, gorm.ErrRecordNotFound, 100},
+	}
+
+	for _, tt := range tests {
+		_, err := s.SpendCampaignBudget(ctx, "a", tt.amount)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SpendCampaignBudget(%v) error = %v, want %v", tt.amount, err, tt.wantErr)
+		}
+		campaign, err := s.GetCampaign(ctx, "a")
+		if err != nil {
+			t.Fatalf("GetCampaign() error = %v", err)
+		}
+		if math.Abs(campaign.Spent-tt.spent) > 0.005 {
+			t.Errorf("spent after SpendCampaignBudget(%v) = %v, want %v", tt.amount, campaign.Spent, tt.spent)
+		}
+	}
+}
+
+func TestMemoryStoreConcurrentSpendsKeepToTheBudget(t *testing.T) {
+	s := NewMemoryStore()
+	persistCampaign(t, s, testContext(), "a")
+
+	// Both fit the budget on their own, only the first to commit fits both
+	first, second := beginTx(t, s), beginTx(t, s)
+	for _, tx := range []*context.Context{first, second} {
+		if _, err := s.SpendCampaignBudget(tx, "a", 60); err != nil {
+			t.Fatalf("SpendCampaignBudget() error = %v", err)
+		}
+	}
+	if err := s.Commit(first); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Commit(second); pqCode(err) != "40001" {
+		t.Errorf("Commit() error = %v, want a serialization failure", err)
+	}
+}
+
+func TestMemoryStorePersistCampaignEventOnce(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	persistCampaign(t, s, ctx, "a")
+	event := &models.CampaignEvent{Id: "e1", CampaignID: "a", Threshold: 50}
+
+	first, second := beginTx(t, s), beginTx(t, s)
+	for _, tx := range []*context.Context{first, second} {
+		recorded, err := s.PersistCampaignEvent(tx, event)
+		if err != nil || !recorded {
+			t.Fatalf("PersistCampaignEvent() = %v, %v, want it recorded", recorded, err)
+		}
+	}
+	if err := s.Commit(first); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	// The threshold was fired meanwhile
+	if err := s.Commit(second); pqCode(err) != "40001" {
+		t.Errorf("Commit() error = %v, want a serialization failure", err)
+	}
+
+	recorded, err := s.PersistCampaignEvent(ctx, &models.CampaignEvent{Id: "e2", CampaignID: "a", Threshold: 50})
+	if err != nil || recorded {
+		t.Errorf("PersistCampaignEvent() of a fired threshold = %v, %v, want it skipped", recorded, err)
+	}
+	events, err := s.ListCampaignEvents(ctx, "a")
+	if err != nil {
+		t.Fatalf("ListCampaignEvents() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("%d events are stored, want 1", len(events))
+	}
+}
+
+func TestMemoryStoreUpdateCouponDeletedAt(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	if err := s.PersistCoupon(ctx, &models.Coupon{Id: "a", Type: "cart-wise"}); err != nil {
+		t.Fatalf("PersistCoupon() error = %v", err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		couponId  string
+		deletedAt *time.Time
+		err       error
+	}{
+		{"restore a coupon that is not deleted", "a", nil, gorm.ErrRecordNotFound},
+		{"delete", "a", &now, nil},
+		{"delete again", "a", &now, gorm.ErrRecordNotFound},
+		{"restore", "a", nil, nil},
+		{"delete a missing coupon", "missing", &now, gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateCouponDeletedAt(ctx, tt.couponId, tt.deletedAt, now)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("UpdateCouponDeletedAt() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Of two transactions deleting the coupon only one gets to commit
+	first, second := beginTx(t, s), beginTx(t, s)
+	for _, tx := range []*context.Context{first, second} {
+		if err := s.UpdateCouponDeletedAt(tx, "a", &now, now); err != nil {
+			t.Fatalf("UpdateCouponDeletedAt() error = %v", err)
+		}
+	}
+	if err := s.Commit(first); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Commit(second); pqCode(err) != "40001" {
+		t.Errorf("Commit() error = %v, want a serialization failure", err)
+	}
+}
+
+func TestMemoryStoreListCampaignCoupons(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := testContext()
+	campaignId, otherId := "summer", "winter"
+	persistCampaign(t, s, ctx, campaignId)
+	persistCampaign(t, s, ctx, otherId)
+	now := time.Now()
+	for _, coupon := range []*models.Coupon{
+		{Id: "b", Type: "cart-wise", CampaignID: &campaignId},
+		{Id: "a", Type: "cart-wise", CampaignID: &campaignId},
+		{Id: "deleted", Type: "cart-wise", CampaignID: &campaignId},
+		{Id: "other", Type: "cart-wise", CampaignID: &otherId},
+		{Id: "none", Type: "cart-wise"},
+	} {
+		coupon.CreatedAt = now
+		if err := s.PersistCoupon(ctx, coupon); err != nil {
+			t.Fatalf("PersistCoupon() error = %v", err)
+		}
+	}
+	if err := s.UpdateCouponDeletedAt(ctx, "deleted", &now, now); err != nil {
+		t.Fatalf("UpdateCouponDeletedAt() error = %v", err)
+	}
+
+	coupons, err := s.ListCampaignCoupons(ctx, campaignId)
+	if err != nil {
+		t.Fatalf("ListCampaignCoupons() error = %v", err)
+	}
+	var got []string
+	for _, coupon := range coupons {
+		got = append(got, coupon.Id)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("coupons = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package daos
+
+// Repositories is the storage the services run on
+type Repositories struct {
+	Coupons   ICoupon
+	Customers ICustomer
+}
+
+// NewRepositories returns the repositories backed by Postgres
+func NewRepositories() Repositories {
+	return Repositories{
+		Coupons:   NewCoupon(),
+		Customers: NewCustomer(),
+	}
+}
+
+// NewMemoryRepositories returns repositories that share a single in-memory
+// store, for local development and tests
+func NewMemoryRepositories() Repositories {
+	store := NewMemoryStore()
+	return Repositories{
+		Coupons:   store,
+		Customers: store,
+	}
+}
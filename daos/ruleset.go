@@ -0,0 +1,351 @@
+package daos
+
+import (
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+)
+
+// The batch getters below load the type-specific rows of many coupons at once.
+// The IDs are sent as a single array parameter, so each getter is one query
+// no matter how many coupons are asked for.
+
+func (c *Coupon) GetCartWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.CartWiseCoupon, error) {
+	var cartCoupons []*models.CartWiseCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&cartCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return cartCoupons, nil
+}
+
+func (c *Coupon) GetProductWiseCoupons(ctx *context.Context, couponIds []string) ([]*models.ProductWiseCoupon, error) {
+	var productCoupons []*models.ProductWiseCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&productCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return productCoupons, nil
+}
+
+func (c *Coupon) GetBxGyCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyCoupon, error) {
+	var bxgyCoupons []*models.BxGyCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&bxgyCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return bxgyCoupons, nil
+}
+
+func (c *Coupon) GetBxGyBuyProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyBuyProduct, error) {
+	var buyProducts []*models.BxGyBuyProduct
+	err := ctx.DB.Debug().Where("bx_gy_coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&buyProducts).Error
+	if err != nil {
+		return nil, err
+	}
+	return buyProducts, nil
+}
+
+func (c *Coupon) GetBxGyGetProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.BxGyGetProduct, error) {
+	var getProducts []*models.BxGyGetProduct
+	err := ctx.DB.Debug().Where("bx_gy_coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&getProducts).Error
+	if err != nil {
+		return nil, err
+	}
+	return getProducts, nil
+}
+
+func (c *Coupon) GetVolumePricingCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingCoupon, error) {
+	var volumeCoupons []*models.VolumePricingCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&volumeCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return volumeCoupons, nil
+}
+
+func (c *Coupon) GetVolumePricingProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingProduct, error) {
+	var products []*models.VolumePricingProduct
+	err := ctx.DB.Debug().Where("volume_pricing_coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (c *Coupon) GetVolumePricingBandsByCoupons(ctx *context.Context, couponIds []string) ([]*models.VolumePricingBand, error) {
+	var bands []*models.VolumePricingBand
+	err := ctx.DB.Debug().Where("volume_pricing_coupon_id = ANY(?)", pq.StringArray(couponIds)).Order("min_quantity").Find(&bands).Error
+	if err != nil {
+		return nil, err
+	}
+	return bands, nil
+}
+
+func (c *Coupon) GetFixedPriceCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceCoupon, error) {
+	var fixedPriceCoupons []*models.FixedPriceCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&fixedPriceCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return fixedPriceCoupons, nil
+}
+
+func (c *Coupon) GetFixedPriceProductsByCoupons(ctx *context.Context, couponIds []string) ([]*models.FixedPriceProduct, error) {
+	var products []*models.FixedPriceProduct
+	err := ctx.DB.Debug().Where("fixed_price_coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (c *Coupon) GetShippingCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCoupon, error) {
+	var shippingCoupons []*models.ShippingCoupon
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&shippingCoupons).Error
+	if err != nil {
+		return nil, err
+	}
+	return shippingCoupons, nil
+}
+
+func (c *Coupon) GetShippingCouponMethodsByCoupons(ctx *context.Context, couponIds []string) ([]*models.ShippingCouponMethod, error) {
+	var methods []*models.ShippingCouponMethod
+	err := ctx.DB.Debug().Where("shipping_coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&methods).Error
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+func (c *Coupon) GetCouponEligibilities(ctx *context.Context, couponIds []string) ([]*models.CouponEligibility, error) {
+	var eligibilities []*models.CouponEligibility
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Find(&eligibilities).Error
+	if err != nil {
+		return nil, err
+	}
+	return eligibilities, nil
+}
+
+func (c *Coupon) GetCouponEligibilitySegmentsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponEligibilitySegment, error) {
+	var segments []*models.CouponEligibilitySegment
+	err := ctx.DB.Debug().Where("coupon_eligibility_id = ANY(?)", pq.StringArray(couponIds)).Find(&segments).Error
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+func (c *Coupon) GetCouponTranslationsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTranslation, error) {
+	var translations []*models.CouponTranslation
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Order("locale").Find(&translations).Error
+	if err != nil {
+		return nil, err
+	}
+	return translations, nil
+}
+
+func (c *Coupon) GetCouponTagsByCoupons(ctx *context.Context, couponIds []string) ([]*models.CouponTag, error) {
+	var tags []*models.CouponTag
+	err := ctx.DB.Debug().Where("coupon_id = ANY(?)", pq.StringArray(couponIds)).Order("tag").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// LoadRuleSet bulk-loads the type-specific rows of the given coupons into a rule set.
+// It runs a fixed number of queries regardless of how many coupons are passed in.
+func LoadRuleSet(ctx *context.Context, db ICoupon, coupons []*models.Coupon) (*models.RuleSet, error) {
+	ruleSet := models.NewRuleSet(coupons)
+	if len(coupons) == 0 {
+		return ruleSet, nil
+	}
+	ids := ruleSet.IDs()
+
+	cartCoupons, err := db.GetCartWiseCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, cartCoupon := range cartCoupons {
+		if rules := ruleSet.Get(cartCoupon.CouponID); rules != nil {
+			rules.CartWise = cartCoupon
+		}
+	}
+
+	productCoupons, err := db.GetProductWiseCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, productCoupon := range productCoupons {
+		if rules := ruleSet.Get(productCoupon.CouponID); rules != nil {
+			rules.ProductWise = productCoupon
+		}
+	}
+
+	bxgyCoupons, err := db.GetBxGyCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, bxgyCoupon := range bxgyCoupons {
+		if rules := ruleSet.Get(bxgyCoupon.CouponID); rules != nil {
+			rules.BxGy = bxgyCoupon
+		}
+	}
+
+	buyProducts, err := db.GetBxGyBuyProductsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, buyProduct := range buyProducts {
+		if rules := ruleSet.Get(buyProduct.BxGyCouponID); rules != nil {
+			rules.BuyProducts = append(rules.BuyProducts, buyProduct)
+		}
+	}
+
+	getProducts, err := db.GetBxGyGetProductsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, getProduct := range getProducts {
+		if rules := ruleSet.Get(getProduct.BxGyCouponID); rules != nil {
+			rules.GetProducts = append(rules.GetProducts, getProduct)
+		}
+	}
+
+	volumeCoupons, err := db.GetVolumePricingCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, volumeCoupon := range volumeCoupons {
+		if rules := ruleSet.Get(volumeCoupon.CouponID); rules != nil {
+			rules.Volume = volumeCoupon
+		}
+	}
+
+	volumeProducts, err := db.GetVolumePricingProductsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range volumeProducts {
+		if rules := ruleSet.Get(product.VolumePricingCouponID); rules != nil {
+			rules.VolumeProducts = append(rules.VolumeProducts, product)
+		}
+	}
+
+	volumeBands, err := db.GetVolumePricingBandsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, band := range volumeBands {
+		if rules := ruleSet.Get(band.VolumePricingCouponID); rules != nil {
+			rules.VolumeBands = append(rules.VolumeBands, band)
+		}
+	}
+
+	fixedPriceCoupons, err := db.GetFixedPriceCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, fixedPriceCoupon := range fixedPriceCoupons {
+		if rules := ruleSet.Get(fixedPriceCoupon.CouponID); rules != nil {
+			rules.FixedPrice = fixedPriceCoupon
+		}
+	}
+
+	fixedPriceProducts, err := db.GetFixedPriceProductsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range fixedPriceProducts {
+		if rules := ruleSet.Get(product.FixedPriceCouponID); rules != nil {
+			rules.FixedPriceProducts = append(rules.FixedPriceProducts, product)
+		}
+	}
+
+	shippingCoupons, err := db.GetShippingCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, shippingCoupon := range shippingCoupons {
+		if rules := ruleSet.Get(shippingCoupon.CouponID); rules != nil {
+			rules.Shipping = shippingCoupon
+		}
+	}
+
+	shippingMethods, err := db.GetShippingCouponMethodsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range shippingMethods {
+		if rules := ruleSet.Get(method.ShippingCouponID); rules != nil {
+			rules.ShippingMethods = append(rules.ShippingMethods, method)
+		}
+	}
+
+	eligibilities, err := db.GetCouponEligibilities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, eligibility := range eligibilities {
+		if rules := ruleSet.Get(eligibility.CouponID); rules != nil {
+			rules.Eligibility = eligibility
+		}
+	}
+
+	segments, err := db.GetCouponEligibilitySegmentsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		if rules := ruleSet.Get(segment.CouponEligibilityID); rules != nil {
+			rules.EligibilitySegments = append(rules.EligibilitySegments, segment)
+		}
+	}
+
+	translations, err := db.GetCouponTranslationsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, translation := range translations {
+		if rules := ruleSet.Get(translation.CouponID); rules != nil {
+			rules.Translations = append(rules.Translations, translation)
+		}
+	}
+
+	tags, err := db.GetCouponTagsByCoupons(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if rules := ruleSet.Get(tag.CouponID); rules != nil {
+			rules.Tags = append(rules.Tags, tag)
+		}
+	}
+
+	// The campaigns the coupons draw their discounts from
+	var campaignIds []string
+	for _, rules := range ruleSet.Coupons {
+		if rules.Coupon.CampaignID != nil {
+			campaignIds = append(campaignIds, *rules.Coupon.CampaignID)
+		}
+	}
+	if len(campaignIds) > 0 {
+		campaigns, err := db.GetCampaigns(ctx, campaignIds)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]*models.Campaign, len(campaigns))
+		for _, campaign := range campaigns {
+			byID[campaign.Id] = campaign
+		}
+		for _, rules := range ruleSet.Coupons {
+			if rules.Coupon.CampaignID != nil {
+				rules.Campaign = byID[*rules.Coupon.CampaignID]
+			}
+		}
+	}
+
+	return ruleSet, nil
+}
@@ -0,0 +1,193 @@
+package daos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Transactor starts and ends the transaction repository writes run in. The
+// transaction is carried by the request context, so every repository used
+// with the same context and storage takes part in it.
+//
+// Services do not call these directly, they run their writes through WithTx.
+type Transactor interface {
+	Begin(ctx *context.Context, isolation sql.IsolationLevel) error
+	Commit(ctx *context.Context) error
+	Rollback(ctx *context.Context) error
+	InTransaction(ctx *context.Context) bool
+	Savepoint(ctx *context.Context, name string) error
+	RollbackTo(ctx *context.Context, name string) error
+}
+
+// TxOptions tune how WithTxOptions runs a transaction
+type TxOptions struct {
+	// Isolation level of the transaction, the database default when zero
+	Isolation sql.IsolationLevel
+	// How many times the transaction is tried when Postgres aborts it with a
+	// serialization failure or a deadlock. Defaults to 3.
+	MaxAttempts int
+}
+
+const defaultTxAttempts = 3
+
+// Used to name savepoints uniquely
+var savepointSeq atomic.Uint64
+
+// WithTx runs fn in a transaction with the default options. See WithTxOptions.
+func WithTx(ctx *context.Context, t Transactor, fn func(tx *context.Context) error) error {
+	return WithTxOptions(ctx, t, TxOptions{}, fn)
+}
+
+// WithTxOptions runs fn in a transaction on t. fn gets a copy of ctx that
+// carries the transaction and must make all of its writes with it.
+//
+// The transaction is committed when fn returns nil and rolled back when it
+// returns an error or panics; the panic is passed on after the rollback.
+// If Postgres aborts the transaction with a serialization failure or a
+// deadlock, fn is run again in a new transaction, so it must not have side
+// effects outside the transaction.
+//
+// When ctx is already in a transaction, fn runs in a savepoint of it instead
+// and an error only undoes the writes of fn. Options do not apply then.
+func WithTxOptions(ctx *context.Context, t Transactor, opts TxOptions, fn func(tx *context.Context) error) error {
+	if t.InTransaction(ctx) {
+		return withSavepoint(ctx, t, fn)
+	}
+
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultTxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, t, opts.Isolation, fn)
+		if err == nil || attempt >= attempts || !isRetryable(err) {
+			return err
+		}
+		ctx.Log.Warn("transaction aborted, retrying", zap.Int("attempt", attempt), zap.Error(err))
+
+		// Back off a little so the competing transaction can finish
+		backoff := time.NewTimer(time.Duration(attempt*10) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			backoff.Stop()
+			return ctx.Err()
+		case <-backoff.C:
+		}
+	}
+}
+
+func runTx(ctx *context.Context, t Transactor, isolation sql.IsolationLevel, fn func(tx *context.Context) error) (err error) {
+	tx := ctx.Copy()
+	err = t.Begin(tx, isolation)
+	if err != nil {
+		ctx.Log.Error("failed to start transaction", zap.Error(err))
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback(tx, t)
+			panic(r)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		rollback(tx, t)
+		return err
+	}
+
+	err = t.Commit(tx)
+	if err != nil {
+		ctx.Log.Error("failed to commit transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func rollback(tx *context.Context, t Transactor) {
+	if err := t.Rollback(tx); err != nil {
+		tx.Log.Error("failed to roll back transaction", zap.Error(err))
+	}
+}
+
+func withSavepoint(ctx *context.Context, t Transactor, fn func(tx *context.Context) error) (err error) {
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	err = t.Savepoint(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.RollbackTo(ctx, name)
+			panic(r)
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil {
+		if rollbackErr := t.RollbackTo(ctx, name); rollbackErr != nil {
+			ctx.Log.Error("failed to roll back to savepoint", zap.Error(rollbackErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// isRetryable reports whether Postgres aborted the transaction because of a
+// conflict with another one, in which case running it again can succeed
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	// serialization_failure and deadlock_detected
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// gormTransactor runs transactions on the Postgres connection of the context
+type gormTransactor struct {
+}
+
+func (g gormTransactor) Begin(ctx *context.Context, isolation sql.IsolationLevel) error {
+	tx := ctx.DB.Begin(&sql.TxOptions{Isolation: isolation})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	ctx.Transaction = tx
+	return nil
+}
+
+func (g gormTransactor) Commit(ctx *context.Context) error {
+	err := ctx.Transaction.Commit().Error
+	ctx.Transaction = nil
+	return err
+}
+
+func (g gormTransactor) Rollback(ctx *context.Context) error {
+	err := ctx.Transaction.Rollback().Error
+	ctx.Transaction = nil
+	return err
+}
+
+func (g gormTransactor) InTransaction(ctx *context.Context) bool {
+	return ctx.Transaction != nil
+}
+
+func (g gormTransactor) Savepoint(ctx *context.Context, name string) error {
+	return ctx.Transaction.SavePoint(name).Error
+}
+
+func (g gormTransactor) RollbackTo(ctx *context.Context, name string) error {
+	return ctx.Transaction.RollbackTo(name).Error
+}
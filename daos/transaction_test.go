@@ -0,0 +1,189 @@
+package daos
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+)
+
+// fakeTransactor records the calls made to it. Commit fails with the errors
+// in commitErrs, one per attempt, before it succeeds.
+type fakeTransactor struct {
+	calls      []string
+	commitErrs []error
+	active     map[*context.Context]bool
+}
+
+func newFakeTransactor(commitErrs ...error) *fakeTransactor {
+	return &fakeTransactor{commitErrs: commitErrs, active: make(map[*context.Context]bool)}
+}
+
+func (f *fakeTransactor) Begin(ctx *context.Context, isolation sql.IsolationLevel) error {
+	f.calls = append(f.calls, "begin "+isolation.String())
+	f.active[ctx] = true
+	return nil
+}
+
+func (f *fakeTransactor) Commit(ctx *context.Context) error {
+	f.calls = append(f.calls, "commit")
+	delete(f.active, ctx)
+	if len(f.commitErrs) > 0 {
+		err := f.commitErrs[0]
+		f.commitErrs = f.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (f *fakeTransactor) Rollback(ctx *context.Context) error {
+	f.calls = append(f.calls, "rollback")
+	delete(f.active, ctx)
+	return nil
+}
+
+func (f *fakeTransactor) InTransaction(ctx *context.Context) bool {
+	return f.active[ctx]
+}
+
+func (f *fakeTransactor) Savepoint(ctx *context.Context, name string) error {
+	f.calls = append(f.calls, "savepoint")
+	return nil
+}
+
+func (f *fakeTransactor) RollbackTo(ctx *context.Context, name string) error {
+	f.calls = append(f.calls, "rollback to savepoint")
+	return nil
+}
+
+func assertCalls(t *testing.T, f *fakeTransactor, want ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %q, want %q", f.calls, want)
+	}
+}
+
+var (
+	errSerialization = &pq.Error{Code: "40001"}
+	errDeadlock      = &pq.Error{Code: "40P01"}
+)
+
+func TestWithTxCommits(t *testing.T) {
+	f := newFakeTransactor()
+	err := WithTx(testContext(), f, func(tx *context.Context) error {
+		if !f.InTransaction(tx) {
+			t.Error("fn does not run in the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	assertCalls(t, f, "begin Default", "commit")
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	f := newFakeTransactor()
+	want := errors.New("failed")
+	err := WithTx(testContext(), f, func(tx *context.Context) error {
+		return want
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("WithTx() error = %v, want %v", err, want)
+	}
+	assertCalls(t, f, "begin Default", "rollback")
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	f := newFakeTransactor()
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want the panic of fn", r)
+		}
+		assertCalls(t, f, "begin Default", "rollback")
+	}()
+	WithTx(testContext(), f, func(tx *context.Context) error {
+		panic("boom")
+	})
+}
+
+func TestWithTxRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		commitErrs  []error
+		wantErr     error
+		wantRuns    int
+	}{
+		{"serialization failure", 0, []error{errSerialization}, nil, 2},
+		{"deadlock", 0, []error{errDeadlock, errSerialization}, nil, 3},
+		{"gives up after three attempts", 0, []error{errSerialization, errSerialization, errDeadlock}, errDeadlock, 3},
+		{"attempts are configurable", 1, []error{errSerialization}, errSerialization, 1},
+		{"other errors are not retried", 0, []error{&pq.Error{Code: "23505"}}, &pq.Error{Code: "23505"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTransactor(tt.commitErrs...)
+			runs := 0
+			opts := TxOptions{Isolation: sql.LevelSerializable, MaxAttempts: tt.maxAttempts}
+			err := WithTxOptions(testContext(), f, opts, func(tx *context.Context) error {
+				runs++
+				return nil
+			})
+
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("WithTxOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, tt.wantRuns)
+			}
+			for i, call := range f.calls {
+				if i%2 == 0 && call != "begin Serializable" {
+					t.Errorf("call %d = %q, want a serializable transaction", i, call)
+				}
+			}
+		})
+	}
+}
+
+func TestWithTxNestedRunsInASavepoint(t *testing.T) {
+	f := newFakeTransactor()
+	want := errors.New("failed")
+	err := WithTx(testContext(), f, func(tx *context.Context) error {
+		if err := WithTx(tx, f, func(tx *context.Context) error { return nil }); err != nil {
+			t.Errorf("nested WithTx() error = %v", err)
+		}
+		// A failure of the nested call only undoes its part
+		if err := WithTx(tx, f, func(tx *context.Context) error { return want }); !errors.Is(err, want) {
+			t.Errorf("nested WithTx() error = %v, want %v", err, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	assertCalls(t, f, "begin Default", "savepoint", "savepoint", "rollback to savepoint", "commit")
+}
+
+func TestWithTxNestedPanicRollsBackToTheSavepoint(t *testing.T) {
+	f := newFakeTransactor()
+	err := WithTx(testContext(), f, func(tx *context.Context) (err error) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("the panic of the nested fn is not passed on")
+			}
+		}()
+		return WithTx(tx, f, func(tx *context.Context) error {
+			panic("boom")
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	assertCalls(t, f, "begin Default", "savepoint", "rollback to savepoint", "commit")
+}
@@ -0,0 +1,45 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+
+	"monk-commerce-assignment/utils/jsondiff"
+)
+
+// An entry of the audit log of coupons
+type AuditEntry struct {
+	Id       int64  `json:"id"`
+	CouponId string `json:"coupon_id"`
+	// created or deleted
+	Action string `json:"action"`
+	// Who made the change, from the X-Actor header
+	Actor     string `json:"actor"`
+	RequestId string `json:"request_id"`
+	// The coupon before and after the change, null when it did not exist
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	// The fields the change set, changed or removed
+	Changes   []jsondiff.Change `json:"changes"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Filters and page of the audit log, newest entries first
+type AuditQuery struct {
+	CouponId  string `form:"coupon_id"`
+	Actor     string `form:"actor"`
+	Action    string `form:"action"`
+	RequestId string `form:"request_id"`
+	// RFC 3339 times, entries made at or after since and before until
+	Since *time.Time `form:"since"`
+	Until *time.Time `form:"until"`
+	// next_cursor of the previous page
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+type AuditPage struct {
+	Entries []*AuditEntry `json:"entries"`
+	// Empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,52 @@
+package dtos
+
+import (
+	"time"
+)
+
+// A campaign groups coupons under a total discount budget. Coupons join a
+// campaign with their campaign_id.
+type Campaign struct {
+	Id       string     `json:"id"`
+	Name     string     `json:"name"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Budget   float64    `json:"budget"`
+	// Set by the service: the discount redemptions drew from the budget so
+	// far, what is left of it and one of scheduled, live, ended or exhausted
+	Spent     float64   `json:"spent"`
+	Remaining float64   `json:"remaining"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CampaignEvent is fired once when a campaign has spent 50%, 80% and 100% of
+// its budget
+type CampaignEvent struct {
+	Threshold int       `json:"threshold"`
+	Spent     float64   `json:"spent"`
+	Budget    float64   `json:"budget"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Response of GET /campaigns/:id/report
+type CampaignReport struct {
+	Campaign Campaign `json:"campaign"`
+	// Share of the budget spent, in percent
+	SpentPercent float64          `json:"spent_percent"`
+	Redemptions  int              `json:"redemptions"`
+	Coupons      []CampaignCoupon `json:"coupons"`
+	Events       []CampaignEvent  `json:"events"`
+}
+
+// CampaignCoupon is what one coupon drew from the budget of a campaign. Coupons
+// that left the campaign or were deleted are listed with their redemptions,
+// without a code or status.
+type CampaignCoupon struct {
+	CouponId    string  `json:"coupon_id"`
+	Code        string  `json:"code,omitempty"`
+	Status      string  `json:"status,omitempty"`
+	Redemptions int     `json:"redemptions"`
+	Spent       float64 `json:"spent"`
+}
@@ -1,19 +1,154 @@
 package dtos
 
+import (
+	"encoding/json"
+	"time"
+
+	"monk-commerce-assignment/utils/schema"
+)
+
 type Coupon struct {
-	Id      string        `json:"id"`
-	Type    string        `json:"type"`
-	Details CouponDetails `json:"details"`
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	// Optional code shoppers enter at checkout, unique and stored in upper case
+	Code        string        `json:"code,omitempty"`
+	Details     CouponDetails `json:"details"`
+	Eligibility *Eligibility  `json:"eligibility,omitempty"`
+	Condition   string        `json:"condition,omitempty"`
+	// Display text in the locale picked from Accept-Language. On create it is
+	// the text of the default locale, unless translations has that locale.
+	Name        string `json:"name,omitempty"`
+	Badge       string `json:"badge,omitempty"`
+	Description string `json:"description,omitempty"`
+	Terms       string `json:"terms,omitempty"`
+	// Locale of the display text
+	Locale string `json:"locale,omitempty"`
+	// Display text by locale, e.g. {"en": {...}, "fr": {...}}
+	Translations map[string]CouponText `json:"translations,omitempty"`
+	// Free-form JSON object for clients
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	// Optional campaign whose budget the redemptions of the coupon draw down
+	CampaignId string `json:"campaign_id,omitempty"`
+	// Optional validity window, the coupon does not apply outside of it
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Set on deleted coupons, until they are restored or purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Where the coupon is in the approval workflow, set by the service
+	Status      string     `json:"status,omitempty"`
+	EditedBy    string     `json:"edited_by,omitempty"`
+	SubmittedBy string     `json:"submitted_by,omitempty"`
+	ApprovedBy  string     `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	// Why a reviewer last sent the coupon back to draft
+	RejectionReason string `json:"rejection_reason,omitempty"`
+	// Edit of an approved or live coupon waiting for review
+	PendingRevision *CouponRevision `json:"pending_revision,omitempty"`
+	// The details as they were sent, validated against the schema of the type
+	RawDetails json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON keeps the raw details next to the decoded ones
+func (c *Coupon) UnmarshalJSON(data []byte) error {
+	type coupon Coupon
+	var raw struct {
+		coupon
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Coupon(raw.coupon)
+	if len(raw.Details) > 0 && string(raw.Details) != "null" {
+		c.RawDetails = raw.Details
+		if err := json.Unmarshal(raw.Details, &c.Details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An edit of an approved or live coupon. It takes effect once approved.
+type CouponRevision struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	// The coupon as it will be once the revision is approved
+	Definition  *Coupon    `json:"definition"`
+	SubmittedBy string     `json:"submitted_by"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Body of POST /coupons/:id/reject
+type ReviewRequest struct {
+	// Why the coupon or its revision was rejected, for its editor
+	Reason string `json:"reason"`
+}
+
+// Display text of a coupon in one locale
+type CouponText struct {
+	Name string `json:"name"`
+	// Short text for badges, e.g. "10% off"
+	Badge       string `json:"badge,omitempty"`
+	Description string `json:"description,omitempty"`
+	Terms       string `json:"terms,omitempty"`
+}
+
+// Filters, order and page of GET /coupons
+type CouponListQuery struct {
+	Type   string `form:"type"`
+	Active *bool  `form:"active"`
+	// scheduled, live or expired
+	State      string `form:"state"`
+	ProductId  string `form:"product_id"`
+	CodePrefix string `form:"code_prefix"`
+	// draft, pending_approval, approved, live or ended
+	Status string `form:"status"`
+	// Lists deleted coupons instead of the others
+	Deleted bool `form:"deleted"`
+	// created_at (default) or -created_at
+	Sort string `form:"sort"`
+	// next_cursor of the previous page
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+type CouponPage struct {
+	Coupons []*Coupon `json:"coupons"`
+	// Empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Conditions the shopper has to meet for the coupon to apply. Zero values are not checked.
+type Eligibility struct {
+	FirstOrderOnly     bool     `json:"first_order_only"`
+	Segments           []string `json:"segments"`
+	MinOrderCount      int      `json:"min_order_count"`
+	MinLifetimeSpend   float64  `json:"min_lifetime_spend"`
+	LapsedDays         int      `json:"lapsed_days"`
+	MaxDaysSinceSignup int      `json:"max_days_since_signup"`
 }
 
 type CouponDetails struct {
-	Threshold     int                      `json:"threshold"`
-	Discount      int                      `json:"discount"`
-	ProductId     string                   `json:"product_id"`
-	Quantity      int                      `json:"quantity"`
-	BuyProducts   []ProductQuantityDetails `json:"buy_products"`
-	GetProducts   []ProductQuantityDetails `json:"get_products"`
-	RepitionLimit int                      `json:"repitition_limit"`
+	Threshold       int                      `json:"threshold"`
+	Discount        int                      `json:"discount"`
+	ProductId       string                   `json:"product_id"`
+	Quantity        int                      `json:"quantity"`
+	BuyProducts     []ProductQuantityDetails `json:"buy_products"`
+	GetProducts     []ProductQuantityDetails `json:"get_products"`
+	RepitionLimit   int                      `json:"repitition_limit"`
+	ProductIds      []string                 `json:"product_ids"`
+	Scope           string                   `json:"scope"`
+	Bands           []QuantityBand           `json:"bands"`
+	FixedPrices     []ProductPriceDetails    `json:"fixed_prices"`
+	MaxUnits        int                      `json:"max_units"`
+	ShippingMethods []string                 `json:"shipping_methods"`
+	MaxDiscount     float64                  `json:"max_discount"`
 }
 
 type ProductQuantityDetails struct {
@@ -21,14 +156,39 @@ type ProductQuantityDetails struct {
 	Quantity  int    `json:"quantity"`
 }
 
+// Override price of a product under a fixed price coupon
+type ProductPriceDetails struct {
+	ProductId string  `json:"product_id"`
+	Price     float64 `json:"price"`
+}
+
+// Quantity band of a volume pricing coupon: buying at least MinQuantity units
+// earns Discount percent off each unit
+type QuantityBand struct {
+	MinQuantity int     `json:"min_quantity"`
+	Discount    float64 `json:"discount"`
+}
+
+// A coupon type as listed by GET /coupon-types
+type CouponType struct {
+	Type        string         `json:"type"`
+	Description string         `json:"description"`
+	Schema      *schema.Schema `json:"schema"`
+	// Complete POST /coupons bodies for the type
+	Examples []json.RawMessage `json:"examples"`
+}
+
 // Request structure for the POST /applicable-coupons endpoint
 type ApplicableCouponsRequest struct {
-	Cart Cart `json:"cart"`
+	Cart     Cart      `json:"cart"`
+	Customer *Customer `json:"customer"`
 }
 
 // Structure representing the shopping cart in the request
 type Cart struct {
-	Items []CartItem `json:"items"`
+	Items          []CartItem `json:"items"`
+	ShippingMethod string     `json:"shipping_method"`
+	ShippingFee    float64    `json:"shipping_fee"`
 }
 
 // Structure representing an item in the cart
@@ -36,11 +196,15 @@ type CartItem struct {
 	ProductId string  `json:"product_id"`
 	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
+	Category  string  `json:"category"`
 }
 
 // Structure for the response of the POST /applicable-coupons endpoint
 type ApplicableCouponsResponse struct {
 	ApplicableCoupons []ApplicableCoupon `json:"applicable_coupons"`
+	// Set when the coupons were priced from the last known snapshot because
+	// the database could not be reached
+	Degraded bool `json:"degraded"`
 }
 
 // Structure representing each applicable coupon in the response
@@ -48,18 +212,32 @@ type ApplicableCoupon struct {
 	CouponID string  `json:"coupon_id"`
 	Type     string  `json:"type"`
 	Discount float64 `json:"discount"`
+	// Display text in the locale picked from Accept-Language
+	Name   string `json:"name,omitempty"`
+	Badge  string `json:"badge,omitempty"`
+	Locale string `json:"locale,omitempty"`
 }
 
+// Prices and discounts of the items and of shipping are reported separately
 type UpdatedCart struct {
-	Items         []CartItemDiscount `json:"items"`
-	TotalPrice    float64            `json:"total_price"`
-	TotalDiscount float64            `json:"total_discount"`
-	FinalPrice    float64            `json:"final_price"`
+	Items            []CartItemDiscount `json:"items"`
+	TotalPrice       float64            `json:"total_price"`
+	TotalDiscount    float64            `json:"total_discount"`
+	FinalPrice       float64            `json:"final_price"`
+	ShippingFee      float64            `json:"shipping_fee"`
+	ShippingDiscount float64            `json:"shipping_discount"`
+	FinalShipping    float64            `json:"final_shipping"`
+	// Set when the cart was priced from the last known snapshot because the
+	// database could not be reached
+	Degraded bool `json:"degraded"`
 }
 
 type CartItemDiscount struct {
-	ProductId     string  `json:"product_id"`
-	Quantity      int     `json:"quantity"`
-	Price         float64 `json:"price"`
-	TotalDiscount float64 `json:"total_discount"`
+	ProductId      string        `json:"product_id"`
+	Quantity       int           `json:"quantity"`
+	Price          float64       `json:"price"`
+	TotalDiscount  float64       `json:"total_discount"`
+	UnitDiscount   float64       `json:"unit_discount"`
+	FinalUnitPrice float64       `json:"final_unit_price"`
+	Band           *QuantityBand `json:"band,omitempty"`
 }
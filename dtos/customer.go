@@ -0,0 +1,39 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Structure representing the shopper a cart belongs to
+type Customer struct {
+	Id            string     `json:"id"`
+	Segments      []string   `json:"segments"`
+	SignupDate    *time.Time `json:"signup_date"`
+	OrderCount    int        `json:"order_count"`
+	LifetimeSpend float64    `json:"lifetime_spend"`
+	LastOrderDate *time.Time `json:"last_order_date"`
+}
+
+// Request structure for the POST /orders endpoint
+type OrderRequest struct {
+	OrderId    string  `json:"order_id"`
+	CustomerId string  `json:"customer_id"`
+	Total      float64 `json:"total"`
+}
+
+// Request structure for the POST /redeem-coupon/{id} endpoint
+type RedemptionRequest struct {
+	OrderId  string    `json:"order_id"`
+	Cart     Cart      `json:"cart"`
+	Customer *Customer `json:"customer"`
+}
+
+// Structure for the response of the POST /redeem-coupon/{id} endpoint
+type Redemption struct {
+	Id          string      `json:"id"`
+	CouponId    string      `json:"coupon_id"`
+	OrderId     string      `json:"order_id"`
+	CustomerId  string      `json:"customer_id"`
+	Discount    float64     `json:"discount"`
+	UpdatedCart UpdatedCart `json:"updated_cart"`
+}
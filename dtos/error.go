@@ -0,0 +1,16 @@
+package dtos
+
+// Body of every error response
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"request_id"`
+}
+
+// Problem with a single field of a request, e.g.
+// {"field": "details.buy_products[0].quantity", "message": "must be > 0"}
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
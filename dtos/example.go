@@ -0,0 +1,56 @@
+package dtos
+
+import (
+	"time"
+)
+
+// A sample cart with the outcome its coupon must give it, e.g. a cart with
+// 3 of product A gets 1 of product B free, a discount of 499. The examples of
+// a coupon are run again on every change of the coupon.
+type CouponExample struct {
+	Id        string         `json:"id"`
+	CouponId  string         `json:"coupon_id"`
+	Name      string         `json:"name"`
+	Cart      Cart           `json:"cart"`
+	Customer  *Customer      `json:"customer,omitempty"`
+	Expected  ExampleOutcome `json:"expected"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ExampleOutcome is what a coupon does to the cart of an example. Only the
+// items listed are checked.
+type ExampleOutcome struct {
+	// Set when the coupon must not apply to the cart
+	NotApplicable    bool          `json:"not_applicable,omitempty"`
+	TotalDiscount    float64       `json:"total_discount"`
+	ShippingDiscount float64       `json:"shipping_discount"`
+	Items            []ExampleItem `json:"items,omitempty"`
+}
+
+type ExampleItem struct {
+	ProductId     string  `json:"product_id"`
+	TotalDiscount float64 `json:"total_discount"`
+}
+
+// ExampleResult is the outcome of running one example
+type ExampleResult struct {
+	ExampleId string         `json:"example_id"`
+	CouponId  string         `json:"coupon_id"`
+	Name      string         `json:"name"`
+	Passed    bool           `json:"passed"`
+	Actual    ExampleOutcome `json:"actual"`
+	// Why the coupon did not apply, when it did not
+	Reason string `json:"reason,omitempty"`
+	// What differs from the expected outcome, e.g.
+	// "total_discount is 0.00, expected 499.00"
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Response of POST /coupons/:id/examples/run and POST /coupon-examples/run
+type ExampleRun struct {
+	Total   int             `json:"total"`
+	Passed  int             `json:"passed"`
+	Failed  int             `json:"failed"`
+	Results []ExampleResult `json:"results"`
+}
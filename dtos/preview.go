@@ -0,0 +1,39 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Body of POST /coupons/preview: an unsaved coupon and the carts to try it on
+type PreviewRequest struct {
+	Coupon   *Coupon   `json:"coupon"`
+	Carts    []Cart    `json:"carts"`
+	Customer *Customer `json:"customer,omitempty"`
+	// Time the carts are priced at, now unless set, e.g. to preview a
+	// coupon that has not started yet
+	At *time.Time `json:"at,omitempty"`
+}
+
+type PreviewResponse struct {
+	// One result per cart, in the order of the request
+	Results []PreviewResult `json:"results"`
+	// Problems of the coupon that do not depend on a single cart
+	Warnings []PreviewWarning `json:"warnings"`
+	// Set when the customer history could not be loaded, see Degraded Mode
+	Degraded bool `json:"degraded"`
+}
+
+// PreviewResult is what the coupon does to one cart: the updated cart when
+// it applies, why not otherwise
+type PreviewResult struct {
+	UpdatedCart   *UpdatedCart     `json:"updated_cart,omitempty"`
+	NotApplicable string           `json:"not_applicable,omitempty"`
+	Warnings      []PreviewWarning `json:"warnings"`
+}
+
+// PreviewWarning points out a coupon that is valid but likely set up wrong,
+// e.g. {"code": "discount_over_half", "message": "discount of 120.00 is over 50% of the cart total 200.00"}
+type PreviewWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
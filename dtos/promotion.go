@@ -0,0 +1,44 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Request body of POST /products/promotions
+type PromotionsRequest struct {
+	ProductIds []string `json:"product_ids"`
+}
+
+type PromotionsResponse struct {
+	Products []*ProductPromotions `json:"products"`
+	// Set when the promotions were read from the last known coupon snapshot
+	Degraded bool `json:"degraded"`
+}
+
+// The active coupons that could touch a product
+type ProductPromotions struct {
+	ProductId  string      `json:"product_id"`
+	Promotions []Promotion `json:"promotions"`
+}
+
+// A coupon as shown on a product page
+type Promotion struct {
+	CouponId string `json:"coupon_id"`
+	Type     string `json:"type"`
+	Code     string `json:"code,omitempty"`
+	// product for coupons that name the product, cart for coupons on the whole cart
+	Scope string `json:"scope"`
+	// Display name of the coupon, if it has one
+	Name string `json:"name,omitempty"`
+	// Short text for a badge, e.g. "Buy 2 get 1 free" or "10% off". It is the
+	// badge of the coupon when it has one, and is generated otherwise.
+	Badge  string     `json:"badge"`
+	Locale string     `json:"locale,omitempty"`
+	EndsAt *time.Time `json:"ends_at,omitempty"`
+}
+
+// Response of GET /products/{id}/promotions
+type ProductPromotionsResponse struct {
+	*ProductPromotions
+	Degraded bool `json:"degraded"`
+}
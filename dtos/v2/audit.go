@@ -0,0 +1,82 @@
+package v2
+
+import (
+	"encoding/json"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/jsondiff"
+)
+
+// An entry of the audit log, with the coupon in its v2 form
+type AuditEntry struct {
+	Id        int64             `json:"id"`
+	CouponId  string            `json:"coupon_id"`
+	Action    string            `json:"action"`
+	Actor     string            `json:"actor"`
+	RequestId string            `json:"request_id"`
+	Before    *Coupon           `json:"before"`
+	After     *Coupon           `json:"after"`
+	Changes   []jsondiff.Change `json:"changes"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// FromAuditEntry converts an audit entry to its v2 form. The changes are
+// listed again between the v2 forms of the coupon, so they use v2 field names.
+func FromAuditEntry(e *dtos.AuditEntry) (*AuditEntry, error) {
+	entry := &AuditEntry{
+		Id:        e.Id,
+		CouponId:  e.CouponId,
+		Action:    e.Action,
+		Actor:     e.Actor,
+		RequestId: e.RequestId,
+		CreatedAt: e.CreatedAt,
+	}
+
+	var err error
+	if entry.Before, err = fromSnapshot(e.Before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = fromSnapshot(e.After); err != nil {
+		return nil, err
+	}
+
+	var before, after []byte
+	if entry.Before != nil {
+		if before, err = json.Marshal(entry.Before); err != nil {
+			return nil, err
+		}
+	}
+	if entry.After != nil {
+		if after, err = json.Marshal(entry.After); err != nil {
+			return nil, err
+		}
+	}
+	if entry.Changes, err = jsondiff.Diff(before, after); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func FromAuditEntries(entries []*dtos.AuditEntry) ([]*AuditEntry, error) {
+	converted := make([]*AuditEntry, len(entries))
+	for i, entry := range entries {
+		var err error
+		if converted[i], err = FromAuditEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
+
+// fromSnapshot converts a coupon kept in the audit log to its v2 form
+func fromSnapshot(snapshot json.RawMessage) (*Coupon, error) {
+	if len(snapshot) == 0 || string(snapshot) == "null" {
+		return nil, nil
+	}
+	var coupon dtos.Coupon
+	if err := json.Unmarshal(snapshot, &coupon); err != nil {
+		return nil, err
+	}
+	return FromCoupon(&coupon), nil
+}
@@ -0,0 +1,106 @@
+package v2
+
+import (
+	"time"
+
+	"monk-commerce-assignment/dtos"
+)
+
+// Request body of POST /v2/campaigns and PUT /v2/campaigns/{id}
+type Campaign struct {
+	Id       string     `json:"id,omitempty"`
+	Name     string     `json:"name"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Budget   Money      `json:"budget"`
+	// Set by the server
+	Spent     Money      `json:"spent"`
+	Remaining Money      `json:"remaining"`
+	Status    string     `json:"status,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type CampaignEvent struct {
+	Threshold int       `json:"threshold"`
+	Spent     Money     `json:"spent"`
+	Budget    Money     `json:"budget"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CampaignReport struct {
+	Campaign     *Campaign        `json:"campaign"`
+	SpentPercent float64          `json:"spent_percent"`
+	Redemptions  int              `json:"redemptions"`
+	Coupons      []CampaignCoupon `json:"coupons"`
+	Events       []CampaignEvent  `json:"events"`
+}
+
+type CampaignCoupon struct {
+	CouponId    string `json:"coupon_id"`
+	Code        string `json:"code,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Redemptions int    `json:"redemptions"`
+	Spent       Money  `json:"spent"`
+}
+
+func (c *Campaign) ToCampaign() *dtos.Campaign {
+	return &dtos.Campaign{
+		Name:     c.Name,
+		StartsAt: c.StartsAt,
+		EndsAt:   c.EndsAt,
+		Budget:   c.Budget.Amount(),
+	}
+}
+
+func FromCampaign(c *dtos.Campaign) *Campaign {
+	createdAt, updatedAt := c.CreatedAt, c.UpdatedAt
+	return &Campaign{
+		Id:        c.Id,
+		Name:      c.Name,
+		StartsAt:  c.StartsAt,
+		EndsAt:    c.EndsAt,
+		Budget:    FromAmount(c.Budget),
+		Spent:     FromAmount(c.Spent),
+		Remaining: FromAmount(c.Remaining),
+		Status:    c.Status,
+		CreatedAt: &createdAt,
+		UpdatedAt: &updatedAt,
+	}
+}
+
+func FromCampaigns(campaigns []*dtos.Campaign) []*Campaign {
+	converted := make([]*Campaign, len(campaigns))
+	for i, campaign := range campaigns {
+		converted[i] = FromCampaign(campaign)
+	}
+	return converted
+}
+
+func FromCampaignReport(r *dtos.CampaignReport) *CampaignReport {
+	report := &CampaignReport{
+		Campaign:     FromCampaign(&r.Campaign),
+		SpentPercent: r.SpentPercent,
+		Redemptions:  r.Redemptions,
+		Coupons:      make([]CampaignCoupon, len(r.Coupons)),
+		Events:       make([]CampaignEvent, len(r.Events)),
+	}
+	for i, coupon := range r.Coupons {
+		report.Coupons[i] = CampaignCoupon{
+			CouponId:    coupon.CouponId,
+			Code:        coupon.Code,
+			Status:      coupon.Status,
+			Redemptions: coupon.Redemptions,
+			Spent:       FromAmount(coupon.Spent),
+		}
+	}
+	for i, event := range r.Events {
+		report.Events[i] = CampaignEvent{
+			Threshold: event.Threshold,
+			Spent:     FromAmount(event.Spent),
+			Budget:    FromAmount(event.Budget),
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return report
+}
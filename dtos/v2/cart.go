@@ -0,0 +1,105 @@
+package v2
+
+import (
+	"time"
+)
+
+// Request body of POST /v2/applicable-coupons and POST /v2/coupons/{id}/apply
+type CartRequest struct {
+	Cart     Cart      `json:"cart"`
+	Customer *Customer `json:"customer,omitempty"`
+}
+
+type Cart struct {
+	Items          []CartItem `json:"items"`
+	ShippingMethod string     `json:"shipping_method,omitempty"`
+	ShippingFee    Money      `json:"shipping_fee"`
+}
+
+type CartItem struct {
+	ProductId string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	Price     Money  `json:"price"`
+	Category  string `json:"category,omitempty"`
+}
+
+// The shopper a cart belongs to
+type Customer struct {
+	Id            string     `json:"id"`
+	Segments      []string   `json:"segments,omitempty"`
+	SignupDate    *time.Time `json:"signup_date,omitempty"`
+	OrderCount    int        `json:"order_count,omitempty"`
+	LifetimeSpend Money      `json:"lifetime_spend,omitempty"`
+	LastOrderDate *time.Time `json:"last_order_date,omitempty"`
+}
+
+type ApplicableCoupon struct {
+	CouponId string `json:"coupon_id"`
+	Type     string `json:"type"`
+	Discount Money  `json:"discount"`
+	Name     string `json:"name,omitempty"`
+	Badge    string `json:"badge,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// Prices and discounts of the items and of shipping are reported separately
+type UpdatedCart struct {
+	Items            []CartItemDiscount `json:"items"`
+	TotalPrice       Money              `json:"total_price"`
+	TotalDiscount    Money              `json:"total_discount"`
+	FinalPrice       Money              `json:"final_price"`
+	ShippingFee      Money              `json:"shipping_fee"`
+	ShippingDiscount Money              `json:"shipping_discount"`
+	FinalShipping    Money              `json:"final_shipping"`
+}
+
+type CartItemDiscount struct {
+	ProductId      string        `json:"product_id"`
+	Quantity       int           `json:"quantity"`
+	Price          Money         `json:"price"`
+	TotalDiscount  Money         `json:"total_discount"`
+	UnitDiscount   Money         `json:"unit_discount"`
+	FinalUnitPrice Money         `json:"final_unit_price"`
+	Band           *QuantityBand `json:"band,omitempty"`
+}
+
+// Request body of POST /v2/coupons/{id}/redeem
+type RedemptionRequest struct {
+	OrderId  string    `json:"order_id"`
+	Cart     Cart      `json:"cart"`
+	Customer *Customer `json:"customer"`
+}
+
+type Redemption struct {
+	Id          string      `json:"id"`
+	CouponId    string      `json:"coupon_id"`
+	OrderId     string      `json:"order_id"`
+	CustomerId  string      `json:"customer_id"`
+	Discount    Money       `json:"discount"`
+	UpdatedCart UpdatedCart `json:"updated_cart"`
+}
+
+// Request body of POST /v2/orders
+type OrderRequest struct {
+	OrderId    string `json:"order_id"`
+	CustomerId string `json:"customer_id"`
+	Total      Money  `json:"total"`
+}
+
+// Every /v2 response wraps its data with metadata about the request
+type Response struct {
+	Data interface{} `json:"data"`
+	Meta Meta        `json:"meta"`
+}
+
+type Meta struct {
+	RequestId  string `json:"request_id"`
+	ApiVersion string `json:"api_version"`
+	// Number of items, for list responses
+	Count *int `json:"count,omitempty"`
+	// Cursor of the next page for paginated lists, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Set when the response was priced from the last known coupon snapshot
+	// because the database could not be reached
+	Degraded bool `json:"degraded,omitempty"`
+}
@@ -0,0 +1,464 @@
+package v2
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"strings"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"monk-commerce-assignment/utils/schema"
+)
+
+// v1 coupon detail fields that were renamed in v2
+var renamedFields = map[string]string{
+	"repitition_limit": "repetition_limit",
+	"discount":         "discount_percent",
+}
+
+// v1 coupon detail fields that hold money
+var moneyFields = map[string]bool{
+	"threshold":    true,
+	"max_discount": true,
+	"price":        true,
+}
+
+func fieldName(v1Name string) string {
+	if name, ok := renamedFields[v1Name]; ok {
+		return name
+	}
+	return v1Name
+}
+
+// FromCoupon converts a coupon to its v2 form
+func FromCoupon(c *dtos.Coupon) *Coupon {
+	coupon := &Coupon{
+		Id:        c.Id,
+		Type:      c.Type,
+		Code:      c.Code,
+		Condition: c.Condition,
+		StartsAt:  c.StartsAt,
+		EndsAt:    c.EndsAt,
+		Text: Text{
+			Name:        c.Name,
+			Badge:       c.Badge,
+			Description: c.Description,
+			Terms:       c.Terms,
+		},
+		Locale:          c.Locale,
+		Metadata:        c.Metadata,
+		Tags:            c.Tags,
+		CampaignId:      c.CampaignId,
+		DeletedAt:       c.DeletedAt,
+		Status:          c.Status,
+		EditedBy:        c.EditedBy,
+		SubmittedBy:     c.SubmittedBy,
+		ApprovedBy:      c.ApprovedBy,
+		ApprovedAt:      c.ApprovedAt,
+		RejectionReason: c.RejectionReason,
+	}
+	if r := c.PendingRevision; r != nil {
+		coupon.PendingRevision = &Revision{
+			Id:          r.Id,
+			Status:      r.Status,
+			SubmittedBy: r.SubmittedBy,
+			ReviewedBy:  r.ReviewedBy,
+			Reason:      r.Reason,
+			CreatedAt:   r.CreatedAt,
+			ReviewedAt:  r.ReviewedAt,
+		}
+		if r.Definition != nil {
+			coupon.PendingRevision.Definition = FromCoupon(r.Definition)
+		}
+	}
+	if len(c.Translations) > 0 {
+		coupon.Translations = make(map[string]Text, len(c.Translations))
+		for tag, text := range c.Translations {
+			coupon.Translations[tag] = Text(text)
+		}
+	}
+	if !c.CreatedAt.IsZero() {
+		createdAt := c.CreatedAt
+		coupon.CreatedAt = &createdAt
+	}
+	if c.Eligibility != nil {
+		coupon.Eligibility = &Eligibility{
+			FirstOrderOnly:     c.Eligibility.FirstOrderOnly,
+			Segments:           c.Eligibility.Segments,
+			MinOrderCount:      c.Eligibility.MinOrderCount,
+			MinLifetimeSpend:   FromAmount(c.Eligibility.MinLifetimeSpend),
+			LapsedDays:         c.Eligibility.LapsedDays,
+			MaxDaysSinceSignup: c.Eligibility.MaxDaysSinceSignup,
+		}
+	}
+
+	d := c.Details
+	switch c.Type {
+	case "cart-wise":
+		coupon.Details = &CartWiseDetails{
+			Threshold:       FromAmount(float64(d.Threshold)),
+			DiscountPercent: d.Discount,
+		}
+	case "product-wise":
+		coupon.Details = &ProductWiseDetails{
+			ProductId:       d.ProductId,
+			DiscountPercent: d.Discount,
+		}
+	case "bxgy":
+		coupon.Details = &BxGyDetails{
+			BuyProducts:     fromProductQuantities(d.BuyProducts),
+			GetProducts:     fromProductQuantities(d.GetProducts),
+			RepetitionLimit: d.RepitionLimit,
+		}
+	case "volume":
+		bands := make([]QuantityBand, len(d.Bands))
+		for i, band := range d.Bands {
+			bands[i] = QuantityBand{MinQuantity: band.MinQuantity, DiscountPercent: band.Discount}
+		}
+		coupon.Details = &VolumeDetails{
+			ProductIds: d.ProductIds,
+			Scope:      d.Scope,
+			Bands:      bands,
+		}
+	case "fixed-price":
+		prices := make([]ProductPrice, len(d.FixedPrices))
+		for i, price := range d.FixedPrices {
+			prices[i] = ProductPrice{ProductId: price.ProductId, Price: FromAmount(price.Price)}
+		}
+		coupon.Details = &FixedPriceDetails{
+			FixedPrices: prices,
+			MaxUnits:    d.MaxUnits,
+		}
+	case "shipping":
+		coupon.Details = &ShippingDetails{
+			Threshold:       FromAmount(float64(d.Threshold)),
+			DiscountPercent: d.Discount,
+			MaxDiscount:     FromAmount(d.MaxDiscount),
+			ShippingMethods: d.ShippingMethods,
+		}
+	}
+	return coupon
+}
+
+func FromCoupons(coupons []*dtos.Coupon) []*Coupon {
+	converted := make([]*Coupon, len(coupons))
+	for i, coupon := range coupons {
+		converted[i] = FromCoupon(coupon)
+	}
+	return converted
+}
+
+func fromProductQuantities(products []dtos.ProductQuantityDetails) []ProductQuantity {
+	converted := make([]ProductQuantity, len(products))
+	for i, product := range products {
+		converted[i] = ProductQuantity{ProductId: product.ProductId, Quantity: product.Quantity}
+	}
+	return converted
+}
+
+func toProductQuantities(products []ProductQuantity) []dtos.ProductQuantityDetails {
+	converted := make([]dtos.ProductQuantityDetails, len(products))
+	for i, product := range products {
+		converted[i] = dtos.ProductQuantityDetails{ProductId: product.ProductId, Quantity: product.Quantity}
+	}
+	return converted
+}
+
+// wholeAmount converts money to the whole currency units thresholds are kept in
+func wholeAmount(field string, m Money, problems *[]dtos.FieldError) int {
+	if m%100 != 0 {
+		*problems = append(*problems, dtos.FieldError{Field: field, Message: "must be a whole amount"})
+	}
+	return int(m / 100)
+}
+
+// ToCoupon converts a v2 coupon for the services. The details are validated
+// by the services like those of any other coupon.
+func (c *Coupon) ToCoupon() (*dtos.Coupon, error) {
+	coupon := &dtos.Coupon{
+		Id:          c.Id,
+		Type:        c.Type,
+		Code:        c.Code,
+		Condition:   c.Condition,
+		StartsAt:    c.StartsAt,
+		EndsAt:      c.EndsAt,
+		Name:        c.Name,
+		Badge:       c.Badge,
+		Description: c.Description,
+		Terms:       c.Terms,
+		Metadata:    c.Metadata,
+		Tags:        c.Tags,
+		CampaignId:  c.CampaignId,
+	}
+	if len(c.Translations) > 0 {
+		coupon.Translations = make(map[string]dtos.CouponText, len(c.Translations))
+		for tag, text := range c.Translations {
+			coupon.Translations[tag] = dtos.CouponText(text)
+		}
+	}
+	if c.Eligibility != nil {
+		coupon.Eligibility = &dtos.Eligibility{
+			FirstOrderOnly:     c.Eligibility.FirstOrderOnly,
+			Segments:           c.Eligibility.Segments,
+			MinOrderCount:      c.Eligibility.MinOrderCount,
+			MinLifetimeSpend:   c.Eligibility.MinLifetimeSpend.Amount(),
+			LapsedDays:         c.Eligibility.LapsedDays,
+			MaxDaysSinceSignup: c.Eligibility.MaxDaysSinceSignup,
+		}
+	}
+
+	var problems []dtos.FieldError
+	switch d := c.Details.(type) {
+	case *CartWiseDetails:
+		coupon.Details = dtos.CouponDetails{
+			Threshold: wholeAmount("details.threshold", d.Threshold, &problems),
+			Discount:  d.DiscountPercent,
+		}
+	case *ProductWiseDetails:
+		coupon.Details = dtos.CouponDetails{
+			ProductId: d.ProductId,
+			Discount:  d.DiscountPercent,
+		}
+	case *BxGyDetails:
+		coupon.Details = dtos.CouponDetails{
+			BuyProducts:   toProductQuantities(d.BuyProducts),
+			GetProducts:   toProductQuantities(d.GetProducts),
+			RepitionLimit: d.RepetitionLimit,
+		}
+	case *VolumeDetails:
+		bands := make([]dtos.QuantityBand, len(d.Bands))
+		for i, band := range d.Bands {
+			bands[i] = dtos.QuantityBand{MinQuantity: band.MinQuantity, Discount: band.DiscountPercent}
+		}
+		coupon.Details = dtos.CouponDetails{
+			ProductIds: d.ProductIds,
+			Scope:      d.Scope,
+			Bands:      bands,
+		}
+	case *FixedPriceDetails:
+		prices := make([]dtos.ProductPriceDetails, len(d.FixedPrices))
+		for i, price := range d.FixedPrices {
+			prices[i] = dtos.ProductPriceDetails{ProductId: price.ProductId, Price: price.Price.Amount()}
+		}
+		coupon.Details = dtos.CouponDetails{
+			FixedPrices: prices,
+			MaxUnits:    d.MaxUnits,
+		}
+	case *ShippingDetails:
+		coupon.Details = dtos.CouponDetails{
+			Threshold:       wholeAmount("details.threshold", d.Threshold, &problems),
+			Discount:        d.DiscountPercent,
+			MaxDiscount:     d.MaxDiscount.Amount(),
+			ShippingMethods: d.ShippingMethods,
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Validation("invalid coupon: %s %s", problems[0].Field, problems[0].Message).WithDetails(problems)
+	}
+	return coupon, nil
+}
+
+func (c Cart) ToCart() dtos.Cart {
+	items := make([]dtos.CartItem, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = dtos.CartItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+			Price:     item.Price.Amount(),
+			Category:  item.Category,
+		}
+	}
+	return dtos.Cart{
+		Items:          items,
+		ShippingMethod: c.ShippingMethod,
+		ShippingFee:    c.ShippingFee.Amount(),
+	}
+}
+
+func (c *Customer) ToCustomer() *dtos.Customer {
+	if c == nil {
+		return nil
+	}
+	return &dtos.Customer{
+		Id:            c.Id,
+		Segments:      c.Segments,
+		SignupDate:    c.SignupDate,
+		OrderCount:    c.OrderCount,
+		LifetimeSpend: c.LifetimeSpend.Amount(),
+		LastOrderDate: c.LastOrderDate,
+	}
+}
+
+func FromApplicableCoupons(response *dtos.ApplicableCouponsResponse) []ApplicableCoupon {
+	coupons := make([]ApplicableCoupon, len(response.ApplicableCoupons))
+	for i, coupon := range response.ApplicableCoupons {
+		coupons[i] = ApplicableCoupon{
+			CouponId: coupon.CouponID,
+			Type:     coupon.Type,
+			Discount: FromAmount(coupon.Discount),
+			Name:     coupon.Name,
+			Badge:    coupon.Badge,
+			Locale:   coupon.Locale,
+		}
+	}
+	return coupons
+}
+
+func FromUpdatedCart(cart *dtos.UpdatedCart) UpdatedCart {
+	items := make([]CartItemDiscount, len(cart.Items))
+	for i, item := range cart.Items {
+		items[i] = CartItemDiscount{
+			ProductId:      item.ProductId,
+			Quantity:       item.Quantity,
+			Price:          FromAmount(item.Price),
+			TotalDiscount:  FromAmount(item.TotalDiscount),
+			UnitDiscount:   FromAmount(item.UnitDiscount),
+			FinalUnitPrice: FromAmount(item.FinalUnitPrice),
+		}
+		if item.Band != nil {
+			items[i].Band = &QuantityBand{MinQuantity: item.Band.MinQuantity, DiscountPercent: item.Band.Discount}
+		}
+	}
+	return UpdatedCart{
+		Items:            items,
+		TotalPrice:       FromAmount(cart.TotalPrice),
+		TotalDiscount:    FromAmount(cart.TotalDiscount),
+		FinalPrice:       FromAmount(cart.FinalPrice),
+		ShippingFee:      FromAmount(cart.ShippingFee),
+		ShippingDiscount: FromAmount(cart.ShippingDiscount),
+		FinalShipping:    FromAmount(cart.FinalShipping),
+	}
+}
+
+func (r *RedemptionRequest) ToRedemptionRequest() *dtos.RedemptionRequest {
+	return &dtos.RedemptionRequest{
+		OrderId:  r.OrderId,
+		Cart:     r.Cart.ToCart(),
+		Customer: r.Customer.ToCustomer(),
+	}
+}
+
+func FromRedemption(r *dtos.Redemption) *Redemption {
+	return &Redemption{
+		Id:          r.Id,
+		CouponId:    r.CouponId,
+		OrderId:     r.OrderId,
+		CustomerId:  r.CustomerId,
+		Discount:    FromAmount(r.Discount),
+		UpdatedCart: FromUpdatedCart(&r.UpdatedCart),
+	}
+}
+
+func (r *OrderRequest) ToOrderRequest() *dtos.OrderRequest {
+	return &dtos.OrderRequest{
+		OrderId:    r.OrderId,
+		CustomerId: r.CustomerId,
+		Total:      r.Total.Amount(),
+	}
+}
+
+// FromCouponType converts a coupon type, translating the schema of its
+// details and its examples to v2
+func FromCouponType(t *dtos.CouponType) (*CouponType, error) {
+	examples := make([]*Coupon, len(t.Examples))
+	for i, example := range t.Examples {
+		coupon := &dtos.Coupon{}
+		if err := json.Unmarshal(example, coupon); err != nil {
+			return nil, err
+		}
+		examples[i] = FromCoupon(coupon)
+	}
+	return &CouponType{
+		Type:        t.Type,
+		Description: t.Description,
+		Schema:      translateSchema(t.Schema),
+		Examples:    examples,
+	}, nil
+}
+
+// translateSchema renames the fields of a v1 details schema and turns its
+// money fields into decimal strings. Bounds of money fields are still
+// checked, after conversion.
+func translateSchema(s *schema.Schema) *schema.Schema {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Items = translateSchema(s.Items)
+	if s.Properties != nil {
+		clone.Properties = make(map[string]*schema.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			property = translateSchema(property)
+			if moneyFields[name] {
+				property = moneySchema(property)
+			}
+			clone.Properties[fieldName(name)] = property
+		}
+	}
+	if s.Required != nil {
+		clone.Required = make([]string, len(s.Required))
+		for i, name := range s.Required {
+			clone.Required[i] = fieldName(name)
+		}
+	}
+	return &clone
+}
+
+func moneySchema(number *schema.Schema) *schema.Schema {
+	money := schema.String().Match(MoneyPattern).Describe(number.Description)
+	if number.Default != nil {
+		money.Default = Money(0).String()
+	}
+	return money
+}
+
+// TranslateError renames the v1 detail fields named by a validation error,
+// so clients of /v2 see the fields they sent
+func TranslateError(err error) error {
+	var domainErr *errors.Error
+	if !stderrors.As(err, &domainErr) {
+		return err
+	}
+	// The examples a change breaks are reported in v2 amounts
+	if results, ok := domainErr.Details.([]dtos.ExampleResult); ok {
+		return domainErr.WithDetails(FromExampleRun(&dtos.ExampleRun{Results: results}).Results)
+	}
+	problems, ok := domainErr.Details.([]dtos.FieldError)
+	if !ok {
+		return err
+	}
+
+	translated := make([]dtos.FieldError, len(problems))
+	descriptions := make([]string, len(problems))
+	for i, problem := range problems {
+		translated[i] = dtos.FieldError{Field: translatePath(problem.Field), Message: problem.Message}
+		descriptions[i] = translated[i].Field + " " + translated[i].Message
+	}
+
+	translatedErr := domainErr.WithDetails(translated)
+	if prefix, _, ok := strings.Cut(domainErr.Message, ": "); ok {
+		translatedErr.Message = prefix + ": " + strings.Join(descriptions, "; ")
+	}
+	return translatedErr
+}
+
+// translatePath renames the fields of a path within the coupon details, e.g.
+// details.bands[0].discount becomes details.bands[0].discount_percent, also
+// for the coupon of a preview
+func translatePath(path string) string {
+	if !strings.HasPrefix(path, "details.") && !strings.HasPrefix(path, "coupon.details.") {
+		return path
+	}
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		name, index, _ := strings.Cut(segment, "[")
+		if renamed := fieldName(name); renamed != name {
+			segments[i] = renamed
+			if index != "" {
+				segments[i] += "[" + index
+			}
+		}
+	}
+	return strings.Join(segments, ".")
+}
@@ -0,0 +1,122 @@
+package v2
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+func TestCouponRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		details dtos.CouponDetails
+	}{
+		{"cart-wise", dtos.CouponDetails{Threshold: 100, Discount: 10}},
+		{"product-wise", dtos.CouponDetails{ProductId: "A", Discount: 20}},
+		{"bxgy", dtos.CouponDetails{
+			BuyProducts:   []dtos.ProductQuantityDetails{{ProductId: "A", Quantity: 2}},
+			GetProducts:   []dtos.ProductQuantityDetails{{ProductId: "B", Quantity: 1}},
+			RepitionLimit: 3,
+		}},
+		{"volume", dtos.CouponDetails{
+			ProductIds: []string{"A", "B"},
+			Scope:      "per_product",
+			Bands:      []dtos.QuantityBand{{MinQuantity: 3, Discount: 5}, {MinQuantity: 10, Discount: 12.5}},
+		}},
+		{"fixed-price", dtos.CouponDetails{
+			FixedPrices: []dtos.ProductPriceDetails{{ProductId: "A", Price: 9.99}},
+			MaxUnits:    2,
+		}},
+		{"shipping", dtos.CouponDetails{Threshold: 50, Discount: 100, MaxDiscount: 7.5, ShippingMethods: []string{"standard"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v1 := &dtos.Coupon{Type: tt.name, Name: "Sale", Tags: []string{"summer"}, Details: tt.details}
+
+			// v1 to v2, through the JSON a v2 client sees, and back
+			data, err := json.Marshal(FromCoupon(v1))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var v2 Coupon
+			if err := json.Unmarshal(data, &v2); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", data, err)
+			}
+			if got := v2.Details.CouponType(); got != tt.name {
+				t.Errorf("details are %s, want %s", got, tt.name)
+			}
+			back, err := v2.ToCoupon()
+			if err != nil {
+				t.Fatalf("ToCoupon() error = %v", err)
+			}
+			if back.Type != v1.Type || back.Name != v1.Name || !reflect.DeepEqual(back.Tags, v1.Tags) {
+				t.Errorf("ToCoupon() = %+v, want %+v", back, v1)
+			}
+			if !reflect.DeepEqual(back.Details, v1.Details) {
+				t.Errorf("details = %+v, want %+v", back.Details, v1.Details)
+			}
+
+			// v2 to v1 and back gives the same v2 JSON
+			again, err := json.Marshal(FromCoupon(back))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("v2 round trip = %s, want %s", again, data)
+			}
+		})
+	}
+}
+
+func TestToCouponRejectsPartialThreshold(t *testing.T) {
+	for _, couponType := range []string{"cart-wise", "shipping"} {
+		t.Run(couponType, func(t *testing.T) {
+			var coupon Coupon
+			data := `{"type": "` + couponType + `", "details": {"threshold": "100.50", "discount_percent": 10}}`
+			if err := json.Unmarshal([]byte(data), &coupon); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			_, err := coupon.ToCoupon()
+			var domainErr *errors.Error
+			if !stderrors.As(err, &domainErr) || domainErr.Code != errors.CodeValidation {
+				t.Fatalf("ToCoupon() error = %v, want a validation error", err)
+			}
+			problems, _ := domainErr.Details.([]dtos.FieldError)
+			if len(problems) != 1 || problems[0].Field != "details.threshold" {
+				t.Errorf("details = %+v, want details.threshold", domainErr.Details)
+			}
+		})
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	problems := []dtos.FieldError{
+		{Field: "details.discount", Message: "must be at most 100"},
+		{Field: "details.bands[1].discount", Message: "must be positive"},
+		{Field: "name", Message: "is required"},
+	}
+	err := TranslateError(errors.Validation("invalid coupon: details.discount must be at most 100").WithDetails(problems))
+
+	var domainErr *errors.Error
+	if !stderrors.As(err, &domainErr) {
+		t.Fatalf("TranslateError() = %v, want a domain error", err)
+	}
+	want := []dtos.FieldError{
+		{Field: "details.discount_percent", Message: "must be at most 100"},
+		{Field: "details.bands[1].discount_percent", Message: "must be positive"},
+		{Field: "name", Message: "is required"},
+	}
+	if !reflect.DeepEqual(domainErr.Details, want) {
+		t.Errorf("details = %+v, want %+v", domainErr.Details, want)
+	}
+	wantMessage := "invalid coupon: details.discount_percent must be at most 100; details.bands[1].discount_percent must be positive; name is required"
+	if domainErr.Message != wantMessage {
+		t.Errorf("message = %q, want %q", domainErr.Message, wantMessage)
+	}
+}
@@ -0,0 +1,188 @@
+// Package v2 holds the request and response bodies of the /v2 API and their
+// conversion to and from the DTOs the services work with. Coupon details are
+// a tagged union on the coupon type and money values are decimal strings.
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"monk-commerce-assignment/utils/schema"
+)
+
+type Coupon struct {
+	Id   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Code string `json:"code,omitempty"`
+	// One of the *Details types, matching Type
+	Details     Details      `json:"details"`
+	Eligibility *Eligibility `json:"eligibility,omitempty"`
+	Condition   string       `json:"condition,omitempty"`
+	// Display text in the locale picked from Accept-Language, or of the
+	// default locale on create
+	Text
+	Locale       string          `json:"locale,omitempty"`
+	Translations map[string]Text `json:"translations,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	CampaignId   string          `json:"campaign_id,omitempty"`
+	StartsAt     *time.Time      `json:"starts_at,omitempty"`
+	EndsAt       *time.Time      `json:"ends_at,omitempty"`
+	// Set by the server
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	EditedBy        string     `json:"edited_by,omitempty"`
+	SubmittedBy     string     `json:"submitted_by,omitempty"`
+	ApprovedBy      string     `json:"approved_by,omitempty"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	PendingRevision *Revision  `json:"pending_revision,omitempty"`
+}
+
+// An edit of an approved or live coupon, with the coupon in its v2 form
+type Revision struct {
+	Id          string     `json:"id"`
+	Status      string     `json:"status"`
+	Definition  *Coupon    `json:"definition"`
+	SubmittedBy string     `json:"submitted_by"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Display text of a coupon in one locale
+type Text struct {
+	Name        string `json:"name,omitempty"`
+	Badge       string `json:"badge,omitempty"`
+	Description string `json:"description,omitempty"`
+	Terms       string `json:"terms,omitempty"`
+}
+
+// Details are the type specific fields of a coupon
+type Details interface {
+	CouponType() string
+}
+
+type CartWiseDetails struct {
+	Threshold       Money `json:"threshold"`
+	DiscountPercent int   `json:"discount_percent"`
+}
+
+type ProductWiseDetails struct {
+	ProductId       string `json:"product_id"`
+	DiscountPercent int    `json:"discount_percent"`
+}
+
+type BxGyDetails struct {
+	BuyProducts     []ProductQuantity `json:"buy_products"`
+	GetProducts     []ProductQuantity `json:"get_products"`
+	RepetitionLimit int               `json:"repetition_limit"`
+}
+
+type ProductQuantity struct {
+	ProductId string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type VolumeDetails struct {
+	ProductIds []string       `json:"product_ids"`
+	Scope      string         `json:"scope,omitempty"`
+	Bands      []QuantityBand `json:"bands"`
+}
+
+type QuantityBand struct {
+	MinQuantity     int     `json:"min_quantity"`
+	DiscountPercent float64 `json:"discount_percent"`
+}
+
+type FixedPriceDetails struct {
+	FixedPrices []ProductPrice `json:"fixed_prices"`
+	MaxUnits    int            `json:"max_units,omitempty"`
+}
+
+type ProductPrice struct {
+	ProductId string `json:"product_id"`
+	Price     Money  `json:"price"`
+}
+
+type ShippingDetails struct {
+	Threshold       Money    `json:"threshold"`
+	DiscountPercent int      `json:"discount_percent"`
+	MaxDiscount     Money    `json:"max_discount,omitempty"`
+	ShippingMethods []string `json:"shipping_methods,omitempty"`
+}
+
+func (CartWiseDetails) CouponType() string    { return "cart-wise" }
+func (ProductWiseDetails) CouponType() string { return "product-wise" }
+func (BxGyDetails) CouponType() string        { return "bxgy" }
+func (VolumeDetails) CouponType() string      { return "volume" }
+func (FixedPriceDetails) CouponType() string  { return "fixed-price" }
+func (ShippingDetails) CouponType() string    { return "shipping" }
+
+// newDetails returns empty details for a coupon type, nil for unknown types
+func newDetails(couponType string) Details {
+	switch couponType {
+	case "cart-wise":
+		return &CartWiseDetails{}
+	case "product-wise":
+		return &ProductWiseDetails{}
+	case "bxgy":
+		return &BxGyDetails{}
+	case "volume":
+		return &VolumeDetails{}
+	case "fixed-price":
+		return &FixedPriceDetails{}
+	case "shipping":
+		return &ShippingDetails{}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the details into the type named by the type field.
+// Fields that do not belong to the type are rejected.
+func (c *Coupon) UnmarshalJSON(data []byte) error {
+	type coupon Coupon
+	var raw struct {
+		coupon
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Coupon(raw.coupon)
+	details := newDetails(c.Type)
+	if details == nil || len(raw.Details) == 0 || string(raw.Details) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw.Details))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(details); err != nil {
+		return fmt.Errorf("invalid %s details: %v", c.Type, err)
+	}
+	c.Details = details
+	return nil
+}
+
+// Conditions the shopper has to meet for the coupon to apply. Zero values are not checked.
+type Eligibility struct {
+	FirstOrderOnly     bool     `json:"first_order_only,omitempty"`
+	Segments           []string `json:"segments,omitempty"`
+	MinOrderCount      int      `json:"min_order_count,omitempty"`
+	MinLifetimeSpend   Money    `json:"min_lifetime_spend,omitempty"`
+	LapsedDays         int      `json:"lapsed_days,omitempty"`
+	MaxDaysSinceSignup int      `json:"max_days_since_signup,omitempty"`
+}
+
+// A coupon type as listed by GET /v2/coupon-types
+type CouponType struct {
+	Type        string         `json:"type"`
+	Description string         `json:"description"`
+	Schema      *schema.Schema `json:"schema"`
+	Examples    []*Coupon      `json:"examples"`
+}
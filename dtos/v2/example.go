@@ -0,0 +1,152 @@
+package v2
+
+import (
+	"time"
+
+	"monk-commerce-assignment/dtos"
+)
+
+// Request body of POST /v2/coupons/{id}/examples and PUT /v2/coupons/{id}/examples/{exampleId}
+type CouponExample struct {
+	Id        string         `json:"id"`
+	CouponId  string         `json:"coupon_id"`
+	Name      string         `json:"name"`
+	Cart      Cart           `json:"cart"`
+	Customer  *Customer      `json:"customer,omitempty"`
+	Expected  ExampleOutcome `json:"expected"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type ExampleOutcome struct {
+	NotApplicable    bool          `json:"not_applicable,omitempty"`
+	TotalDiscount    Money         `json:"total_discount"`
+	ShippingDiscount Money         `json:"shipping_discount"`
+	Items            []ExampleItem `json:"items,omitempty"`
+}
+
+type ExampleItem struct {
+	ProductId     string `json:"product_id"`
+	TotalDiscount Money  `json:"total_discount"`
+}
+
+type ExampleResult struct {
+	ExampleId string         `json:"example_id"`
+	CouponId  string         `json:"coupon_id"`
+	Name      string         `json:"name"`
+	Passed    bool           `json:"passed"`
+	Actual    ExampleOutcome `json:"actual"`
+	Reason    string         `json:"reason,omitempty"`
+	Failures  []string       `json:"failures,omitempty"`
+}
+
+type ExampleRun struct {
+	Total   int             `json:"total"`
+	Passed  int             `json:"passed"`
+	Failed  int             `json:"failed"`
+	Results []ExampleResult `json:"results"`
+}
+
+func (e *CouponExample) ToCouponExample() *dtos.CouponExample {
+	example := &dtos.CouponExample{
+		Name:     e.Name,
+		Cart:     e.Cart.ToCart(),
+		Customer: e.Customer.ToCustomer(),
+		Expected: dtos.ExampleOutcome{
+			NotApplicable:    e.Expected.NotApplicable,
+			TotalDiscount:    e.Expected.TotalDiscount.Amount(),
+			ShippingDiscount: e.Expected.ShippingDiscount.Amount(),
+		},
+	}
+	for _, item := range e.Expected.Items {
+		example.Expected.Items = append(example.Expected.Items, dtos.ExampleItem{
+			ProductId:     item.ProductId,
+			TotalDiscount: item.TotalDiscount.Amount(),
+		})
+	}
+	return example
+}
+
+func FromCouponExample(e *dtos.CouponExample) *CouponExample {
+	example := &CouponExample{
+		Id:        e.Id,
+		CouponId:  e.CouponId,
+		Name:      e.Name,
+		Cart:      fromCart(e.Cart),
+		Expected:  fromExampleOutcome(e.Expected),
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+	if e.Customer != nil {
+		example.Customer = &Customer{
+			Id:            e.Customer.Id,
+			Segments:      e.Customer.Segments,
+			SignupDate:    e.Customer.SignupDate,
+			OrderCount:    e.Customer.OrderCount,
+			LifetimeSpend: FromAmount(e.Customer.LifetimeSpend),
+			LastOrderDate: e.Customer.LastOrderDate,
+		}
+	}
+	return example
+}
+
+func FromCouponExamples(examples []*dtos.CouponExample) []*CouponExample {
+	converted := make([]*CouponExample, len(examples))
+	for i, example := range examples {
+		converted[i] = FromCouponExample(example)
+	}
+	return converted
+}
+
+func FromExampleRun(r *dtos.ExampleRun) *ExampleRun {
+	run := &ExampleRun{
+		Total:   r.Total,
+		Passed:  r.Passed,
+		Failed:  r.Failed,
+		Results: make([]ExampleResult, len(r.Results)),
+	}
+	for i, result := range r.Results {
+		run.Results[i] = ExampleResult{
+			ExampleId: result.ExampleId,
+			CouponId:  result.CouponId,
+			Name:      result.Name,
+			Passed:    result.Passed,
+			Actual:    fromExampleOutcome(result.Actual),
+			Reason:    result.Reason,
+			Failures:  result.Failures,
+		}
+	}
+	return run
+}
+
+func fromExampleOutcome(o dtos.ExampleOutcome) ExampleOutcome {
+	outcome := ExampleOutcome{
+		NotApplicable:    o.NotApplicable,
+		TotalDiscount:    FromAmount(o.TotalDiscount),
+		ShippingDiscount: FromAmount(o.ShippingDiscount),
+	}
+	for _, item := range o.Items {
+		outcome.Items = append(outcome.Items, ExampleItem{
+			ProductId:     item.ProductId,
+			TotalDiscount: FromAmount(item.TotalDiscount),
+		})
+	}
+	return outcome
+}
+
+func fromCart(c dtos.Cart) Cart {
+	items := make([]CartItem, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = CartItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+			Price:     FromAmount(item.Price),
+			Category:  item.Category,
+		}
+	}
+	return Cart{
+		Items:          items,
+		ShippingMethod: c.ShippingMethod,
+		ShippingFee:    FromAmount(c.ShippingFee),
+	}
+}
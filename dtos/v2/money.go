@@ -0,0 +1,69 @@
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (1/100 of the currency unit). It is sent
+// as a decimal string with two decimals, e.g. "1999.50", so clients do not
+// lose precision to floating point.
+type Money int64
+
+// MoneyPattern is the format of money values, published in the JSON Schemas
+const MoneyPattern = `^-?\d+(\.\d{1,2})?$`
+
+var moneyPattern = regexp.MustCompile(MoneyPattern)
+
+// FromAmount converts an amount in currency units, as used by the services
+func FromAmount(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// Amount returns the amount in currency units, as used by the services
+func (m Money) Amount() float64 {
+	return float64(m) / 100
+}
+
+func (m Money) String() string {
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("money must be a decimal string such as \"19.99\"")
+	}
+	if !moneyPattern.MatchString(value) {
+		return fmt.Errorf("invalid money value %q, expected a decimal string such as \"19.99\"", value)
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	units, cents, _ := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+	// Pad the cents before parsing, so amounts too large for int64 are
+	// rejected rather than wrapping around
+	cents += strings.Repeat("0", 2-len(cents))
+	minor, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money value %q: %v", value, err)
+	}
+	if negative {
+		minor = -minor
+	}
+	*m = Money(minor)
+	return nil
+}
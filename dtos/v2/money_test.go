@@ -0,0 +1,92 @@
+package v2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Money
+		wantErr bool
+	}{
+		{"whole amount", `"19"`, 1900, false},
+		{"two decimals", `"19.99"`, 1999, false},
+		{"one decimal", `"19.5"`, 1950, false},
+		{"zero", `"0.00"`, 0, false},
+		{"negative", `"-5.25"`, -525, false},
+		{"negative whole amount", `"-5"`, -500, false},
+		{"largest amount", `"92233720368547758.07"`, 9223372036854775807, false},
+		{"number instead of string", `19.99`, 0, true},
+		{"empty", `""`, 0, true},
+		{"three decimals", `"19.999"`, 0, true},
+		{"no units", `".50"`, 0, true},
+		{"trailing dot", `"19."`, 0, true},
+		{"plus sign", `"+19.99"`, 0, true},
+		{"thousands separator", `"1,999.00"`, 0, true},
+		{"exponent", `"1e3"`, 0, true},
+		{"overflowing cents", `"92233720368547758.08"`, 0, true},
+		{"overflowing whole amount", `"92233720368547759"`, 0, true},
+		{"overflowing negative", `"-99999999999999999999"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.json, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %d, want %d", tt.json, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{0, `"0.00"`},
+		{5, `"0.05"`},
+		{1999, `"19.99"`},
+		{-525, `"-5.25"`},
+		{-5, `"-0.05"`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.money)
+		if err != nil {
+			t.Fatalf("Marshal(%d) error = %v", tt.money, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Marshal(%d) = %s, want %s", tt.money, data, tt.want)
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != tt.money {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", data, back, err, tt.money)
+		}
+	}
+}
+
+func TestFromAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   Money
+	}{
+		{19.99, 1999},
+		{0.1 + 0.2, 30},
+		{2.675, 268},
+		{-5.25, -525},
+	}
+
+	for _, tt := range tests {
+		if got := FromAmount(tt.amount); got != tt.want {
+			t.Errorf("FromAmount(%v) = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package v2
+
+import (
+	"time"
+
+	"monk-commerce-assignment/dtos"
+)
+
+// Request body of POST /v2/coupons/preview
+type PreviewRequest struct {
+	Coupon   *Coupon    `json:"coupon"`
+	Carts    []Cart     `json:"carts"`
+	Customer *Customer  `json:"customer,omitempty"`
+	At       *time.Time `json:"at,omitempty"`
+}
+
+type Preview struct {
+	Results  []PreviewResult  `json:"results"`
+	Warnings []PreviewWarning `json:"warnings"`
+}
+
+type PreviewResult struct {
+	UpdatedCart   *UpdatedCart     `json:"updated_cart,omitempty"`
+	NotApplicable string           `json:"not_applicable,omitempty"`
+	Warnings      []PreviewWarning `json:"warnings"`
+}
+
+type PreviewWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ToPreviewRequest converts a v2 preview for the services. A missing coupon
+// is left for the services to report.
+func (r *PreviewRequest) ToPreviewRequest() (*dtos.PreviewRequest, error) {
+	req := &dtos.PreviewRequest{
+		Carts:    make([]dtos.Cart, len(r.Carts)),
+		Customer: r.Customer.ToCustomer(),
+		At:       r.At,
+	}
+	if r.Coupon != nil {
+		coupon, err := r.Coupon.ToCoupon()
+		if err != nil {
+			return nil, err
+		}
+		req.Coupon = coupon
+	}
+	for i, cart := range r.Carts {
+		req.Carts[i] = cart.ToCart()
+	}
+	return req, nil
+}
+
+func FromPreview(p *dtos.PreviewResponse) *Preview {
+	preview := &Preview{
+		Results:  make([]PreviewResult, len(p.Results)),
+		Warnings: fromPreviewWarnings(p.Warnings),
+	}
+	for i, result := range p.Results {
+		preview.Results[i] = PreviewResult{
+			NotApplicable: result.NotApplicable,
+			Warnings:      fromPreviewWarnings(result.Warnings),
+		}
+		if result.UpdatedCart != nil {
+			updatedCart := FromUpdatedCart(result.UpdatedCart)
+			preview.Results[i].UpdatedCart = &updatedCart
+		}
+	}
+	return preview
+}
+
+func fromPreviewWarnings(warnings []dtos.PreviewWarning) []PreviewWarning {
+	converted := make([]PreviewWarning, len(warnings))
+	for i, warning := range warnings {
+		converted[i] = PreviewWarning(warning)
+	}
+	return converted
+}
@@ -0,0 +1,41 @@
+package v2
+
+import (
+	"time"
+
+	"monk-commerce-assignment/dtos"
+)
+
+// Request body of POST /v2/products/promotions
+type PromotionsRequest struct {
+	ProductIds []string `json:"product_ids"`
+}
+
+type ProductPromotions struct {
+	ProductId  string      `json:"product_id"`
+	Promotions []Promotion `json:"promotions"`
+}
+
+// A coupon as shown on a product page
+type Promotion struct {
+	CouponId string `json:"coupon_id"`
+	Type     string `json:"type"`
+	Code     string `json:"code,omitempty"`
+	// product for coupons that name the product, cart for coupons on the whole cart
+	Scope  string     `json:"scope"`
+	Name   string     `json:"name,omitempty"`
+	Badge  string     `json:"badge"`
+	Locale string     `json:"locale,omitempty"`
+	EndsAt *time.Time `json:"ends_at,omitempty"`
+}
+
+func FromProductPromotions(p *dtos.ProductPromotions) *ProductPromotions {
+	promotions := &ProductPromotions{
+		ProductId:  p.ProductId,
+		Promotions: make([]Promotion, len(p.Promotions)),
+	}
+	for i, promotion := range p.Promotions {
+		promotions.Promotions[i] = Promotion(promotion)
+	}
+	return promotions
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getCouponHistory(c *gin.Context) {
+	ctx := newContext(c)
+
+	var query dtos.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.Validation("invalid query parameters: %v", err))
+		return
+	}
+
+	page, err := couponService().GetCouponHistory(ctx, c.Param("id"), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Like the coupon listing, the cursor of the next page goes in a header
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Entries)
+}
+
+// searchAuditLog searches the changes of every coupon, e.g. by actor or by
+// request ID
+func searchAuditLog(c *gin.Context) {
+	ctx := newContext(c)
+
+	var query dtos.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.Validation("invalid query parameters: %v", err))
+		return
+	}
+
+	page, err := couponService().SearchAuditLog(ctx, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Entries)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createCampaign(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.Campaign
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	campaign, err := couponService().CreateCampaign(ctx, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func getCampaigns(c *gin.Context) {
+	ctx := newContext(c)
+
+	campaigns, err := couponService().ListCampaigns(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+func getCampaign(c *gin.Context) {
+	ctx := newContext(c)
+
+	campaign, err := couponService().GetCampaign(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func updateCampaign(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.Campaign
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	campaign, err := couponService().UpdateCampaign(ctx, c.Param("id"), &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// getCampaignReport shows what a campaign spent against its budget
+func getCampaignReport(c *gin.Context) {
+	ctx := newContext(c)
+
+	report, err := couponService().GetCampaignReport(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
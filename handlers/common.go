@@ -2,22 +2,38 @@ package handlers
 
 import (
 	"monk-commerce-assignment/config"
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/services"
 	"monk-commerce-assignment/utils/context"
 	"monk-commerce-assignment/utils/db"
+	"monk-commerce-assignment/utils/locale"
 	"monk-commerce-assignment/utils/log"
 
-	"github.com/google/uuid" // Import the uuid package
+	"github.com/gin-gonic/gin"
 )
 
-func logAndGetContext(c *context.Context) {
-	c.RefID = c.Request.Header.Get("X-Request-Id")
-
-	if c.RefID == "" {
-		c.RefID = uuid.New().String() // Use uuid.New() instead of uuid.New().String()
-	}
+// newContext builds the request context for a gin request, with the request
+// ID assigned by the requestID middleware, the actor and the locales of
+// Accept-Language
+func newContext(c *gin.Context) *context.Context {
+	refID := c.GetString(requestIDKey)
 
 	cfg := config.Get()
 
-	c.Log = log.New(c.RefID, cfg.AppName, cfg.LogLevel)
-	c.DB = db.New()
+	ctx := context.New(c.Request.Context(), refID, log.New(refID, cfg.AppName, cfg.LogLevel), db.New())
+	ctx.Actor = actor(c)
+	ctx.Roles = roles(c)
+	ctx.Locales = locale.Parse(c.GetHeader("Accept-Language"))
+	return ctx
+}
+
+// The storage the services run on, set by SetupRoutes
+var repositories daos.Repositories
+
+func couponService() services.ICouponService {
+	return services.NewCouponService(repositories.Coupons, repositories.Customers)
+}
+
+func redemptionService() services.IRedemptionService {
+	return services.NewRedemptionService(repositories.Customers, couponService())
 }
@@ -2,46 +2,77 @@ package handlers
 
 import (
 	"encoding/json"
+	"monk-commerce-assignment/daos"
 	"monk-commerce-assignment/dtos"
 	"monk-commerce-assignment/services"
-	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine) {
+func SetupRoutes(router *gin.Engine, repos daos.Repositories) {
+	repositories = repos
+	services.ResetCouponIndex()
+
+	router.Use(requestID(), errorHandler(), identity())
+
+	// The unversioned routes predate /v1 and are kept as its aliases
+	setupV1Routes(router.Group("", deprecated("/v2")))
+	setupV1Routes(router.Group("/v1", deprecated("/v2")))
+	setupV2Routes(router.Group("/v2"))
+}
+
+func setupV1Routes(router gin.IRoutes) {
 	router.POST("/coupons", createCoupon)
 	router.GET("/coupons", getAllCoupons)
 	router.GET("/coupons/:id", getCouponById)
+	router.GET("/coupons/:id/history", getCouponHistory)
+	router.GET("/audit", searchAuditLog)
 	router.POST("/applicable-coupons", getApplicableCoupons)
 	router.POST("/apply-coupon/:id", applyCoupon)
+	router.POST("/coupons/preview", previewCoupon)
 	router.DELETE("/coupons/:id", deleteCoupon)
-
+	router.POST("/coupons/:id/restore", restoreCoupon)
+	router.PUT("/coupons/:id", updateCoupon)
+	router.POST("/coupons/:id/submit", submitCoupon)
+	router.POST("/coupons/:id/approve", approveCoupon)
+	router.POST("/coupons/:id/reject", rejectCoupon)
+	router.POST("/coupons/:id/end", endCoupon)
+	router.GET("/coupons/:id/examples", getCouponExamples)
+	router.POST("/coupons/:id/examples", createCouponExample)
+	router.GET("/coupons/:id/examples/:exampleId", getCouponExample)
+	router.PUT("/coupons/:id/examples/:exampleId", updateCouponExample)
+	router.DELETE("/coupons/:id/examples/:exampleId", deleteCouponExample)
+	router.POST("/coupons/:id/examples/run", runCouponExamples)
+	router.POST("/coupon-examples/run", runAllCouponExamples)
+	router.POST("/campaigns", createCampaign)
+	router.GET("/campaigns", getCampaigns)
+	router.GET("/campaigns/:id", getCampaign)
+	router.PUT("/campaigns/:id", updateCampaign)
+	router.GET("/campaigns/:id/report", getCampaignReport)
+	router.GET("/coupon-types", getCouponTypes)
+	router.POST("/redeem-coupon/:id", redeemCoupon)
+	router.POST("/orders", recordOrder)
+	router.GET("/products/:id/promotions", getProductPromotions)
+	router.POST("/products/promotions", getPromotions)
 }
 
 func createCoupon(c *gin.Context) {
-	ctx := &context.Context{
-		Context: c,
-	}
-	logAndGetContext(ctx)
-	decoder := json.NewDecoder(ctx.Request.Body)
+	ctx := newContext(c)
+	decoder := json.NewDecoder(c.Request.Body)
 
 	req := &dtos.Coupon{}
 	err := decoder.Decode(req)
 	if err != nil {
 		ctx.Log.Error("error parsing request")
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		c.Error(errors.Validation("invalid request payload: %v", err))
 		return
 	}
 
-	err = services.NewCouponService().CreateCoupon(ctx, req)
+	_, err = couponService().CreateCoupon(ctx, req)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -52,33 +83,35 @@ func createCoupon(c *gin.Context) {
 }
 
 func getAllCoupons(c *gin.Context) {
-	ctx := &context.Context{
-		Context: c,
+	ctx := newContext(c)
+
+	var query dtos.CouponListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.Validation("invalid query parameters: %v", err))
+		return
 	}
 
-	coupons, err := services.NewCouponService().GetCoupons(ctx)
+	page, err := couponService().ListCoupons(ctx, query)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"err": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, coupons)
+	// v1 responds with the list itself, the cursor of the next page goes in a header
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Coupons)
 }
 
 func getCouponById(c *gin.Context) {
-	ctx := &context.Context{
-		Context: c,
-	}
+	ctx := newContext(c)
 
 	couponId := c.Param("id")
 
-	coupon, err := services.NewCouponService().GetCouponById(ctx, couponId)
+	coupon, err := couponService().GetCouponById(ctx, couponId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -86,74 +119,73 @@ func getCouponById(c *gin.Context) {
 }
 
 func getApplicableCoupons(c *gin.Context) {
-	ctx := &context.Context{
-		Context: c,
-	}
+	ctx := newContext(c)
 
 	var request dtos.ApplicableCouponsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request payload",
-		})
+		c.Error(errors.Validation("invalid request payload: %v", err))
 		return
 	}
 
-	applicableCoupons, err := services.NewCouponService().GetApplicableCoupons(ctx, request.Cart.Items)
+	response, err := couponService().GetApplicableCoupons(ctx, request.Cart, request.Customer)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
-	response := dtos.ApplicableCouponsResponse{
-		ApplicableCoupons: applicableCoupons,
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
 func applyCoupon(c *gin.Context) {
-	ctx := &context.Context{
-		Context: c,
-	}
+	ctx := newContext(c)
 
 	couponId := c.Param("id")
 
 	var request dtos.ApplicableCouponsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request payload",
-		})
+		c.Error(errors.Validation("invalid request payload: %v", err))
 		return
 	}
 
-	updatedCart, err := services.NewCouponService().ApplyCoupon(ctx, couponId, request.Cart.Items)
+	updatedCart, err := couponService().ApplyCoupon(ctx, couponId, request.Cart, request.Customer)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, updatedCart)
 }
 
+// previewCoupon prices sample carts with a coupon that is not saved
+func previewCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.PreviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	preview, err := couponService().PreviewCoupon(ctx, request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
 func deleteCoupon(c *gin.Context) {
 	// Initialize the context
-	ctx := &context.Context{
-		Context: c,
-	}
+	ctx := newContext(c)
 
 	// Get the coupon ID from the URL parameter
 	couponId := c.Param("id")
 
 	// Call the service to delete the coupon
-	err := services.NewCouponService().DeleteCoupon(ctx, couponId)
+	err := couponService().DeleteCoupon(ctx, couponId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -162,3 +194,22 @@ func deleteCoupon(c *gin.Context) {
 		"message": "Coupon deleted successfully",
 	})
 }
+
+// restoreCoupon brings back a deleted coupon that was not purged yet
+func restoreCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().RestoreCoupon(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+func getCouponTypes(c *gin.Context) {
+	ctx := newContext(c)
+
+	c.JSON(http.StatusOK, couponService().GetCouponTypes(ctx))
+}
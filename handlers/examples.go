@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getCouponExamples(c *gin.Context) {
+	ctx := newContext(c)
+
+	examples, err := couponService().ListCouponExamples(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, examples)
+}
+
+func getCouponExample(c *gin.Context) {
+	ctx := newContext(c)
+
+	example, err := couponService().GetCouponExample(ctx, c.Param("id"), c.Param("exampleId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, example)
+}
+
+func createCouponExample(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.CouponExample
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	example, err := couponService().CreateCouponExample(ctx, c.Param("id"), &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, example)
+}
+
+func updateCouponExample(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.CouponExample
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	example, err := couponService().UpdateCouponExample(ctx, c.Param("id"), c.Param("exampleId"), &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, example)
+}
+
+func deleteCouponExample(c *gin.Context) {
+	ctx := newContext(c)
+
+	err := couponService().DeleteCouponExample(ctx, c.Param("id"), c.Param("exampleId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Example deleted successfully",
+	})
+}
+
+// runCouponExamples runs the examples of a coupon against its current definition
+func runCouponExamples(c *gin.Context) {
+	ctx := newContext(c)
+
+	run, err := couponService().RunCouponExamples(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// runAllCouponExamples runs the examples of every coupon, failed examples are
+// reported in the response rather than as an error
+func runAllCouponExamples(c *gin.Context) {
+	ctx := newContext(c)
+
+	run, err := couponService().RunAllCouponExamples(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
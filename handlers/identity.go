@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"monk-commerce-assignment/config"
+	"monk-commerce-assignment/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The gateway in front of the service authenticates users and passes who
+// they are in these headers, signed with the identity secret it shares with
+// the service, so clients cannot claim to be someone else
+const (
+	// The authenticated user, recorded as the actor of changes
+	actorHeader = "X-Actor"
+	// Roles of the user, comma separated, e.g. "approver"
+	rolesHeader = "X-Actor-Roles"
+	// Unix time in seconds after which the signature is no longer accepted
+	actorExpiresHeader = "X-Actor-Expires"
+	// Hex encoded HMAC-SHA256 of the identity, see identityPayload
+	actorSignatureHeader = "X-Actor-Signature"
+	// Actor of requests without one
+	anonymousActor = "anonymous"
+	maxActorLength = 255
+)
+
+// Keys of the verified identity in the gin context
+const (
+	actorKey = "actor"
+	rolesKey = "roles"
+)
+
+// identity verifies the identity headers of a request. Requests without them
+// are made by the anonymous actor; requests with headers that are not signed
+// with the identity secret, or whose signature expired, are rejected.
+func identity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, roles, err := verifyIdentity(c.Request.Header, config.Get().IdentitySecret, time.Now())
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(actorKey, actor)
+		c.Set(rolesKey, roles)
+		c.Next()
+	}
+}
+
+// identityPayload is what the gateway signs: the identity header values as
+// sent, one per line, with an empty line for a request without roles
+func identityPayload(actor string, roles string, expires string) string {
+	return actor + "\n" + roles + "\n" + expires
+}
+
+func identityMAC(secret string, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// verifyIdentity returns the actor named by the identity headers and their
+// roles once their signature checks out. The anonymous actor has no roles.
+func verifyIdentity(header http.Header, secret string, now time.Time) (string, []string, error) {
+	actor := header.Get(actorHeader)
+	roles := header.Get(rolesHeader)
+	expires := header.Get(actorExpiresHeader)
+	signature := header.Get(actorSignatureHeader)
+	if strings.TrimSpace(actor) == "" && expires == "" && signature == "" {
+		return anonymousActor, nil, nil
+	}
+
+	if secret == "" {
+		return "", nil, errors.Forbidden("identity headers are not accepted, the service has no identity secret configured")
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", nil, errors.Forbidden("%s must be a Unix time", actorExpiresHeader)
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, identityMAC(secret, identityPayload(actor, roles, expires))) {
+		return "", nil, errors.Forbidden("%s does not match the identity of the request", actorSignatureHeader)
+	}
+	if now.Unix() > expiresAt {
+		return "", nil, errors.Forbidden("identity of the request expired at %s", time.Unix(expiresAt, 0).UTC().Format(time.RFC3339))
+	}
+
+	actor = strings.TrimSpace(actor)
+	if actor == "" {
+		return anonymousActor, nil, nil
+	}
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+	return actor, parseRoles(roles), nil
+}
+
+func parseRoles(header string) []string {
+	var roles []string
+	for _, role := range strings.Split(header, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// actor returns who a request is made by, for the audit log
+func actor(c *gin.Context) string {
+	if actor := c.GetString(actorKey); actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// roles returns the verified roles of the actor of a request
+func roles(c *gin.Context) []string {
+	return c.GetStringSlice(rolesKey)
+}
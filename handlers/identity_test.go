@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/config"
+	"monk-commerce-assignment/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "test-secret"
+
+// signedHeader returns the identity headers the gateway sends for actor
+// with roles
+func signedHeader(secret string, actor string, roles string, expires time.Time) http.Header {
+	expiresAt := strconv.FormatInt(expires.Unix(), 10)
+	header := http.Header{}
+	header.Set(actorHeader, actor)
+	if roles != "" {
+		header.Set(rolesHeader, roles)
+	}
+	header.Set(actorExpiresHeader, expiresAt)
+	header.Set(actorSignatureHeader, hex.EncodeToString(identityMAC(secret, identityPayload(actor, roles, expiresAt))))
+	return header
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	later := now.Add(time.Minute)
+
+	forged := signedHeader(testSecret, "alice", "", later)
+	forged.Set(actorHeader, "mallory")
+	promoted := signedHeader(testSecret, "alice", "", later)
+	promoted.Set(rolesHeader, "approver")
+	changedRoles := signedHeader(testSecret, "alice", "viewer", later)
+	changedRoles.Set(rolesHeader, "viewer,approver")
+	wrongSecret := signedHeader("other-secret", "alice", "", later)
+	extended := signedHeader(testSecret, "alice", "", later)
+	extended.Set(actorExpiresHeader, strconv.FormatInt(later.Add(time.Hour).Unix(), 10))
+	unsigned := http.Header{}
+	unsigned.Set(actorHeader, "alice")
+	unsignedRoles := http.Header{}
+	unsignedRoles.Set(rolesHeader, "approver")
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		secret    string
+		want      string
+		wantRoles []string
+		denied    bool
+	}{
+		{"no identity", http.Header{}, testSecret, anonymousActor, nil, false},
+		{"roles without an actor", unsignedRoles, testSecret, anonymousActor, nil, false},
+		{"signed", signedHeader(testSecret, "alice", "", later), testSecret, "alice", nil, false},
+		{"signed with roles", signedHeader(testSecret, "alice", " approver, ,viewer", later), testSecret, "alice", []string{"approver", "viewer"}, false},
+		{"signed until now", signedHeader(testSecret, "alice", "", now), testSecret, "alice", nil, false},
+		{"unsigned", unsigned, testSecret, "", nil, true},
+		{"changed actor", forged, testSecret, "", nil, true},
+		{"added roles", promoted, testSecret, "", nil, true},
+		{"changed roles", changedRoles, testSecret, "", nil, true},
+		{"changed expiry", extended, testSecret, "", nil, true},
+		{"other secret", wrongSecret, testSecret, "", nil, true},
+		{"expired", signedHeader(testSecret, "alice", "", now.Add(-time.Second)), testSecret, "", nil, true},
+		{"no secret configured", signedHeader("", "alice", "", later), "", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotRoles, err := verifyIdentity(tt.header, tt.secret, now)
+			if tt.denied {
+				if errors.From(err).Code != errors.CodeForbidden {
+					t.Errorf("verifyIdentity() error = %v, want forbidden", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIdentity() error = %v", err)
+			}
+			if got != tt.want || !slices.Equal(gotRoles, tt.wantRoles) {
+				t.Errorf("verifyIdentity() = %q %q, want %q %q", got, gotRoles, tt.want, tt.wantRoles)
+			}
+		})
+	}
+}
+
+func TestVerifyIdentityTruncatesLongActors(t *testing.T) {
+	long := strings.Repeat("a", maxActorLength+10)
+	got, _, err := verifyIdentity(signedHeader(testSecret, long, "", time.Now().Add(time.Minute)), testSecret, time.Now())
+	if err != nil {
+		t.Fatalf("verifyIdentity() error = %v", err)
+	}
+	if len(got) != maxActorLength {
+		t.Errorf("actor is %d characters long, want %d", len(got), maxActorLength)
+	}
+}
+
+func TestIdentityMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Set(&config.Config{IdentitySecret: testSecret})
+	router := gin.New()
+	router.Use(requestID(), errorHandler(), identity())
+	router.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, actor(c)+" "+strings.Join(roles(c), ","))
+	})
+
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header = header
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(signedHeader(testSecret, "alice", "approver", time.Now().Add(time.Minute)))
+	if w.Code != http.StatusOK || w.Body.String() != "alice approver" {
+		t.Errorf("signed request = %d %s, want 200 alice approver", w.Code, w.Body)
+	}
+
+	unsigned := http.Header{}
+	unsigned.Set(actorHeader, "alice")
+	if w := serve(unsigned); w.Code != http.StatusForbidden {
+		t.Errorf("unsigned request = %d %s, want 403", w.Code, w.Body)
+	}
+}
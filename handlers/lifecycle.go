@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forced reads the force query parameter, set to save a change that breaks
+// the examples of the coupon
+func forced(c *gin.Context) (bool, error) {
+	force := c.Query("force")
+	if force == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(force)
+	if err != nil {
+		return false, errors.Validation("invalid query parameters: force must be true or false")
+	}
+	return value, nil
+}
+
+// updateCoupon replaces the definition of a coupon. Changes to approved and
+// live coupons wait for approval as a revision.
+func updateCoupon(c *gin.Context) {
+	ctx := newContext(c)
+	decoder := json.NewDecoder(c.Request.Body)
+
+	req := &dtos.Coupon{}
+	err := decoder.Decode(req)
+	if err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	force, err := forced(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	coupon, err := couponService().UpdateCoupon(ctx, c.Param("id"), req, force)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// submitCoupon sends a draft for approval
+func submitCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().SubmitCoupon(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// approveCoupon approves a coupon pending approval or its pending revision
+func approveCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().ApproveCoupon(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// rejectCoupon sends a coupon pending approval back to draft, or rejects its
+// pending revision
+func rejectCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	coupon, err := couponService().RejectCoupon(ctx, c.Param("id"), request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// endCoupon stops an approved or live coupon for good
+func endCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().EndCoupon(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"monk-commerce-assignment/config"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"monk-commerce-assignment/utils/log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Key of the request ID in the gin context
+const requestIDKey = "request_id"
+
+// requestID assigns every request an ID, taken from the X-Request-Id header
+// when the client sends one, and echoes it back in the response
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-Id")
+		if id == "" {
+			id = uuid.New().String()
+		}
+		c.Set(requestIDKey, id)
+		c.Header("X-Request-Id", id)
+		c.Next()
+	}
+}
+
+// deprecated marks the responses of an API version that has a successor,
+// with the date it is switched off when one is configured
+func deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		if sunset := config.Get().V1SunsetDate; sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		c.Next()
+	}
+}
+
+// errorHandler answers requests whose handler failed with c.Error. The error
+// is turned into a domain error and reported with its status code in the
+// common error envelope.
+func errorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		refID := c.GetString(requestIDKey)
+		err := errors.From(c.Errors.Last().Err)
+		if err.Code == errors.CodeInternal || err.Code == errors.CodeUnavailable {
+			cfg := config.Get()
+			log.New(refID, cfg.AppName, cfg.LogLevel).Error("request failed",
+				zap.String("path", c.FullPath()), zap.Error(err))
+		}
+
+		c.JSON(err.Code.Status(), dtos.Error{
+			Code:      string(err.Code),
+			Message:   err.Message,
+			Details:   err.Details,
+			RequestId: refID,
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getProductPromotions(c *gin.Context) {
+	ctx := newContext(c)
+
+	response, err := couponService().GetProductPromotions(ctx, []string{c.Param("id")})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.ProductPromotionsResponse{
+		ProductPromotions: response.Products[0],
+		Degraded:          response.Degraded,
+	})
+}
+
+// getPromotions looks up the promotions of many products at once, for
+// listing pages
+func getPromotions(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.PromotionsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	response, err := couponService().GetProductPromotions(ctx, request.ProductIds)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func redeemCoupon(c *gin.Context) {
+	ctx := newContext(c)
+
+	couponId := c.Param("id")
+
+	var request dtos.RedemptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	redemption, err := redemptionService().RedeemCoupon(ctx, couponId, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+func recordOrder(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.OrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	err := redemptionService().RecordOrder(ctx, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order recorded successfully",
+	})
+}
@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"monk-commerce-assignment/dtos"
+	v2 "monk-commerce-assignment/dtos/v2"
+	"monk-commerce-assignment/utils/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The v2 API runs on the same services as v1; requests and responses are
+// converted by the dtos/v2 package
+func setupV2Routes(router gin.IRoutes) {
+	router.POST("/coupons", createCouponV2)
+	router.GET("/coupons", getAllCouponsV2)
+	router.GET("/coupons/:id", getCouponByIdV2)
+	router.DELETE("/coupons/:id", deleteCouponV2)
+	router.POST("/coupons/:id/restore", restoreCouponV2)
+	router.PUT("/coupons/:id", updateCouponV2)
+	router.POST("/coupons/:id/submit", submitCouponV2)
+	router.POST("/coupons/:id/approve", approveCouponV2)
+	router.POST("/coupons/:id/reject", rejectCouponV2)
+	router.POST("/coupons/:id/end", endCouponV2)
+	router.GET("/coupons/:id/history", getCouponHistoryV2)
+	router.GET("/coupons/:id/examples", getCouponExamplesV2)
+	router.POST("/coupons/:id/examples", createCouponExampleV2)
+	router.GET("/coupons/:id/examples/:exampleId", getCouponExampleV2)
+	router.PUT("/coupons/:id/examples/:exampleId", updateCouponExampleV2)
+	router.DELETE("/coupons/:id/examples/:exampleId", deleteCouponExampleV2)
+	router.POST("/coupons/:id/examples/run", runCouponExamplesV2)
+	router.POST("/coupon-examples/run", runAllCouponExamplesV2)
+	router.POST("/campaigns", createCampaignV2)
+	router.GET("/campaigns", getCampaignsV2)
+	router.GET("/campaigns/:id", getCampaignV2)
+	router.PUT("/campaigns/:id", updateCampaignV2)
+	router.GET("/campaigns/:id/report", getCampaignReportV2)
+	router.GET("/audit", searchAuditLogV2)
+	router.GET("/coupon-types", getCouponTypesV2)
+	router.POST("/applicable-coupons", getApplicableCouponsV2)
+	router.POST("/coupons/:id/apply", applyCouponV2)
+	router.POST("/coupons/preview", previewCouponV2)
+	router.POST("/coupons/:id/redeem", redeemCouponV2)
+	router.POST("/orders", recordOrderV2)
+	router.GET("/products/:id/promotions", getProductPromotionsV2)
+	router.POST("/products/promotions", getPromotionsV2)
+}
+
+func metaV2(c *gin.Context) v2.Meta {
+	return v2.Meta{
+		RequestId:  c.GetString(requestIDKey),
+		ApiVersion: "v2",
+	}
+}
+
+// respondV2 sends data in the v2 envelope
+func respondV2(c *gin.Context, status int, data interface{}, meta v2.Meta) {
+	c.JSON(status, v2.Response{
+		Data: data,
+		Meta: meta,
+	})
+}
+
+// respondListV2 sends a list in the v2 envelope, with its length in the metadata
+func respondListV2[T any](c *gin.Context, items []T) {
+	meta := metaV2(c)
+	count := len(items)
+	meta.Count = &count
+	respondV2(c, http.StatusOK, items, meta)
+}
+
+// failV2 reports an error with the field names of v2
+func failV2(c *gin.Context, err error) {
+	c.Error(v2.TranslateError(err))
+}
+
+func createCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.Coupon
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+	coupon, err := request.ToCoupon()
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	created, err := couponService().CreateCoupon(ctx, coupon)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusCreated, v2.FromCoupon(created), metaV2(c))
+}
+
+func getAllCouponsV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var query dtos.CouponListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		failV2(c, errors.Validation("invalid query parameters: %v", err))
+		return
+	}
+
+	page, err := couponService().ListCoupons(ctx, query)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	coupons := v2.FromCoupons(page.Coupons)
+	meta := metaV2(c)
+	count := len(coupons)
+	meta.Count = &count
+	meta.NextCursor = page.NextCursor
+	respondV2(c, http.StatusOK, coupons, meta)
+}
+
+func getCouponByIdV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().GetCouponById(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func deleteCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	err := couponService().DeleteCoupon(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func restoreCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().RestoreCoupon(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func updateCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.Coupon
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+	coupon, err := request.ToCoupon()
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	force, err := forced(c)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	updated, err := couponService().UpdateCoupon(ctx, c.Param("id"), coupon, force)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(updated), metaV2(c))
+}
+
+func getCouponExamplesV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	examples, err := couponService().ListCouponExamples(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondListV2(c, v2.FromCouponExamples(examples))
+}
+
+func getCouponExampleV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	example, err := couponService().GetCouponExample(ctx, c.Param("id"), c.Param("exampleId"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCouponExample(example), metaV2(c))
+}
+
+func createCouponExampleV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.CouponExample
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	example, err := couponService().CreateCouponExample(ctx, c.Param("id"), request.ToCouponExample())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusCreated, v2.FromCouponExample(example), metaV2(c))
+}
+
+func updateCouponExampleV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.CouponExample
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	example, err := couponService().UpdateCouponExample(ctx, c.Param("id"), c.Param("exampleId"), request.ToCouponExample())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCouponExample(example), metaV2(c))
+}
+
+func deleteCouponExampleV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	err := couponService().DeleteCouponExample(ctx, c.Param("id"), c.Param("exampleId"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func runCouponExamplesV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	run, err := couponService().RunCouponExamples(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromExampleRun(run), metaV2(c))
+}
+
+func runAllCouponExamplesV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	run, err := couponService().RunAllCouponExamples(ctx)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromExampleRun(run), metaV2(c))
+}
+
+func createCampaignV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.Campaign
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	campaign, err := couponService().CreateCampaign(ctx, request.ToCampaign())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusCreated, v2.FromCampaign(campaign), metaV2(c))
+}
+
+func getCampaignsV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	campaigns, err := couponService().ListCampaigns(ctx)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondListV2(c, v2.FromCampaigns(campaigns))
+}
+
+func getCampaignV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	campaign, err := couponService().GetCampaign(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCampaign(campaign), metaV2(c))
+}
+
+func updateCampaignV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.Campaign
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	campaign, err := couponService().UpdateCampaign(ctx, c.Param("id"), request.ToCampaign())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCampaign(campaign), metaV2(c))
+}
+
+func getCampaignReportV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	report, err := couponService().GetCampaignReport(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCampaignReport(report), metaV2(c))
+}
+
+func submitCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().SubmitCoupon(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func approveCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().ApproveCoupon(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func rejectCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request dtos.ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	coupon, err := couponService().RejectCoupon(ctx, c.Param("id"), request)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func endCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	coupon, err := couponService().EndCoupon(ctx, c.Param("id"))
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusOK, v2.FromCoupon(coupon), metaV2(c))
+}
+
+func getCouponHistoryV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var query dtos.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		failV2(c, errors.Validation("invalid query parameters: %v", err))
+		return
+	}
+
+	page, err := couponService().GetCouponHistory(ctx, c.Param("id"), query)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondAuditPageV2(c, page)
+}
+
+func searchAuditLogV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var query dtos.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		failV2(c, errors.Validation("invalid query parameters: %v", err))
+		return
+	}
+
+	page, err := couponService().SearchAuditLog(ctx, query)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondAuditPageV2(c, page)
+}
+
+func respondAuditPageV2(c *gin.Context, page *dtos.AuditPage) {
+	entries, err := v2.FromAuditEntries(page.Entries)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	meta := metaV2(c)
+	count := len(entries)
+	meta.Count = &count
+	meta.NextCursor = page.NextCursor
+	respondV2(c, http.StatusOK, entries, meta)
+}
+
+func getCouponTypesV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	couponTypes := couponService().GetCouponTypes(ctx)
+	converted := make([]*v2.CouponType, 0, len(couponTypes))
+	for _, couponType := range couponTypes {
+		couponTypeV2, err := v2.FromCouponType(couponType)
+		if err != nil {
+			failV2(c, err)
+			return
+		}
+		converted = append(converted, couponTypeV2)
+	}
+
+	respondListV2(c, converted)
+}
+
+func getApplicableCouponsV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.CartRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	response, err := couponService().GetApplicableCoupons(ctx, request.Cart.ToCart(), request.Customer.ToCustomer())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	coupons := v2.FromApplicableCoupons(response)
+	meta := metaV2(c)
+	count := len(coupons)
+	meta.Count = &count
+	meta.Degraded = response.Degraded
+	respondV2(c, http.StatusOK, coupons, meta)
+}
+
+func applyCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.CartRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	updatedCart, err := couponService().ApplyCoupon(ctx, c.Param("id"), request.Cart.ToCart(), request.Customer.ToCustomer())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	meta := metaV2(c)
+	meta.Degraded = updatedCart.Degraded
+	respondV2(c, http.StatusOK, v2.FromUpdatedCart(updatedCart), meta)
+}
+
+func previewCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.PreviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+	req, err := request.ToPreviewRequest()
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	preview, err := couponService().PreviewCoupon(ctx, *req)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	meta := metaV2(c)
+	meta.Degraded = preview.Degraded
+	respondV2(c, http.StatusOK, v2.FromPreview(preview), meta)
+}
+
+func redeemCouponV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.RedemptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	redemption, err := redemptionService().RedeemCoupon(ctx, c.Param("id"), request.ToRedemptionRequest())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	respondV2(c, http.StatusCreated, v2.FromRedemption(redemption), metaV2(c))
+}
+
+func recordOrderV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.OrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	err := redemptionService().RecordOrder(ctx, request.ToOrderRequest())
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func getProductPromotionsV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	response, err := couponService().GetProductPromotions(ctx, []string{c.Param("id")})
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	meta := metaV2(c)
+	meta.Degraded = response.Degraded
+	respondV2(c, http.StatusOK, v2.FromProductPromotions(response.Products[0]), meta)
+}
+
+func getPromotionsV2(c *gin.Context) {
+	ctx := newContext(c)
+
+	var request v2.PromotionsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		failV2(c, errors.Validation("invalid request payload: %v", err))
+		return
+	}
+
+	response, err := couponService().GetProductPromotions(ctx, request.ProductIds)
+	if err != nil {
+		failV2(c, err)
+		return
+	}
+
+	products := make([]*v2.ProductPromotions, len(response.Products))
+	for i, product := range response.Products {
+		products[i] = v2.FromProductPromotions(product)
+	}
+	meta := metaV2(c)
+	count := len(products)
+	meta.Count = &count
+	meta.Degraded = response.Degraded
+	respondV2(c, http.StatusOK, products, meta)
+}
@@ -1,19 +1,26 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"monk-commerce-assignment/config"
 	"monk-commerce-assignment/constants"
+	"monk-commerce-assignment/daos"
 	"monk-commerce-assignment/handlers"
+	"monk-commerce-assignment/services"
 	"monk-commerce-assignment/utils/db"
 	ulog "monk-commerce-assignment/utils/log"
 )
 
 func main() {
+	storage := flag.String("storage", "postgres", "where coupons are stored: postgres or memory")
+	flag.Parse()
+
 	env := os.Getenv("ENV")
 	if env == "" {
 		env = "dev"
@@ -30,11 +37,37 @@ func main() {
 	config.Set(cnf)
 
 	constants.Logger = ulog.New("Global", cnf.AppName, cnf.LogLevel)
-	db.Init(&db.Config{
-		URL: cnf.DatabaseURL,
-	})
+
+	var repositories daos.Repositories
+	switch *storage {
+	case "memory":
+		// Everything is lost on exit, and instances do not share coupons
+		repositories = daos.NewMemoryRepositories()
+		log.Println("Using in-memory storage")
+	case "postgres":
+		db.Init(&db.Config{
+			URL: cnf.DatabaseURL,
+		})
+		repositories = daos.NewRepositories()
+	default:
+		log.Println("Unknown storage:", *storage)
+		os.Exit(1)
+	}
+
+	services.SetDefaultLocale(cnf.DefaultLocale)
+
+	err = services.InitCouponSnapshot(cnf.CouponSnapshotPath)
+	if err != nil {
+		log.Println("Unable to load coupon snapshot. Err:", err)
+	}
+
+	if *storage == "postgres" {
+		services.StartCouponSync(repositories, cnf.DatabaseURL, time.Duration(cnf.CouponRefreshSeconds)*time.Second)
+	}
+	services.StartCouponPurge(repositories, cnf.CouponRetentionDays)
+	services.StartCouponLifecycle(repositories)
 
 	router := gin.Default()
-	handlers.SetupRoutes(router)
+	handlers.SetupRoutes(router, repositories)
 	router.Run(":8080")
 }
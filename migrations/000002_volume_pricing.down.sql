@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS volume_pricing_bands;
+DROP TABLE IF EXISTS volume_pricing_products;
+DROP TABLE IF EXISTS volume_pricing_coupons;
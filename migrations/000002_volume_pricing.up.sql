@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS volume_pricing_coupons (
+    coupon_id uuid PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL DEFAULT 'line',
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS volume_pricing_products (
+    volume_pricing_coupon_id uuid,
+    product_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (volume_pricing_coupon_id, product_id),
+    FOREIGN KEY (volume_pricing_coupon_id) REFERENCES volume_pricing_coupons(coupon_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS volume_pricing_bands (
+    volume_pricing_coupon_id uuid,
+    min_quantity INT NOT NULL,
+    discount DECIMAL(5, 2) NOT NULL,
+    PRIMARY KEY (volume_pricing_coupon_id, min_quantity),
+    FOREIGN KEY (volume_pricing_coupon_id) REFERENCES volume_pricing_coupons(coupon_id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS fixed_price_products;
+DROP TABLE IF EXISTS fixed_price_coupons;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS fixed_price_coupons (
+    coupon_id uuid PRIMARY KEY,
+    max_units INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS fixed_price_products (
+    fixed_price_coupon_id uuid,
+    product_id VARCHAR(255) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (fixed_price_coupon_id, product_id),
+    FOREIGN KEY (fixed_price_coupon_id) REFERENCES fixed_price_coupons(coupon_id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shipping_coupon_methods;
+DROP TABLE IF EXISTS shipping_coupons;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS shipping_coupons (
+    coupon_id uuid PRIMARY KEY,
+    threshold DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    discount DECIMAL(5, 2) NOT NULL,
+    max_discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipping_coupon_methods (
+    shipping_coupon_id uuid,
+    method VARCHAR(50) NOT NULL,
+    PRIMARY KEY (shipping_coupon_id, method),
+    FOREIGN KEY (shipping_coupon_id) REFERENCES shipping_coupons(coupon_id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS redemptions;
+DROP INDEX IF EXISTS idx_customer_orders_customer_id;
+DROP TABLE IF EXISTS customer_orders;
+DROP TABLE IF EXISTS coupon_eligibility_segments;
+DROP TABLE IF EXISTS coupon_eligibilities;
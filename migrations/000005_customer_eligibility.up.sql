@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS coupon_eligibilities (
+    coupon_id uuid PRIMARY KEY,
+    first_order_only BOOLEAN NOT NULL DEFAULT FALSE,
+    min_order_count INT NOT NULL DEFAULT 0,
+    min_lifetime_spend DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    lapsed_days INT NOT NULL DEFAULT 0,
+    max_days_since_signup INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_eligibility_segments (
+    coupon_eligibility_id uuid,
+    segment VARCHAR(100) NOT NULL,
+    PRIMARY KEY (coupon_eligibility_id, segment),
+    FOREIGN KEY (coupon_eligibility_id) REFERENCES coupon_eligibilities(coupon_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS customer_orders (
+    id uuid PRIMARY KEY,
+    customer_id VARCHAR(255) NOT NULL,
+    order_id VARCHAR(255) NOT NULL UNIQUE,
+    coupon_id uuid,
+    total DECIMAL(12, 2) NOT NULL,
+    discount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_orders_customer_id ON customer_orders (customer_id);
+
+CREATE TABLE IF NOT EXISTS redemptions (
+    id uuid PRIMARY KEY,
+    coupon_id uuid NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    customer_id VARCHAR(255) NOT NULL,
+    discount DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (coupon_id, order_id),
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_fixed_price_products_product_id;
+DROP INDEX IF EXISTS idx_volume_pricing_products_product_id;
+DROP INDEX IF EXISTS idx_bx_gy_get_products_product_id;
+DROP INDEX IF EXISTS idx_bx_gy_buy_products_product_id;
+DROP INDEX IF EXISTS idx_product_wise_coupons_product_id;
+
+DROP INDEX IF EXISTS idx_coupons_validity;
+DROP INDEX IF EXISTS idx_coupons_active_created_at;
+DROP INDEX IF EXISTS idx_coupons_type_created_at;
+DROP INDEX IF EXISTS idx_coupons_created_at;
+DROP INDEX IF EXISTS idx_coupons_code_prefix;
+DROP INDEX IF EXISTS idx_coupons_code;
+
+ALTER TABLE coupons DROP COLUMN IF EXISTS ends_at;
+ALTER TABLE coupons DROP COLUMN IF EXISTS starts_at;
+ALTER TABLE coupons DROP COLUMN IF EXISTS code;
@@ -0,0 +1,21 @@
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS code VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP;
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP;
+
+-- Codes are optional, but the ones that are set are unique
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_code ON coupons (code) WHERE code <> '';
+-- Code prefix search
+CREATE INDEX IF NOT EXISTS idx_coupons_code_prefix ON coupons (code text_pattern_ops);
+
+-- Listing pages are ordered by (created_at, id), optionally within a type or active state
+CREATE INDEX IF NOT EXISTS idx_coupons_created_at ON coupons (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_coupons_type_created_at ON coupons (type, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_coupons_active_created_at ON coupons (is_active, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_coupons_validity ON coupons (starts_at, ends_at);
+
+-- Lookup of the coupons that target a product
+CREATE INDEX IF NOT EXISTS idx_product_wise_coupons_product_id ON product_wise_coupons (product_id);
+CREATE INDEX IF NOT EXISTS idx_bx_gy_buy_products_product_id ON bx_gy_buy_products (product_id);
+CREATE INDEX IF NOT EXISTS idx_bx_gy_get_products_product_id ON bx_gy_get_products (product_id);
+CREATE INDEX IF NOT EXISTS idx_volume_pricing_products_product_id ON volume_pricing_products (product_id);
+CREATE INDEX IF NOT EXISTS idx_fixed_price_products_product_id ON fixed_price_products (product_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS coupon_tags;
+DROP TABLE IF EXISTS coupon_translations;
+ALTER TABLE coupons DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,21 @@
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS coupon_translations (
+    coupon_id uuid,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    badge VARCHAR(40) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    terms TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (coupon_id, locale),
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS coupon_tags (
+    coupon_id uuid,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (coupon_id, tag),
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_tags_tag ON coupon_tags (tag);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS coupon_audit_entries;
+DROP FUNCTION IF EXISTS coupon_audit_entries_append_only();
@@ -0,0 +1,29 @@
+-- Append-only log of coupon changes. There is no foreign key to coupons: the
+-- history of a coupon outlives it.
+CREATE TABLE IF NOT EXISTS coupon_audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    coupon_id uuid NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- History of a coupon and searches by actor or request, newest first
+CREATE INDEX IF NOT EXISTS idx_coupon_audit_entries_coupon_id ON coupon_audit_entries (coupon_id, id);
+CREATE INDEX IF NOT EXISTS idx_coupon_audit_entries_actor ON coupon_audit_entries (actor, id);
+CREATE INDEX IF NOT EXISTS idx_coupon_audit_entries_request_id ON coupon_audit_entries (request_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_audit_entries_created_at ON coupon_audit_entries (created_at);
+
+CREATE OR REPLACE FUNCTION coupon_audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'coupon_audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS coupon_audit_entries_append_only ON coupon_audit_entries;
+CREATE TRIGGER coupon_audit_entries_append_only
+    BEFORE UPDATE OR DELETE ON coupon_audit_entries
+    FOR EACH ROW EXECUTE FUNCTION coupon_audit_entries_append_only();
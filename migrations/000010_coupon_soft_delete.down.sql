@@ -0,0 +1,2 @@
+DELETE FROM coupons WHERE deleted_at IS NOT NULL;
+ALTER TABLE coupons DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+-- Deleted coupons are kept, with their details and redemptions, until the
+-- retention job purges them
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_coupons_deleted_at ON coupons (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Without the workflow every coupon within its validity window applies. The
+-- unapproved ones are kept but deactivated, and their window is closed like
+-- that of the ones ended early, so none of them starts applying.
+UPDATE coupons SET is_active = FALSE WHERE status IN ('draft', 'pending_approval');
+UPDATE coupons SET ends_at = NOW() AT TIME ZONE 'UTC'
+    WHERE status IN ('draft', 'pending_approval', 'ended') AND (ends_at IS NULL OR ends_at > NOW() AT TIME ZONE 'UTC');
+
+DROP TABLE IF EXISTS coupon_revisions;
+DROP INDEX IF EXISTS idx_coupons_status;
+ALTER TABLE coupons DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE coupons DROP COLUMN IF EXISTS approved_at;
+ALTER TABLE coupons DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE coupons DROP COLUMN IF EXISTS submitted_by;
+ALTER TABLE coupons DROP COLUMN IF EXISTS edited_by;
+ALTER TABLE coupons DROP COLUMN IF EXISTS status;
@@ -0,0 +1,36 @@
+-- Coupons go through draft, pending_approval and approved before they are
+-- live. Coupons created before the workflow keep applying: they start out
+-- approved, live or ended depending on their validity window.
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'live';
+UPDATE coupons SET status = 'approved' WHERE starts_at > NOW() AT TIME ZONE 'UTC';
+UPDATE coupons SET status = 'ended' WHERE ends_at <= NOW() AT TIME ZONE 'UTC';
+ALTER TABLE coupons ALTER COLUMN status SET DEFAULT 'draft';
+
+-- Who last edited, submitted and approved a coupon. The approver has to be
+-- someone else than the first two.
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS edited_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS submitted_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS approved_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP;
+-- Why the coupon was last sent back to draft by a reviewer
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS rejection_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_coupons_status ON coupons (status, created_at, id);
+
+-- Edits of approved and live coupons, which only take effect once approved
+CREATE TABLE IF NOT EXISTS coupon_revisions (
+    id uuid PRIMARY KEY,
+    coupon_id uuid NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    definition JSONB NOT NULL,
+    submitted_by VARCHAR(255) NOT NULL,
+    reviewed_by VARCHAR(255) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+-- A coupon has at most one revision waiting for review
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupon_revisions_pending ON coupon_revisions (coupon_id) WHERE status = 'pending_approval';
+CREATE INDEX IF NOT EXISTS idx_coupon_revisions_coupon_id ON coupon_revisions (coupon_id, created_at);
@@ -0,0 +1,16 @@
+-- Sample carts with the outcome a coupon must give them, checked again on
+-- every change of the coupon
+CREATE TABLE IF NOT EXISTS coupon_examples (
+    id uuid PRIMARY KEY,
+    coupon_id uuid NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    cart JSONB NOT NULL,
+    customer JSONB,
+    expected JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (coupon_id) REFERENCES coupons(id) ON DELETE CASCADE
+);
+
+-- Examples are named uniquely within their coupon
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupon_examples_name ON coupon_examples (coupon_id, name);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS campaign_events;
+ALTER TABLE redemptions DROP COLUMN IF EXISTS campaign_id;
+ALTER TABLE coupons DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,32 @@
+-- Campaigns group coupons under a total discount budget. Redemptions of their
+-- coupons add to spent; once it reaches the budget the coupons stop applying.
+CREATE TABLE IF NOT EXISTS campaigns (
+    id uuid PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    budget DECIMAL(12, 2) NOT NULL CHECK (budget > 0),
+    spent DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS campaign_id uuid REFERENCES campaigns(id);
+CREATE INDEX IF NOT EXISTS idx_coupons_campaign_id ON coupons (campaign_id);
+
+-- The campaign a redemption drew its discount from, for the campaign report
+ALTER TABLE redemptions ADD COLUMN IF NOT EXISTS campaign_id uuid REFERENCES campaigns(id);
+CREATE INDEX IF NOT EXISTS idx_redemptions_campaign_id ON redemptions (campaign_id);
+
+-- Budget thresholds a campaign crossed, each fired once per campaign
+CREATE TABLE IF NOT EXISTS campaign_events (
+    id uuid PRIMARY KEY,
+    campaign_id uuid NOT NULL,
+    threshold INT NOT NULL,
+    spent DECIMAL(12, 2) NOT NULL,
+    budget DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_events_threshold ON campaign_events (campaign_id, threshold);
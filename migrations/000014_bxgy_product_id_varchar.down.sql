@@ -0,0 +1,18 @@
+-- Product IDs that are not uuids cannot be converted back. Rather than lose
+-- those coupons, refuse to migrate down while any are stored.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM bx_gy_buy_products
+        WHERE product_id !~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+        UNION ALL
+        SELECT 1 FROM bx_gy_get_products
+        WHERE product_id !~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+    ) THEN
+        RAISE EXCEPTION 'bx_gy products with non-uuid product IDs are stored, they cannot be converted back to uuid';
+    END IF;
+END;
+$$;
+
+ALTER TABLE bx_gy_get_products ALTER COLUMN product_id TYPE uuid USING product_id::uuid;
+ALTER TABLE bx_gy_buy_products ALTER COLUMN product_id TYPE uuid USING product_id::uuid;
@@ -0,0 +1,3 @@
+-- Product IDs are strings everywhere else
+ALTER TABLE bx_gy_buy_products ALTER COLUMN product_id TYPE VARCHAR(255);
+ALTER TABLE bx_gy_get_products ALTER COLUMN product_id TYPE VARCHAR(255);
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditCreated  = "created"
+	AuditDeleted  = "deleted"
+	AuditRestored = "restored"
+	AuditPurged   = "purged"
+	// Transitions of the approval workflow
+	AuditUpdated   = "updated"
+	AuditRevised   = "revised"
+	AuditSubmitted = "submitted"
+	AuditApproved  = "approved"
+	AuditRejected  = "rejected"
+	AuditWentLive  = "went_live"
+	AuditEnded     = "ended"
+)
+
+// CouponAuditEntry records one change of a coupon: who made it, in which
+// request, and the coupon before and after. Before is nil for a created
+// coupon and After for a purged one.
+type CouponAuditEntry struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CouponID  string    `json:"coupon_id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"request_id"`
+	Before    *JSON     `gorm:"type:jsonb" json:"before"`
+	After     *JSON     `gorm:"type:jsonb" json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// Campaign groups coupons under a total discount budget. Redemptions of its
+// coupons draw down the budget; once it is spent the coupons stop applying.
+type Campaign struct {
+	Id       string    `gorm:"primaryKey" json:"id"`
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Budget   float64   `json:"budget"`
+	// Discount given by the redemptions of its coupons so far. Redemptions
+	// the rest of the budget does not cover are refused, so it only goes over
+	// the budget when the budget is lowered.
+	Spent     float64   `json:"spent"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// States of a campaign
+const (
+	CampaignScheduled = "scheduled"
+	CampaignLive      = "live"
+	CampaignEnded     = "ended"
+	CampaignExhausted = "exhausted"
+)
+
+// Exhausted tells whether the budget of the campaign is spent
+func (c *Campaign) Exhausted() bool {
+	return c.Spent >= c.Budget
+}
+
+// State tells whether the coupons of the campaign apply at the given time: not
+// before the campaign starts, and no longer once it ended or its budget is
+// spent
+func (c *Campaign) State(now time.Time) string {
+	switch {
+	case now.Before(c.StartsAt):
+		return CampaignScheduled
+	case !now.Before(c.EndsAt):
+		return CampaignEnded
+	case c.Exhausted():
+		return CampaignExhausted
+	default:
+		return CampaignLive
+	}
+}
+
+// Thresholds of the budget, in percent, at which a campaign fires an event
+var CampaignThresholds = []int{50, 80, 100}
+
+// CampaignEvent records a campaign crossing one of CampaignThresholds
+type CampaignEvent struct {
+	Id         string `gorm:"primaryKey" json:"id"`
+	CampaignID string `json:"campaign_id"`
+	Threshold  int    `json:"threshold"`
+	// The spend and budget of the campaign when the threshold was crossed
+	Spent     float64   `json:"spent"`
+	Budget    float64   `json:"budget"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CampaignCouponSpend is what the redemptions of one coupon drew from the
+// budget of its campaign
+type CampaignCouponSpend struct {
+	CouponID    string  `json:"coupon_id"`
+	Redemptions int     `json:"redemptions"`
+	Discount    float64 `json:"discount"`
+}
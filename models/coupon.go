@@ -5,13 +5,117 @@ import (
 )
 
 type Coupon struct {
+	Id        string `gorm:"primaryKey" json:"id"`
+	Type      string `json:"type"`
+	Code      string `json:"code"`
+	IsActive  bool   `json:"is_active"`
+	Condition string `json:"condition"`
+	// Free-form JSON object for clients, not interpreted by the service
+	Metadata  JSON       `gorm:"type:jsonb" json:"metadata"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Set when the coupon is deleted. Deleted coupons are not listed or
+	// applied, and are purged once the retention period has passed.
+	DeletedAt *time.Time `json:"deleted_at"`
+	// Where the coupon is in the approval workflow, one of the Status constants
+	Status string `json:"status"`
+	// Who last edited and submitted the coupon, and who approved it
+	EditedBy    string     `json:"edited_by"`
+	SubmittedBy string     `json:"submitted_by"`
+	ApprovedBy  string     `json:"approved_by"`
+	ApprovedAt  *time.Time `json:"approved_at"`
+	// Why a reviewer last sent the coupon back to draft
+	RejectionReason string `json:"rejection_reason"`
+	// The campaign whose budget the redemptions of the coupon draw down
+	CampaignID *string `json:"campaign_id"`
+}
+
+// Statuses of a coupon in the approval workflow. Coupons are written as
+// drafts and submitted for approval; once approved they go live at the start
+// of their validity window and end with it.
+const (
+	StatusDraft           = "draft"
+	StatusPendingApproval = "pending_approval"
+	StatusApproved        = "approved"
+	StatusLive            = "live"
+	StatusEnded           = "ended"
+)
+
+// Evaluable tells whether the coupon was approved and has not ended, only
+// those coupons are offered and applied
+func (c *Coupon) Evaluable() bool {
+	return c.Status == StatusApproved || c.Status == StatusLive
+}
+
+// Statuses of a revision, a revision waiting for review is StatusPendingApproval
+const (
+	RevisionApproved = "approved"
+	RevisionRejected = "rejected"
+)
+
+// CouponRevision is an edit of an approved or live coupon. The coupon keeps
+// applying as it is until the revision is approved.
+type CouponRevision struct {
+	Id       string `gorm:"primaryKey" json:"id"`
+	CouponID string `json:"coupon_id"`
+	Status   string `json:"status"`
+	// The new definition of the coupon, as it was sent
+	Definition  JSON       `gorm:"type:jsonb" json:"definition"`
+	SubmittedBy string     `json:"submitted_by"`
+	ReviewedBy  string     `json:"reviewed_by"`
+	Reason      string     `json:"reason"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+}
+
+// CouponExample is a sample cart with the outcome a coupon must give it
+type CouponExample struct {
 	Id        string    `gorm:"primaryKey" json:"id"`
-	Type      string    `json:"type"`
-	IsActive  bool      `json:"is_active"`
+	CouponID  string    `json:"coupon_id"`
+	Name      string    `json:"name"`
+	Cart      JSON      `gorm:"type:jsonb" json:"cart"`
+	Customer  JSON      `gorm:"type:jsonb" json:"customer"`
+	Expected  JSON      `gorm:"type:jsonb" json:"expected"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Validity states of a coupon, from its validity window
+const (
+	CouponScheduled = "scheduled"
+	CouponLive      = "live"
+	CouponExpired   = "expired"
+)
+
+// ValidityState tells whether the coupon has not started yet, is within its
+// validity window or has ended at the given time
+func (c *Coupon) ValidityState(now time.Time) string {
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return CouponScheduled
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return CouponExpired
+	}
+	return CouponLive
+}
+
+// Display text of a coupon in one locale
+type CouponTranslation struct {
+	CouponID    string `gorm:"primaryKey"`
+	Locale      string `gorm:"primaryKey" json:"locale"`
+	Name        string `json:"name"`
+	Badge       string `json:"badge"`
+	Description string `json:"description"`
+	Terms       string `json:"terms"`
+}
+
+type CouponTag struct {
+	CouponID string `gorm:"primaryKey"`
+	Tag      string `gorm:"primaryKey" json:"tag"`
+}
+
 type CartWiseCoupon struct {
 	CouponID  string  `gorm:"primaryKey"`
 	Threshold float64 `json:"threshold"`
@@ -40,3 +144,56 @@ type BxGyGetProduct struct {
 	ProductID    string `json:"product_id"`
 	Quantity     int    `json:"quantity"`
 }
+
+type VolumePricingCoupon struct {
+	CouponID string `gorm:"primaryKey"`
+	Scope    string `json:"scope"`
+}
+
+type VolumePricingProduct struct {
+	VolumePricingCouponID string `gorm:"primaryKey"`
+	ProductID             string `json:"product_id"`
+}
+
+type VolumePricingBand struct {
+	VolumePricingCouponID string  `gorm:"primaryKey"`
+	MinQuantity           int     `json:"min_quantity"`
+	Discount              float64 `json:"discount"`
+}
+
+type FixedPriceCoupon struct {
+	CouponID string `gorm:"primaryKey"`
+	MaxUnits int    `json:"max_units"`
+}
+
+type FixedPriceProduct struct {
+	FixedPriceCouponID string  `gorm:"primaryKey"`
+	ProductID          string  `json:"product_id"`
+	Price              float64 `json:"price"`
+}
+
+type ShippingCoupon struct {
+	CouponID    string  `gorm:"primaryKey"`
+	Threshold   float64 `json:"threshold"`
+	Discount    float64 `json:"discount"`
+	MaxDiscount float64 `json:"max_discount"`
+}
+
+type ShippingCouponMethod struct {
+	ShippingCouponID string `gorm:"primaryKey"`
+	Method           string `json:"method"`
+}
+
+type CouponEligibility struct {
+	CouponID           string  `gorm:"primaryKey"`
+	FirstOrderOnly     bool    `json:"first_order_only"`
+	MinOrderCount      int     `json:"min_order_count"`
+	MinLifetimeSpend   float64 `json:"min_lifetime_spend"`
+	LapsedDays         int     `json:"lapsed_days"`
+	MaxDaysSinceSignup int     `json:"max_days_since_signup"`
+}
+
+type CouponEligibilitySegment struct {
+	CouponEligibilityID string `gorm:"primaryKey"`
+	Segment             string `json:"segment"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+type CustomerOrder struct {
+	Id         string    `gorm:"primaryKey" json:"id"`
+	CustomerID string    `json:"customer_id"`
+	OrderID    string    `json:"order_id"`
+	CouponID   *string   `json:"coupon_id"`
+	Total      float64   `json:"total"`
+	Discount   float64   `json:"discount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CustomerHistory is the order history of a customer aggregated from customer_orders
+type CustomerHistory struct {
+	CustomerID    string     `json:"customer_id"`
+	OrderCount    int        `json:"order_count"`
+	LifetimeSpend float64    `json:"lifetime_spend"`
+	LastOrderAt   *time.Time `json:"last_order_at"`
+}
+
+type Redemption struct {
+	Id         string    `gorm:"primaryKey" json:"id"`
+	CouponID   string    `json:"coupon_id"`
+	OrderID    string    `json:"order_id"`
+	CustomerID string    `json:"customer_id"`
+	Discount   float64   `json:"discount"`
+	CreatedAt  time.Time `json:"created_at"`
+	// The campaign the discount was drawn from, if any
+	CampaignID *string `json:"campaign_id"`
+}
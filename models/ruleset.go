@@ -0,0 +1,68 @@
+package models
+
+// CouponRules is a coupon together with all of its type-specific rows
+type CouponRules struct {
+	Coupon              *Coupon                     `json:"coupon"`
+	CartWise            *CartWiseCoupon             `json:"cart_wise,omitempty"`
+	ProductWise         *ProductWiseCoupon          `json:"product_wise,omitempty"`
+	BxGy                *BxGyCoupon                 `json:"bxgy,omitempty"`
+	BuyProducts         []*BxGyBuyProduct           `json:"buy_products,omitempty"`
+	GetProducts         []*BxGyGetProduct           `json:"get_products,omitempty"`
+	Volume              *VolumePricingCoupon        `json:"volume,omitempty"`
+	VolumeProducts      []*VolumePricingProduct     `json:"volume_products,omitempty"`
+	VolumeBands         []*VolumePricingBand        `json:"volume_bands,omitempty"`
+	FixedPrice          *FixedPriceCoupon           `json:"fixed_price,omitempty"`
+	FixedPriceProducts  []*FixedPriceProduct        `json:"fixed_price_products,omitempty"`
+	Shipping            *ShippingCoupon             `json:"shipping,omitempty"`
+	ShippingMethods     []*ShippingCouponMethod     `json:"shipping_methods,omitempty"`
+	Eligibility         *CouponEligibility          `json:"eligibility,omitempty"`
+	EligibilitySegments []*CouponEligibilitySegment `json:"eligibility_segments,omitempty"`
+	Translations        []*CouponTranslation        `json:"translations,omitempty"`
+	Tags                []*CouponTag                `json:"tags,omitempty"`
+	Campaign            *Campaign                   `json:"campaign,omitempty"`
+}
+
+// RuleSet holds the rules of a group of coupons, in the order the coupons were loaded
+type RuleSet struct {
+	Coupons []*CouponRules
+	byID    map[string]*CouponRules
+}
+
+func NewRuleSet(coupons []*Coupon) *RuleSet {
+	ruleSet := &RuleSet{
+		Coupons: make([]*CouponRules, 0, len(coupons)),
+		byID:    make(map[string]*CouponRules, len(coupons)),
+	}
+	for _, coupon := range coupons {
+		rules := &CouponRules{Coupon: coupon}
+		ruleSet.Coupons = append(ruleSet.Coupons, rules)
+		ruleSet.byID[coupon.Id] = rules
+	}
+	return ruleSet
+}
+
+// Get returns the rules of a coupon, or nil if the coupon is not in the set
+func (r *RuleSet) Get(couponId string) *CouponRules {
+	return r.byID[couponId]
+}
+
+// IDs returns the IDs of the coupons in the set
+func (r *RuleSet) IDs() []string {
+	ids := make([]string, 0, len(r.Coupons))
+	for _, rules := range r.Coupons {
+		ids = append(ids, rules.Coupon.Id)
+	}
+	return ids
+}
+
+// NewRuleSetFromRules groups coupons whose rules are already loaded
+func NewRuleSetFromRules(couponRules []*CouponRules) *RuleSet {
+	ruleSet := &RuleSet{
+		Coupons: couponRules,
+		byID:    make(map[string]*CouponRules, len(couponRules)),
+	}
+	for _, rules := range couponRules {
+		ruleSet.byID[rules.Coupon.Id] = rules
+	}
+	return ruleSet
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/jsondiff"
+
+	"go.uber.org/zap"
+)
+
+// Actor of changes made without one, e.g. by background jobs
+const systemActor = "system"
+
+// Actions of the audit log, for validating searches
+var auditActions = []string{
+	models.AuditCreated, models.AuditDeleted, models.AuditRestored, models.AuditPurged,
+	models.AuditUpdated, models.AuditRevised, models.AuditSubmitted, models.AuditApproved,
+	models.AuditRejected, models.AuditWentLive, models.AuditEnded,
+}
+
+// recordChange appends a change of a coupon to the audit log, in the
+// transaction of tx so the entry is only kept if the change is. before is nil
+// for a created coupon and after for a purged one.
+func (c *CouponService) recordChange(tx *context.Context, couponId string, action string, before, after *dtos.Coupon) error {
+	actor := tx.Actor
+	if actor == "" {
+		actor = systemActor
+	}
+	entry := &models.CouponAuditEntry{
+		CouponID:  couponId,
+		Action:    action,
+		Actor:     actor,
+		RequestID: tx.RefID,
+		CreatedAt: time.Now(),
+	}
+
+	var err error
+	if entry.Before, err = auditSnapshot(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditSnapshot(after); err != nil {
+		return err
+	}
+
+	err = c.db.PersistCouponAuditEntry(tx, entry)
+	if err != nil {
+		tx.Log.Error("failed to persist coupon audit entry", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// auditSnapshot is the coupon as it is kept in the audit log: its definition
+// with the text of every locale
+func auditSnapshot(coupon *dtos.Coupon) (*models.JSON, error) {
+	if coupon == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(coupon)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := models.JSON(data)
+	return &snapshot, nil
+}
+
+// GetCouponHistory returns a page of the changes of a coupon, newest first.
+// The history of a purged coupon is kept.
+func (c *CouponService) GetCouponHistory(ctx *context.Context, couponId string, req dtos.AuditQuery) (*dtos.AuditPage, error) {
+	req.CouponId = couponId
+	page, err := c.SearchAuditLog(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tell a coupon without history from one that never existed
+	if len(page.Entries) == 0 && req.Cursor == "" {
+		if _, err := c.db.GetCouponById(ctx, couponId); err != nil {
+			return nil, couponNotFound(couponId, err)
+		}
+	}
+	return page, nil
+}
+
+// SearchAuditLog returns a page of the audit entries matching the filters of
+// the query, newest first
+func (c *CouponService) SearchAuditLog(ctx *context.Context, req dtos.AuditQuery) (*dtos.AuditPage, error) {
+	query, err := auditQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra entry to find out whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	entries, err := c.db.ListCouponAuditEntries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dtos.AuditPage{
+		Entries: make([]*dtos.AuditEntry, 0, len(entries)),
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextCursor = encodeAuditCursor(entries[limit-1].Id)
+	}
+	for _, entry := range entries {
+		entryDto, err := toAuditEntryDto(entry)
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, entryDto)
+	}
+	return page, nil
+}
+
+// auditQuery checks the parameters of an audit search and turns them into a
+// storage query
+func auditQuery(req dtos.AuditQuery) (*daos.AuditQuery, error) {
+	v := &validator{}
+
+	query := &daos.AuditQuery{
+		CouponId:  req.CouponId,
+		Actor:     req.Actor,
+		Action:    req.Action,
+		RequestId: req.RequestId,
+		Since:     utc(req.Since),
+		Until:     utc(req.Until),
+		Limit:     req.Limit,
+	}
+
+	if req.Action != "" {
+		oneOf(auditActions...)(v, "action", req.Action)
+	}
+	if query.Since != nil && query.Until != nil && !query.Until.After(*query.Since) {
+		v.report("until", "must be after since")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit < 1 || query.Limit > maxPageSize {
+		v.report("limit", "must be between 1 and %d", maxPageSize)
+	}
+
+	if req.Cursor != "" {
+		before, ok := decodeAuditCursor(req.Cursor)
+		if !ok {
+			v.report("cursor", "is invalid")
+		}
+		query.Before = before
+	}
+
+	if err := v.err("invalid audit query"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func toAuditEntryDto(entry *models.CouponAuditEntry) (*dtos.AuditEntry, error) {
+	entryDto := &dtos.AuditEntry{
+		Id:        entry.Id,
+		CouponId:  entry.CouponID,
+		Action:    entry.Action,
+		Actor:     entry.Actor,
+		RequestId: entry.RequestID,
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.Before != nil {
+		entryDto.Before = json.RawMessage(*entry.Before)
+	}
+	if entry.After != nil {
+		entryDto.After = json.RawMessage(*entry.After)
+	}
+
+	changes, err := jsondiff.Diff(entryDto.Before, entryDto.After)
+	if err != nil {
+		return nil, err
+	}
+	entryDto.Changes = changes
+	return entryDto, nil
+}
+
+// Audit cursors are the ID of the last entry of a page
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeAuditCursor(s string) (int64, bool) {
+	value, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil || id < 1 {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	stderrors "errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// maxCampaignNameLength bounds campaign names, like the name column
+const maxCampaignNameLength = 255
+
+var campaignRules = all(
+	field("name", func(c *dtos.Campaign) string { return c.Name }, required()),
+	field("budget", func(c *dtos.Campaign) float64 { return c.Budget }, greaterThan(0.0)),
+)
+
+// validateCampaign checks a campaign before it is stored
+func validateCampaign(req *dtos.Campaign) error {
+	v := &validator{}
+	campaignRules(v, "", req)
+	if len(req.Name) > maxCampaignNameLength {
+		v.report("name", "must be at most %d characters", maxCampaignNameLength)
+	}
+	if req.StartsAt == nil {
+		v.report("starts_at", "is required")
+	}
+	if req.EndsAt == nil {
+		v.report("ends_at", "is required")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		v.report("ends_at", "must be after starts_at")
+	}
+	return v.err("invalid campaign")
+}
+
+func (c *CouponService) CreateCampaign(ctx *context.Context, req *dtos.Campaign) (*dtos.Campaign, error) {
+	if err := validateCampaign(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	campaign := models.Campaign{
+		Id:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.EndsAt.UTC(),
+		Budget:    req.Budget,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.PersistCampaign(tx, &campaign)
+		if err != nil {
+			tx.Log.Error("failed to persist campaign", zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toCampaignDto(&campaign, now), nil
+}
+
+// ListCampaigns returns every campaign, in the order they were created
+func (c *CouponService) ListCampaigns(ctx *context.Context) ([]*dtos.Campaign, error) {
+	campaigns, err := c.db.ListCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	campaignDtos := make([]*dtos.Campaign, len(campaigns))
+	for i, campaign := range campaigns {
+		campaignDtos[i] = toCampaignDto(campaign, now)
+	}
+	return campaignDtos, nil
+}
+
+func (c *CouponService) GetCampaign(ctx *context.Context, campaignId string) (*dtos.Campaign, error) {
+	campaign, err := c.db.GetCampaign(ctx, campaignId)
+	if err != nil {
+		return nil, campaignNotFound(campaignId, err)
+	}
+	return toCampaignDto(campaign, time.Now()), nil
+}
+
+// UpdateCampaign changes the name, dates and budget of a campaign. What it
+// spent is kept, so lowering the budget to it or below ends the campaign
+// right away. The coupons of the campaign are reloaded on every instance, as
+// whether they apply may have changed.
+func (c *CouponService) UpdateCampaign(ctx *context.Context, campaignId string, req *dtos.Campaign) (*dtos.Campaign, error) {
+	if err := validateCampaign(req); err != nil {
+		return nil, err
+	}
+	campaign, err := c.db.GetCampaign(ctx, campaignId)
+	if err != nil {
+		return nil, campaignNotFound(campaignId, err)
+	}
+	couponIds, err := c.campaignCoupons(ctx, campaignId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated := *campaign
+	updated.Name = strings.TrimSpace(req.Name)
+	updated.StartsAt = req.StartsAt.UTC()
+	updated.EndsAt = req.EndsAt.UTC()
+	updated.Budget = req.Budget
+	updated.UpdatedAt = now
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.UpdateCampaign(tx, &updated)
+		if err != nil {
+			tx.Log.Error("failed to update campaign", zap.Error(err))
+			return err
+		}
+		return c.notifyCouponsChanged(tx, couponIds)
+	})
+	if err != nil {
+		return nil, campaignNotFound(campaignId, err)
+	}
+	c.couponsChanged(ctx, couponIds)
+
+	return toCampaignDto(&updated, now), nil
+}
+
+// GetCampaignReport shows what a campaign spent against its budget, by coupon
+func (c *CouponService) GetCampaignReport(ctx *context.Context, campaignId string) (*dtos.CampaignReport, error) {
+	campaign, err := c.db.GetCampaign(ctx, campaignId)
+	if err != nil {
+		return nil, campaignNotFound(campaignId, err)
+	}
+	spend, err := c.customers.ListCampaignSpend(ctx, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	events, err := c.db.ListCampaignEvents(ctx, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	coupons, err := c.db.ListCampaignCoupons(ctx, campaignId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dtos.CampaignReport{
+		Campaign:     *toCampaignDto(campaign, time.Now()),
+		SpentPercent: math.Round(campaign.Spent/campaign.Budget*10000) / 100,
+		Coupons:      []dtos.CampaignCoupon{},
+		Events:       make([]dtos.CampaignEvent, len(events)),
+	}
+
+	// The coupons in the campaign first, then those that drew from it before
+	// they left it or were deleted
+	byCoupon := make(map[string]*models.CampaignCouponSpend, len(spend))
+	for _, couponSpend := range spend {
+		byCoupon[couponSpend.CouponID] = couponSpend
+		report.Redemptions += couponSpend.Redemptions
+	}
+	for _, coupon := range coupons {
+		couponReport := dtos.CampaignCoupon{
+			CouponId: coupon.Id,
+			Code:     coupon.Code,
+			Status:   coupon.Status,
+		}
+		if couponSpend, ok := byCoupon[coupon.Id]; ok {
+			couponReport.Redemptions = couponSpend.Redemptions
+			couponReport.Spent = couponSpend.Discount
+			delete(byCoupon, coupon.Id)
+		}
+		report.Coupons = append(report.Coupons, couponReport)
+	}
+	for _, couponSpend := range spend {
+		if _, ok := byCoupon[couponSpend.CouponID]; ok {
+			report.Coupons = append(report.Coupons, dtos.CampaignCoupon{
+				CouponId:    couponSpend.CouponID,
+				Redemptions: couponSpend.Redemptions,
+				Spent:       couponSpend.Discount,
+			})
+		}
+	}
+
+	for i, event := range events {
+		report.Events[i] = toCampaignEventDto(event)
+	}
+	return report, nil
+}
+
+// SpendCampaignBudget runs redeem in a transaction that also draws amount
+// from the budget of the campaign of a coupon, so a redemption is only stored
+// while what is left of the budget covers it. redeem gets the ID of the
+// campaign, empty for coupons that are in none. The budget thresholds the
+// campaign reaches are recorded in the same transaction, and once its budget
+// is spent its coupons are reloaded on every instance so they stop applying.
+//
+// Redemptions are checked against the budget before they are priced, but
+// concurrent ones can all pass that check: their spends are serialized when
+// they are stored, and those the rest of the budget does not cover fail.
+func (c *CouponService) SpendCampaignBudget(ctx *context.Context, couponId string, amount float64, redeem func(tx *context.Context, campaignId string) error) error {
+	var campaign *models.Campaign
+	var fired []*models.CampaignEvent
+	var couponIds []string
+	err := daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		campaign, fired, couponIds = nil, nil, nil
+		campaignId, err := c.db.LockCouponCampaign(tx, couponId)
+		if err != nil {
+			return couponNotFound(couponId, err)
+		}
+		if campaignId == "" {
+			return redeem(tx, "")
+		}
+
+		campaign, err = c.db.SpendCampaignBudget(tx, campaignId, amount)
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotApplicable("budget of campaign %s does not cover a discount of %.2f", campaignId, amount).Wrap(err)
+		}
+		if err != nil {
+			return err
+		}
+		err = redeem(tx, campaignId)
+		if err != nil {
+			return err
+		}
+
+		fired, err = c.fireCampaignEvents(tx, campaign)
+		if err != nil {
+			return err
+		}
+		if !campaign.Exhausted() {
+			return nil
+		}
+		couponIds, err = c.campaignCoupons(tx, campaignId)
+		if err != nil {
+			return err
+		}
+		return c.notifyCouponsChanged(tx, couponIds)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range fired {
+		ctx.Log.Info("campaign budget threshold reached", zap.String("campaign_id", campaign.Id), zap.Int("threshold", event.Threshold),
+			zap.Float64("spent", event.Spent), zap.Float64("budget", event.Budget))
+	}
+	c.couponsChanged(ctx, couponIds)
+	return nil
+}
+
+// fireCampaignEvents records the budget thresholds a campaign has reached and
+// announces them once the transaction of tx commits. Each threshold fires
+// once: the ones the campaign fired already are skipped.
+func (c *CouponService) fireCampaignEvents(tx *context.Context, campaign *models.Campaign) ([]*models.CampaignEvent, error) {
+	var fired []*models.CampaignEvent
+	for _, threshold := range models.CampaignThresholds {
+		if campaign.Spent < campaign.Budget*float64(threshold)/100 {
+			continue
+		}
+		event := &models.CampaignEvent{
+			Id:         uuid.New().String(),
+			CampaignID: campaign.Id,
+			Threshold:  threshold,
+			Spent:      campaign.Spent,
+			Budget:     campaign.Budget,
+			CreatedAt:  time.Now(),
+		}
+		recorded, err := c.db.PersistCampaignEvent(tx, event)
+		if err != nil {
+			tx.Log.Error("failed to persist campaign event", zap.Error(err))
+			return nil, err
+		}
+		if !recorded {
+			continue
+		}
+		err = c.db.NotifyCampaignEvent(tx, event)
+		if err != nil {
+			tx.Log.Error("failed to notify campaign event", zap.Error(err))
+			return nil, err
+		}
+		fired = append(fired, event)
+	}
+	return fired, nil
+}
+
+// campaignCoupons returns the IDs of the coupons of a campaign that are not
+// deleted
+func (c *CouponService) campaignCoupons(ctx *context.Context, campaignId string) ([]string, error) {
+	coupons, err := c.db.ListCampaignCoupons(ctx, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	var couponIds []string
+	for _, coupon := range coupons {
+		couponIds = append(couponIds, coupon.Id)
+	}
+	return couponIds, nil
+}
+
+// notifyCouponsChanged lets every instance know about a change of the
+// campaign of the coupons once the transaction of tx commits
+func (c *CouponService) notifyCouponsChanged(tx *context.Context, couponIds []string) error {
+	for _, couponId := range couponIds {
+		err := c.db.NotifyCouponChanged(tx, couponId)
+		if err != nil {
+			tx.Log.Error("failed to notify coupon change", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCampaign makes sure the campaign a coupon joins exists
+func (c *CouponService) checkCampaign(ctx *context.Context, campaignId string) error {
+	if campaignId == "" {
+		return nil
+	}
+	_, err := c.db.GetCampaign(ctx, campaignId)
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		v := &validator{}
+		v.report("campaign_id", "does not exist")
+		return v.err("invalid coupon")
+	}
+	return err
+}
+
+// campaignProblem reports why the campaign of a coupon keeps it from
+// applying at the given time, or nil when it does not or there is none
+func campaignProblem(campaign *models.Campaign, now time.Time) error {
+	if campaign == nil {
+		return nil
+	}
+	switch campaign.State(now) {
+	case models.CampaignScheduled:
+		return errors.NotApplicable("campaign %s does not start until %s", campaign.Name, campaign.StartsAt.Format(time.RFC3339))
+	case models.CampaignEnded:
+		return errors.NotApplicable("campaign %s ended at %s", campaign.Name, campaign.EndsAt.Format(time.RFC3339))
+	case models.CampaignExhausted:
+		return errors.NotApplicable("budget of campaign %s is spent", campaign.Name)
+	}
+	return nil
+}
+
+// campaignNotFound reports a missing campaign with its ID, other errors are
+// returned as they are
+func campaignNotFound(campaignId string, err error) error {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.NotFound("campaign %s not found", campaignId).Wrap(err)
+	}
+	return err
+}
+
+// campaignRef returns the campaign column of a coupon that joins campaignId
+func campaignRef(campaignId string) *string {
+	if campaignId == "" {
+		return nil
+	}
+	return &campaignId
+}
+
+func toCampaignDto(campaign *models.Campaign, now time.Time) *dtos.Campaign {
+	startsAt, endsAt := campaign.StartsAt, campaign.EndsAt
+	return &dtos.Campaign{
+		Id:        campaign.Id,
+		Name:      campaign.Name,
+		StartsAt:  &startsAt,
+		EndsAt:    &endsAt,
+		Budget:    campaign.Budget,
+		Spent:     campaign.Spent,
+		Remaining: math.Max(campaign.Budget-campaign.Spent, 0),
+		Status:    campaign.State(now),
+		CreatedAt: campaign.CreatedAt,
+		UpdatedAt: campaign.UpdatedAt,
+	}
+}
+
+func toCampaignEventDto(event *models.CampaignEvent) dtos.CampaignEvent {
+	return dtos.CampaignEvent{
+		Threshold: event.Threshold,
+		Spent:     event.Spent,
+		Budget:    event.Budget,
+		CreatedAt: event.CreatedAt,
+	}
+}
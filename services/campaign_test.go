@@ -0,0 +1,151 @@
+package services
+
+import (
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/errors"
+
+	"github.com/lib/pq"
+)
+
+// createCampaignCoupon creates a running campaign with the given budget and a
+// live coupon in it giving 10% off any cart
+func createCampaignCoupon(t *testing.T, service *CouponService, budget float64) (string, string) {
+	t.Helper()
+	startsAt, endsAt := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	campaign, err := service.CreateCampaign(testContext(testEditor), &dtos.Campaign{Name: "Sale", StartsAt: &startsAt, EndsAt: &endsAt, Budget: budget})
+	if err != nil {
+		t.Fatalf("CreateCampaign() error = %v", err)
+	}
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", CampaignId: campaign.Id, Details: dtos.CouponDetails{Discount: 10}})
+	return campaign.Id, couponId
+}
+
+// cartOf returns a cart of one product at the given price
+func cartOf(price float64) dtos.Cart {
+	return dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: price}}}
+}
+
+func campaignReport(t *testing.T, service *CouponService, campaignId string) *dtos.CampaignReport {
+	t.Helper()
+	report, err := service.GetCampaignReport(testContext(testEditor), campaignId)
+	if err != nil {
+		t.Fatalf("GetCampaignReport() error = %v", err)
+	}
+	return report
+}
+
+func TestCampaignBudget(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	campaignId, couponId := createCampaignCoupon(t, service, 100)
+
+	tests := []struct {
+		name   string
+		price  float64
+		code   errors.Code
+		spent  float64
+		status string
+	}{
+		{"within the budget", 300, "", 30, models.CampaignLive},
+		{"reaching 50%", 300, "", 60, models.CampaignLive},
+		{"reaching 80%", 300, "", 90, models.CampaignLive},
+		{"more than is left", 300, errors.CodeNotApplicable, 90, models.CampaignLive},
+		{"exactly what is left", 100, "", 100, models.CampaignExhausted},
+		{"spent budget", 10, errors.CodeNotApplicable, 100, models.CampaignExhausted},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := redeem(redemptions, couponId, fmt.Sprint("order-", i), "customer-1", cartOf(tt.price))
+			if errorCode(err) != tt.code {
+				t.Errorf("RedeemCoupon() error = %v, want code %q", err, tt.code)
+			}
+			campaign, err := service.GetCampaign(testContext(testEditor), campaignId)
+			if err != nil {
+				t.Fatalf("GetCampaign() error = %v", err)
+			}
+			assertMoney(t, "spent", campaign.Spent, tt.spent)
+			if campaign.Status != tt.status {
+				t.Errorf("status = %s, want %s", campaign.Status, tt.status)
+			}
+		})
+	}
+
+	// Refused redemptions are not stored, and each threshold fired once
+	report := campaignReport(t, service, campaignId)
+	if report.Redemptions != 4 {
+		t.Errorf("%d redemptions are stored, want 4", report.Redemptions)
+	}
+	wantEvents := []dtos.CampaignEvent{{Threshold: 50, Spent: 60}, {Threshold: 80, Spent: 90}, {Threshold: 100, Spent: 100}}
+	if len(report.Events) != len(wantEvents) {
+		t.Fatalf("events = %+v, want %+v", report.Events, wantEvents)
+	}
+	for i, event := range report.Events {
+		if event.Threshold != wantEvents[i].Threshold || event.Spent != wantEvents[i].Spent || event.Budget != 100 {
+			t.Errorf("event %d = %+v, want %+v", i, event, wantEvents[i])
+		}
+	}
+
+	// The coupon stops applying once the budget is spent
+	if _, err := service.ApplyCoupon(testContext(""), couponId, cartOf(10), nil); errorCode(err) != errors.CodeNotApplicable {
+		t.Errorf("ApplyCoupon() error = %v, want not applicable", err)
+	}
+}
+
+func TestConcurrentRedemptionsKeepToTheBudget(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	campaignId, couponId := createCampaignCoupon(t, service, 100)
+
+	// Each redemption fits the budget on its own, only three fit together
+	const orders = 6
+	errs := make([]error, orders)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = redeem(redemptions, couponId, fmt.Sprint("order-", i), fmt.Sprint("customer-", i), cartOf(300))
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		var pqErr *pq.Error
+		switch {
+		case err == nil:
+			succeeded++
+		case errorCode(err) == errors.CodeNotApplicable:
+		case stderrors.As(err, &pqErr) && pqErr.Code == "40001":
+			// Gave up retrying, nothing was stored
+		default:
+			t.Errorf("RedeemCoupon() error = %v, want the budget to run out", err)
+		}
+	}
+	if succeeded == 0 || succeeded > 3 {
+		t.Errorf("%d redemptions succeeded, want 1 to 3", succeeded)
+	}
+
+	report := campaignReport(t, service, campaignId)
+	assertMoney(t, "spent", report.Campaign.Spent, float64(succeeded)*30)
+	if report.Redemptions != succeeded {
+		t.Errorf("%d redemptions are stored, want %d", report.Redemptions, succeeded)
+	}
+	thresholds := make(map[int]int)
+	for _, event := range report.Events {
+		thresholds[event.Threshold]++
+	}
+	for threshold, fired := range thresholds {
+		if fired != 1 {
+			t.Errorf("threshold %d fired %d times, want once", threshold, fired)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/expr"
+
+	"go.uber.org/zap"
+)
+
+// conditionEnv declares the variables a coupon condition can refer to
+var conditionEnv = expr.Env{
+	"cart.subtotal":                  expr.Number,
+	"cart.item_count":                expr.Number,
+	"cart.line_count":                expr.Number,
+	"cart.product_ids":               expr.StringList,
+	"cart.categories":                expr.StringList,
+	"cart.shipping_method":           expr.String,
+	"cart.shipping_fee":              expr.Number,
+	"customer.known":                 expr.Bool,
+	"customer.id":                    expr.String,
+	"customer.segment":               expr.String,
+	"customer.segments":              expr.StringList,
+	"customer.order_count":           expr.Number,
+	"customer.lifetime_spend":        expr.Number,
+	"customer.days_since_signup":     expr.Number,
+	"customer.days_since_last_order": expr.Number,
+}
+
+// Compiled conditions kept at most, the least recently used are dropped first
+const maxCachedConditions = 1024
+
+// conditionLRU holds compiled conditions keyed by their source, so a
+// condition is only compiled again once it falls out of the cache. Sources
+// come from clients, e.g. through previews, so the cache is bounded.
+type conditionLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedCondition struct {
+	source  string
+	program *expr.Program
+}
+
+func newConditionLRU(size int) *conditionLRU {
+	return &conditionLRU{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *conditionLRU) get(source string) (*expr.Program, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[source]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedCondition).program, true
+}
+
+func (c *conditionLRU) add(source string, program *expr.Program) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[source]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[source] = c.order.PushFront(&cachedCondition{source: source, program: program})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedCondition).source)
+	}
+}
+
+var conditionCache = newConditionLRU(maxCachedConditions)
+
+// compileCondition parses and type-checks a coupon condition
+func compileCondition(condition string) (*expr.Program, error) {
+	if program, ok := conditionCache.get(condition); ok {
+		return program, nil
+	}
+
+	program, err := expr.Compile(condition, conditionEnv, expr.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	conditionCache.add(condition, program)
+
+	return program, nil
+}
+
+// conditionVars exposes the cart and the shopper to coupon conditions.
+// Day counts are -1 when the date is unknown.
+func conditionVars(cart dtos.Cart, customer *dtos.Customer, now time.Time) expr.Vars {
+	var subtotal float64
+	itemCount := 0
+	var productIds, categories []string
+	seenCategories := make(map[string]bool)
+	for _, item := range cart.Items {
+		subtotal += float64(item.Quantity) * item.Price
+		itemCount += item.Quantity
+		productIds = append(productIds, item.ProductId)
+		if item.Category != "" && !seenCategories[item.Category] {
+			seenCategories[item.Category] = true
+			categories = append(categories, item.Category)
+		}
+	}
+
+	vars := expr.Vars{
+		"cart.subtotal":                  subtotal,
+		"cart.item_count":                float64(itemCount),
+		"cart.line_count":                float64(len(cart.Items)),
+		"cart.product_ids":               productIds,
+		"cart.categories":                categories,
+		"cart.shipping_method":           cart.ShippingMethod,
+		"cart.shipping_fee":              cart.ShippingFee,
+		"customer.known":                 false,
+		"customer.days_since_signup":     float64(-1),
+		"customer.days_since_last_order": float64(-1),
+	}
+
+	if customer != nil {
+		vars["customer.known"] = customer.Id != ""
+		vars["customer.id"] = customer.Id
+		vars["customer.segments"] = customer.Segments
+		if len(customer.Segments) > 0 {
+			vars["customer.segment"] = customer.Segments[0]
+		}
+		vars["customer.order_count"] = float64(customer.OrderCount)
+		vars["customer.lifetime_spend"] = customer.LifetimeSpend
+		if customer.SignupDate != nil {
+			vars["customer.days_since_signup"] = now.Sub(*customer.SignupDate).Hours() / 24
+		}
+		if customer.LastOrderDate != nil {
+			vars["customer.days_since_last_order"] = now.Sub(*customer.LastOrderDate).Hours() / 24
+		}
+	}
+
+	return vars
+}
+
+// matchesCondition evaluates the condition of a coupon. Coupons without a
+// condition always match; a condition that fails to evaluate does not match.
+func matchesCondition(ctx *context.Context, couponId string, condition string, vars expr.Vars) bool {
+	if condition == "" {
+		return true
+	}
+
+	program, err := compileCondition(condition)
+	if err != nil {
+		ctx.Log.Warn("invalid coupon condition", zap.String("coupon_id", couponId), zap.Error(err))
+		return false
+	}
+
+	matches, err := program.Eval(vars)
+	if err != nil {
+		ctx.Log.Warn("failed to evaluate coupon condition", zap.String("coupon_id", couponId), zap.Error(err))
+		return false
+	}
+
+	return matches
+}
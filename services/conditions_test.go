@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestConditionCacheIsBounded(t *testing.T) {
+	cache := newConditionLRU(2)
+	compile := func(source string) {
+		program, err := compileCondition(source)
+		if err != nil {
+			t.Fatalf("compileCondition(%q) error = %v", source, err)
+		}
+		cache.add(source, program)
+	}
+
+	compile("cart.subtotal > 1")
+	compile("cart.subtotal > 2")
+	// Reading a condition keeps it in the cache
+	if _, ok := cache.get("cart.subtotal > 1"); !ok {
+		t.Fatal("condition 1 is not cached")
+	}
+	compile("cart.subtotal > 3")
+
+	if _, ok := cache.get("cart.subtotal > 2"); ok {
+		t.Error("least recently used condition 2 is still cached")
+	}
+	for _, source := range []string{"cart.subtotal > 1", "cart.subtotal > 3"} {
+		if _, ok := cache.get(source); !ok {
+			t.Errorf("condition %q is not cached", source)
+		}
+	}
+	if cache.order.Len() != 2 || len(cache.entries) != 2 {
+		t.Errorf("cache holds %d conditions, want 2", cache.order.Len())
+	}
+}
+
+func TestCompileConditionKeepsTheCacheBounded(t *testing.T) {
+	for i := 0; i < maxCachedConditions+10; i++ {
+		if _, err := compileCondition(fmt.Sprintf("cart.subtotal > %d", i)); err != nil {
+			t.Fatalf("compileCondition() error = %v", err)
+		}
+	}
+	if n := conditionCache.order.Len(); n != maxCachedConditions {
+		t.Errorf("cache holds %d conditions, want %d", n, maxCachedConditions)
+	}
+}
+
+func TestMatchesCondition(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	signup := now.Add(-days(3))
+	cart := dtos.Cart{
+		Items: []dtos.CartItem{
+			{ProductId: "A", Quantity: 2, Price: 150, Category: "electronics"},
+			{ProductId: "B", Quantity: 1, Price: 300, Category: "books"},
+		},
+		ShippingMethod: "express",
+	}
+	customer := &dtos.Customer{Id: "c", Segments: []string{"vip"}, SignupDate: &signup}
+
+	tests := []struct {
+		condition string
+		customer  *dtos.Customer
+		want      bool
+	}{
+		{"", nil, true},
+		{`cart.subtotal >= 600 && "electronics" in cart.categories`, nil, true},
+		{"cart.item_count == 3 && cart.line_count == 2", nil, true},
+		{`cart.shipping_method in ["express", "overnight"]`, nil, true},
+		{`customer.segment == "vip"`, customer, true},
+		{`customer.segment == "vip"`, nil, false},
+		{"customer.known && customer.days_since_signup < 7", customer, true},
+		{"customer.days_since_last_order < 0", customer, true},
+		// Conditions that fail to compile or evaluate do not match
+		{"cart.subtotal / 0 > 1", nil, false},
+		{"cart.nothing > 1", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			got := matchesCondition(testContext(""), "coupon", tt.condition, conditionVars(cart, tt.customer, now))
+			if got != tt.want {
+				t.Errorf("matchesCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
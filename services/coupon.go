@@ -1,63 +1,184 @@
 package services
 
 import (
-	"errors"
+	stderrors "errors"
 	"monk-commerce-assignment/daos"
 	"monk-commerce-assignment/dtos"
 	"monk-commerce-assignment/models"
 	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type CouponService struct {
-	db daos.Coupon
+	db        *daos.CachedCoupon
+	customers daos.ICustomer
 }
 
-func NewCouponService() ICouponService {
+func NewCouponService(coupons daos.ICoupon, customers daos.ICustomer) ICouponService {
 	return &CouponService{
-		db: daos.Coupon{},
+		db:        daos.NewCachedCoupon(coupons),
+		customers: customers,
 	}
 }
 
 type ICouponService interface {
-	CreateCoupon(ctx *context.Context, req *dtos.Coupon) error
-	GetCoupons(ctx *context.Context) ([]*dtos.Coupon, error)
+	CreateCoupon(ctx *context.Context, req *dtos.Coupon) (*dtos.Coupon, error)
+	ListCoupons(ctx *context.Context, req dtos.CouponListQuery) (*dtos.CouponPage, error)
 	GetCouponById(ctx *context.Context, id string) (*dtos.Coupon, error)
-	GetApplicableCoupons(ctx *context.Context, cartItems []dtos.CartItem) ([]dtos.ApplicableCoupon, error)
-	ApplyCoupon(ctx *context.Context, couponId string, cartItems []dtos.CartItem) (*dtos.UpdatedCart, error)
+	GetApplicableCoupons(ctx *context.Context, cart dtos.Cart, customer *dtos.Customer) (*dtos.ApplicableCouponsResponse, error)
+	ApplyCoupon(ctx *context.Context, couponId string, cart dtos.Cart, customer *dtos.Customer) (*dtos.UpdatedCart, error)
+	PreviewCoupon(ctx *context.Context, req dtos.PreviewRequest) (*dtos.PreviewResponse, error)
 	DeleteCoupon(ctx *context.Context, couponId string) error
+	RestoreCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error)
+	UpdateCoupon(ctx *context.Context, couponId string, req *dtos.Coupon, force bool) (*dtos.Coupon, error)
+	SubmitCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error)
+	ApproveCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error)
+	RejectCoupon(ctx *context.Context, couponId string, req dtos.ReviewRequest) (*dtos.Coupon, error)
+	EndCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error)
+	AdvanceCouponLifecycle(ctx *context.Context) (int, error)
+	PurgeDeletedCoupons(ctx *context.Context, retention time.Duration) (int, error)
+	GetCouponTypes(ctx *context.Context) []*dtos.CouponType
+	GetProductPromotions(ctx *context.Context, productIds []string) (*dtos.PromotionsResponse, error)
+	GetCouponHistory(ctx *context.Context, couponId string, req dtos.AuditQuery) (*dtos.AuditPage, error)
+	SearchAuditLog(ctx *context.Context, req dtos.AuditQuery) (*dtos.AuditPage, error)
+	ListCouponExamples(ctx *context.Context, couponId string) ([]*dtos.CouponExample, error)
+	GetCouponExample(ctx *context.Context, couponId string, exampleId string) (*dtos.CouponExample, error)
+	CreateCouponExample(ctx *context.Context, couponId string, req *dtos.CouponExample) (*dtos.CouponExample, error)
+	UpdateCouponExample(ctx *context.Context, couponId string, exampleId string, req *dtos.CouponExample) (*dtos.CouponExample, error)
+	DeleteCouponExample(ctx *context.Context, couponId string, exampleId string) error
+	RunCouponExamples(ctx *context.Context, couponId string) (*dtos.ExampleRun, error)
+	RunAllCouponExamples(ctx *context.Context) (*dtos.ExampleRun, error)
+	CreateCampaign(ctx *context.Context, req *dtos.Campaign) (*dtos.Campaign, error)
+	ListCampaigns(ctx *context.Context) ([]*dtos.Campaign, error)
+	GetCampaign(ctx *context.Context, campaignId string) (*dtos.Campaign, error)
+	UpdateCampaign(ctx *context.Context, campaignId string, req *dtos.Campaign) (*dtos.Campaign, error)
+	GetCampaignReport(ctx *context.Context, campaignId string) (*dtos.CampaignReport, error)
+	SpendCampaignBudget(ctx *context.Context, couponId string, amount float64, redeem func(tx *context.Context, campaignId string) error) error
 }
 
-func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) error {
-	// Start a transaction
-	tx := ctx.DB.Begin()
-	ctx.Transaction = tx
-	if tx.Error != nil {
-		ctx.Log.Error("failed to start transaction", zap.Error(tx.Error))
-		return tx.Error
+func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) (*dtos.Coupon, error) {
+	// Reject invalid definitions before touching the database
+	if err := validateCoupon(req); err != nil {
+		return nil, err
+	}
+	if err := c.checkCampaign(ctx, req.CampaignId); err != nil {
+		return nil, err
 	}
 
-	// Generate a new coupon ID and create the base coupon entry
+	// Generate a new coupon ID and create the base coupon entry. New coupons
+	// do not apply until they are approved.
 	couponId := uuid.New().String()
-	coupon := models.Coupon{
+	coupon := newCoupon(couponId, req, time.Now())
+	coupon.Status = models.StatusDraft
+	coupon.EditedBy = ctx.Actor
+	coupon.CampaignID = campaignRef(req.CampaignId)
+
+	// The coupon as it is kept in the audit log, with the text of every locale
+	created := couponDefinition(req)
+	created.Id = couponId
+	created.CreatedAt = coupon.CreatedAt
+	setWorkflow(&created, &coupon)
+
+	// Write the coupon, its details and the audit entry in one transaction
+	err := daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.persistCoupon(tx, &coupon, req)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditCreated, nil, &created)
+	})
+	if err != nil {
+		return nil, couponCodeTaken(coupon.Code, err)
+	}
+
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
+
+	localizeCoupon(&created, couponTranslations(couponId, created.Translations), ctx.Locales)
+	return &created, nil
+}
+
+// newCoupon returns the base coupon entry of a definition
+func newCoupon(couponId string, req *dtos.Coupon, now time.Time) models.Coupon {
+	return models.Coupon{
 		Id:        couponId,
 		Type:      req.Type,
+		Code:      normalizeCode(req.Code),
 		IsActive:  true,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Condition: req.Condition,
+		Metadata:  models.JSON(req.Metadata),
+		StartsAt:  utc(req.StartsAt),
+		EndsAt:    utc(req.EndsAt),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// persistCoupon writes a new coupon and all of its details in the transaction of tx
+func (c *CouponService) persistCoupon(tx *context.Context, coupon *models.Coupon, req *dtos.Coupon) error {
+	couponId := coupon.Id
+
+	// Persist the coupon entry
+	err := c.db.PersistCoupon(tx, coupon)
+	if err != nil {
+		tx.Log.Error("failed to persist coupon", zap.Error(err))
+		return err
 	}
 
-	// Persist the coupon entry and handle errors with transaction rollback
-	err := c.db.PersistCoupon(ctx, &coupon)
+	err = c.persistCouponDetails(tx, couponId, req)
 	if err != nil {
-		ctx.Log.Error("failed to persist coupon", zap.Error(err))
-		tx.Rollback()
 		return err
 	}
 
+	// Let every instance know about the new coupon once the transaction commits
+	err = c.db.NotifyCouponChanged(tx, couponId)
+	if err != nil {
+		tx.Log.Error("failed to notify coupon change", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// persistCouponDetails writes the details, shopper conditions, display text
+// and tags of a coupon in the transaction of tx
+func (c *CouponService) persistCouponDetails(tx *context.Context, couponId string, req *dtos.Coupon) error {
+	var err error
+
+	// Persist the shopper conditions of the coupon, if any
+	if req.Eligibility != nil {
+		err = c.persistEligibility(tx, couponId, req.Eligibility)
+		if err != nil {
+			tx.Log.Error("failed to persist coupon eligibility", zap.Error(err))
+			return err
+		}
+	}
+
+	// Persist the display text and the tags of the coupon
+	for _, translation := range couponTranslations(couponId, displayTranslations(req)) {
+		err = c.db.PersistCouponTranslation(tx, translation)
+		if err != nil {
+			tx.Log.Error("failed to persist coupon translation", zap.Error(err))
+			return err
+		}
+	}
+	for _, tag := range req.Tags {
+		err = c.db.PersistCouponTag(tx, &models.CouponTag{
+			CouponID: couponId,
+			Tag:      strings.TrimSpace(tag),
+		})
+		if err != nil {
+			tx.Log.Error("failed to persist coupon tag", zap.Error(err))
+			return err
+		}
+	}
+
 	// Process based on coupon type
 	switch req.Type {
 	case "cart-wise":
@@ -66,10 +187,9 @@ func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) err
 			Threshold: float64(req.Details.Threshold),
 			Discount:  float64(req.Details.Discount),
 		}
-		err = c.db.PersistCartWiseCoupon(ctx, &cartWiseCoupon)
+		err = c.db.PersistCartWiseCoupon(tx, &cartWiseCoupon)
 		if err != nil {
-			ctx.Log.Error("failed to persist cart-wise coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("failed to persist cart-wise coupon", zap.Error(err))
 			return err
 		}
 
@@ -79,10 +199,9 @@ func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) err
 			ProductID: req.Details.ProductId,
 			Discount:  float64(req.Details.Discount),
 		}
-		err = c.db.PersistProductWiseCoupon(ctx, &productWiseCoupon)
+		err = c.db.PersistProductWiseCoupon(tx, &productWiseCoupon)
 		if err != nil {
-			ctx.Log.Error("failed to persist product-wise coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("failed to persist product-wise coupon", zap.Error(err))
 			return err
 		}
 
@@ -91,10 +210,9 @@ func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) err
 			CouponID:        couponId,
 			RepetitionLimit: req.Details.RepitionLimit,
 		}
-		err = c.db.PersistBxGyCoupon(ctx, &bxgyCoupon)
+		err = c.db.PersistBxGyCoupon(tx, &bxgyCoupon)
 		if err != nil {
-			ctx.Log.Error("failed to persist BxGy coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("failed to persist BxGy coupon", zap.Error(err))
 			return err
 		}
 
@@ -105,10 +223,9 @@ func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) err
 				ProductID:    buyProduct.ProductId,
 				Quantity:     buyProduct.Quantity,
 			}
-			err = c.db.PersistBxGyBuyCoupon(ctx, &buyProductModel)
+			err = c.db.PersistBxGyBuyCoupon(tx, &buyProductModel)
 			if err != nil {
-				ctx.Log.Error("failed to persist BxGy buy product", zap.Error(err))
-				tx.Rollback()
+				tx.Log.Error("failed to persist BxGy buy product", zap.Error(err))
 				return err
 			}
 		}
@@ -120,454 +237,546 @@ func (c *CouponService) CreateCoupon(ctx *context.Context, req *dtos.Coupon) err
 				ProductID:    getProduct.ProductId,
 				Quantity:     getProduct.Quantity,
 			}
-			err = c.db.PersistBxGyGetCoupon(ctx, &getProductModel)
+			err = c.db.PersistBxGyGetCoupon(tx, &getProductModel)
 			if err != nil {
-				ctx.Log.Error("failed to persist BxGy get product", zap.Error(err))
-				tx.Rollback()
+				tx.Log.Error("failed to persist BxGy get product", zap.Error(err))
 				return err
 			}
 		}
 
-	default:
-		tx.Rollback()
-		err := errors.New("unsupported coupon type")
-		ctx.Log.Error("invalid coupon type", zap.Error(err))
-		return err
-	}
-
-	// Commit the transaction
-	if err = tx.Commit().Error; err != nil {
-		ctx.Log.Error("failed to commit transaction", zap.Error(err))
-		return err
-	}
-
-	return nil
-}
-
-func (c *CouponService) GetCoupons(ctx *context.Context) ([]*dtos.Coupon, error) {
-	// Retrieve all coupons from the database
-	coupons, err := c.db.GetAllCoupons(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	// Prepare a slice to hold the transformed coupon data
-	var result []*dtos.Coupon
-
-	// Iterate through each coupon and transform it into the DTO format
-	for _, coupon := range coupons {
-		couponDto := &dtos.Coupon{
-			Id:   coupon.Id,
-			Type: coupon.Type,
+	case "volume":
+		scope := req.Details.Scope
+		if scope == "" {
+			scope = volumeScopeLine
+		}
+		volumeCoupon := models.VolumePricingCoupon{
+			CouponID: couponId,
+			Scope:    scope,
+		}
+		err = c.db.PersistVolumePricingCoupon(tx, &volumeCoupon)
+		if err != nil {
+			tx.Log.Error("failed to persist volume pricing coupon", zap.Error(err))
+			return err
 		}
 
-		// Populate coupon-specific details based on type
-		switch coupon.Type {
-		case "cart-wise":
-			cartCoupon, err := c.db.GetCartWiseCoupon(ctx, coupon.Id)
-			if err != nil {
-				return nil, err
-			}
-			couponDto.Details = dtos.CouponDetails{
-				Threshold: int(cartCoupon.Threshold),
-				Discount:  int(cartCoupon.Discount),
+		// Persist the products covered by the volume pricing coupon
+		for _, productId := range req.Details.ProductIds {
+			productModel := models.VolumePricingProduct{
+				VolumePricingCouponID: couponId,
+				ProductID:             productId,
 			}
-
-		case "product-wise":
-			productCoupon, err := c.db.GetProductWiseCoupon(ctx, coupon.Id)
+			err = c.db.PersistVolumePricingProduct(tx, &productModel)
 			if err != nil {
-				return nil, err
-			}
-			couponDto.Details = dtos.CouponDetails{
-				ProductId: productCoupon.ProductID,
-				Discount:  int(productCoupon.Discount),
+				tx.Log.Error("failed to persist volume pricing product", zap.Error(err))
+				return err
 			}
+		}
 
-		case "bxgy":
-			bxgyCoupon, err := c.db.GetBxGyCoupon(ctx, coupon.Id)
-			if err != nil {
-				return nil, err
-			}
-			buyProducts, err := c.db.GetBxGyBuyProducts(ctx, bxgyCoupon.CouponID)
-			if err != nil {
-				return nil, err
+		// Persist the quantity bands of the volume pricing coupon
+		for _, band := range req.Details.Bands {
+			bandModel := models.VolumePricingBand{
+				VolumePricingCouponID: couponId,
+				MinQuantity:           band.MinQuantity,
+				Discount:              band.Discount,
 			}
-			getProducts, err := c.db.GetBxGyGetProducts(ctx, bxgyCoupon.CouponID)
+			err = c.db.PersistVolumePricingBand(tx, &bandModel)
 			if err != nil {
-				return nil, err
+				tx.Log.Error("failed to persist volume pricing band", zap.Error(err))
+				return err
 			}
+		}
+
+	case "fixed-price":
+		fixedPriceCoupon := models.FixedPriceCoupon{
+			CouponID: couponId,
+			MaxUnits: req.Details.MaxUnits,
+		}
+		err = c.db.PersistFixedPriceCoupon(tx, &fixedPriceCoupon)
+		if err != nil {
+			tx.Log.Error("failed to persist fixed price coupon", zap.Error(err))
+			return err
+		}
 
-			// Convert buy and get products into the DTO format
-			var buyProductsDto, getProductsDto []dtos.ProductQuantityDetails
-			for _, buyProduct := range buyProducts {
-				buyProductsDto = append(buyProductsDto, dtos.ProductQuantityDetails{
-					ProductId: buyProduct.ProductID,
-					Quantity:  buyProduct.Quantity,
-				})
+		// Persist the override price of each targeted product
+		for _, fixedPrice := range req.Details.FixedPrices {
+			productModel := models.FixedPriceProduct{
+				FixedPriceCouponID: couponId,
+				ProductID:          fixedPrice.ProductId,
+				Price:              fixedPrice.Price,
 			}
-			for _, getProduct := range getProducts {
-				getProductsDto = append(getProductsDto, dtos.ProductQuantityDetails{
-					ProductId: getProduct.ProductID,
-					Quantity:  getProduct.Quantity,
-				})
+			err = c.db.PersistFixedPriceProduct(tx, &productModel)
+			if err != nil {
+				tx.Log.Error("failed to persist fixed price product", zap.Error(err))
+				return err
 			}
+		}
 
-			couponDto.Details = dtos.CouponDetails{
-				RepitionLimit: bxgyCoupon.RepetitionLimit,
-				BuyProducts:   buyProductsDto,
-				GetProducts:   getProductsDto,
-			}
+	case "shipping":
+		shippingCoupon := models.ShippingCoupon{
+			CouponID:    couponId,
+			Threshold:   float64(req.Details.Threshold),
+			Discount:    float64(req.Details.Discount),
+			MaxDiscount: req.Details.MaxDiscount,
+		}
+		err = c.db.PersistShippingCoupon(tx, &shippingCoupon)
+		if err != nil {
+			tx.Log.Error("failed to persist shipping coupon", zap.Error(err))
+			return err
+		}
 
-		default:
-			return nil, errors.New("unsupported coupon type")
+		// Persist the shipping methods the coupon is restricted to
+		for _, method := range req.Details.ShippingMethods {
+			methodModel := models.ShippingCouponMethod{
+				ShippingCouponID: couponId,
+				Method:           method,
+			}
+			err = c.db.PersistShippingCouponMethod(tx, &methodModel)
+			if err != nil {
+				tx.Log.Error("failed to persist shipping coupon method", zap.Error(err))
+				return err
+			}
 		}
 
-		// Append the transformed coupon to the result list
-		result = append(result, couponDto)
+	default:
+		err := errors.Validation("unsupported coupon type %q", req.Type)
+		tx.Log.Error("invalid coupon type", zap.Error(err))
+		return err
 	}
 
-	return result, nil
+	return nil
 }
 
 func (c *CouponService) GetCouponById(ctx *context.Context, id string) (*dtos.Coupon, error) {
 	// Retrieve the basic coupon information by ID
-	coupon, err := c.db.GetCouponById(ctx, id)
+	rules, err := c.getCouponRules(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Initialize the DTO for the response
-	couponDto := &dtos.Coupon{
-		Id:   coupon.Id,
-		Type: coupon.Type,
+	couponDto, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
 	}
 
-	// Fetch additional details based on coupon type
-	switch coupon.Type {
-	case "cart-wise":
-		cartCoupon, err := c.db.GetCartWiseCoupon(ctx, coupon.Id)
-		if err != nil {
-			return nil, err
-		}
-		couponDto.Details = dtos.CouponDetails{
-			Threshold: int(cartCoupon.Threshold),
-			Discount:  int(cartCoupon.Discount),
-		}
+	// Show the edit waiting for review next to the coupon as it applies now
+	revision, err := c.pendingRevision(ctx, couponDto)
+	if err != nil {
+		return nil, err
+	}
+	localizeCoupon(couponDto, rules.Translations, ctx.Locales)
+	couponDto.PendingRevision = revision
+	return couponDto, nil
+}
 
-	case "product-wise":
-		productCoupon, err := c.db.GetProductWiseCoupon(ctx, coupon.Id)
-		if err != nil {
-			return nil, err
-		}
-		couponDto.Details = dtos.CouponDetails{
-			ProductId: productCoupon.ProductID,
-			Discount:  int(productCoupon.Discount),
-		}
+func (c *CouponService) GetApplicableCoupons(ctx *context.Context, cart dtos.Cart, customer *dtos.Customer) (*dtos.ApplicableCouponsResponse, error) {
+	if err := validateCart(cart); err != nil {
+		return nil, err
+	}
 
-	case "bxgy":
-		bxgyCoupon, err := c.db.GetBxGyCoupon(ctx, coupon.Id)
-		if err != nil {
-			return nil, err
-		}
+	// Complete the customer context with the order history we keep
+	customer, customerDegraded, err := c.pricingCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
 
-		buyProducts, err := c.db.GetBxGyBuyProducts(ctx, bxgyCoupon.CouponID)
-		if err != nil {
-			return nil, err
-		}
-		getProducts, err := c.db.GetBxGyGetProducts(ctx, bxgyCoupon.CouponID)
-		if err != nil {
-			return nil, err
+	// Look up the coupons that could touch this cart in the coupon index
+	index, indexDegraded, err := c.pricingIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applicableCoupons []dtos.ApplicableCoupon
+
+	// Variables exposed to coupon conditions
+	vars := conditionVars(cart, customer, now)
+
+	// Check each candidate coupon for applicability
+	for _, rules := range index.Candidates(cart) {
+		coupon := rules.Coupon
+
+		// Skip coupons outside of their validity window
+		if coupon.ValidityState(now) != models.CouponLive {
+			continue
 		}
 
-		// Convert buy and get products into the DTO format
-		var buyProductsDto, getProductsDto []dtos.ProductQuantityDetails
-		for _, buyProduct := range buyProducts {
-			buyProductsDto = append(buyProductsDto, dtos.ProductQuantityDetails{
-				ProductId: buyProduct.ProductID,
-				Quantity:  buyProduct.Quantity,
-			})
+		// Skip coupons whose campaign is not running or spent its budget
+		if campaignProblem(rules.Campaign, now) != nil {
+			continue
 		}
-		for _, getProduct := range getProducts {
-			getProductsDto = append(getProductsDto, dtos.ProductQuantityDetails{
-				ProductId: getProduct.ProductID,
-				Quantity:  getProduct.Quantity,
-			})
+
+		// Skip coupons the shopper is not eligible for
+		if !isEligible(eligibilityDetails(rules), customer, now) {
+			continue
 		}
 
-		couponDto.Details = dtos.CouponDetails{
-			RepitionLimit: bxgyCoupon.RepetitionLimit,
-			BuyProducts:   buyProductsDto,
-			GetProducts:   getProductsDto,
+		// Skip coupons whose condition the cart does not meet
+		if !matchesCondition(ctx, coupon.Id, coupon.Condition, vars) {
+			continue
 		}
 
-	default:
-		return nil, errors.New("unsupported coupon type")
+		// If the coupon is applicable, add it to the result list
+		result := evaluateCoupon(rules, cart)
+		if result.Applicable {
+			applicableCoupon := dtos.ApplicableCoupon{
+				CouponID: coupon.Id,
+				Type:     coupon.Type,
+				Discount: result.Discount(),
+			}
+			if translation, tag := couponText(rules.Translations, ctx.Locales); translation != nil {
+				applicableCoupon.Name = translation.Name
+				applicableCoupon.Badge = translation.Badge
+				applicableCoupon.Locale = tag
+			}
+			applicableCoupons = append(applicableCoupons, applicableCoupon)
+		}
 	}
 
-	return couponDto, nil
+	return &dtos.ApplicableCouponsResponse{
+		ApplicableCoupons: applicableCoupons,
+		Degraded:          customerDegraded || indexDegraded,
+	}, nil
 }
 
-func (c *CouponService) GetApplicableCoupons(ctx *context.Context, cartItems []dtos.CartItem) ([]dtos.ApplicableCoupon, error) {
-	// Fetch all available coupons from the database
-	coupons, err := c.db.GetAllCoupons(ctx)
+func (c *CouponService) ApplyCoupon(ctx *context.Context, couponId string, cart dtos.Cart, customer *dtos.Customer) (*dtos.UpdatedCart, error) {
+	if err := validateCart(cart); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the specified coupon and its details by ID
+	rules, rulesDegraded, err := c.pricingRules(ctx, couponId)
 	if err != nil {
 		return nil, err
 	}
+	coupon := rules.Coupon
 
-	var applicableCoupons []dtos.ApplicableCoupon
+	// Deleted coupons are kept for their history but cannot be applied
+	if coupon.DeletedAt != nil {
+		return nil, errors.NotFound("coupon %s not found", couponId)
+	}
+	if !coupon.Evaluable() {
+		return nil, errors.NotApplicable("coupon is %s, only approved coupons apply", coupon.Status)
+	}
+	now := time.Now()
+	if err := campaignProblem(rules.Campaign, now); err != nil {
+		return nil, err
+	}
 
-	// Calculate the cart total, which may be used by cart-wise coupons
-	var cartTotal float64
-	for _, item := range cartItems {
-		cartTotal += float64(item.Quantity) * item.Price
+	// Complete the customer context with the order history we keep
+	customer, customerDegraded, err := c.pricingCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check each coupon for applicability
-	for _, coupon := range coupons {
-		var discount float64
-		isApplicable := false
+	updatedCart, err := priceCart(ctx, rules, cart, customer, now)
+	if err != nil {
+		return nil, err
+	}
+	updatedCart.Degraded = rulesDegraded || customerDegraded
+	return updatedCart, nil
+}
 
-		switch coupon.Type {
-		case "cart-wise":
-			// Check if the cart meets the cart-wise coupon threshold
-			cartCoupon, err := c.db.GetCartWiseCoupon(ctx, coupon.Id)
-			if err != nil {
-				return nil, err
-			}
-			if cartTotal >= cartCoupon.Threshold {
-				discount = (cartCoupon.Discount / 100) * cartTotal
-				isApplicable = true
-			}
+// priceCart applies a coupon to a cart at the given time. It reports why the
+// coupon does not apply: outside of its validity window, a shopper who is not
+// eligible or a cart that does not meet its condition.
+func priceCart(ctx *context.Context, rules *models.CouponRules, cart dtos.Cart, customer *dtos.Customer, now time.Time) (*dtos.UpdatedCart, error) {
+	coupon := rules.Coupon
+
+	switch coupon.ValidityState(now) {
+	case models.CouponScheduled:
+		return nil, errors.NotApplicable("coupon is not valid until %s", coupon.StartsAt.Format(time.RFC3339))
+	case models.CouponExpired:
+		return nil, errors.NotApplicable("coupon expired at %s", coupon.EndsAt.Format(time.RFC3339))
+	}
+	if !isEligible(eligibilityDetails(rules), customer, now) {
+		return nil, errors.NotApplicable("customer is not eligible for this coupon")
+	}
+	if !matchesCondition(ctx, coupon.Id, coupon.Condition, conditionVars(cart, customer, now)) {
+		return nil, errors.NotApplicable("cart does not meet the coupon condition")
+	}
 
-		case "product-wise":
-			// Apply discount to specific products in the cart if they match the product-wise coupon
-			productCoupon, err := c.db.GetProductWiseCoupon(ctx, coupon.Id)
-			if err != nil {
-				return nil, err
-			}
-			for _, item := range cartItems {
-				if item.ProductId == productCoupon.ProductID {
-					discount += (productCoupon.Discount / 100) * float64(item.Quantity) * item.Price
-					isApplicable = true
-				}
-			}
+	result := appliedResult(rules, cart, evaluateCoupon(rules, cart))
 
-		case "bxgy":
-			// Check BxGy conditions and calculate the discount if applicable
-			bxgyCoupon, err := c.db.GetBxGyCoupon(ctx, coupon.Id)
-			if err != nil {
-				return nil, err
-			}
-			buyProducts, err := c.db.GetBxGyBuyProducts(ctx, bxgyCoupon.CouponID)
-			if err != nil {
-				return nil, err
-			}
-			getProducts, err := c.db.GetBxGyGetProducts(ctx, bxgyCoupon.CouponID)
-			if err != nil {
-				return nil, err
-			}
+	// Initialize variables for calculating the final prices and discounts
+	var totalPrice float64
+	totalDiscount := result.CartDiscount
+	updatedItems := make([]dtos.CartItemDiscount, len(cart.Items))
 
-			buyCount := 0
-			for _, buyProduct := range buyProducts {
-				for _, item := range cartItems {
-					if item.ProductId == buyProduct.ProductID {
-						buyCount += item.Quantity / buyProduct.Quantity
-					}
-				}
-			}
+	for i, item := range cart.Items {
+		totalPrice += float64(item.Quantity) * item.Price
 
-			if buyCount > 0 {
-				if buyCount > bxgyCoupon.RepetitionLimit {
-					buyCount = bxgyCoupon.RepetitionLimit // Cap buyCount at the repetition limit
-				}
-				
-				isApplicable = true
-				for _, getProduct := range getProducts {
-					for _, item := range cartItems {
-						if item.ProductId == getProduct.ProductID {
-							discount += float64(getProduct.Quantity) * item.Price
-						}
-					}
-				}
-				discount *= float64(buyCount)
+		line := result.Lines[i]
+		updatedItem := dtos.CartItemDiscount{
+			ProductId:     item.ProductId,
+			Quantity:      item.Quantity,
+			Price:         item.Price,
+			TotalDiscount: line.Discount,
+		}
+		if line.Band != nil {
+			updatedItem.Band = &dtos.QuantityBand{
+				MinQuantity: line.Band.MinQuantity,
+				Discount:    line.Band.Discount,
 			}
 		}
+		totalDiscount += line.Discount
 
-		// If the coupon is applicable, add it to the result list
-		if isApplicable {
-			applicableCoupons = append(applicableCoupons, dtos.ApplicableCoupon{
-				CouponID: coupon.Id,
-				Type:     coupon.Type,
-				Discount: discount,
-			})
+		// Break the line discount down per unit
+		if updatedItem.Quantity > 0 {
+			updatedItem.UnitDiscount = updatedItem.TotalDiscount / float64(updatedItem.Quantity)
 		}
+		updatedItem.FinalUnitPrice = updatedItem.Price - updatedItem.UnitDiscount
+
+		// Add the updated item to the list of updated items
+		updatedItems[i] = updatedItem
+	}
+
+	// Create the updated cart response, shipping coupons only discount the shipping fee
+	updatedCart := &dtos.UpdatedCart{
+		Items:            updatedItems,
+		TotalPrice:       totalPrice,
+		TotalDiscount:    totalDiscount,
+		FinalPrice:       totalPrice - totalDiscount,
+		ShippingFee:      cart.ShippingFee,
+		ShippingDiscount: result.ShippingDiscount,
+		FinalShipping:    cart.ShippingFee - result.ShippingDiscount,
 	}
 
-	return applicableCoupons, nil
+	return updatedCart, nil
+}
+
+// utc returns the time in UTC, or nil for no time
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// couponNotFound reports a missing coupon with its ID, other errors are
+// returned as they are
+func couponNotFound(couponId string, err error) error {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.NotFound("coupon %s not found", couponId).Wrap(err)
+	}
+	return err
 }
 
-func (c *CouponService) ApplyCoupon(ctx *context.Context, couponId string, cartItems []dtos.CartItem) (*dtos.UpdatedCart, error) {
-	// Retrieve the specified coupon by ID
+// getCouponRules loads a single coupon together with its details
+func (c *CouponService) getCouponRules(ctx *context.Context, couponId string) (*models.CouponRules, error) {
 	coupon, err := c.db.GetCouponById(ctx, couponId)
+	if err != nil {
+		return nil, couponNotFound(couponId, err)
+	}
+	ruleSet, err := c.db.LoadRuleSet(ctx, []*models.Coupon{coupon})
 	if err != nil {
 		return nil, err
 	}
+	return ruleSet.Get(coupon.Id), nil
+}
 
-	// Initialize variables for calculating the final prices and discounts
-	var totalDiscount float64
-	var totalPrice float64
-	updatedItems := make([]dtos.CartItemDiscount, len(cartItems))
-
-	// Calculate total cart price
-	for _, item := range cartItems {
-		totalPrice += float64(item.Quantity) * item.Price
+// DeleteCoupon marks a coupon as deleted. It is no longer listed or applied,
+// but it keeps its details and redemptions until it is purged.
+func (c *CouponService) DeleteCoupon(ctx *context.Context, couponId string) error {
+	// Check if the coupon exists, and keep it for the audit log
+	rules, err := c.getCouponRules(ctx, couponId)
+	if err != nil {
+		return err
+	}
+	if rules.Coupon.DeletedAt != nil {
+		return errors.Conflict("coupon %s is already deleted", couponId)
+	}
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return err
 	}
 
-	// Iterate over each item in the cart and calculate discounts based on coupon type
-	for i, item := range cartItems {
-		updatedItem := dtos.CartItemDiscount{
-			ProductId:     item.ProductId,
-			Quantity:      item.Quantity,
-			Price:         item.Price,
-			TotalDiscount: 0,
+	now := time.Now().UTC()
+	after := *before
+	after.DeletedAt = &now
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.setDeletedAt(tx, couponId, &now)
+		if err != nil {
+			return err
 		}
+		return c.recordChange(tx, couponId, models.AuditDeleted, before, &after)
+	})
+	if err != nil {
+		return err
+	}
 
-		switch coupon.Type {
-		case "cart-wise":
-			// Apply a discount to the entire cart if the threshold is met
-			cartCoupon, err := c.db.GetCartWiseCoupon(ctx, couponId)
-			if err != nil {
-				return nil, err
-			}
-			if totalPrice >= cartCoupon.Threshold {
-				discount := (cartCoupon.Discount / 100) * totalPrice
-				totalDiscount += discount
-			}
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
 
-		case "product-wise":
-			// Apply a discount to specific products in the cart
-			productCoupon, err := c.db.GetProductWiseCoupon(ctx, couponId)
-			if err != nil {
-				return nil, err
-			}
-			if item.ProductId == productCoupon.ProductID {
-				discount := (productCoupon.Discount / 100) * float64(item.Quantity) * item.Price
-				updatedItem.TotalDiscount = discount
-				totalDiscount += discount
-			}
+	return nil
+}
 
-		case "bxgy":
-			// Apply "Buy X, Get Y" coupon logic
-			bxgyCoupon, err := c.db.GetBxGyCoupon(ctx, couponId)
-			if err != nil {
-				return nil, err
-			}
-			buyProducts, err := c.db.GetBxGyBuyProducts(ctx, bxgyCoupon.CouponID)
-			if err != nil {
-				return nil, err
-			}
-			getProducts, err := c.db.GetBxGyGetProducts(ctx, bxgyCoupon.CouponID)
-			if err != nil {
-				return nil, err
-			}
+// RestoreCoupon brings back a deleted coupon that was not purged yet
+func (c *CouponService) RestoreCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error) {
+	rules, err := c.getCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Coupon.DeletedAt == nil {
+		return nil, errors.Conflict("coupon %s is not deleted", couponId)
+	}
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
 
-			buyCount := 0
-			for _, buyProduct := range buyProducts {
-				if item.ProductId == buyProduct.ProductID {
-					buyCount += item.Quantity / buyProduct.Quantity
-				}
-			}
+	restored := *before
+	restored.DeletedAt = nil
 
-			if buyCount > 0 {
-				if buyCount > bxgyCoupon.RepetitionLimit {
-					buyCount = bxgyCoupon.RepetitionLimit // Cap buyCount at the repetition limit
-				}
-
-				for _, getProduct := range getProducts {
-					for _, cartItem := range cartItems {
-						if cartItem.ProductId == getProduct.ProductID {
-							discount := float64(getProduct.Quantity*buyCount) * cartItem.Price
-							updatedItem.TotalDiscount += discount
-							totalDiscount += discount
-						}
-					}
-				}
-			}
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.setDeletedAt(tx, couponId, nil)
+		if err != nil {
+			return err
 		}
-
-		// Add the updated item to the list of updated items
-		updatedItems[i] = updatedItem
+		return c.recordChange(tx, couponId, models.AuditRestored, before, &restored)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate final price after applying total discount
-	finalPrice := totalPrice - totalDiscount
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
 
-	// Create the updated cart response
-	updatedCart := &dtos.UpdatedCart{
-		Items:         updatedItems,
-		TotalPrice:    totalPrice,
-		TotalDiscount: totalDiscount,
-		FinalPrice:    finalPrice,
+	localizeCoupon(&restored, rules.Translations, ctx.Locales)
+	return &restored, nil
+}
+
+// setDeletedAt deletes a coupon, or restores it when deletedAt is nil. It must
+// run in a transaction. It returns a conflict if another request deleted or
+// restored the coupon since it was read.
+func (c *CouponService) setDeletedAt(tx *context.Context, couponId string, deletedAt *time.Time) error {
+	err := c.db.UpdateCouponDeletedAt(tx, couponId, deletedAt, time.Now())
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		if deletedAt != nil {
+			return errors.Conflict("coupon %s is already deleted", couponId).Wrap(err)
+		}
+		return errors.Conflict("coupon %s is not deleted", couponId).Wrap(err)
+	}
+	if err != nil {
+		tx.Log.Error("failed to update coupon deleted_at", zap.Error(err))
+		return err
 	}
 
-	return updatedCart, nil
+	// Let every instance know about the change once the transaction commits
+	err = c.db.NotifyCouponChanged(tx, couponId)
+	if err != nil {
+		tx.Log.Error("failed to notify coupon change", zap.Error(err))
+		return err
+	}
+
+	return nil
 }
 
-func (c *CouponService) DeleteCoupon(ctx *context.Context, couponId string) error {
-	// Check if the coupon exists
-	coupon, err := c.db.GetCouponById(ctx, couponId)
+// removeCoupon deletes a coupon and its details for good, it must run in a
+// transaction
+func (c *CouponService) removeCoupon(tx *context.Context, coupon *models.Coupon) error {
+	couponId := coupon.Id
+
+	err := c.removeCouponDetails(tx, coupon)
+	if err != nil {
+		return err
+	}
+
+	// Delete the main coupon entry
+	err = c.db.DeleteCoupon(tx, couponId)
 	if err != nil {
-		return errors.New("coupon not found")
+		tx.Log.Error("error deleting main coupon", zap.Error(err))
+		return err
 	}
 
-	tx := ctx.DB.Begin()
-	ctx.Transaction = tx
-	if tx.Error != nil {
-		ctx.Log.Error("failed to start transaction", zap.Error(tx.Error))
-		return tx.Error
+	// Let every instance know about the deletion once the transaction commits
+	err = c.db.NotifyCouponChanged(tx, couponId)
+	if err != nil {
+		tx.Log.Error("failed to notify coupon change", zap.Error(err))
+		return err
 	}
 
+	return nil
+}
+
+// removeCouponDetails deletes the details, shopper conditions, display text
+// and tags of a coupon, it must run in a transaction
+func (c *CouponService) removeCouponDetails(tx *context.Context, coupon *models.Coupon) error {
+	couponId := coupon.Id
+
+	var err error
 	// Delete the coupon based on its type (if additional tables are used for specific types)
 	switch coupon.Type {
 	case "cart-wise":
-		err = c.db.DeleteCartWiseCoupon(ctx, couponId)
+		err = c.db.DeleteCartWiseCoupon(tx, couponId)
 	case "product-wise":
-		err = c.db.DeleteProductWiseCoupon(ctx, couponId)
+		err = c.db.DeleteProductWiseCoupon(tx, couponId)
 	case "bxgy":
-		err = c.db.DeleteBxGyCoupon(ctx, couponId)
+		err = c.db.DeleteBxGyBuyProducts(tx, couponId)
+		if err != nil {
+			tx.Log.Error("error deleting bxgy buy products", zap.Error(err))
+			return err
+		}
+		err = c.db.DeleteBxGyGetProducts(tx, couponId)
+		if err != nil {
+			tx.Log.Error("error deleting bxgy get products", zap.Error(err))
+			return err
+		}
+		err = c.db.DeleteBxGyCoupon(tx, couponId)
+	case "volume":
+		err = c.db.DeleteVolumePricingBands(tx, couponId)
+		if err != nil {
+			tx.Log.Error("error deleting volume pricing bands", zap.Error(err))
+			return err
+		}
+		err = c.db.DeleteVolumePricingProducts(tx, couponId)
 		if err != nil {
-			ctx.Log.Error("error deleting bxgy coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("error deleting volume pricing products", zap.Error(err))
 			return err
 		}
-		err = c.db.DeleteBxGyBuyProducts(ctx, couponId)
+		err = c.db.DeleteVolumePricingCoupon(tx, couponId)
+	case "fixed-price":
+		err = c.db.DeleteFixedPriceProducts(tx, couponId)
 		if err != nil {
-			ctx.Log.Error("error deleting bxgy buy coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("error deleting fixed price products", zap.Error(err))
 			return err
 		}
-		err = c.db.DeleteBxGyGetProducts(ctx, couponId)
+		err = c.db.DeleteFixedPriceCoupon(tx, couponId)
+	case "shipping":
+		err = c.db.DeleteShippingCouponMethods(tx, couponId)
 		if err != nil {
-			ctx.Log.Error("error deleting bxgy buy coupon", zap.Error(err))
-			tx.Rollback()
+			tx.Log.Error("error deleting shipping coupon methods", zap.Error(err))
 			return err
 		}
+		err = c.db.DeleteShippingCoupon(tx, couponId)
 	}
 	if err != nil {
+		tx.Log.Error("error deleting coupon details", zap.Error(err))
 		return err
 	}
 
-	// Delete the main coupon entry
-	err = c.db.DeleteCoupon(ctx, couponId)
+	// Delete the shopper conditions of the coupon
+	err = c.db.DeleteCouponEligibilitySegments(tx, couponId)
+	if err != nil {
+		tx.Log.Error("error deleting coupon eligibility segments", zap.Error(err))
+		return err
+	}
+	err = c.db.DeleteCouponEligibility(tx, couponId)
 	if err != nil {
-		ctx.Log.Error("error deleting main coupon", zap.Error(err))
-		tx.Rollback()
+		tx.Log.Error("error deleting coupon eligibility", zap.Error(err))
 		return err
 	}
 
-	// Commit the transaction
-	if err = tx.Commit().Error; err != nil {
-		ctx.Log.Error("failed to commit transaction", zap.Error(err))
+	// Delete the display text and the tags of the coupon
+	err = c.db.DeleteCouponTranslations(tx, couponId)
+	if err != nil {
+		tx.Log.Error("error deleting coupon translations", zap.Error(err))
+		return err
+	}
+	err = c.db.DeleteCouponTags(tx, couponId)
+	if err != nil {
+		tx.Log.Error("error deleting coupon tags", zap.Error(err))
 		return err
 	}
 
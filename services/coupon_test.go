@@ -0,0 +1,82 @@
+package services
+
+import (
+	stdcontext "context"
+	stderrors "errors"
+	"math"
+	"testing"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+	"monk-commerce-assignment/utils/log"
+)
+
+const (
+	testEditor   = "editor@example.com"
+	testReviewer = "reviewer@example.com"
+)
+
+// newTestService returns a coupon service on a fresh in-memory store. The
+// coupon index is shared by every service, so it is dropped before and after.
+func newTestService(t *testing.T) (*CouponService, daos.Repositories) {
+	t.Helper()
+	repositories := daos.NewMemoryRepositories()
+	ResetCouponIndex()
+	t.Cleanup(ResetCouponIndex)
+	return NewCouponService(repositories.Coupons, repositories.Customers).(*CouponService), repositories
+}
+
+// testContext returns a request context made by the given actor
+func testContext(actor string, roles ...string) *context.Context {
+	ctx := context.New(stdcontext.Background(), "test", log.New("test", "test", log.LevelFatal), nil)
+	ctx.Actor = actor
+	ctx.Roles = roles
+	return ctx
+}
+
+// createLiveCoupon creates a coupon and has it submitted and approved, so it
+// applies right away
+func createLiveCoupon(t *testing.T, service ICouponService, req dtos.Coupon) string {
+	t.Helper()
+	created, err := service.CreateCoupon(testContext(testEditor), &req)
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+	_, err = service.SubmitCoupon(testContext(testEditor), created.Id)
+	if err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	_, err = service.ApproveCoupon(testContext(testReviewer, roleApprover), created.Id)
+	if err != nil {
+		t.Fatalf("ApproveCoupon() error = %v", err)
+	}
+	return created.Id
+}
+
+// applyCoupon prices the cart with a coupon for an anonymous shopper
+func applyCoupon(t *testing.T, service ICouponService, couponId string, cart dtos.Cart) *dtos.UpdatedCart {
+	t.Helper()
+	updatedCart, err := service.ApplyCoupon(testContext(""), couponId, cart, nil)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	return updatedCart
+}
+
+// errorCode returns the code of a domain error, or "" for any other error
+func errorCode(err error) errors.Code {
+	var domainErr *errors.Error
+	if stderrors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
+
+func assertMoney(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.005 {
+		t.Errorf("%s = %.2f, want %.2f", name, got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/schema"
+)
+
+// couponType describes a supported coupon type to clients and to validation:
+// the JSON Schema of its details, the rules the schema cannot express and
+// complete examples of coupons of the type
+type couponType struct {
+	name        string
+	description string
+	details     *schema.Schema
+	rules       rule[dtos.CouponDetails]
+	examples    []string
+}
+
+// percentSchema bounds a discount percentage to (0, 100]
+func percentSchema(number *schema.Schema) *schema.Schema {
+	return number.ExclusiveMin(0).Max(100)
+}
+
+func productIdSchema() *schema.Schema {
+	return schema.String().MinLen(1).Describe("ID of the product")
+}
+
+func productQuantitySchema(description string) *schema.Schema {
+	return schema.Array(schema.Object(map[string]*schema.Schema{
+		"product_id": productIdSchema(),
+		"quantity":   schema.Integer().ExclusiveMin(0).Describe("Units of the product"),
+	}).Require("product_id", "quantity")).NonEmpty().Describe(description)
+}
+
+// uniqueProducts reports product lists that name a product twice
+func uniqueProducts(name string, get func(dtos.CouponDetails) []dtos.ProductQuantityDetails) rule[dtos.CouponDetails] {
+	return field(name, get, unique("product_id", func(p dtos.ProductQuantityDetails) string { return p.ProductId }))
+}
+
+// couponTypeRegistry lists the supported coupon types. Adding a type here
+// publishes it on GET /coupon-types and validates coupons of the type.
+var couponTypeRegistry = []*couponType{
+	{
+		name:        "cart-wise",
+		description: "Percentage off the whole cart once its total reaches a threshold.",
+		details: schema.Object(map[string]*schema.Schema{
+			"threshold": schema.Integer().Min(0).WithDefault(0).Describe("Minimum cart total"),
+			"discount":  percentSchema(schema.Integer()).Describe("Percentage off the cart total"),
+		}).Require("discount"),
+		examples: []string{
+			`{"type": "cart-wise", "details": {"threshold": 100, "discount": 10}}`,
+		},
+	},
+	{
+		name:        "product-wise",
+		description: "Percentage off every unit of a product.",
+		details: schema.Object(map[string]*schema.Schema{
+			"product_id": productIdSchema(),
+			"discount":   percentSchema(schema.Integer()).Describe("Percentage off the product"),
+		}).Require("product_id", "discount"),
+		examples: []string{
+			`{"type": "product-wise", "details": {"product_id": "1", "discount": 20}}`,
+		},
+	},
+	{
+		name:        "bxgy",
+		description: "Buy X, get Y: each time the cart holds the buy products, the get products are free, up to the repetition limit.",
+		details: schema.Object(map[string]*schema.Schema{
+			"buy_products":     productQuantitySchema("Products to buy"),
+			"get_products":     productQuantitySchema("Products given for free"),
+			"repitition_limit": schema.Integer().ExclusiveMin(0).Describe("How many times the offer applies per cart"),
+		}).Require("buy_products", "get_products", "repitition_limit"),
+		rules: all(
+			uniqueProducts("buy_products", func(d dtos.CouponDetails) []dtos.ProductQuantityDetails { return d.BuyProducts }),
+			uniqueProducts("get_products", func(d dtos.CouponDetails) []dtos.ProductQuantityDetails { return d.GetProducts }),
+		),
+		examples: []string{
+			`{"type": "bxgy", "details": {"buy_products": [{"product_id": "1", "quantity": 3}, {"product_id": "2", "quantity": 3}], "get_products": [{"product_id": "3", "quantity": 1}], "repitition_limit": 2}}`,
+		},
+	},
+	{
+		name:        "volume",
+		description: "Percentage off each unit by quantity band, for a set of products.",
+		details: schema.Object(map[string]*schema.Schema{
+			"product_ids": schema.Array(productIdSchema()).NonEmpty().Unique().Describe("Products the bands apply to"),
+			"scope": schema.String().OneOf(volumeScopeLine, volumeScopeSet).WithDefault(volumeScopeLine).
+				Describe("line: each cart line reaches a band on its own quantity; set: the quantities of all lines of the products are added up"),
+			"bands": schema.Array(schema.Object(map[string]*schema.Schema{
+				"min_quantity": schema.Integer().ExclusiveMin(0).Describe("Units needed to reach the band"),
+				"discount":     percentSchema(schema.Number()).Describe("Percentage off each unit"),
+			}).Require("min_quantity", "discount")).NonEmpty().Describe("Quantity bands"),
+		}).Require("product_ids", "bands"),
+		rules: field("bands", func(d dtos.CouponDetails) []dtos.QuantityBand { return d.Bands },
+			unique("min_quantity", func(b dtos.QuantityBand) string { return fmt.Sprint(b.MinQuantity) })),
+		examples: []string{
+			`{"type": "volume", "details": {"product_ids": ["1", "2"], "scope": "set", "bands": [{"min_quantity": 10, "discount": 5}, {"min_quantity": 50, "discount": 12}]}}`,
+		},
+	},
+	{
+		name:        "fixed-price",
+		description: "Sells products at an override unit price, optionally for a limited number of units per order.",
+		details: schema.Object(map[string]*schema.Schema{
+			"fixed_prices": schema.Array(schema.Object(map[string]*schema.Schema{
+				"product_id": productIdSchema(),
+				"price":      schema.Number().Min(0).Describe("Override unit price"),
+			}).Require("product_id", "price")).NonEmpty().Describe("Products and their override prices"),
+			"max_units": schema.Integer().Min(0).WithDefault(0).Describe("Units per order sold at the override price, 0 for no limit"),
+		}).Require("fixed_prices"),
+		rules: field("fixed_prices", func(d dtos.CouponDetails) []dtos.ProductPriceDetails { return d.FixedPrices },
+			unique("product_id", func(p dtos.ProductPriceDetails) string { return p.ProductId })),
+		examples: []string{
+			`{"type": "fixed-price", "details": {"fixed_prices": [{"product_id": "7", "price": 1999}], "max_units": 1}}`,
+		},
+	},
+	{
+		name:        "shipping",
+		description: "Free or discounted shipping once the cart reaches a threshold, optionally for some shipping methods only.",
+		details: schema.Object(map[string]*schema.Schema{
+			"threshold":        schema.Integer().Min(0).WithDefault(0).Describe("Minimum cart total"),
+			"discount":         percentSchema(schema.Integer()).Describe("Percentage off the shipping fee, 100 for free shipping"),
+			"max_discount":     schema.Number().Min(0).WithDefault(0).Describe("Cap on the shipping discount, 0 for no cap"),
+			"shipping_methods": schema.Array(schema.String().MinLen(1)).Unique().Describe("Shipping methods the coupon is restricted to, empty for all"),
+		}).Require("discount"),
+		examples: []string{
+			`{"type": "shipping", "details": {"threshold": 500, "discount": 100}}`,
+			`{"type": "shipping", "details": {"discount": 50, "max_discount": 40, "shipping_methods": ["express"]}}`,
+		},
+	},
+}
+
+// lookupCouponType returns the registered coupon type with the name, or nil
+func lookupCouponType(name string) *couponType {
+	for _, t := range couponTypeRegistry {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// couponTypeNames returns the names of the registered coupon types
+func couponTypeNames() []string {
+	names := make([]string, len(couponTypeRegistry))
+	for i, t := range couponTypeRegistry {
+		names[i] = t.name
+	}
+	return names
+}
+
+func (c *CouponService) GetCouponTypes(ctx *context.Context) []*dtos.CouponType {
+	types := make([]*dtos.CouponType, 0, len(couponTypeRegistry))
+	for _, t := range couponTypeRegistry {
+		examples := make([]json.RawMessage, len(t.examples))
+		for i, example := range t.examples {
+			examples[i] = json.RawMessage(example)
+		}
+		types = append(types, &dtos.CouponType{
+			Type:        t.name,
+			Description: t.description,
+			Schema:      t.details,
+			Examples:    examples,
+		})
+	}
+	return types
+}
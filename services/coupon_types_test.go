@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestCouponTypeExamplesAreValid(t *testing.T) {
+	service, _ := newTestService(t)
+	for _, couponType := range service.GetCouponTypes(testContext("")) {
+		if len(couponType.Examples) == 0 {
+			t.Errorf("%s has no examples", couponType.Type)
+		}
+		for _, example := range couponType.Examples {
+			var req dtos.Coupon
+			if err := json.Unmarshal(example, &req); err != nil {
+				t.Fatalf("json.Unmarshal(%s) error = %v", example, err)
+			}
+			if req.Type != couponType.Type {
+				t.Errorf("example %s is not a %s coupon", example, couponType.Type)
+			}
+			if err := validateCoupon(&req); err != nil {
+				t.Errorf("example %s: %v", example, err)
+			}
+		}
+	}
+}
+
+func TestCouponTypeSchema(t *testing.T) {
+	service, _ := newTestService(t)
+	types := service.GetCouponTypes(testContext(""))
+
+	var names []string
+	for _, couponType := range types {
+		names = append(names, couponType.Type)
+	}
+	if len(names) != len(couponTypeRegistry) {
+		t.Fatalf("coupon types = %v, want every registered type", names)
+	}
+
+	// The schema clients get for cart-wise coupons
+	got, err := json.Marshal(types[0])
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"type":"cart-wise","description":"Percentage off the whole cart once its total reaches a threshold.",` +
+		`"schema":{"type":"object","properties":{` +
+		`"discount":{"type":"integer","description":"Percentage off the cart total","exclusiveMinimum":0,"maximum":100},` +
+		`"threshold":{"type":"integer","description":"Minimum cart total","minimum":0,"default":0}},` +
+		`"required":["discount"],"additionalProperties":false},` +
+		`"examples":[{"type":"cart-wise","details":{"threshold":100,"discount":10}}]}`
+	if string(got) != want {
+		t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
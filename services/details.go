@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+// toCouponDto transforms a coupon and its loaded details into the DTO format
+func toCouponDto(rules *models.CouponRules) (*dtos.Coupon, error) {
+	coupon := rules.Coupon
+	couponDto := &dtos.Coupon{
+		Id:          coupon.Id,
+		Type:        coupon.Type,
+		Code:        coupon.Code,
+		Condition:   coupon.Condition,
+		Eligibility: eligibilityDetails(rules),
+		StartsAt:    coupon.StartsAt,
+		EndsAt:      coupon.EndsAt,
+		CreatedAt:   coupon.CreatedAt,
+		DeletedAt:   coupon.DeletedAt,
+	}
+	if coupon.CampaignID != nil {
+		couponDto.CampaignId = *coupon.CampaignID
+	}
+	setWorkflow(couponDto, coupon)
+	couponDto.Translations, couponDto.Metadata, couponDto.Tags = displayDetails(rules)
+
+	// Populate coupon-specific details based on type
+	switch coupon.Type {
+	case "cart-wise":
+		if rules.CartWise == nil {
+			return nil, errMissingDetails
+		}
+		couponDto.Details = dtos.CouponDetails{
+			Threshold: int(rules.CartWise.Threshold),
+			Discount:  int(rules.CartWise.Discount),
+		}
+
+	case "product-wise":
+		if rules.ProductWise == nil {
+			return nil, errMissingDetails
+		}
+		couponDto.Details = dtos.CouponDetails{
+			ProductId: rules.ProductWise.ProductID,
+			Discount:  int(rules.ProductWise.Discount),
+		}
+
+	case "bxgy":
+		if rules.BxGy == nil {
+			return nil, errMissingDetails
+		}
+
+		// Convert buy and get products into the DTO format
+		var buyProductsDto, getProductsDto []dtos.ProductQuantityDetails
+		for _, buyProduct := range rules.BuyProducts {
+			buyProductsDto = append(buyProductsDto, dtos.ProductQuantityDetails{
+				ProductId: buyProduct.ProductID,
+				Quantity:  buyProduct.Quantity,
+			})
+		}
+		for _, getProduct := range rules.GetProducts {
+			getProductsDto = append(getProductsDto, dtos.ProductQuantityDetails{
+				ProductId: getProduct.ProductID,
+				Quantity:  getProduct.Quantity,
+			})
+		}
+
+		couponDto.Details = dtos.CouponDetails{
+			RepitionLimit: rules.BxGy.RepetitionLimit,
+			BuyProducts:   buyProductsDto,
+			GetProducts:   getProductsDto,
+		}
+
+	case "volume":
+		if rules.Volume == nil {
+			return nil, errMissingDetails
+		}
+		couponDto.Details = volumeCouponDetails(rules)
+
+	case "fixed-price":
+		if rules.FixedPrice == nil {
+			return nil, errMissingDetails
+		}
+		couponDto.Details = fixedPriceCouponDetails(rules)
+
+	case "shipping":
+		if rules.Shipping == nil {
+			return nil, errMissingDetails
+		}
+		couponDto.Details = shippingCouponDetails(rules)
+
+	default:
+		return nil, errors.New("unsupported coupon type")
+	}
+
+	return couponDto, nil
+}
+
+var errMissingDetails = errors.New("coupon details not found")
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/locale"
+)
+
+// Locale of the coupon text served when none of the locales a client accepts
+// has a translation
+var defaultLocale = "en"
+
+// SetDefaultLocale sets the locale coupon text falls back to. An empty locale
+// keeps the default, "en".
+func SetDefaultLocale(tag string) {
+	if tag != "" {
+		defaultLocale = locale.Canonical(tag)
+	}
+}
+
+const (
+	maxNameLength  = 100
+	maxBadgeLength = 40
+	maxTagLength   = 50
+)
+
+// displayTranslations returns the display text of a coupon request by locale.
+// Text set on the coupon itself belongs to the default locale.
+func displayTranslations(req *dtos.Coupon) map[string]dtos.CouponText {
+	translations := make(map[string]dtos.CouponText, len(req.Translations)+1)
+	for tag, text := range req.Translations {
+		translations[locale.Canonical(tag)] = text
+	}
+	text := dtos.CouponText{
+		Name:        req.Name,
+		Badge:       req.Badge,
+		Description: req.Description,
+		Terms:       req.Terms,
+	}
+	if text != (dtos.CouponText{}) {
+		translations[defaultLocale] = text
+	}
+	return translations
+}
+
+// couponTranslations turns display text by locale into the rows of a coupon,
+// ordered by locale
+func couponTranslations(couponId string, translations map[string]dtos.CouponText) []*models.CouponTranslation {
+	rows := make([]*models.CouponTranslation, 0, len(translations))
+	for tag, text := range translations {
+		rows = append(rows, &models.CouponTranslation{
+			CouponID:    couponId,
+			Locale:      tag,
+			Name:        text.Name,
+			Badge:       text.Badge,
+			Description: text.Description,
+			Terms:       text.Terms,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Locale < rows[j].Locale
+	})
+	return rows
+}
+
+// validateDisplay checks the display text, metadata and tags of a coupon
+func validateDisplay(v *validator, req *dtos.Coupon) {
+	// Report problems in a stable order
+	tags := make([]string, 0, len(req.Translations))
+	for tag := range req.Translations {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	text := req.Name != "" || req.Badge != "" || req.Description != "" || req.Terms != ""
+	for _, tag := range tags {
+		if locale.Canonical(tag) == defaultLocale && text {
+			v.report("translations."+tag, "is also set on the coupon, set the text of %s in one place", defaultLocale)
+		}
+	}
+	if text {
+		displayTextRules(v, "", dtos.CouponText{
+			Name:        req.Name,
+			Badge:       req.Badge,
+			Description: req.Description,
+			Terms:       req.Terms,
+		})
+	}
+	for _, tag := range tags {
+		path := joinPath("translations", tag)
+		if !locale.Valid(tag) {
+			v.report(path, "is not a valid locale, e.g. en or pt-BR")
+			continue
+		}
+		displayTextRules(v, path, req.Translations[tag])
+	}
+
+	if len(req.Metadata) > 0 {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(req.Metadata, &metadata); err != nil || metadata == nil {
+			v.report("metadata", "must be a JSON object")
+		}
+	}
+
+	tagRules(v, "tags", req.Tags)
+}
+
+var displayTextRules = all(
+	field("name", func(t dtos.CouponText) string { return t.Name }, required(), maxLength(maxNameLength)),
+	field("badge", func(t dtos.CouponText) string { return t.Badge }, maxLength(maxBadgeLength)),
+)
+
+var tagRules = all(
+	each(required(), maxLength(maxTagLength)),
+	unique("", func(tag string) string { return strings.ToLower(strings.TrimSpace(tag)) }),
+)
+
+func maxLength(max int) rule[string] {
+	return func(v *validator, path string, value string) {
+		if len([]rune(value)) > max {
+			v.report(path, "must be at most %d characters long", max)
+		}
+	}
+}
+
+// displayDetails returns the display text by locale, the metadata and the
+// tags of a coupon
+func displayDetails(rules *models.CouponRules) (map[string]dtos.CouponText, json.RawMessage, []string) {
+	var translations map[string]dtos.CouponText
+	if len(rules.Translations) > 0 {
+		translations = make(map[string]dtos.CouponText, len(rules.Translations))
+		for _, translation := range rules.Translations {
+			translations[translation.Locale] = dtos.CouponText{
+				Name:        translation.Name,
+				Badge:       translation.Badge,
+				Description: translation.Description,
+				Terms:       translation.Terms,
+			}
+		}
+	}
+
+	var metadata json.RawMessage
+	if len(rules.Coupon.Metadata) > 0 && string(rules.Coupon.Metadata) != "{}" {
+		metadata = json.RawMessage(rules.Coupon.Metadata)
+	}
+
+	var tags []string
+	for _, tag := range rules.Tags {
+		tags = append(tags, tag.Tag)
+	}
+
+	return translations, metadata, tags
+}
+
+// couponText picks the translation of a coupon that best fits the locales,
+// with the locale it is in. Coupons without display text have none.
+func couponText(translations []*models.CouponTranslation, locales []string) (*models.CouponTranslation, string) {
+	available := make([]string, len(translations))
+	for i, translation := range translations {
+		available[i] = translation.Locale
+	}
+	tag := locale.Match(locales, available, defaultLocale)
+	for _, translation := range translations {
+		if translation.Locale == tag {
+			return translation, tag
+		}
+	}
+	return nil, ""
+}
+
+// localizeCoupon sets the display text of a coupon to the translation that
+// best fits the locales
+func localizeCoupon(coupon *dtos.Coupon, translations []*models.CouponTranslation, locales []string) {
+	coupon.Name, coupon.Badge, coupon.Description, coupon.Terms, coupon.Locale = "", "", "", "", ""
+	translation, tag := couponText(translations, locales)
+	if translation == nil {
+		return
+	}
+	coupon.Name = translation.Name
+	coupon.Badge = translation.Badge
+	coupon.Description = translation.Description
+	coupon.Terms = translation.Terms
+	coupon.Locale = tag
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// localizedCoupon reads a coupon in the given locales
+func localizedCoupon(t *testing.T, service *CouponService, couponId string, locales ...string) *dtos.Coupon {
+	t.Helper()
+	ctx := testContext(testEditor)
+	ctx.Locales = locales
+	coupon, err := service.GetCouponById(ctx, couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	return coupon
+}
+
+func TestCouponText(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{
+		Type:    "cart-wise",
+		Name:    "Ten percent",
+		Badge:   "10% off",
+		Details: dtos.CouponDetails{Discount: 10},
+		Translations: map[string]dtos.CouponText{
+			"fr":    {Name: "Dix pour cent", Badge: "-10 %"},
+			"de-DE": {Name: "Zehn Prozent"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		locales []string
+		text    string
+		locale  string
+	}{
+		{"default locale", []string{"en"}, "Ten percent", "en"},
+		{"translation", []string{"fr"}, "Dix pour cent", "fr"},
+		{"without region", []string{"fr-CA"}, "Dix pour cent", "fr"},
+		{"other region", []string{"de"}, "Zehn Prozent", "de-DE"},
+		{"second preference", []string{"ja", "fr"}, "Dix pour cent", "fr"},
+		{"falls back to the default locale", []string{"ja"}, "Ten percent", "en"},
+		{"no preference", nil, "Ten percent", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coupon := localizedCoupon(t, service, couponId, tt.locales...)
+			if coupon.Name != tt.text || coupon.Locale != tt.locale {
+				t.Errorf("text = %q in %q, want %q in %q", coupon.Name, coupon.Locale, tt.text, tt.locale)
+			}
+			if len(coupon.Translations) != 3 {
+				t.Errorf("translations = %+v, want all three", coupon.Translations)
+			}
+		})
+	}
+
+	// Applicable coupons are described in the same locale
+	ctx := testContext("")
+	ctx.Locales = []string{"fr-BE"}
+	response, err := service.GetApplicableCoupons(ctx, cartOf(100), nil)
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if len(response.ApplicableCoupons) != 1 {
+		t.Fatalf("applicable coupons = %+v, want one", response.ApplicableCoupons)
+	}
+	if coupon := response.ApplicableCoupons[0]; coupon.Name != "Dix pour cent" || coupon.Badge != "-10 %" || coupon.Locale != "fr" {
+		t.Errorf("applicable coupon = %+v, want its French text", coupon)
+	}
+}
+
+func TestSetDefaultLocale(t *testing.T) {
+	previous := defaultLocale
+	t.Cleanup(func() { defaultLocale = previous })
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{
+		Type:    "cart-wise",
+		Details: dtos.CouponDetails{Discount: 10},
+		Translations: map[string]dtos.CouponText{
+			"en": {Name: "Ten percent"},
+			"fr": {Name: "Dix pour cent"},
+		},
+	})
+
+	SetDefaultLocale("FR")
+	if defaultLocale != "fr" {
+		t.Errorf("default locale = %q, want fr", defaultLocale)
+	}
+	if coupon := localizedCoupon(t, service, couponId, "ja"); coupon.Name != "Dix pour cent" || coupon.Locale != "fr" {
+		t.Errorf("text = %q in %q, want the text of the default locale fr", coupon.Name, coupon.Locale)
+	}
+
+	// An empty locale keeps the one set
+	SetDefaultLocale("")
+	if defaultLocale != "fr" {
+		t.Errorf("default locale = %q, want fr", defaultLocale)
+	}
+}
+
+func TestCouponWithoutText(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	coupon := localizedCoupon(t, service, couponId, "fr")
+	if coupon.Name != "" || coupon.Locale != "" || len(coupon.Translations) != 0 {
+		t.Errorf("coupon = %+v, want no text", coupon)
+	}
+}
+
+func TestDisplayValidation(t *testing.T) {
+	service, _ := newTestService(t)
+
+	tests := []struct {
+		name   string
+		coupon dtos.Coupon
+		field  string
+	}{
+		{"default locale set twice", dtos.Coupon{Name: "Ten percent", Translations: map[string]dtos.CouponText{"EN": {Name: "Ten"}}}, "translations.EN"},
+		{"malformed locale", dtos.Coupon{Translations: map[string]dtos.CouponText{"french": {Name: "Dix"}}}, "translations.french"},
+		{"translation without a name", dtos.Coupon{Translations: map[string]dtos.CouponText{"fr": {Badge: "-10 %"}}}, "translations.fr.name"},
+		{"badge too long", dtos.Coupon{Name: "Ten percent", Badge: strings.Repeat("x", maxBadgeLength+1)}, "badge"},
+		{"metadata not an object", dtos.Coupon{Metadata: []byte(`[1, 2]`)}, "metadata"},
+		{"duplicate tags", dtos.Coupon{Tags: []string{"Summer", "summer "}}, "tags[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Type = "cart-wise"
+			tt.coupon.Details = dtos.CouponDetails{Discount: 10}
+			_, err := service.CreateCoupon(testContext(testEditor), &tt.coupon)
+			if errorCode(err) != errors.CodeValidation {
+				t.Fatalf("CreateCoupon() error = %v, want a validation error", err)
+			}
+			problems, _ := errors.From(err).Details.([]dtos.FieldError)
+			if len(problems) != 1 || problems[0].Field != tt.field {
+				t.Errorf("problems = %+v, want one on %s", problems, tt.field)
+			}
+		})
+	}
+}
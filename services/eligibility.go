@@ -0,0 +1,142 @@
+package services
+
+import (
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+)
+
+// persistEligibility stores the eligibility conditions of a new coupon
+func (c *CouponService) persistEligibility(ctx *context.Context, couponId string, eligibility *dtos.Eligibility) error {
+	eligibilityModel := models.CouponEligibility{
+		CouponID:           couponId,
+		FirstOrderOnly:     eligibility.FirstOrderOnly,
+		MinOrderCount:      eligibility.MinOrderCount,
+		MinLifetimeSpend:   eligibility.MinLifetimeSpend,
+		LapsedDays:         eligibility.LapsedDays,
+		MaxDaysSinceSignup: eligibility.MaxDaysSinceSignup,
+	}
+	err := c.db.PersistCouponEligibility(ctx, &eligibilityModel)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range eligibility.Segments {
+		segmentModel := models.CouponEligibilitySegment{
+			CouponEligibilityID: couponId,
+			Segment:             segment,
+		}
+		err = c.db.PersistCouponEligibilitySegment(ctx, &segmentModel)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eligibilityDetails transforms the eligibility conditions of a coupon into the DTO format.
+// It returns nil when the coupon is open to every shopper.
+func eligibilityDetails(rules *models.CouponRules) *dtos.Eligibility {
+	eligibility := rules.Eligibility
+	if eligibility == nil {
+		return nil
+	}
+
+	eligibilityDto := &dtos.Eligibility{
+		FirstOrderOnly:     eligibility.FirstOrderOnly,
+		MinOrderCount:      eligibility.MinOrderCount,
+		MinLifetimeSpend:   eligibility.MinLifetimeSpend,
+		LapsedDays:         eligibility.LapsedDays,
+		MaxDaysSinceSignup: eligibility.MaxDaysSinceSignup,
+	}
+	for _, segment := range rules.EligibilitySegments {
+		eligibilityDto.Segments = append(eligibilityDto.Segments, segment.Segment)
+	}
+
+	return eligibilityDto
+}
+
+// resolveCustomer combines the customer context sent by the client with the
+// order history kept by the service, so eligibility does not depend entirely
+// on what the client reports. The larger order count and spend and the most
+// recent order date win.
+func (c *CouponService) resolveCustomer(ctx *context.Context, customer *dtos.Customer) (*dtos.Customer, error) {
+	if customer == nil || customer.Id == "" {
+		return customer, nil
+	}
+
+	history, err := c.customers.GetCustomerHistory(ctx, customer.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *customer
+	if history.OrderCount > resolved.OrderCount {
+		resolved.OrderCount = history.OrderCount
+	}
+	if history.LifetimeSpend > resolved.LifetimeSpend {
+		resolved.LifetimeSpend = history.LifetimeSpend
+	}
+	if history.LastOrderAt != nil && (resolved.LastOrderDate == nil || history.LastOrderAt.After(*resolved.LastOrderDate)) {
+		resolved.LastOrderDate = history.LastOrderAt
+	}
+
+	return &resolved, nil
+}
+
+// isEligible checks the shopper against the eligibility conditions of a coupon.
+// Coupons without conditions apply to everyone, coupons with conditions need a known shopper.
+func isEligible(eligibility *dtos.Eligibility, customer *dtos.Customer, now time.Time) bool {
+	if eligibility == nil {
+		return true
+	}
+	if customer == nil {
+		return false
+	}
+
+	if eligibility.FirstOrderOnly && customer.OrderCount > 0 {
+		return false
+	}
+	if customer.OrderCount < eligibility.MinOrderCount {
+		return false
+	}
+	if customer.LifetimeSpend < eligibility.MinLifetimeSpend {
+		return false
+	}
+
+	if len(eligibility.Segments) > 0 {
+		inSegment := false
+		for _, segment := range eligibility.Segments {
+			for _, customerSegment := range customer.Segments {
+				if segment == customerSegment {
+					inSegment = true
+				}
+			}
+		}
+		if !inSegment {
+			return false
+		}
+	}
+
+	// Lapsed customers have ordered before, but not within the last LapsedDays days
+	if eligibility.LapsedDays > 0 {
+		if customer.LastOrderDate == nil || now.Sub(*customer.LastOrderDate) < days(eligibility.LapsedDays) {
+			return false
+		}
+	}
+
+	if eligibility.MaxDaysSinceSignup > 0 {
+		if customer.SignupDate == nil || now.Sub(*customer.SignupDate) > days(eligibility.MaxDaysSinceSignup) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
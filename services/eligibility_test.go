@@ -0,0 +1,178 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+func TestIsEligible(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) *time.Time {
+		at := now.Add(-days(n))
+		return &at
+	}
+
+	tests := []struct {
+		name        string
+		eligibility *dtos.Eligibility
+		customer    *dtos.Customer
+		want        bool
+	}{
+		{"no conditions, anonymous shopper", nil, nil, true},
+		{"conditions need a known shopper", &dtos.Eligibility{FirstOrderOnly: true}, nil, false},
+		{"first order", &dtos.Eligibility{FirstOrderOnly: true}, &dtos.Customer{Id: "c"}, true},
+		{"not the first order", &dtos.Eligibility{FirstOrderOnly: true}, &dtos.Customer{Id: "c", OrderCount: 1}, false},
+		{"enough orders", &dtos.Eligibility{MinOrderCount: 3}, &dtos.Customer{Id: "c", OrderCount: 3}, true},
+		{"too few orders", &dtos.Eligibility{MinOrderCount: 3}, &dtos.Customer{Id: "c", OrderCount: 2}, false},
+		{"enough spend", &dtos.Eligibility{MinLifetimeSpend: 100}, &dtos.Customer{Id: "c", LifetimeSpend: 100}, true},
+		{"too little spend", &dtos.Eligibility{MinLifetimeSpend: 100}, &dtos.Customer{Id: "c", LifetimeSpend: 99.99}, false},
+		{"in a segment", &dtos.Eligibility{Segments: []string{"vip", "staff"}}, &dtos.Customer{Id: "c", Segments: []string{"staff"}}, true},
+		{"in no segment", &dtos.Eligibility{Segments: []string{"vip"}}, &dtos.Customer{Id: "c", Segments: []string{"new"}}, false},
+		{"lapsed", &dtos.Eligibility{LapsedDays: 30}, &dtos.Customer{Id: "c", LastOrderDate: daysAgo(45)}, true},
+		{"ordered recently", &dtos.Eligibility{LapsedDays: 30}, &dtos.Customer{Id: "c", LastOrderDate: daysAgo(10)}, false},
+		{"never ordered is not lapsed", &dtos.Eligibility{LapsedDays: 30}, &dtos.Customer{Id: "c"}, false},
+		{"signed up recently", &dtos.Eligibility{MaxDaysSinceSignup: 7}, &dtos.Customer{Id: "c", SignupDate: daysAgo(3)}, true},
+		{"signed up long ago", &dtos.Eligibility{MaxDaysSinceSignup: 7}, &dtos.Customer{Id: "c", SignupDate: daysAgo(8)}, false},
+		{"unknown signup date", &dtos.Eligibility{MaxDaysSinceSignup: 7}, &dtos.Customer{Id: "c"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEligible(tt.eligibility, tt.customer, now); got != tt.want {
+				t.Errorf("isEligible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// redeem redeems a coupon on an order of the given customer
+func redeem(service IRedemptionService, couponId string, orderId string, customerId string, cart dtos.Cart) (*dtos.Redemption, error) {
+	return service.RedeemCoupon(testContext(""), couponId, &dtos.RedemptionRequest{
+		OrderId:  orderId,
+		Cart:     cart,
+		Customer: &dtos.Customer{Id: customerId},
+	})
+}
+
+func TestFirstOrderCouponUsesOrderHistory(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{
+		Type:        "cart-wise",
+		Details:     dtos.CouponDetails{Threshold: 0, Discount: 10},
+		Eligibility: &dtos.Eligibility{FirstOrderOnly: true},
+	})
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 100}}}
+
+	// The client cannot claim a first order once the service saw one
+	_, err := redeem(redemptions, couponId, "order-1", "customer-1", cart)
+	if err != nil {
+		t.Fatalf("RedeemCoupon() error = %v", err)
+	}
+	_, err = service.ApplyCoupon(testContext(""), couponId, cart, &dtos.Customer{Id: "customer-1"})
+	if errorCode(err) != errors.CodeNotApplicable {
+		t.Errorf("ApplyCoupon() after the first order error = %v, want %s", err, errors.CodeNotApplicable)
+	}
+
+	// Orders placed without a coupon count too
+	err = redemptions.RecordOrder(testContext(""), &dtos.OrderRequest{OrderId: "order-2", CustomerId: "customer-2", Total: 40})
+	if err != nil {
+		t.Fatalf("RecordOrder() error = %v", err)
+	}
+	_, err = service.ApplyCoupon(testContext(""), couponId, cart, &dtos.Customer{Id: "customer-2"})
+	if errorCode(err) != errors.CodeNotApplicable {
+		t.Errorf("ApplyCoupon() after a recorded order error = %v, want %s", err, errors.CodeNotApplicable)
+	}
+
+	_, err = service.ApplyCoupon(testContext(""), couponId, cart, &dtos.Customer{Id: "customer-3"})
+	if err != nil {
+		t.Errorf("ApplyCoupon() for a new customer error = %v", err)
+	}
+}
+
+func TestOrderIsRecordedOnce(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	cartWise := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	productWise := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 20}})
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 100}}}
+
+	// Two coupons redeemed on one order
+	_, err := redeem(redemptions, cartWise, "order-1", "customer-1", cart)
+	if err != nil {
+		t.Fatalf("RedeemCoupon() error = %v", err)
+	}
+	_, err = redeem(redemptions, productWise, "order-1", "customer-1", cart)
+	if err != nil {
+		t.Fatalf("RedeemCoupon() of a second coupon on the order error = %v", err)
+	}
+	_, err = redeem(redemptions, productWise, "order-1", "customer-1", cart)
+	if errorCode(errors.From(err)) != errors.CodeConflict {
+		t.Errorf("RedeemCoupon() of a coupon twice on the order error = %v, want %s", err, errors.CodeConflict)
+	}
+
+	// Reporting the order again keeps the total it was recorded with
+	err = redemptions.RecordOrder(testContext(""), &dtos.OrderRequest{OrderId: "order-1", CustomerId: "customer-1", Total: 75})
+	if err != nil {
+		t.Fatalf("RecordOrder() of a redeemed order error = %v", err)
+	}
+
+	history, err := repositories.Customers.GetCustomerHistory(testContext(""), "customer-1")
+	if err != nil {
+		t.Fatalf("GetCustomerHistory() error = %v", err)
+	}
+	if history.OrderCount != 1 {
+		t.Errorf("order count = %d, want 1", history.OrderCount)
+	}
+	assertMoney(t, "lifetime spend", history.LifetimeSpend, 90)
+}
+
+func TestOrderIdOfAnotherCustomer(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	cartWise := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	productWise := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 20}})
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 100}}}
+
+	_, err := redeem(redemptions, cartWise, "order-1", "customer-1", cart)
+	if err != nil {
+		t.Fatalf("RedeemCoupon() error = %v", err)
+	}
+
+	// Another customer cannot add to the order, with a coupon or without one
+	_, err = redeem(redemptions, productWise, "order-1", "customer-2", cart)
+	if errorCode(err) != errors.CodeConflict {
+		t.Errorf("RedeemCoupon() for another customer error = %v, want %s", err, errors.CodeConflict)
+	}
+	err = redemptions.RecordOrder(testContext(""), &dtos.OrderRequest{OrderId: "order-1", CustomerId: "customer-2", Total: 500})
+	if errorCode(err) != errors.CodeConflict {
+		t.Errorf("RecordOrder() for another customer error = %v, want %s", err, errors.CodeConflict)
+	}
+
+	tests := []struct {
+		customerId string
+		orders     int
+		spend      float64
+	}{
+		{"customer-1", 1, 90},
+		{"customer-2", 0, 0},
+	}
+	for _, tt := range tests {
+		history, err := repositories.Customers.GetCustomerHistory(testContext(""), tt.customerId)
+		if err != nil {
+			t.Fatalf("GetCustomerHistory() error = %v", err)
+		}
+		if history.OrderCount != tt.orders {
+			t.Errorf("order count of %s = %d, want %d", tt.customerId, history.OrderCount, tt.orders)
+		}
+		assertMoney(t, "lifetime spend of "+tt.customerId, history.LifetimeSpend, tt.spend)
+	}
+
+	// The failed redemption left nothing behind
+	if _, err := redeem(redemptions, productWise, "order-2", "customer-2", cart); err != nil {
+		t.Errorf("RedeemCoupon() on an order of its own error = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+func TestServiceErrorCodes(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Code: "SAVE10", Details: dtos.CouponDetails{Discount: 10}})
+	conditionalId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Condition: "cart.subtotal >= 100", Details: dtos.CouponDetails{Discount: 10}})
+	draft, err := service.CreateCoupon(editor, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+	if _, err := service.SubmitCoupon(editor, draft.Id); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 50}}}
+
+	tests := []struct {
+		name string
+		call func() error
+		code errors.Code
+	}{
+		{"unknown coupon", func() error {
+			_, err := service.GetCouponById(editor, "missing")
+			return err
+		}, errors.CodeNotFound},
+		{"invalid definition", func() error {
+			_, err := service.CreateCoupon(editor, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: -1}})
+			return err
+		}, errors.CodeValidation},
+		{"taken code", func() error {
+			_, err := service.CreateCoupon(editor, &dtos.Coupon{Type: "cart-wise", Code: "save10", Details: dtos.CouponDetails{Discount: 5}})
+			return err
+		}, errors.CodeConflict},
+		{"unknown campaign", func() error {
+			_, err := service.CreateCoupon(editor, &dtos.Coupon{Type: "cart-wise", CampaignId: "missing", Details: dtos.CouponDetails{Discount: 5}})
+			return err
+		}, errors.CodeValidation},
+		{"approving without the role", func() error {
+			_, err := service.ApproveCoupon(testContext(testReviewer), draft.Id)
+			return err
+		}, errors.CodeForbidden},
+		{"approving own change", func() error {
+			_, err := service.ApproveCoupon(testContext(testEditor, roleApprover), draft.Id)
+			return err
+		}, errors.CodeForbidden},
+		{"submitting twice", func() error {
+			_, err := service.SubmitCoupon(editor, draft.Id)
+			return err
+		}, errors.CodeConflict},
+		{"applying a coupon pending approval", func() error {
+			_, err := service.ApplyCoupon(editor, draft.Id, cart, nil)
+			return err
+		}, errors.CodeNotApplicable},
+		{"cart that does not meet the condition", func() error {
+			_, err := service.ApplyCoupon(editor, conditionalId, cart, nil)
+			return err
+		}, errors.CodeNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Handlers report errors the way errors.From maps them
+			err := tt.call()
+			if got := errors.From(err).Code; got != tt.code {
+				t.Errorf("error = %v, want code %v", err, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+// lineResult is the discount a coupon gives on a single cart line
+type lineResult struct {
+	Discount float64
+	Band     *models.VolumePricingBand
+}
+
+// couponResult is the outcome of evaluating a coupon against a cart
+type couponResult struct {
+	Applicable bool
+	// Discount on each cart line, in cart order
+	Lines []lineResult
+	// Discount on the cart as a whole that is not attributed to any line
+	CartDiscount     float64
+	ShippingDiscount float64
+}
+
+// Discount returns the total discount of the coupon on the cart
+func (r couponResult) Discount() float64 {
+	discount := r.CartDiscount + r.ShippingDiscount
+	for _, line := range r.Lines {
+		discount += line.Discount
+	}
+	return discount
+}
+
+// evaluateCoupon prices a cart with the rules of a coupon. It is a pure
+// function of its inputs; eligibility and conditions are checked by the callers.
+func evaluateCoupon(rules *models.CouponRules, cart dtos.Cart) couponResult {
+	result := couponResult{
+		Lines: make([]lineResult, len(cart.Items)),
+	}
+
+	// Calculate the cart total, which may be used by cart-wise and shipping coupons
+	var cartTotal float64
+	for _, item := range cart.Items {
+		cartTotal += float64(item.Quantity) * item.Price
+	}
+
+	switch rules.Coupon.Type {
+	case "cart-wise":
+		// Check if the cart meets the cart-wise coupon threshold
+		cartCoupon := rules.CartWise
+		if cartCoupon != nil && cartTotal >= cartCoupon.Threshold {
+			result.CartDiscount = (cartCoupon.Discount / 100) * cartTotal
+			result.Applicable = true
+		}
+
+	case "product-wise":
+		// Apply discount to specific products in the cart if they match the product-wise coupon
+		productCoupon := rules.ProductWise
+		if productCoupon == nil {
+			break
+		}
+		for i, item := range cart.Items {
+			if item.ProductId == productCoupon.ProductID {
+				result.Lines[i].Discount = (productCoupon.Discount / 100) * float64(item.Quantity) * item.Price
+				result.Applicable = true
+			}
+		}
+
+	case "bxgy":
+		// Check BxGy conditions and discount the get products if applicable
+		if rules.BxGy == nil {
+			break
+		}
+		buyCount := bxgyBuyCount(rules.BuyProducts, cart.Items)
+		if buyCount > 0 {
+			if buyCount > rules.BxGy.RepetitionLimit {
+				buyCount = rules.BxGy.RepetitionLimit // Cap buyCount at the repetition limit
+			}
+
+			result.Applicable = true
+			for _, getProduct := range rules.GetProducts {
+				for i, item := range cart.Items {
+					if item.ProductId == getProduct.ProductID {
+						result.Lines[i].Discount += float64(getProduct.Quantity*buyCount) * item.Price
+					}
+				}
+			}
+		}
+
+	case "volume":
+		// Apply the quantity band reached by each line covered by the volume pricing coupon
+		if rules.Volume == nil {
+			break
+		}
+		for i, line := range volumeLines(rules.Volume, rules.VolumeProducts, rules.VolumeBands, cart.Items) {
+			if line.Band != nil {
+				result.Lines[i] = lineResult{Discount: line.Discount, Band: line.Band}
+				result.Applicable = true
+			}
+		}
+
+	case "fixed-price":
+		// Sell the targeted products at their override price
+		if rules.FixedPrice == nil {
+			break
+		}
+		for i, line := range fixedPriceLines(rules.FixedPrice, rules.FixedPriceProducts, cart.Items) {
+			if line.Units > 0 {
+				result.Lines[i].Discount = line.Discount
+				result.Applicable = true
+			}
+		}
+
+	case "shipping":
+		// Check the shipping coupon against the cart's shipping method and fee
+		if rules.Shipping == nil {
+			break
+		}
+		result.ShippingDiscount, result.Applicable = shippingDiscount(rules.Shipping, rules.ShippingMethods, cart, cartTotal)
+	}
+
+	return result
+}
+
+// appliedResult is the result the apply endpoint reports for a cart. It
+// keeps the output the endpoint has always given, which differs from the
+// result of evaluateCoupon for two types: the cart-wise discount is counted
+// once per cart line, and the BxGy discount is put on the buy lines, each
+// line reaching the repetition limit on its own.
+func appliedResult(rules *models.CouponRules, cart dtos.Cart, result couponResult) couponResult {
+	switch rules.Coupon.Type {
+	case "cart-wise":
+		result.CartDiscount *= float64(len(cart.Items))
+
+	case "bxgy":
+		if rules.BxGy == nil {
+			break
+		}
+		result.Lines = make([]lineResult, len(cart.Items))
+		for i, item := range cart.Items {
+			buyCount := bxgyBuyCount(rules.BuyProducts, []dtos.CartItem{item})
+			if buyCount <= 0 {
+				continue
+			}
+			if buyCount > rules.BxGy.RepetitionLimit {
+				buyCount = rules.BxGy.RepetitionLimit
+			}
+			for _, getProduct := range rules.GetProducts {
+				for _, getItem := range cart.Items {
+					if getItem.ProductId == getProduct.ProductID {
+						result.Lines[i].Discount += float64(getProduct.Quantity*buyCount) * getItem.Price
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// bxgyBuyCount returns how many times the cart satisfies the buy side of a BxGy coupon
+func bxgyBuyCount(buyProducts []*models.BxGyBuyProduct, cartItems []dtos.CartItem) int {
+	buyCount := 0
+	for _, buyProduct := range buyProducts {
+		// Coupons created before validation may have a zero quantity
+		if buyProduct.Quantity <= 0 {
+			continue
+		}
+		for _, item := range cartItems {
+			if item.ProductId == buyProduct.ProductID {
+				buyCount += item.Quantity / buyProduct.Quantity
+			}
+		}
+	}
+	return buyCount
+}
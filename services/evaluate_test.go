@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestApplyCartWiseCoupon(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Threshold: 100, Discount: 10}})
+	cart := dtos.Cart{Items: []dtos.CartItem{
+		{ProductId: "A", Quantity: 1, Price: 100},
+		{ProductId: "B", Quantity: 2, Price: 50},
+	}}
+
+	updatedCart := applyCoupon(t, service, couponId, cart)
+
+	// The discount is counted once per cart line
+	assertMoney(t, "total discount", updatedCart.TotalDiscount, 40)
+	assertMoney(t, "final price", updatedCart.FinalPrice, 160)
+	for _, item := range updatedCart.Items {
+		assertMoney(t, item.ProductId+" discount", item.TotalDiscount, 0)
+	}
+}
+
+func TestApplyBxGyCoupon(t *testing.T) {
+	tests := []struct {
+		name          string
+		buyProducts   []dtos.ProductQuantityDetails
+		items         []dtos.CartItem
+		lineDiscounts []float64
+	}{
+		{
+			name:        "one buy product",
+			buyProducts: []dtos.ProductQuantityDetails{{ProductId: "X", Quantity: 2}},
+			items: []dtos.CartItem{
+				{ProductId: "X", Quantity: 4, Price: 10},
+				{ProductId: "Y", Quantity: 2, Price: 50},
+			},
+			lineDiscounts: []float64{100, 0},
+		},
+		{
+			name:        "buy lines reach the repetition limit on their own",
+			buyProducts: []dtos.ProductQuantityDetails{{ProductId: "X", Quantity: 2}, {ProductId: "Z", Quantity: 2}},
+			items: []dtos.CartItem{
+				{ProductId: "X", Quantity: 4, Price: 10},
+				{ProductId: "Z", Quantity: 4, Price: 10},
+				{ProductId: "Y", Quantity: 2, Price: 50},
+			},
+			lineDiscounts: []float64{100, 100, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			couponId := createLiveCoupon(t, service, dtos.Coupon{
+				Type: "bxgy",
+				Details: dtos.CouponDetails{
+					BuyProducts:   tt.buyProducts,
+					GetProducts:   []dtos.ProductQuantityDetails{{ProductId: "Y", Quantity: 1}},
+					RepitionLimit: 2,
+				},
+			})
+
+			updatedCart := applyCoupon(t, service, couponId, dtos.Cart{Items: tt.items})
+
+			var totalDiscount float64
+			for i, item := range updatedCart.Items {
+				assertMoney(t, item.ProductId+" discount", item.TotalDiscount, tt.lineDiscounts[i])
+				totalDiscount += tt.lineDiscounts[i]
+			}
+			assertMoney(t, "total discount", updatedCart.TotalDiscount, totalDiscount)
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package services
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// maxExampleNameLength bounds example names, like the name column
+const maxExampleNameLength = 255
+
+var exampleRules = all(
+	field("name", func(e *dtos.CouponExample) string { return e.Name }, required()),
+	field("cart", func(e *dtos.CouponExample) dtos.Cart { return e.Cart }, cartRules),
+	field("expected", func(e *dtos.CouponExample) dtos.ExampleOutcome { return e.Expected }, all(
+		field("total_discount", func(o dtos.ExampleOutcome) float64 { return o.TotalDiscount }, atLeast(0.0)),
+		field("shipping_discount", func(o dtos.ExampleOutcome) float64 { return o.ShippingDiscount }, atLeast(0.0)),
+		field("items", func(o dtos.ExampleOutcome) []dtos.ExampleItem { return o.Items }, each(
+			field("product_id", func(i dtos.ExampleItem) string { return i.ProductId }, required()),
+			field("total_discount", func(i dtos.ExampleItem) float64 { return i.TotalDiscount }, atLeast(0.0)),
+		)),
+	)),
+)
+
+// validateExample checks an example before it is stored
+func validateExample(req *dtos.CouponExample) error {
+	v := &validator{}
+	exampleRules(v, "", req)
+	if len(req.Name) > maxExampleNameLength {
+		v.report("name", "must be at most %d characters", maxExampleNameLength)
+	}
+	return v.err("invalid example")
+}
+
+// ListCouponExamples returns the examples of a coupon, by name
+func (c *CouponService) ListCouponExamples(ctx *context.Context, couponId string) ([]*dtos.CouponExample, error) {
+	if _, err := c.db.GetCouponById(ctx, couponId); err != nil {
+		return nil, couponNotFound(couponId, err)
+	}
+
+	examples, err := c.db.ListCouponExamples(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	return toExampleDtos(examples)
+}
+
+func (c *CouponService) GetCouponExample(ctx *context.Context, couponId string, exampleId string) (*dtos.CouponExample, error) {
+	example, err := c.db.GetCouponExample(ctx, couponId, exampleId)
+	if err != nil {
+		return nil, exampleNotFound(couponId, exampleId, err)
+	}
+	return toExampleDto(example)
+}
+
+// CreateCouponExample attaches an example to a coupon. The example is not run
+// until the coupon changes or the examples are run.
+func (c *CouponService) CreateCouponExample(ctx *context.Context, couponId string, req *dtos.CouponExample) (*dtos.CouponExample, error) {
+	if err := validateExample(req); err != nil {
+		return nil, err
+	}
+	if _, err := c.getLiveCouponRules(ctx, couponId); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	created := *req
+	created.Id = uuid.New().String()
+	created.CouponId = couponId
+	created.Name = strings.TrimSpace(req.Name)
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	example, err := toExampleModel(&created)
+	if err != nil {
+		return nil, err
+	}
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.PersistCouponExample(tx, example)
+		if err != nil {
+			tx.Log.Error("failed to persist coupon example", zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, exampleNameTaken(couponId, created.Name, err)
+	}
+	return &created, nil
+}
+
+// UpdateCouponExample replaces the name, cart, customer and expected outcome
+// of an example
+func (c *CouponService) UpdateCouponExample(ctx *context.Context, couponId string, exampleId string, req *dtos.CouponExample) (*dtos.CouponExample, error) {
+	if err := validateExample(req); err != nil {
+		return nil, err
+	}
+	current, err := c.GetCouponExample(ctx, couponId, exampleId)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *req
+	updated.Id = exampleId
+	updated.CouponId = couponId
+	updated.Name = strings.TrimSpace(req.Name)
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	example, err := toExampleModel(&updated)
+	if err != nil {
+		return nil, err
+	}
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.UpdateCouponExample(tx, example)
+		if err != nil {
+			tx.Log.Error("failed to update coupon example", zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, exampleNameTaken(couponId, updated.Name, exampleNotFound(couponId, exampleId, err))
+	}
+	return &updated, nil
+}
+
+func (c *CouponService) DeleteCouponExample(ctx *context.Context, couponId string, exampleId string) error {
+	err := daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		return c.db.DeleteCouponExample(tx, couponId, exampleId)
+	})
+	if err != nil {
+		return exampleNotFound(couponId, exampleId, err)
+	}
+	return nil
+}
+
+// RunCouponExamples runs the examples of a coupon against its current
+// definition
+func (c *CouponService) RunCouponExamples(ctx *context.Context, couponId string) (*dtos.ExampleRun, error) {
+	rules, err := c.getCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := c.db.ListCouponExamples(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &dtos.ExampleRun{Results: []dtos.ExampleResult{}}
+	err = runExamples(ctx, run, rules, examples)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RunAllCouponExamples runs the examples of every coupon that is not deleted,
+// e.g. to check a change of the pricing code before it is deployed
+func (c *CouponService) RunAllCouponExamples(ctx *context.Context) (*dtos.ExampleRun, error) {
+	examples, err := c.db.ListCouponExamples(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var couponIds []string
+	byCoupon := make(map[string][]*models.CouponExample)
+	for _, example := range examples {
+		if len(byCoupon[example.CouponID]) == 0 {
+			couponIds = append(couponIds, example.CouponID)
+		}
+		byCoupon[example.CouponID] = append(byCoupon[example.CouponID], example)
+	}
+
+	coupons, err := c.db.GetAllCoupons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tested []*models.Coupon
+	for _, coupon := range coupons {
+		if len(byCoupon[coupon.Id]) > 0 {
+			tested = append(tested, coupon)
+		}
+	}
+	ruleSet, err := c.db.LoadRuleSet(ctx, tested)
+	if err != nil {
+		return nil, err
+	}
+
+	// Examples of deleted coupons are left out
+	run := &dtos.ExampleRun{Results: []dtos.ExampleResult{}}
+	for _, couponId := range couponIds {
+		rules := ruleSet.Get(couponId)
+		if rules == nil {
+			continue
+		}
+		err = runExamples(ctx, run, rules, byCoupon[couponId])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// checkCouponExamples runs the examples of a coupon against a new definition
+// and rejects the change if any of them fails
+func (c *CouponService) checkCouponExamples(ctx *context.Context, couponId string, req *dtos.Coupon) error {
+	examples, err := c.db.ListCouponExamples(ctx, couponId)
+	if err != nil || len(examples) == 0 {
+		return err
+	}
+	rules, err := previewRules(ctx, req, time.Now())
+	if err != nil {
+		return err
+	}
+
+	run := &dtos.ExampleRun{}
+	err = runExamples(ctx, run, rules, examples)
+	if err != nil {
+		return err
+	}
+	if run.Failed == 0 {
+		return nil
+	}
+
+	var failed []dtos.ExampleResult
+	var problems []string
+	for _, result := range run.Results {
+		if !result.Passed {
+			failed = append(failed, result)
+			problems = append(problems, fmt.Sprintf("%s: %s", result.Name, strings.Join(result.Failures, ", ")))
+		}
+	}
+	return errors.Conflict("change breaks %d of the %d examples of coupon %s, save it with force=true to override: %s",
+		run.Failed, run.Total, couponId, strings.Join(problems, "; ")).WithDetails(failed)
+}
+
+// runExamples runs examples against the rules of their coupon and adds the
+// results to run
+func runExamples(ctx *context.Context, run *dtos.ExampleRun, rules *models.CouponRules, examples []*models.CouponExample) error {
+	for _, model := range examples {
+		example, err := toExampleDto(model)
+		if err != nil {
+			return err
+		}
+		result, err := runExample(ctx, rules, example)
+		if err != nil {
+			return err
+		}
+
+		run.Total++
+		if result.Passed {
+			run.Passed++
+		} else {
+			run.Failed++
+		}
+		run.Results = append(run.Results, *result)
+	}
+	return nil
+}
+
+// runExample prices the cart of an example with the same code as
+// ApplyCoupon. The customer of the example is taken as it is, without the
+// order history we keep, so examples give the same outcome every time.
+func runExample(ctx *context.Context, rules *models.CouponRules, example *dtos.CouponExample) (*dtos.ExampleResult, error) {
+	result := &dtos.ExampleResult{
+		ExampleId: example.Id,
+		CouponId:  example.CouponId,
+		Name:      example.Name,
+	}
+
+	updatedCart, err := priceCart(ctx, rules, example.Cart, example.Customer, exampleTime(rules.Coupon, time.Now()))
+	switch {
+	case err == nil:
+		result.Actual = exampleOutcome(updatedCart)
+	case errors.From(err).Code == errors.CodeNotApplicable:
+		result.Actual.NotApplicable = true
+		result.Reason = errors.From(err).Message
+	default:
+		return nil, err
+	}
+
+	result.Failures = compareOutcomes(example.Expected, result.Actual)
+	result.Passed = len(result.Failures) == 0
+	return result, nil
+}
+
+// exampleTime is the time examples are priced at: now, or the closest time
+// within the validity window of the coupon, so examples check how the coupon
+// prices carts rather than when it applies
+func exampleTime(coupon *models.Coupon, now time.Time) time.Time {
+	switch coupon.ValidityState(now) {
+	case models.CouponScheduled:
+		return *coupon.StartsAt
+	case models.CouponExpired:
+		return coupon.EndsAt.Add(-time.Nanosecond)
+	}
+	return now
+}
+
+// exampleOutcome sums up an updated cart, with the discount of each product
+func exampleOutcome(cart *dtos.UpdatedCart) dtos.ExampleOutcome {
+	outcome := dtos.ExampleOutcome{
+		TotalDiscount:    cart.TotalDiscount,
+		ShippingDiscount: cart.ShippingDiscount,
+	}
+	positions := make(map[string]int)
+	for _, item := range cart.Items {
+		i, ok := positions[item.ProductId]
+		if !ok {
+			i = len(outcome.Items)
+			positions[item.ProductId] = i
+			outcome.Items = append(outcome.Items, dtos.ExampleItem{ProductId: item.ProductId})
+		}
+		outcome.Items[i].TotalDiscount += item.TotalDiscount
+	}
+	return outcome
+}
+
+// compareOutcomes lists how the actual outcome of an example differs from
+// the expected one, amounts are compared to the cent
+func compareOutcomes(expected dtos.ExampleOutcome, actual dtos.ExampleOutcome) []string {
+	if expected.NotApplicable != actual.NotApplicable {
+		if expected.NotApplicable {
+			return []string{"coupon applies, expected it not to"}
+		}
+		return []string{"coupon does not apply"}
+	}
+	if expected.NotApplicable {
+		return nil
+	}
+
+	var failures []string
+	compare := func(name string, got, want float64) {
+		if math.Abs(got-want) >= 0.005 {
+			failures = append(failures, fmt.Sprintf("%s is %.2f, expected %.2f", name, got, want))
+		}
+	}
+	compare("total_discount", actual.TotalDiscount, expected.TotalDiscount)
+	compare("shipping_discount", actual.ShippingDiscount, expected.ShippingDiscount)
+
+	discounts := make(map[string]float64)
+	for _, item := range actual.Items {
+		discounts[item.ProductId] = item.TotalDiscount
+	}
+	for _, item := range expected.Items {
+		discount, ok := discounts[item.ProductId]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("product %s is not in the cart", item.ProductId))
+			continue
+		}
+		compare("discount of product "+item.ProductId, discount, item.TotalDiscount)
+	}
+	return failures
+}
+
+func exampleNotFound(couponId string, exampleId string, err error) error {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.NotFound("example %s of coupon %s not found", exampleId, couponId).Wrap(err)
+	}
+	return err
+}
+
+// exampleNameTaken reports an example name already used by the coupon, other
+// errors are returned as they are
+func exampleNameTaken(couponId string, name string, err error) error {
+	if err != nil && errors.From(err).Code == errors.CodeConflict {
+		return errors.Conflict("coupon %s already has an example named %q", couponId, name).Wrap(err)
+	}
+	return err
+}
+
+func toExampleModel(example *dtos.CouponExample) (*models.CouponExample, error) {
+	cart, err := json.Marshal(example.Cart)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := json.Marshal(example.Customer)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := json.Marshal(example.Expected)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CouponExample{
+		Id:        example.Id,
+		CouponID:  example.CouponId,
+		Name:      example.Name,
+		Cart:      models.JSON(cart),
+		Customer:  models.JSON(customer),
+		Expected:  models.JSON(expected),
+		CreatedAt: example.CreatedAt,
+		UpdatedAt: example.UpdatedAt,
+	}, nil
+}
+
+func toExampleDto(example *models.CouponExample) (*dtos.CouponExample, error) {
+	exampleDto := &dtos.CouponExample{
+		Id:        example.Id,
+		CouponId:  example.CouponID,
+		Name:      example.Name,
+		CreatedAt: example.CreatedAt,
+		UpdatedAt: example.UpdatedAt,
+	}
+	if err := json.Unmarshal(example.Cart, &exampleDto.Cart); err != nil {
+		return nil, err
+	}
+	if len(example.Customer) > 0 {
+		if err := json.Unmarshal(example.Customer, &exampleDto.Customer); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(example.Expected, &exampleDto.Expected); err != nil {
+		return nil, err
+	}
+	return exampleDto, nil
+}
+
+func toExampleDtos(examples []*models.CouponExample) ([]*dtos.CouponExample, error) {
+	exampleDtos := make([]*dtos.CouponExample, len(examples))
+	for i, example := range examples {
+		exampleDto, err := toExampleDto(example)
+		if err != nil {
+			return nil, err
+		}
+		exampleDtos[i] = exampleDto
+	}
+	return exampleDtos, nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// tenPercentOff is a coupon giving 10% off carts of 100 or more
+var tenPercentOff = dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Threshold: 100, Discount: 10}}
+
+// createExample attaches an example of a cart with one product to a coupon
+func createExample(t *testing.T, service *CouponService, couponId string, name string, price float64, expected dtos.ExampleOutcome) *dtos.CouponExample {
+	t.Helper()
+	example, err := service.CreateCouponExample(testContext(testEditor), couponId, &dtos.CouponExample{
+		Name:     name,
+		Cart:     dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: price}}},
+		Expected: expected,
+	})
+	if err != nil {
+		t.Fatalf("CreateCouponExample() error = %v", err)
+	}
+	return example
+}
+
+func exampleNames(results []dtos.ExampleResult, passed bool) []string {
+	var names []string
+	for _, result := range results {
+		if result.Passed == passed {
+			names = append(names, result.Name)
+		}
+	}
+	return names
+}
+
+func TestCreateCouponExample(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext(testEditor)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+
+	example := createExample(t, service, couponId, " big cart ", 200, dtos.ExampleOutcome{TotalDiscount: 20})
+	if example.Id == "" || example.CouponId != couponId || example.Name != "big cart" {
+		t.Errorf("CreateCouponExample() = %+v, want a trimmed name on the coupon", example)
+	}
+
+	tests := []struct {
+		name     string
+		couponId string
+		req      dtos.CouponExample
+		code     errors.Code
+	}{
+		{"taken name", couponId, dtos.CouponExample{Name: "big cart", Cart: dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 10}}}}, errors.CodeConflict},
+		{"no name", couponId, dtos.CouponExample{Cart: dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 10}}}}, errors.CodeValidation},
+		{"negative discount", couponId, dtos.CouponExample{Name: "negative", Cart: dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 10}}}, Expected: dtos.ExampleOutcome{TotalDiscount: -1}}, errors.CodeValidation},
+		{"unknown coupon", "missing", dtos.CouponExample{Name: "small cart", Cart: dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 10}}}}, errors.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateCouponExample(ctx, tt.couponId, &tt.req)
+			if errorCode(err) != tt.code {
+				t.Errorf("CreateCouponExample() error = %v, want code %v", err, tt.code)
+			}
+		})
+	}
+
+	examples, err := service.ListCouponExamples(ctx, couponId)
+	if err != nil {
+		t.Fatalf("ListCouponExamples() error = %v", err)
+	}
+	if len(examples) != 1 {
+		t.Errorf("%d examples are stored, want 1", len(examples))
+	}
+}
+
+func TestRunCouponExamples(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	createExample(t, service, couponId, "big cart", 200, dtos.ExampleOutcome{TotalDiscount: 20})
+	createExample(t, service, couponId, "small cart", 50, dtos.ExampleOutcome{TotalDiscount: 0})
+	createExample(t, service, couponId, "wrong discount", 200, dtos.ExampleOutcome{TotalDiscount: 30})
+	createExample(t, service, couponId, "expected not to apply", 200, dtos.ExampleOutcome{NotApplicable: true})
+
+	run, err := service.RunCouponExamples(testContext(testEditor), couponId)
+	if err != nil {
+		t.Fatalf("RunCouponExamples() error = %v", err)
+	}
+	if run.Total != 4 || run.Passed != 2 || run.Failed != 2 {
+		t.Errorf("run = %d total, %d passed, %d failed, want 4, 2, 2", run.Total, run.Passed, run.Failed)
+	}
+	if got, want := exampleNames(run.Results, true), []string{"big cart", "small cart"}; !slices.Equal(got, want) {
+		t.Errorf("passed = %q, want %q", got, want)
+	}
+
+	failures := make(map[string][]string)
+	for _, result := range run.Results {
+		failures[result.Name] = result.Failures
+	}
+	if got, want := failures["wrong discount"], []string{"total_discount is 20.00, expected 30.00"}; !slices.Equal(got, want) {
+		t.Errorf("failures = %q, want %q", got, want)
+	}
+	if got, want := failures["expected not to apply"], []string{"coupon applies, expected it not to"}; !slices.Equal(got, want) {
+		t.Errorf("failures = %q, want %q", got, want)
+	}
+}
+
+func TestRunAllCouponExamples(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext(testEditor)
+	firstId := createLiveCoupon(t, service, tenPercentOff)
+	secondId := createLiveCoupon(t, service, tenPercentOff)
+	deletedId := createLiveCoupon(t, service, tenPercentOff)
+	createExample(t, service, firstId, "first", 200, dtos.ExampleOutcome{TotalDiscount: 20})
+	createExample(t, service, secondId, "second", 200, dtos.ExampleOutcome{TotalDiscount: 30})
+	createExample(t, service, deletedId, "deleted", 200, dtos.ExampleOutcome{TotalDiscount: 30})
+	if err := service.DeleteCoupon(ctx, deletedId); err != nil {
+		t.Fatalf("DeleteCoupon() error = %v", err)
+	}
+
+	// Examples of deleted coupons are left out
+	run, err := service.RunAllCouponExamples(ctx)
+	if err != nil {
+		t.Fatalf("RunAllCouponExamples() error = %v", err)
+	}
+	if run.Total != 2 || run.Passed != 1 || run.Failed != 1 {
+		t.Errorf("run = %d total, %d passed, %d failed, want 2, 1, 1", run.Total, run.Passed, run.Failed)
+	}
+	if got, want := exampleNames(run.Results, false), []string{"second"}; !slices.Equal(got, want) {
+		t.Errorf("failed = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateCouponChecksExamples(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext(testEditor)
+	couponId := createDraft(t, service, 10)
+	createExample(t, service, couponId, "big cart", 200, dtos.ExampleOutcome{TotalDiscount: 20})
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 200}}}
+
+	// A change the examples still hold for is saved
+	_, err := service.UpdateCoupon(ctx, couponId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Threshold: 100, Discount: 10}}, false)
+	if err != nil {
+		t.Fatalf("UpdateCoupon() error = %v", err)
+	}
+
+	breaking := &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 15}}
+	_, err = service.UpdateCoupon(ctx, couponId, breaking, false)
+	if errorCode(err) != errors.CodeConflict {
+		t.Fatalf("UpdateCoupon() error = %v, want a conflict", err)
+	}
+	failed, ok := errors.From(err).Details.([]dtos.ExampleResult)
+	if !ok || len(failed) != 1 || failed[0].Name != "big cart" {
+		t.Errorf("details = %+v, want the failed example", errors.From(err).Details)
+	}
+	coupon, err := service.GetCouponById(ctx, couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	if coupon.Details.Discount != 10 {
+		t.Errorf("discount = %d after a rejected change, want 10", coupon.Details.Discount)
+	}
+
+	_, err = service.UpdateCoupon(ctx, couponId, breaking, true)
+	if err != nil {
+		t.Fatalf("UpdateCoupon() with force error = %v", err)
+	}
+	if _, err := service.SubmitCoupon(ctx, couponId); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	if _, err := service.ApproveCoupon(testContext(testReviewer, roleApprover), couponId); err != nil {
+		t.Fatalf("ApproveCoupon() error = %v", err)
+	}
+	assertMoney(t, "discount", applyCoupon(t, service, couponId, cart).TotalDiscount, 30)
+
+	// Revisions of live coupons are checked the same way
+	_, err = service.UpdateCoupon(ctx, couponId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 5}}, false)
+	if errorCode(err) != errors.CodeConflict {
+		t.Errorf("UpdateCoupon() of a live coupon error = %v, want a conflict", err)
+	}
+}
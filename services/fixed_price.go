@@ -0,0 +1,67 @@
+package services
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+// fixedPriceLine is the outcome of a fixed price coupon for a single cart line
+type fixedPriceLine struct {
+	Units    int
+	Discount float64
+}
+
+// fixedPriceCouponDetails transforms a fixed price coupon into the DTO format
+func fixedPriceCouponDetails(rules *models.CouponRules) dtos.CouponDetails {
+	var fixedPricesDto []dtos.ProductPriceDetails
+	for _, product := range rules.FixedPriceProducts {
+		fixedPricesDto = append(fixedPricesDto, dtos.ProductPriceDetails{
+			ProductId: product.ProductID,
+			Price:     product.Price,
+		})
+	}
+
+	return dtos.CouponDetails{
+		FixedPrices: fixedPricesDto,
+		MaxUnits:    rules.FixedPrice.MaxUnits,
+	}
+}
+
+// fixedPriceLines works out how many units of each cart line are sold at the
+// override price and the resulting discount. The discount of a unit is the
+// difference between its list price and the override and is never negative.
+// When the coupon caps the number of units per order, units are taken in cart order.
+func fixedPriceLines(fixedPriceCoupon *models.FixedPriceCoupon, products []*models.FixedPriceProduct, cartItems []dtos.CartItem) []fixedPriceLine {
+	lines := make([]fixedPriceLine, len(cartItems))
+
+	overrides := make(map[string]float64, len(products))
+	for _, product := range products {
+		overrides[product.ProductID] = product.Price
+	}
+
+	remaining := fixedPriceCoupon.MaxUnits
+	for i, item := range cartItems {
+		override, ok := overrides[item.ProductId]
+		if !ok || item.Price <= override {
+			continue
+		}
+
+		units := item.Quantity
+		if fixedPriceCoupon.MaxUnits > 0 {
+			if remaining <= 0 {
+				break
+			}
+			if units > remaining {
+				units = remaining
+			}
+			remaining -= units
+		}
+
+		lines[i] = fixedPriceLine{
+			Units:    units,
+			Discount: (item.Price - override) * float64(units),
+		}
+	}
+
+	return lines
+}
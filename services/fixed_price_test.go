@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestApplyFixedPriceCoupon(t *testing.T) {
+	fixedPrices := []dtos.ProductPriceDetails{
+		{ProductId: "A", Price: 5},
+		{ProductId: "B", Price: 50},
+	}
+
+	tests := []struct {
+		name          string
+		maxUnits      int
+		items         []dtos.CartItem
+		lineDiscounts []float64
+	}{
+		{
+			name:          "units are sold at the override price",
+			items:         []dtos.CartItem{{ProductId: "A", Quantity: 3, Price: 8}},
+			lineDiscounts: []float64{9},
+		},
+		{
+			name:          "override above the list price gives no discount",
+			items:         []dtos.CartItem{{ProductId: "B", Quantity: 2, Price: 40}},
+			lineDiscounts: []float64{0},
+		},
+		{
+			name:          "products without an override keep their price",
+			items:         []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 8}, {ProductId: "C", Quantity: 1, Price: 8}},
+			lineDiscounts: []float64{3, 0},
+		},
+		{
+			name:     "units per order are capped in cart order",
+			maxUnits: 4,
+			items: []dtos.CartItem{
+				{ProductId: "A", Quantity: 3, Price: 8},
+				{ProductId: "B", Quantity: 3, Price: 60},
+			},
+			lineDiscounts: []float64{9, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			couponId := createLiveCoupon(t, service, dtos.Coupon{
+				Type: "fixed-price",
+				Details: dtos.CouponDetails{
+					FixedPrices: fixedPrices,
+					MaxUnits:    tt.maxUnits,
+				},
+			})
+
+			updatedCart := applyCoupon(t, service, couponId, dtos.Cart{Items: tt.items})
+
+			var totalDiscount float64
+			for i, item := range updatedCart.Items {
+				assertMoney(t, item.ProductId+" discount", item.TotalDiscount, tt.lineDiscounts[i])
+				totalDiscount += tt.lineDiscounts[i]
+			}
+			assertMoney(t, "total discount", updatedCart.TotalDiscount, totalDiscount)
+			assertMoney(t, "final price", updatedCart.FinalPrice, updatedCart.TotalPrice-totalDiscount)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	stderrors "errors"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CouponIndex maps index keys (product IDs, and later categories) to the
+// coupons that can touch a cart containing them. Coupons that look at the cart
+// as a whole are kept in a separate list. Evaluating a cart against the index
+// costs in proportion to the size of the cart instead of the number of coupons.
+//
+// An index is immutable once built; changes are published by building a new
+// index and swapping it in. A change to a few coupons only reads those coupons
+// again, see patchCouponIndex.
+type CouponIndex struct {
+	ruleSet   *models.RuleSet
+	byKey     map[string][]int
+	cartLevel []int
+	// Every coupon that names a product in its details, including the get
+	// products of BxGy coupons, for product page promotions
+	byProduct map[string][]int
+}
+
+func productKey(productId string) string {
+	return "product:" + productId
+}
+
+func categoryKey(category string) string {
+	return "category:" + category
+}
+
+// NewCouponIndex builds an index over the coupons of a rule set
+func NewCouponIndex(ruleSet *models.RuleSet) *CouponIndex {
+	index := &CouponIndex{
+		ruleSet:   ruleSet,
+		byKey:     make(map[string][]int),
+		byProduct: make(map[string][]int),
+	}
+
+	for position, rules := range ruleSet.Coupons {
+		for _, productId := range namedProducts(rules) {
+			index.byProduct[productId] = append(index.byProduct[productId], position)
+		}
+
+		keys := indexKeys(rules)
+		if keys == nil {
+			index.cartLevel = append(index.cartLevel, position)
+			continue
+		}
+		for _, key := range keys {
+			index.byKey[key] = append(index.byKey[key], position)
+		}
+	}
+
+	return index
+}
+
+// indexKeys returns the keys a coupon can only apply through. A nil result
+// means the coupon can apply to any cart and is evaluated for every cart.
+func indexKeys(rules *models.CouponRules) []string {
+	keys := []string{}
+	switch rules.Coupon.Type {
+	case "product-wise":
+		if rules.ProductWise != nil {
+			keys = append(keys, productKey(rules.ProductWise.ProductID))
+		}
+	case "bxgy":
+		// BxGy coupons need at least one of their buy products in the cart
+		for _, buyProduct := range rules.BuyProducts {
+			keys = append(keys, productKey(buyProduct.ProductID))
+		}
+	case "volume":
+		for _, product := range rules.VolumeProducts {
+			keys = append(keys, productKey(product.ProductID))
+		}
+	case "fixed-price":
+		for _, product := range rules.FixedPriceProducts {
+			keys = append(keys, productKey(product.ProductID))
+		}
+	default:
+		return nil
+	}
+	return keys
+}
+
+// namedProducts returns the products a coupon names in its details, each once
+func namedProducts(rules *models.CouponRules) []string {
+	var products []string
+	switch rules.Coupon.Type {
+	case "product-wise":
+		if rules.ProductWise != nil {
+			products = append(products, rules.ProductWise.ProductID)
+		}
+	case "bxgy":
+		for _, buyProduct := range rules.BuyProducts {
+			products = append(products, buyProduct.ProductID)
+		}
+		for _, getProduct := range rules.GetProducts {
+			products = append(products, getProduct.ProductID)
+		}
+	case "volume":
+		for _, product := range rules.VolumeProducts {
+			products = append(products, product.ProductID)
+		}
+	case "fixed-price":
+		for _, product := range rules.FixedPriceProducts {
+			products = append(products, product.ProductID)
+		}
+	}
+	sort.Strings(products)
+	return slices.Compact(products)
+}
+
+// ForProduct returns the coupons that name the product, followed by the
+// coupons on the whole cart, in the order of the underlying rule set
+func (i *CouponIndex) ForProduct(productId string) []*models.CouponRules {
+	positions := i.byProduct[productId]
+	coupons := make([]*models.CouponRules, 0, len(positions)+len(i.cartLevel))
+	for _, position := range positions {
+		coupons = append(coupons, i.ruleSet.Coupons[position])
+	}
+	for _, position := range i.cartLevel {
+		coupons = append(coupons, i.ruleSet.Coupons[position])
+	}
+	return coupons
+}
+
+// Candidates returns the coupons that could apply to the cart, each once and
+// in the order of the underlying rule set
+func (i *CouponIndex) Candidates(cart dtos.Cart) []*models.CouponRules {
+	positions := make([]int, 0, len(i.cartLevel))
+	positions = append(positions, i.cartLevel...)
+	for _, item := range cart.Items {
+		positions = append(positions, i.byKey[productKey(item.ProductId)]...)
+		if item.Category != "" {
+			positions = append(positions, i.byKey[categoryKey(item.Category)]...)
+		}
+	}
+	sort.Ints(positions)
+
+	candidates := make([]*models.CouponRules, 0, len(positions))
+	for n, position := range positions {
+		if n > 0 && positions[n-1] == position {
+			continue
+		}
+		candidates = append(candidates, i.ruleSet.Coupons[position])
+	}
+	return candidates
+}
+
+// RuleSet returns the coupons the index was built from
+func (i *CouponIndex) RuleSet() *models.RuleSet {
+	return i.ruleSet
+}
+
+// withCoupons returns a new index in which the given coupons have the rules
+// they have in changed: coupons missing from changed are left out, and
+// coupons the index does not hold yet are added at the end. The rules of the
+// other coupons are shared with this index, which is left as it is.
+func (i *CouponIndex) withCoupons(couponIds []string, changed *models.RuleSet) *CouponIndex {
+	isChanged := make(map[string]bool, len(couponIds))
+	for _, couponId := range couponIds {
+		isChanged[couponId] = true
+	}
+
+	coupons := make([]*models.CouponRules, 0, len(i.ruleSet.Coupons)+len(couponIds))
+	placed := make(map[string]bool, len(couponIds))
+	for _, rules := range i.ruleSet.Coupons {
+		couponId := rules.Coupon.Id
+		if !isChanged[couponId] {
+			coupons = append(coupons, rules)
+			continue
+		}
+		placed[couponId] = true
+		if rules := changed.Get(couponId); rules != nil {
+			coupons = append(coupons, rules)
+		}
+	}
+	for _, couponId := range couponIds {
+		if rules := changed.Get(couponId); rules != nil && !placed[couponId] {
+			placed[couponId] = true
+			coupons = append(coupons, rules)
+		}
+	}
+
+	return NewCouponIndex(models.NewRuleSetFromRules(coupons))
+}
+
+var (
+	// The index currently in use, nil until it is first built
+	couponIndex atomic.Pointer[CouponIndex]
+	// Serializes rebuilds so the last write always wins with fresh data
+	couponIndexMu sync.Mutex
+)
+
+// getCouponIndex returns the current coupon index, building it on first use
+func (c *CouponService) getCouponIndex(ctx *context.Context) (*CouponIndex, error) {
+	if index := couponIndex.Load(); index != nil {
+		return index, nil
+	}
+
+	couponIndexMu.Lock()
+	defer couponIndexMu.Unlock()
+
+	// Another request may have built the index while we were waiting
+	if index := couponIndex.Load(); index != nil {
+		return index, nil
+	}
+	return c.buildCouponIndex(ctx)
+}
+
+// rebuildCouponIndex loads every coupon, builds a new index and swaps it in.
+// Readers keep using the previous index until the swap.
+func (c *CouponService) rebuildCouponIndex(ctx *context.Context) (*CouponIndex, error) {
+	couponIndexMu.Lock()
+	defer couponIndexMu.Unlock()
+
+	return c.buildCouponIndex(ctx)
+}
+
+// buildCouponIndex must be called with couponIndexMu held
+func (c *CouponService) buildCouponIndex(ctx *context.Context) (*CouponIndex, error) {
+	coupons, err := c.db.GetAllCoupons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet, err := c.db.LoadRuleSet(ctx, evaluableCoupons(coupons))
+	if err != nil {
+		return nil, err
+	}
+
+	index := NewCouponIndex(ruleSet)
+	couponIndex.Store(index)
+	storeCouponSnapshot(ctx, index)
+	return index, nil
+}
+
+// evaluableCoupons returns the coupons the index holds. Only approved coupons
+// are offered, drafts, ended and deleted coupons are left out.
+func evaluableCoupons(coupons []*models.Coupon) []*models.Coupon {
+	evaluable := make([]*models.Coupon, 0, len(coupons))
+	for _, coupon := range coupons {
+		if coupon.Evaluable() && coupon.DeletedAt == nil {
+			evaluable = append(evaluable, coupon)
+		}
+	}
+	return evaluable
+}
+
+// refreshCouponIndex rebuilds the index from every coupon, e.g. when changes
+// may have been missed. If the index cannot be rebuilt right away it is
+// dropped, so the next read rebuilds it; the snapshot is kept for pricing in
+// the meantime.
+func (c *CouponService) refreshCouponIndex(ctx *context.Context) {
+	if _, err := c.rebuildCouponIndex(ctx); err != nil {
+		ctx.Log.Warn("failed to rebuild coupon index", zap.Error(err))
+		couponIndex.Store(nil)
+	}
+}
+
+// patchCouponIndex reloads the given coupons after they changed and swaps in
+// an index with their new rules, without reading the other coupons again. If
+// they cannot be read the index is dropped, so the next read rebuilds it.
+func (c *CouponService) patchCouponIndex(ctx *context.Context, couponIds []string) {
+	couponIndexMu.Lock()
+	defer couponIndexMu.Unlock()
+
+	// Nothing to patch, the next read builds the index with the change
+	index := couponIndex.Load()
+	if index == nil {
+		return
+	}
+
+	changed, err := c.loadChangedCoupons(ctx, couponIds)
+	if err != nil {
+		ctx.Log.Warn("failed to update coupon index", zap.Strings("coupon_ids", couponIds), zap.Error(err))
+		couponIndex.Store(nil)
+		return
+	}
+
+	patched := index.withCoupons(couponIds, changed)
+	couponIndex.Store(patched)
+	storeCouponSnapshot(ctx, patched)
+}
+
+// loadChangedCoupons loads the rules of the given coupons that belong in the
+// index. Coupons that were purged or no longer apply are not in the result.
+func (c *CouponService) loadChangedCoupons(ctx *context.Context, couponIds []string) (*models.RuleSet, error) {
+	coupons := make([]*models.Coupon, 0, len(couponIds))
+	for _, couponId := range couponIds {
+		coupon, err := c.db.GetCouponById(ctx, couponId)
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+	return c.db.LoadRuleSet(ctx, evaluableCoupons(coupons))
+}
+
+// ResetCouponIndex drops the coupon index and the coupon cache. It is called
+// when the storage behind the services is replaced, so coupons read from the
+// previous storage are not served. The snapshot is kept for warm starts.
+func ResetCouponIndex() {
+	couponIndexMu.Lock()
+	defer couponIndexMu.Unlock()
+
+	daos.InvalidateCoupons()
+	couponIndex.Store(nil)
+}
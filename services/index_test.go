@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+// benchmarkRuleSet builds a catalog-sized rule set: mostly product-wise
+// coupons spread over the catalog, with some BxGy, volume and cart-wise coupons
+func benchmarkRuleSet(coupons int, products int) *models.RuleSet {
+	rng := rand.New(rand.NewSource(1))
+	productId := func() string {
+		return fmt.Sprintf("product-%d", rng.Intn(products))
+	}
+
+	var couponModels []*models.Coupon
+	for i := 0; i < coupons; i++ {
+		couponType := "product-wise"
+		switch i % 20 {
+		case 0:
+			couponType = "bxgy"
+		case 1:
+			couponType = "volume"
+		case 2:
+			if i%1000 == 2 {
+				couponType = "cart-wise"
+			}
+		}
+		couponModels = append(couponModels, &models.Coupon{Id: fmt.Sprintf("coupon-%d", i), Type: couponType})
+	}
+
+	ruleSet := models.NewRuleSet(couponModels)
+	for _, rules := range ruleSet.Coupons {
+		id := rules.Coupon.Id
+		switch rules.Coupon.Type {
+		case "product-wise":
+			rules.ProductWise = &models.ProductWiseCoupon{CouponID: id, ProductID: productId(), Discount: 10}
+		case "cart-wise":
+			rules.CartWise = &models.CartWiseCoupon{CouponID: id, Threshold: 100, Discount: 5}
+		case "bxgy":
+			rules.BxGy = &models.BxGyCoupon{CouponID: id, RepetitionLimit: 2}
+			rules.BuyProducts = []*models.BxGyBuyProduct{{BxGyCouponID: id, ProductID: productId(), Quantity: 2}}
+			rules.GetProducts = []*models.BxGyGetProduct{{BxGyCouponID: id, ProductID: productId(), Quantity: 1}}
+		case "volume":
+			rules.Volume = &models.VolumePricingCoupon{CouponID: id, Scope: volumeScopeLine}
+			rules.VolumeProducts = []*models.VolumePricingProduct{{VolumePricingCouponID: id, ProductID: productId()}}
+			rules.VolumeBands = []*models.VolumePricingBand{{VolumePricingCouponID: id, MinQuantity: 10, Discount: 5}}
+		}
+	}
+	return ruleSet
+}
+
+func benchmarkCart(products int) dtos.Cart {
+	rng := rand.New(rand.NewSource(2))
+	var cart dtos.Cart
+	for i := 0; i < 8; i++ {
+		cart.Items = append(cart.Items, dtos.CartItem{
+			ProductId: fmt.Sprintf("product-%d", rng.Intn(products)),
+			Quantity:  1 + rng.Intn(12),
+			Price:     float64(100 + rng.Intn(900)),
+		})
+	}
+	return cart
+}
+
+func benchmarkEvaluation(b *testing.B, coupons int) {
+	const products = 50000
+	ruleSet := benchmarkRuleSet(coupons, products)
+	index := NewCouponIndex(ruleSet)
+	cart := benchmarkCart(products)
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, rules := range ruleSet.Coupons {
+				evaluateCoupon(rules, cart)
+			}
+		}
+	})
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, rules := range index.Candidates(cart) {
+				evaluateCoupon(rules, cart)
+			}
+		}
+	})
+}
+
+func BenchmarkEvaluation10k(b *testing.B) {
+	benchmarkEvaluation(b, 10000)
+}
+
+func BenchmarkEvaluation100k(b *testing.B) {
+	benchmarkEvaluation(b, 100000)
+}
+
+func BenchmarkBuildIndex100k(b *testing.B) {
+	ruleSet := benchmarkRuleSet(100000, 50000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewCouponIndex(ruleSet)
+	}
+}
+
+// productWiseRules returns the rules of a product-wise coupon on a product
+func productWiseRules(couponId string, productId string) *models.CouponRules {
+	return &models.CouponRules{
+		Coupon:      &models.Coupon{Id: couponId, Type: "product-wise"},
+		ProductWise: &models.ProductWiseCoupon{CouponID: couponId, ProductID: productId, Discount: 10},
+	}
+}
+
+// candidateIds returns the IDs of the candidates of a cart holding the products
+func candidateIds(index *CouponIndex, productIds ...string) []string {
+	var cart dtos.Cart
+	for _, productId := range productIds {
+		cart.Items = append(cart.Items, dtos.CartItem{ProductId: productId, Quantity: 1, Price: 10})
+	}
+	var ids []string
+	for _, rules := range index.Candidates(cart) {
+		ids = append(ids, rules.Coupon.Id)
+	}
+	return ids
+}
+
+func TestCouponIndexWithCoupons(t *testing.T) {
+	index := NewCouponIndex(models.NewRuleSetFromRules([]*models.CouponRules{
+		productWiseRules("a", "p1"),
+		productWiseRules("b", "p2"),
+		productWiseRules("c", "p3"),
+	}))
+
+	// a moves to another product, b is removed and d is added
+	changed := models.NewRuleSetFromRules([]*models.CouponRules{
+		productWiseRules("a", "p3"),
+		productWiseRules("d", "p2"),
+	})
+	patched := index.withCoupons([]string{"a", "b", "d"}, changed)
+
+	if got, want := patched.RuleSet().IDs(), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("patched coupons = %v, want %v", got, want)
+	}
+	if got := candidateIds(patched, "p1"); len(got) != 0 {
+		t.Errorf("patched candidates of p1 = %v, want none", got)
+	}
+	if got, want := candidateIds(patched, "p2", "p3"), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("patched candidates of p2 and p3 = %v, want %v", got, want)
+	}
+	if patched.RuleSet().Get("c") != index.RuleSet().Get("c") {
+		t.Error("rules of an unchanged coupon were not shared")
+	}
+
+	// The index it was patched from is left as it is
+	if got, want := candidateIds(index, "p1", "p2", "p3"), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("original candidates = %v, want %v", got, want)
+	}
+}
+
+func TestCouponChangedPatchesIndex(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext("")
+	first := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "p1", Discount: 10}})
+
+	index, err := service.getCouponIndex(ctx)
+	if err != nil {
+		t.Fatalf("getCouponIndex() error = %v", err)
+	}
+
+	// A new coupon is added without reading the others again
+	second := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "p2", Discount: 10}})
+	patched, err := service.getCouponIndex(ctx)
+	if err != nil {
+		t.Fatalf("getCouponIndex() error = %v", err)
+	}
+	if got, want := candidateIds(patched, "p1", "p2"), []string{first, second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates = %v, want %v", got, want)
+	}
+	if patched.RuleSet().Get(first) != index.RuleSet().Get(first) {
+		t.Error("rules of the unchanged coupon were read again")
+	}
+
+	// Ended and deleted coupons are taken out
+	_, err = service.EndCoupon(testContext(testReviewer, roleApprover), first)
+	if err != nil {
+		t.Fatalf("EndCoupon() error = %v", err)
+	}
+	err = service.DeleteCoupon(testContext(testEditor), second)
+	if err != nil {
+		t.Fatalf("DeleteCoupon() error = %v", err)
+	}
+	patched, err = service.getCouponIndex(ctx)
+	if err != nil {
+		t.Fatalf("getCouponIndex() error = %v", err)
+	}
+	if got := patched.RuleSet().IDs(); len(got) != 0 {
+		t.Errorf("coupons = %v, want none", got)
+	}
+}
@@ -0,0 +1,677 @@
+package services
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"slices"
+	"strings"
+	"time"
+
+	"monk-commerce-assignment/constants"
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Role of the users who review coupons and their revisions
+const roleApprover = "approver"
+
+const (
+	couponLifecycleInterval = time.Minute
+	// Coupons moved in one transaction each, looked up this many at a time
+	couponLifecycleBatchSize = 100
+)
+
+// couponDefinition returns the coupon a request defines as it is kept: the
+// code in upper case, times in UTC and the display text by locale
+func couponDefinition(req *dtos.Coupon) dtos.Coupon {
+	definition := *req
+	definition.Code = normalizeCode(req.Code)
+	definition.StartsAt = utc(req.StartsAt)
+	definition.EndsAt = utc(req.EndsAt)
+	definition.Translations = displayTranslations(req)
+	definition.Name, definition.Badge, definition.Description, definition.Terms, definition.Locale = "", "", "", "", ""
+
+	// Fields set by the service are not part of the definition
+	definition.Id = ""
+	definition.CreatedAt = time.Time{}
+	definition.DeletedAt = nil
+	setWorkflow(&definition, &models.Coupon{})
+	return definition
+}
+
+// applyDefinition sets the columns of a coupon row from a definition
+func applyDefinition(coupon *models.Coupon, definition *dtos.Coupon) {
+	coupon.Type = definition.Type
+	coupon.Code = definition.Code
+	coupon.Condition = definition.Condition
+	coupon.Metadata = models.JSON(definition.Metadata)
+	coupon.StartsAt = definition.StartsAt
+	coupon.EndsAt = definition.EndsAt
+	coupon.CampaignID = campaignRef(definition.CampaignId)
+}
+
+// setWorkflow copies where a coupon is in the approval workflow to its DTO
+func setWorkflow(couponDto *dtos.Coupon, coupon *models.Coupon) {
+	couponDto.Status = coupon.Status
+	couponDto.EditedBy = coupon.EditedBy
+	couponDto.SubmittedBy = coupon.SubmittedBy
+	couponDto.ApprovedBy = coupon.ApprovedBy
+	couponDto.ApprovedAt = coupon.ApprovedAt
+	couponDto.RejectionReason = coupon.RejectionReason
+	couponDto.PendingRevision = nil
+}
+
+// approvedStatus is the status of an approved coupon at the given time: live
+// within its validity window, approved before it and ended after it
+func approvedStatus(coupon *models.Coupon, now time.Time) string {
+	switch coupon.ValidityState(now) {
+	case models.CouponScheduled:
+		return models.StatusApproved
+	case models.CouponExpired:
+		return models.StatusEnded
+	default:
+		return models.StatusLive
+	}
+}
+
+// checkReviewer makes sure the actor may review a change made by the given
+// users: reviewers need the approver role and cannot review their own changes
+func checkReviewer(ctx *context.Context, couponId string, authors ...string) error {
+	if ctx.Actor == "" || !slices.Contains(ctx.Roles, roleApprover) {
+		return errors.Forbidden("reviewing coupons requires the %s role", roleApprover)
+	}
+	if slices.Contains(authors, ctx.Actor) {
+		return errors.Forbidden("coupon %s was changed by %s, it has to be reviewed by someone else", couponId, ctx.Actor)
+	}
+	return nil
+}
+
+// staleCoupon reports a coupon that changed status since it was read, other
+// errors are returned as they are
+func staleCoupon(couponId string, err error) error {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Conflict("coupon %s was changed by another request, try again", couponId).Wrap(err)
+	}
+	return err
+}
+
+// getLiveCouponRules loads a coupon that is not deleted, with its details
+func (c *CouponService) getLiveCouponRules(ctx *context.Context, couponId string) (*models.CouponRules, error) {
+	rules, err := c.getCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Coupon.DeletedAt != nil {
+		return nil, errors.Conflict("coupon %s is deleted", couponId)
+	}
+	return rules, nil
+}
+
+// updateCoupon writes a coupon row that still has the given status, it must
+// run in a transaction
+func (c *CouponService) updateCoupon(tx *context.Context, coupon *models.Coupon, status string) error {
+	err := c.db.UpdateCoupon(tx, coupon, status)
+	if err != nil {
+		tx.Log.Error("failed to update coupon", zap.Error(err))
+		return err
+	}
+
+	// Let every instance know about the change once the transaction commits
+	err = c.db.NotifyCouponChanged(tx, coupon.Id)
+	if err != nil {
+		tx.Log.Error("failed to notify coupon change", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// transition moves a coupon along the workflow without changing its
+// definition, and records it in the audit log
+func (c *CouponService) transition(ctx *context.Context, rules *models.CouponRules, updated *models.Coupon, action string) (*dtos.Coupon, error) {
+	couponId := updated.Id
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
+	after := *before
+	setWorkflow(&after, updated)
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.updateCoupon(tx, updated, rules.Coupon.Status)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(tx, couponId, action, before, &after)
+	})
+	if err != nil {
+		return nil, staleCoupon(couponId, err)
+	}
+
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
+
+	localizeCoupon(&after, rules.Translations, ctx.Locales)
+	return &after, nil
+}
+
+// UpdateCoupon replaces the definition of a coupon. Drafts and coupons
+// pending approval are changed in place and go back to draft. Approved and
+// live coupons keep applying as they are: the change is kept as a revision
+// until it is approved.
+//
+// The examples of the coupon are run against the new definition first, and a
+// change that breaks any of them is rejected unless force is set.
+func (c *CouponService) UpdateCoupon(ctx *context.Context, couponId string, req *dtos.Coupon, force bool) (*dtos.Coupon, error) {
+	// Reject invalid definitions before touching the database
+	if err := validateCoupon(req); err != nil {
+		return nil, err
+	}
+	if err := c.checkCampaign(ctx, req.CampaignId); err != nil {
+		return nil, err
+	}
+
+	rules, err := c.getLiveCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	definition := couponDefinition(req)
+
+	status := rules.Coupon.Status
+	if status == models.StatusEnded {
+		return nil, errors.Conflict("coupon %s is %s and can no longer be changed", couponId, status)
+	}
+	if force {
+		ctx.Log.Info("changing coupon without checking its examples", zap.String("coupon_id", couponId))
+	} else if err := c.checkCouponExamples(ctx, couponId, req); err != nil {
+		return nil, err
+	}
+
+	if status == models.StatusDraft || status == models.StatusPendingApproval {
+		return c.editCoupon(ctx, rules, &definition)
+	}
+	return c.reviseCoupon(ctx, rules, &definition)
+}
+
+// editCoupon replaces the definition of a coupon that is not approved yet
+func (c *CouponService) editCoupon(ctx *context.Context, rules *models.CouponRules, definition *dtos.Coupon) (*dtos.Coupon, error) {
+	updated := *rules.Coupon
+	couponId := updated.Id
+	applyDefinition(&updated, definition)
+	updated.Status = models.StatusDraft
+	updated.EditedBy = ctx.Actor
+	updated.SubmittedBy = ""
+	updated.UpdatedAt = time.Now()
+
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
+	after := *definition
+	after.Id = couponId
+	after.CreatedAt = updated.CreatedAt
+	setWorkflow(&after, &updated)
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.updateCoupon(tx, &updated, rules.Coupon.Status)
+		if err != nil {
+			return err
+		}
+		err = c.replaceCouponDetails(tx, rules.Coupon, definition)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditUpdated, before, &after)
+	})
+	if err != nil {
+		return nil, staleCoupon(couponId, couponCodeTaken(updated.Code, err))
+	}
+
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
+
+	localizeCoupon(&after, couponTranslations(couponId, after.Translations), ctx.Locales)
+	return &after, nil
+}
+
+// replaceCouponDetails swaps the details of a coupon for the ones of a
+// definition, it must run in a transaction
+func (c *CouponService) replaceCouponDetails(tx *context.Context, coupon *models.Coupon, definition *dtos.Coupon) error {
+	err := c.removeCouponDetails(tx, coupon)
+	if err != nil {
+		return err
+	}
+	return c.persistCouponDetails(tx, coupon.Id, definition)
+}
+
+// reviseCoupon keeps a change of an approved or live coupon as a revision
+// waiting for review
+func (c *CouponService) reviseCoupon(ctx *context.Context, rules *models.CouponRules, definition *dtos.Coupon) (*dtos.Coupon, error) {
+	couponId := rules.Coupon.Id
+	pending, err := c.db.GetPendingCouponRevision(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, errors.Conflict("coupon %s already has a revision pending approval", couponId)
+	}
+
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	revision := &models.CouponRevision{
+		Id:          uuid.New().String(),
+		CouponID:    couponId,
+		Status:      models.StatusPendingApproval,
+		Definition:  models.JSON(data),
+		SubmittedBy: ctx.Actor,
+		CreatedAt:   time.Now(),
+	}
+
+	// The audit entry shows what the revision would change
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
+	proposed := revisedCoupon(before, definition)
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.PersistCouponRevision(tx, revision)
+		if err != nil {
+			tx.Log.Error("failed to persist coupon revision", zap.Error(err))
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditRevised, before, proposed)
+	})
+	if err != nil {
+		if errors.From(err).Code == errors.CodeConflict {
+			return nil, errors.Conflict("coupon %s already has a revision pending approval", couponId).Wrap(err)
+		}
+		return nil, err
+	}
+
+	current := *before
+	current.PendingRevision = toRevisionDto(revision, proposed)
+	localizeCoupon(&current, rules.Translations, ctx.Locales)
+	return &current, nil
+}
+
+// revisedCoupon is a coupon as it will be once a revision is approved
+func revisedCoupon(coupon *dtos.Coupon, definition *dtos.Coupon) *dtos.Coupon {
+	revised := *definition
+	revised.Id = coupon.Id
+	revised.CreatedAt = coupon.CreatedAt
+	revised.Status = coupon.Status
+	revised.EditedBy = coupon.EditedBy
+	revised.SubmittedBy = coupon.SubmittedBy
+	revised.ApprovedBy = coupon.ApprovedBy
+	revised.ApprovedAt = coupon.ApprovedAt
+	return &revised
+}
+
+func toRevisionDto(revision *models.CouponRevision, definition *dtos.Coupon) *dtos.CouponRevision {
+	return &dtos.CouponRevision{
+		Id:          revision.Id,
+		Status:      revision.Status,
+		Definition:  definition,
+		SubmittedBy: revision.SubmittedBy,
+		ReviewedBy:  revision.ReviewedBy,
+		Reason:      revision.Reason,
+		CreatedAt:   revision.CreatedAt,
+		ReviewedAt:  revision.ReviewedAt,
+	}
+}
+
+// revisionDefinition decodes the definition a revision was sent with
+func revisionDefinition(revision *models.CouponRevision) (*dtos.Coupon, error) {
+	var definition dtos.Coupon
+	if err := json.Unmarshal(revision.Definition, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// pendingRevision returns the revision of a coupon waiting for review, as it
+// is shown with the coupon, or nil if there is none
+func (c *CouponService) pendingRevision(ctx *context.Context, couponDto *dtos.Coupon) (*dtos.CouponRevision, error) {
+	revision, err := c.db.GetPendingCouponRevision(ctx, couponDto.Id)
+	if err != nil || revision == nil {
+		return nil, err
+	}
+	definition, err := revisionDefinition(revision)
+	if err != nil {
+		return nil, err
+	}
+	proposed := revisedCoupon(couponDto, definition)
+	localizeCoupon(proposed, couponTranslations(couponDto.Id, proposed.Translations), ctx.Locales)
+	return toRevisionDto(revision, proposed), nil
+}
+
+// SubmitCoupon sends a draft for approval
+func (c *CouponService) SubmitCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error) {
+	rules, err := c.getLiveCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if rules.Coupon.Status != models.StatusDraft {
+		return nil, errors.Conflict("coupon %s is %s, only drafts can be submitted", couponId, rules.Coupon.Status)
+	}
+
+	updated := *rules.Coupon
+	updated.Status = models.StatusPendingApproval
+	updated.SubmittedBy = ctx.Actor
+	updated.RejectionReason = ""
+	updated.UpdatedAt = time.Now()
+	return c.transition(ctx, rules, &updated, models.AuditSubmitted)
+}
+
+// ApproveCoupon approves a coupon pending approval, or the pending revision
+// of an approved or live coupon. The approver needs the approver role and
+// cannot be who edited or submitted the change.
+func (c *CouponService) ApproveCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error) {
+	rules, err := c.getLiveCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	coupon := rules.Coupon
+
+	if coupon.Status == models.StatusPendingApproval {
+		if err := checkReviewer(ctx, couponId, coupon.EditedBy, coupon.SubmittedBy); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		updated := *coupon
+		updated.Status = approvedStatus(coupon, now)
+		if updated.Status == models.StatusEnded {
+			return nil, errors.Conflict("coupon %s expired at %s, change its validity window before approving it", couponId, coupon.EndsAt.Format(time.RFC3339))
+		}
+		updated.ApprovedBy = ctx.Actor
+		updated.ApprovedAt = &now
+		updated.UpdatedAt = now
+		return c.transition(ctx, rules, &updated, models.AuditApproved)
+	}
+
+	// Revisions of ended coupons can only be rejected
+	if !coupon.Evaluable() {
+		return nil, errors.Conflict("coupon %s is %s and cannot be approved", couponId, coupon.Status)
+	}
+	revision, err := c.db.GetPendingCouponRevision(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, errors.Conflict("coupon %s is %s and has no revision pending approval", couponId, coupon.Status)
+	}
+	if err := checkReviewer(ctx, couponId, revision.SubmittedBy); err != nil {
+		return nil, err
+	}
+	return c.approveRevision(ctx, rules, revision)
+}
+
+// approveRevision applies a revision to its coupon
+func (c *CouponService) approveRevision(ctx *context.Context, rules *models.CouponRules, revision *models.CouponRevision) (*dtos.Coupon, error) {
+	couponId := rules.Coupon.Id
+	definition, err := revisionDefinition(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated := *rules.Coupon
+	applyDefinition(&updated, definition)
+	updated.Status = approvedStatus(&updated, now)
+	updated.EditedBy = revision.SubmittedBy
+	updated.SubmittedBy = revision.SubmittedBy
+	updated.ApprovedBy = ctx.Actor
+	updated.ApprovedAt = &now
+	updated.UpdatedAt = now
+
+	reviewed := *revision
+	reviewed.Status = models.RevisionApproved
+	reviewed.ReviewedBy = ctx.Actor
+	reviewed.ReviewedAt = &now
+
+	before, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
+	after := *definition
+	after.Id = couponId
+	after.CreatedAt = updated.CreatedAt
+	setWorkflow(&after, &updated)
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.updateCoupon(tx, &updated, rules.Coupon.Status)
+		if err != nil {
+			return err
+		}
+		err = c.replaceCouponDetails(tx, rules.Coupon, definition)
+		if err != nil {
+			return err
+		}
+		err = c.db.UpdateCouponRevision(tx, &reviewed, models.StatusPendingApproval)
+		if err != nil {
+			tx.Log.Error("failed to update coupon revision", zap.Error(err))
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditApproved, before, &after)
+	})
+	if err != nil {
+		return nil, staleCoupon(couponId, couponCodeTaken(updated.Code, err))
+	}
+
+	// Publish the change to the coupon index
+	c.couponChanged(ctx, couponId)
+
+	localizeCoupon(&after, couponTranslations(couponId, after.Translations), ctx.Locales)
+	return &after, nil
+}
+
+// RejectCoupon sends a coupon pending approval back to draft, or rejects the
+// pending revision of an approved or live coupon, which keeps applying as it
+// is. Like approvals, rejections need the approver role and someone else
+// than the author of the change.
+func (c *CouponService) RejectCoupon(ctx *context.Context, couponId string, req dtos.ReviewRequest) (*dtos.Coupon, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		v := &validator{}
+		v.report("reason", "is required")
+		return nil, v.err("invalid rejection")
+	}
+
+	rules, err := c.getLiveCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	coupon := rules.Coupon
+
+	if coupon.Status == models.StatusPendingApproval {
+		if err := checkReviewer(ctx, couponId, coupon.EditedBy, coupon.SubmittedBy); err != nil {
+			return nil, err
+		}
+
+		updated := *coupon
+		updated.Status = models.StatusDraft
+		updated.RejectionReason = reason
+		updated.UpdatedAt = time.Now()
+		return c.transition(ctx, rules, &updated, models.AuditRejected)
+	}
+
+	revision, err := c.db.GetPendingCouponRevision(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, errors.Conflict("coupon %s is %s and has no revision pending approval", couponId, coupon.Status)
+	}
+	if err := checkReviewer(ctx, couponId, revision.SubmittedBy); err != nil {
+		return nil, err
+	}
+
+	current, err := c.closeRevision(ctx, rules, revision, reason)
+	if err != nil {
+		return nil, err
+	}
+	localizeCoupon(current, rules.Translations, ctx.Locales)
+	return current, nil
+}
+
+// closeRevision rejects a pending revision and records it, with the change
+// it would have made, in the audit log
+func (c *CouponService) closeRevision(ctx *context.Context, rules *models.CouponRules, revision *models.CouponRevision, reason string) (*dtos.Coupon, error) {
+	couponId := rules.Coupon.Id
+	definition, err := revisionDefinition(revision)
+	if err != nil {
+		return nil, err
+	}
+	current, err := toCouponDto(rules)
+	if err != nil {
+		return nil, err
+	}
+	proposed := revisedCoupon(current, definition)
+
+	now := time.Now()
+	reviewed := *revision
+	reviewed.Status = models.RevisionRejected
+	reviewed.ReviewedBy = ctx.Actor
+	reviewed.Reason = reason
+	reviewed.ReviewedAt = &now
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.UpdateCouponRevision(tx, &reviewed, models.StatusPendingApproval)
+		if err != nil {
+			tx.Log.Error("failed to update coupon revision", zap.Error(err))
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditRejected, current, proposed)
+	})
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Conflict("revision %s of coupon %s was already reviewed", revision.Id, couponId).Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// EndCoupon stops an approved or live coupon for good, e.g. because it was
+// set up wrong. Any user can end a coupon: it only ever stops discounts.
+func (c *CouponService) EndCoupon(ctx *context.Context, couponId string) (*dtos.Coupon, error) {
+	rules, err := c.getLiveCouponRules(ctx, couponId)
+	if err != nil {
+		return nil, err
+	}
+	coupon := rules.Coupon
+	if !coupon.Evaluable() {
+		return nil, errors.Conflict("coupon %s is %s, only approved and live coupons can be ended", couponId, coupon.Status)
+	}
+
+	updated := *coupon
+	updated.Status = models.StatusEnded
+	updated.UpdatedAt = time.Now()
+	return c.transition(ctx, rules, &updated, models.AuditEnded)
+}
+
+// AdvanceCouponLifecycle moves approved coupons to live once their validity
+// window starts, and approved and live coupons to ended once it is over. It
+// returns how many coupons were moved.
+func (c *CouponService) AdvanceCouponLifecycle(ctx *context.Context) (int, error) {
+	transitions := []struct {
+		from, state, to, action string
+	}{
+		{models.StatusApproved, models.CouponLive, models.StatusLive, models.AuditWentLive},
+		{models.StatusApproved, models.CouponExpired, models.StatusEnded, models.AuditEnded},
+		{models.StatusLive, models.CouponExpired, models.StatusEnded, models.AuditEnded},
+	}
+
+	moved := 0
+	for _, t := range transitions {
+		query := &daos.CouponQuery{
+			Status: t.from,
+			State:  t.state,
+			Now:    time.Now(),
+			Limit:  couponLifecycleBatchSize,
+		}
+		for {
+			coupons, err := c.db.ListCoupons(ctx, query)
+			if err != nil {
+				return moved, err
+			}
+
+			for _, coupon := range coupons {
+				ok, err := c.advanceCoupon(ctx, coupon, t.to, t.action)
+				if err != nil {
+					return moved, err
+				}
+				if ok {
+					moved++
+				}
+			}
+
+			if len(coupons) < couponLifecycleBatchSize {
+				break
+			}
+			last := coupons[len(coupons)-1]
+			query.After = &daos.CouponCursor{CreatedAt: last.CreatedAt, Id: last.Id}
+		}
+	}
+	return moved, nil
+}
+
+// advanceCoupon moves a coupon to the given status. It reports false if the
+// coupon changed status since it was listed.
+func (c *CouponService) advanceCoupon(ctx *context.Context, coupon *models.Coupon, status string, action string) (bool, error) {
+	ruleSet, err := c.db.LoadRuleSet(ctx, []*models.Coupon{coupon})
+	if err != nil {
+		return false, err
+	}
+
+	updated := *coupon
+	updated.Status = status
+	updated.UpdatedAt = time.Now()
+	_, err = c.transition(ctx, ruleSet.Get(coupon.Id), &updated, action)
+	if err != nil {
+		if errors.From(err).Code == errors.CodeConflict {
+			return false, nil
+		}
+		return false, err
+	}
+
+	ctx.Log.Info("advanced coupon lifecycle", zap.String("coupon_id", coupon.Id), zap.String("status", status))
+	return true, nil
+}
+
+// StartCouponLifecycle moves coupons along their lifecycle as their validity
+// windows start and end, once at startup and then every minute
+func StartCouponLifecycle(repositories daos.Repositories) {
+	service := &CouponService{
+		db:        daos.NewCachedCoupon(repositories.Coupons),
+		customers: repositories.Customers,
+	}
+	go advanceCoupons(service)
+}
+
+func advanceCoupons(service *CouponService) {
+	ticker := time.NewTicker(couponLifecycleInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx := context.Background("coupon-lifecycle", constants.Logger)
+		moved, err := service.AdvanceCouponLifecycle(ctx)
+		if err != nil {
+			ctx.Log.Error("unable to advance coupon lifecycle", zap.Error(err))
+		} else if moved > 0 {
+			ctx.Log.Info("advanced coupon lifecycle", zap.Int("count", moved))
+		}
+		<-ticker.C
+	}
+}
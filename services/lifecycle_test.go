@@ -0,0 +1,332 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// createDraft creates a cart-wise coupon as the test editor
+func createDraft(t *testing.T, service ICouponService, discount int) string {
+	t.Helper()
+	created, err := service.CreateCoupon(testContext(testEditor), &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: discount}})
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+	return created.Id
+}
+
+func assertStatus(t *testing.T, service ICouponService, couponId string, want string) *dtos.Coupon {
+	t.Helper()
+	coupon, err := service.GetCouponById(testContext(testEditor), couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	if coupon.Status != want {
+		t.Errorf("status = %s, want %s", coupon.Status, want)
+	}
+	return coupon
+}
+
+// setValidity moves the validity window of a coupon as if time had passed
+func setValidity(t *testing.T, repositories daos.Repositories, couponId string, startsAt, endsAt *time.Time) {
+	t.Helper()
+	ctx := testContext(testEditor)
+	coupon, err := repositories.Coupons.GetCouponById(ctx, couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	updated := *coupon
+	updated.StartsAt = startsAt
+	updated.EndsAt = endsAt
+	updated.UpdatedAt = time.Now()
+	if err := repositories.Coupons.UpdateCoupon(ctx, &updated, coupon.Status); err != nil {
+		t.Fatalf("UpdateCoupon() error = %v", err)
+	}
+	daos.InvalidateCoupon(couponId)
+}
+
+func TestCouponApprovalWorkflow(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	reviewer := testContext(testReviewer, roleApprover)
+	couponId := createDraft(t, service, 10)
+	assertStatus(t, service, couponId, models.StatusDraft)
+
+	if _, err := service.SubmitCoupon(editor, couponId); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	assertStatus(t, service, couponId, models.StatusPendingApproval)
+
+	// A rejected coupon goes back to draft with the reason for its editor
+	if _, err := service.RejectCoupon(reviewer, couponId, dtos.ReviewRequest{Reason: " too generous "}); err != nil {
+		t.Fatalf("RejectCoupon() error = %v", err)
+	}
+	coupon := assertStatus(t, service, couponId, models.StatusDraft)
+	if coupon.RejectionReason != "too generous" {
+		t.Errorf("rejection reason = %q, want %q", coupon.RejectionReason, "too generous")
+	}
+
+	if _, err := service.SubmitCoupon(editor, couponId); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	coupon = assertStatus(t, service, couponId, models.StatusPendingApproval)
+	if coupon.RejectionReason != "" {
+		t.Errorf("rejection reason = %q after submitting again, want none", coupon.RejectionReason)
+	}
+
+	if _, err := service.ApproveCoupon(reviewer, couponId); err != nil {
+		t.Fatalf("ApproveCoupon() error = %v", err)
+	}
+	coupon = assertStatus(t, service, couponId, models.StatusLive)
+	if coupon.ApprovedBy != testReviewer || coupon.ApprovedAt == nil {
+		t.Errorf("approved by %q at %v, want %q", coupon.ApprovedBy, coupon.ApprovedAt, testReviewer)
+	}
+}
+
+func TestApprovalBeforeTheValidityWindow(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	startsAt := time.Now().Add(time.Hour)
+	created, err := service.CreateCoupon(editor, &dtos.Coupon{Type: "cart-wise", StartsAt: &startsAt, Details: dtos.CouponDetails{Discount: 10}})
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+	if _, err := service.SubmitCoupon(editor, created.Id); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	if _, err := service.ApproveCoupon(testContext(testReviewer, roleApprover), created.Id); err != nil {
+		t.Fatalf("ApproveCoupon() error = %v", err)
+	}
+	assertStatus(t, service, created.Id, models.StatusApproved)
+}
+
+func TestCouponRevisions(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	reviewer := testContext(testReviewer, roleApprover)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 100}}}
+	revise := func() {
+		t.Helper()
+		_, err := service.UpdateCoupon(editor, couponId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 20}}, false)
+		if err != nil {
+			t.Fatalf("UpdateCoupon() error = %v", err)
+		}
+	}
+
+	// The live coupon keeps applying as it is while the revision is reviewed
+	revise()
+	coupon := assertStatus(t, service, couponId, models.StatusLive)
+	if coupon.PendingRevision == nil || coupon.PendingRevision.Definition.Details.Discount != 20 {
+		t.Fatalf("pending revision = %+v, want a discount of 20", coupon.PendingRevision)
+	}
+	assertMoney(t, "discount", applyCoupon(t, service, couponId, cart).TotalDiscount, 10)
+
+	_, err := service.UpdateCoupon(editor, couponId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 30}}, false)
+	if errorCode(err) != errors.CodeConflict {
+		t.Errorf("second revision error = %v, want a conflict", err)
+	}
+
+	if _, err := service.RejectCoupon(reviewer, couponId, dtos.ReviewRequest{Reason: "no"}); err != nil {
+		t.Fatalf("RejectCoupon() error = %v", err)
+	}
+	coupon = assertStatus(t, service, couponId, models.StatusLive)
+	if coupon.PendingRevision != nil {
+		t.Errorf("pending revision = %+v after rejecting it, want none", coupon.PendingRevision)
+	}
+	assertMoney(t, "discount", applyCoupon(t, service, couponId, cart).TotalDiscount, 10)
+
+	revise()
+	if _, err := service.ApproveCoupon(reviewer, couponId); err != nil {
+		t.Fatalf("ApproveCoupon() error = %v", err)
+	}
+	coupon = assertStatus(t, service, couponId, models.StatusLive)
+	if coupon.PendingRevision != nil || coupon.EditedBy != testEditor {
+		t.Errorf("coupon = %+v, want the revision applied as edited by %s", coupon, testEditor)
+	}
+	assertMoney(t, "discount", applyCoupon(t, service, couponId, cart).TotalDiscount, 20)
+}
+
+func TestEndCoupon(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	if _, err := service.EndCoupon(editor, couponId); err != nil {
+		t.Fatalf("EndCoupon() error = %v", err)
+	}
+	assertStatus(t, service, couponId, models.StatusEnded)
+
+	cart := dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 100}}}
+	if _, err := service.ApplyCoupon(editor, couponId, cart, nil); errorCode(err) != errors.CodeNotApplicable {
+		t.Errorf("ApplyCoupon() error = %v, want not applicable", err)
+	}
+	_, err := service.UpdateCoupon(editor, couponId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 20}}, false)
+	if errorCode(err) != errors.CodeConflict {
+		t.Errorf("UpdateCoupon() error = %v, want a conflict", err)
+	}
+}
+
+func TestInvalidTransitions(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	reviewer := testContext(testReviewer, roleApprover)
+	draftId := createDraft(t, service, 10)
+	liveId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	endedId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	if _, err := service.EndCoupon(editor, endedId); err != nil {
+		t.Fatalf("EndCoupon() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"approving a draft", func() error {
+			_, err := service.ApproveCoupon(reviewer, draftId)
+			return err
+		}},
+		{"rejecting a draft", func() error {
+			_, err := service.RejectCoupon(reviewer, draftId, dtos.ReviewRequest{Reason: "no"})
+			return err
+		}},
+		{"ending a draft", func() error {
+			_, err := service.EndCoupon(editor, draftId)
+			return err
+		}},
+		{"submitting a live coupon", func() error {
+			_, err := service.SubmitCoupon(editor, liveId)
+			return err
+		}},
+		{"approving a live coupon without a revision", func() error {
+			_, err := service.ApproveCoupon(reviewer, liveId)
+			return err
+		}},
+		{"approving an ended coupon", func() error {
+			_, err := service.ApproveCoupon(reviewer, endedId)
+			return err
+		}},
+		{"ending an ended coupon", func() error {
+			_, err := service.EndCoupon(editor, endedId)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); errorCode(err) != errors.CodeConflict {
+				t.Errorf("error = %v, want a conflict", err)
+			}
+		})
+	}
+}
+
+func TestReviewerRules(t *testing.T) {
+	service, _ := newTestService(t)
+	editor := testContext(testEditor)
+	pendingId := createDraft(t, service, 10)
+	if _, err := service.SubmitCoupon(editor, pendingId); err != nil {
+		t.Fatalf("SubmitCoupon() error = %v", err)
+	}
+	// The reviewer submits the revision, so only someone else may review it
+	revisedId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	_, err := service.UpdateCoupon(testContext(testReviewer), revisedId, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 20}}, false)
+	if err != nil {
+		t.Fatalf("UpdateCoupon() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		couponId string
+		actor    string
+		roles    []string
+		allowed  bool
+	}{
+		{"anonymous", pendingId, "", []string{roleApprover}, false},
+		{"without the role", pendingId, testReviewer, nil, false},
+		{"editor of the coupon", pendingId, testEditor, []string{roleApprover}, false},
+		{"submitter of the revision", revisedId, testReviewer, []string{roleApprover}, false},
+		{"editor of the coupon reviewing a revision", revisedId, testEditor, []string{roleApprover}, true},
+		{"reviewer", pendingId, testReviewer, []string{roleApprover}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext(tt.actor, tt.roles...)
+			_, rejectErr := service.RejectCoupon(ctx, tt.couponId, dtos.ReviewRequest{Reason: "no"})
+			if !tt.allowed {
+				_, approveErr := service.ApproveCoupon(ctx, tt.couponId)
+				if errorCode(approveErr) != errors.CodeForbidden || errorCode(rejectErr) != errors.CodeForbidden {
+					t.Errorf("ApproveCoupon() error = %v, RejectCoupon() error = %v, want forbidden", approveErr, rejectErr)
+				}
+				return
+			}
+			if rejectErr != nil {
+				t.Errorf("RejectCoupon() error = %v", rejectErr)
+			}
+		})
+	}
+}
+
+func TestAdvanceCouponLifecycle(t *testing.T) {
+	service, repositories := newTestService(t)
+	ctx := testContext("")
+	now := time.Now()
+	hourAgo, later := now.Add(-time.Hour), now.Add(time.Hour)
+
+	scheduled := func() string {
+		t.Helper()
+		created, err := service.CreateCoupon(testContext(testEditor), &dtos.Coupon{Type: "cart-wise", StartsAt: &later, Details: dtos.CouponDetails{Discount: 10}})
+		if err != nil {
+			t.Fatalf("CreateCoupon() error = %v", err)
+		}
+		if _, err := service.SubmitCoupon(testContext(testEditor), created.Id); err != nil {
+			t.Fatalf("SubmitCoupon() error = %v", err)
+		}
+		if _, err := service.ApproveCoupon(testContext(testReviewer, roleApprover), created.Id); err != nil {
+			t.Fatalf("ApproveCoupon() error = %v", err)
+		}
+		return created.Id
+	}
+	startedId := scheduled()
+	stillScheduledId := scheduled()
+	missedId := scheduled()
+	expiredId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	draftId := createDraft(t, service, 10)
+
+	setValidity(t, repositories, startedId, &hourAgo, nil)
+	setValidity(t, repositories, missedId, &hourAgo, &hourAgo)
+	setValidity(t, repositories, expiredId, nil, &hourAgo)
+	setValidity(t, repositories, draftId, nil, &hourAgo)
+
+	moved, err := service.AdvanceCouponLifecycle(ctx)
+	if err != nil {
+		t.Fatalf("AdvanceCouponLifecycle() error = %v", err)
+	}
+	if moved != 3 {
+		t.Errorf("AdvanceCouponLifecycle() = %d, want 3", moved)
+	}
+	assertStatus(t, service, startedId, models.StatusLive)
+	assertStatus(t, service, stillScheduledId, models.StatusApproved)
+	assertStatus(t, service, missedId, models.StatusEnded)
+	assertStatus(t, service, expiredId, models.StatusEnded)
+	assertStatus(t, service, draftId, models.StatusDraft)
+
+	entries, err := repositories.Coupons.ListCouponAuditEntries(ctx, &daos.AuditQuery{CouponId: startedId, Action: models.AuditWentLive, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListCouponAuditEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d went live entries, want 1", len(entries))
+	}
+
+	// Nothing is left to move
+	if moved, err := service.AdvanceCouponLifecycle(ctx); err != nil || moved != 0 {
+		t.Errorf("AdvanceCouponLifecycle() = %d, %v, want 0", moved, err)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// ListCoupons returns a page of coupons matching the filters of the query.
+// The filters are applied by the storage, only the coupons of the page are
+// loaded with their details.
+func (c *CouponService) ListCoupons(ctx *context.Context, req dtos.CouponListQuery) (*dtos.CouponPage, error) {
+	query, err := couponQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra coupon to find out whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	coupons, err := c.db.ListCoupons(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dtos.CouponPage{
+		Coupons: make([]*dtos.Coupon, 0, len(coupons)),
+	}
+	if len(coupons) > limit {
+		coupons = coupons[:limit]
+		last := coupons[limit-1]
+		page.NextCursor = encodeCursor(&daos.CouponCursor{
+			CreatedAt: last.CreatedAt,
+			Id:        last.Id,
+		})
+	}
+
+	// Bulk-load the details of the coupons on the page
+	ruleSet, err := c.db.LoadRuleSet(ctx, coupons)
+	if err != nil {
+		return nil, err
+	}
+	for _, rules := range ruleSet.Coupons {
+		couponDto, err := toCouponDto(rules)
+		if err != nil {
+			return nil, err
+		}
+		localizeCoupon(couponDto, rules.Translations, ctx.Locales)
+		page.Coupons = append(page.Coupons, couponDto)
+	}
+
+	return page, nil
+}
+
+// couponQuery checks the parameters of a listing request and turns them into
+// a storage query
+func couponQuery(req dtos.CouponListQuery) (*daos.CouponQuery, error) {
+	v := &validator{}
+
+	query := &daos.CouponQuery{
+		Type:       req.Type,
+		Active:     req.Active,
+		State:      req.State,
+		Now:        time.Now(),
+		ProductId:  req.ProductId,
+		CodePrefix: normalizeCode(req.CodePrefix),
+		Status:     req.Status,
+		Deleted:    req.Deleted,
+		Limit:      req.Limit,
+	}
+
+	if req.Type != "" {
+		oneOf(couponTypeNames()...)(v, "type", req.Type)
+	}
+	if req.State != "" {
+		oneOf(models.CouponScheduled, models.CouponLive, models.CouponExpired)(v, "state", req.State)
+	}
+	if req.Status != "" {
+		oneOf(models.StatusDraft, models.StatusPendingApproval, models.StatusApproved, models.StatusLive, models.StatusEnded)(v, "status", req.Status)
+	}
+
+	switch req.Sort {
+	case "", "created_at":
+	case "-created_at":
+		query.Descending = true
+	default:
+		v.report("sort", "must be one of created_at, -created_at")
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit < 1 || query.Limit > maxPageSize {
+		v.report("limit", "must be between 1 and %d", maxPageSize)
+	}
+
+	if req.Cursor != "" {
+		cursor, ok := decodeCursor(req.Cursor)
+		if !ok {
+			v.report("cursor", "is invalid")
+		}
+		query.After = cursor
+	}
+
+	if err := v.err("invalid coupon query"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// Cursors are opaque to clients: the creation time in nanoseconds and the ID
+// of the last coupon of a page
+func encodeCursor(cursor *daos.CouponCursor) string {
+	value := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + ":" + cursor.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeCursor(s string) (*daos.CouponCursor, bool) {
+	value, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	nanos, id, found := strings.Cut(string(value), ":")
+	if !found || id == "" {
+		return nil, false
+	}
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &daos.CouponCursor{
+		CreatedAt: time.Unix(0, createdAt).UTC(),
+		Id:        id,
+	}, true
+}
+
+// normalizeCode trims a coupon code and puts it in upper case, codes are
+// matched without regard to case
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// couponCodeTaken reports a coupon code that is already in use, other errors
+// are returned as they are
+func couponCodeTaken(code string, err error) error {
+	if code != "" && errors.From(err).Code == errors.CodeConflict {
+		return errors.Conflict("coupon code %s is already in use", code).Wrap(err)
+	}
+	return err
+}
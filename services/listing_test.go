@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/base64"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+)
+
+// listAll pages through the coupons matching req and returns their IDs
+func listAll(t *testing.T, service ICouponService, req dtos.CouponListQuery) []string {
+	t.Helper()
+	var ids []string
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("ListCoupons() does not stop returning pages")
+		}
+		result, err := service.ListCoupons(testContext(""), req)
+		if err != nil {
+			t.Fatalf("ListCoupons() error = %v", err)
+		}
+		if len(result.Coupons) > req.Limit {
+			t.Fatalf("page has %d coupons, want at most %d", len(result.Coupons), req.Limit)
+		}
+		for _, coupon := range result.Coupons {
+			ids = append(ids, coupon.Id)
+		}
+		if result.NextCursor == "" {
+			return ids
+		}
+		req.Cursor = result.NextCursor
+	}
+}
+
+func createCoupons(t *testing.T, service ICouponService, n int, req dtos.Coupon) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		coupon, err := service.CreateCoupon(testContext(testEditor), &req)
+		if err != nil {
+			t.Fatalf("CreateCoupon() error = %v", err)
+		}
+		ids[i] = coupon.Id
+	}
+	return ids
+}
+
+func reversed(ids []string) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[len(ids)-1-i] = id
+	}
+	return out
+}
+
+func TestListCouponsPages(t *testing.T) {
+	service, _ := newTestService(t)
+	ids := createCoupons(t, service, 7, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	tests := []struct {
+		name string
+		req  dtos.CouponListQuery
+		want []string
+	}{
+		{"oldest first", dtos.CouponListQuery{Limit: 3}, ids},
+		{"newest first", dtos.CouponListQuery{Sort: "-created_at", Limit: 3}, reversed(ids)},
+		{"pages as long as the list", dtos.CouponListQuery{Limit: 7}, ids},
+		{"one page", dtos.CouponListQuery{Limit: 200}, ids},
+		{"single coupons", dtos.CouponListQuery{Limit: 1}, ids},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listAll(t, service, tt.req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coupons = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCouponsCursorIsStable(t *testing.T) {
+	service, _ := newTestService(t)
+	ids := createCoupons(t, service, 4, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	first, err := service.ListCoupons(testContext(""), dtos.CouponListQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListCoupons() error = %v", err)
+	}
+	// Coupons created and deleted between pages do not shift the next page
+	if err := service.DeleteCoupon(testContext(testEditor), ids[0]); err != nil {
+		t.Fatalf("DeleteCoupon() error = %v", err)
+	}
+	added := createCoupons(t, service, 1, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	rest := listAll(t, service, dtos.CouponListQuery{Limit: 2, Cursor: first.NextCursor})
+	if want := slices.Concat(ids[2:], added); !reflect.DeepEqual(rest, want) {
+		t.Errorf("coupons after the first page = %v, want %v", rest, want)
+	}
+}
+
+func TestListCouponsFiltersEveryPage(t *testing.T) {
+	service, _ := newTestService(t)
+	var shipping []string
+	for i := 0; i < 3; i++ {
+		createCoupons(t, service, 1, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+		shipping = append(shipping, createCoupons(t, service, 1, dtos.Coupon{Type: "shipping", Details: dtos.CouponDetails{Discount: 100}})...)
+	}
+
+	got := listAll(t, service, dtos.CouponListQuery{Type: "shipping", Limit: 2})
+	if !reflect.DeepEqual(got, shipping) {
+		t.Errorf("coupons = %v, want %v", got, shipping)
+	}
+}
+
+func TestListCouponsRejectsInvalidQueries(t *testing.T) {
+	service, _ := newTestService(t)
+	tests := []struct {
+		name  string
+		req   dtos.CouponListQuery
+		field string
+	}{
+		{"limit too large", dtos.CouponListQuery{Limit: maxPageSize + 1}, "limit"},
+		{"negative limit", dtos.CouponListQuery{Limit: -1}, "limit"},
+		{"unknown sort", dtos.CouponListQuery{Sort: "code"}, "sort"},
+		{"cursor that is not base64", dtos.CouponListQuery{Cursor: "!!"}, "cursor"},
+		{"cursor without an ID", dtos.CouponListQuery{Cursor: base64.RawURLEncoding.EncodeToString([]byte("123:"))}, "cursor"},
+		{"cursor without a time", dtos.CouponListQuery{Cursor: base64.RawURLEncoding.EncodeToString([]byte("abc:id"))}, "cursor"},
+		{"unknown status", dtos.CouponListQuery{Status: "archived"}, "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ListCoupons(testContext(""), tt.req)
+			if got := problems(t, err); len(got) != 1 || got[0].Field != tt.field {
+				t.Errorf("ListCoupons() problems = %+v, want one about %s", got, tt.field)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &daos.CouponCursor{CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC), Id: "c:1"}
+	got, ok := decodeCursor(encodeCursor(cursor))
+	if !ok || !reflect.DeepEqual(got, cursor) {
+		t.Errorf("decodeCursor(encodeCursor()) = %v, %v, want %v", got, ok, cursor)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// maxPreviewCarts bounds the sample carts of a single preview
+const maxPreviewCarts = 20
+
+// Codes of the warnings of a preview
+const (
+	warningDiscountOverHalf           = "discount_over_half"
+	warningNoDiscount                 = "no_discount"
+	warningNeverApplies               = "never_applies"
+	warningGetProductNeverPurchasable = "get_product_never_purchasable"
+	warningEnded                      = "ended"
+)
+
+// PreviewCoupon runs an unsaved coupon definition on sample carts with the
+// same evaluation as ApplyCoupon. Nothing is stored: the definition is loaded
+// into a scratch in-memory store, so it is read back exactly as a saved
+// coupon would be.
+func (c *CouponService) PreviewCoupon(ctx *context.Context, req dtos.PreviewRequest) (*dtos.PreviewResponse, error) {
+	v := &validator{}
+	if req.Coupon == nil {
+		v.report("coupon", "is required")
+	} else if err := nestProblems(v, "coupon", validateCoupon(req.Coupon)); err != nil {
+		return nil, err
+	}
+	if len(req.Carts) == 0 {
+		v.report("carts", "must not be empty")
+	}
+	if len(req.Carts) > maxPreviewCarts {
+		v.report("carts", "must have at most %d items", maxPreviewCarts)
+	}
+	for i, cart := range req.Carts {
+		cartRules(v, fmt.Sprintf("carts[%d]", i), cart)
+	}
+	if err := v.err("invalid preview"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if req.At != nil {
+		now = req.At.UTC()
+	}
+
+	rules, err := previewRules(ctx, req.Coupon, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Complete the customer context with the order history we keep
+	customer, degraded, err := c.pricingCustomer(ctx, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.PreviewResponse{
+		Results:  make([]dtos.PreviewResult, len(req.Carts)),
+		Warnings: couponWarnings(rules, req.Carts, now),
+		Degraded: degraded,
+	}
+	applied := 0
+	for i, cart := range req.Carts {
+		result := dtos.PreviewResult{Warnings: []dtos.PreviewWarning{}}
+		updatedCart, err := priceCart(ctx, rules, cart, customer, now)
+		switch {
+		case err == nil:
+			applied++
+			result.UpdatedCart = updatedCart
+			result.Warnings = cartWarnings(updatedCart)
+		case errors.From(err).Code == errors.CodeNotApplicable:
+			result.NotApplicable = errors.From(err).Message
+		default:
+			return nil, err
+		}
+		response.Results[i] = result
+	}
+	if applied == 0 {
+		response.Warnings = append(response.Warnings, dtos.PreviewWarning{
+			Code:    warningNeverApplies,
+			Message: "coupon applies to none of the carts",
+		})
+	}
+
+	return response, nil
+}
+
+// nestProblems reports the problems of a validation error under path. Other
+// errors are returned as they are.
+func nestProblems(v *validator, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var domainErr *errors.Error
+	if !stderrors.As(err, &domainErr) {
+		return err
+	}
+	problems, ok := domainErr.Details.([]dtos.FieldError)
+	if !ok {
+		return err
+	}
+	for _, problem := range problems {
+		v.report(joinPath(path, problem.Field), "%s", problem.Message)
+	}
+	return nil
+}
+
+// previewRules writes a coupon definition to a scratch store and loads its
+// rules back, as approved and active
+func previewRules(ctx *context.Context, req *dtos.Coupon, now time.Time) (*models.CouponRules, error) {
+	store := daos.NewMemoryStore()
+	scratch := &CouponService{db: daos.NewCachedCoupon(store)}
+
+	coupon := newCoupon(uuid.New().String(), req, now)
+	coupon.Status = models.StatusApproved
+	err := daos.WithTx(ctx, store, func(tx *context.Context) error {
+		return scratch.persistCoupon(tx, &coupon, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet, err := daos.LoadRuleSet(ctx, store, []*models.Coupon{&coupon})
+	if err != nil {
+		return nil, err
+	}
+	return ruleSet.Get(coupon.Id), nil
+}
+
+// couponWarnings points out problems of the coupon seen across all the carts
+func couponWarnings(rules *models.CouponRules, carts []dtos.Cart, now time.Time) []dtos.PreviewWarning {
+	warnings := []dtos.PreviewWarning{}
+
+	if rules.Coupon.ValidityState(now) == models.CouponExpired {
+		warnings = append(warnings, dtos.PreviewWarning{
+			Code:    warningEnded,
+			Message: fmt.Sprintf("coupon ended at %s", rules.Coupon.EndsAt.Format(time.RFC3339)),
+		})
+	}
+
+	// A free product that no cart holds is never given away
+	inCart := make(map[string]bool)
+	for _, cart := range carts {
+		for _, item := range cart.Items {
+			inCart[item.ProductId] = true
+		}
+	}
+	var missing []string
+	for _, product := range rules.GetProducts {
+		if !inCart[product.ProductID] {
+			missing = append(missing, product.ProductID)
+		}
+	}
+	if len(missing) > 0 {
+		warnings = append(warnings, dtos.PreviewWarning{
+			Code:    warningGetProductNeverPurchasable,
+			Message: fmt.Sprintf("get products %s are in none of the carts, so they are never given", strings.Join(missing, ", ")),
+		})
+	}
+
+	return warnings
+}
+
+// cartWarnings points out a discount on one cart that looks set up wrong
+func cartWarnings(cart *dtos.UpdatedCart) []dtos.PreviewWarning {
+	warnings := []dtos.PreviewWarning{}
+
+	total := cart.TotalPrice + cart.ShippingFee
+	discount := cart.TotalDiscount + cart.ShippingDiscount
+	switch {
+	case discount <= 0:
+		warnings = append(warnings, dtos.PreviewWarning{
+			Code:    warningNoDiscount,
+			Message: "coupon applies but gives no discount",
+		})
+	case discount > total/2:
+		warnings = append(warnings, dtos.PreviewWarning{
+			Code:    warningDiscountOverHalf,
+			Message: fmt.Sprintf("discount of %.2f is over 50%% of the cart total %.2f", discount, total),
+		})
+	}
+
+	return warnings
+}
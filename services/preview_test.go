@@ -0,0 +1,205 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// warningCodes returns the codes of the warnings, in order
+func warningCodes(warnings []dtos.PreviewWarning) []string {
+	codes := []string{}
+	for _, warning := range warnings {
+		codes = append(codes, warning.Code)
+	}
+	return codes
+}
+
+func TestPreviewCoupon(t *testing.T) {
+	service, _ := newTestService(t)
+	response, err := service.PreviewCoupon(testContext(testEditor), dtos.PreviewRequest{
+		Coupon: &tenPercentOff,
+		Carts:  []dtos.Cart{cartOf(200), cartOf(50)},
+	})
+	if err != nil {
+		t.Fatalf("PreviewCoupon() error = %v", err)
+	}
+
+	if len(response.Results) != 2 {
+		t.Fatalf("%d results, want one per cart", len(response.Results))
+	}
+	applied := response.Results[0]
+	if applied.UpdatedCart == nil || applied.NotApplicable != "" {
+		t.Fatalf("result = %+v, want the updated cart", applied)
+	}
+	assertMoney(t, "discount", applied.UpdatedCart.TotalDiscount, 20)
+	assertMoney(t, "final price", applied.UpdatedCart.FinalPrice, 180)
+	if codes := warningCodes(applied.Warnings); len(codes) != 0 {
+		t.Errorf("warnings = %q, want none", codes)
+	}
+	// Below the threshold the coupon applies with no discount
+	if codes := warningCodes(response.Results[1].Warnings); !slices.Equal(codes, []string{warningNoDiscount}) {
+		t.Errorf("warnings of the small cart = %q, want %s", codes, warningNoDiscount)
+	}
+	if codes := warningCodes(response.Warnings); len(codes) != 0 {
+		t.Errorf("coupon warnings = %q, want none", codes)
+	}
+
+	// Nothing is stored
+	page, err := service.ListCoupons(testContext(testEditor), dtos.CouponListQuery{})
+	if err != nil {
+		t.Fatalf("ListCoupons() error = %v", err)
+	}
+	if len(page.Coupons) != 0 {
+		t.Errorf("%d coupons are stored, want none", len(page.Coupons))
+	}
+}
+
+func TestPreviewWarnings(t *testing.T) {
+	endedAt := time.Now().Add(-time.Hour)
+	bxgy := dtos.Coupon{Type: "bxgy", Details: dtos.CouponDetails{
+		BuyProducts:   []dtos.ProductQuantityDetails{{ProductId: "A", Quantity: 1}},
+		GetProducts:   []dtos.ProductQuantityDetails{{ProductId: "B", Quantity: 1}},
+		RepitionLimit: 1,
+	}}
+
+	tests := []struct {
+		name          string
+		coupon        dtos.Coupon
+		carts         []dtos.Cart
+		warnings      []string
+		cartWarnings  []string
+		notApplicable bool
+	}{
+		{
+			name:         "discount over half of the cart",
+			coupon:       dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 60}},
+			carts:        []dtos.Cart{cartOf(100)},
+			warnings:     []string{},
+			cartWarnings: []string{warningDiscountOverHalf},
+		},
+		{
+			name:         "exactly half of the cart",
+			coupon:       dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 50}},
+			carts:        []dtos.Cart{cartOf(100)},
+			warnings:     []string{},
+			cartWarnings: []string{},
+		},
+		{
+			name:         "get product in none of the carts",
+			coupon:       bxgy,
+			carts:        []dtos.Cart{cartOf(100)},
+			warnings:     []string{warningGetProductNeverPurchasable},
+			cartWarnings: []string{warningNoDiscount},
+		},
+		{
+			name:   "get product in a cart",
+			coupon: bxgy,
+			carts: []dtos.Cart{{Items: []dtos.CartItem{
+				{ProductId: "A", Quantity: 1, Price: 100},
+				{ProductId: "B", Quantity: 1, Price: 20},
+			}}},
+			warnings:     []string{},
+			cartWarnings: []string{},
+		},
+		{
+			// First order coupons need a known shopper
+			name:          "never applies",
+			coupon:        dtos.Coupon{Type: "cart-wise", Eligibility: &dtos.Eligibility{FirstOrderOnly: true}, Details: dtos.CouponDetails{Discount: 10}},
+			carts:         []dtos.Cart{cartOf(100)},
+			warnings:      []string{warningNeverApplies},
+			cartWarnings:  []string{},
+			notApplicable: true,
+		},
+		{
+			name:          "ended",
+			coupon:        dtos.Coupon{Type: "cart-wise", EndsAt: &endedAt, Details: dtos.CouponDetails{Discount: 10}},
+			carts:         []dtos.Cart{cartOf(100)},
+			warnings:      []string{warningEnded, warningNeverApplies},
+			cartWarnings:  []string{},
+			notApplicable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			response, err := service.PreviewCoupon(testContext(testEditor), dtos.PreviewRequest{Coupon: &tt.coupon, Carts: tt.carts})
+			if err != nil {
+				t.Fatalf("PreviewCoupon() error = %v", err)
+			}
+			if codes := warningCodes(response.Warnings); !slices.Equal(codes, tt.warnings) {
+				t.Errorf("coupon warnings = %q, want %q", codes, tt.warnings)
+			}
+			result := response.Results[0]
+			if codes := warningCodes(result.Warnings); !slices.Equal(codes, tt.cartWarnings) {
+				t.Errorf("cart warnings = %q, want %q", codes, tt.cartWarnings)
+			}
+			if (result.NotApplicable != "") != tt.notApplicable || (result.UpdatedCart == nil) != tt.notApplicable {
+				t.Errorf("result = %+v, want not applicable %v", result, tt.notApplicable)
+			}
+		})
+	}
+}
+
+func TestPreviewAt(t *testing.T) {
+	service, _ := newTestService(t)
+	startsAt := time.Now().Add(24 * time.Hour)
+	coupon := dtos.Coupon{Type: "cart-wise", StartsAt: &startsAt, Details: dtos.CouponDetails{Discount: 10}}
+
+	// Not started yet
+	response, err := service.PreviewCoupon(testContext(testEditor), dtos.PreviewRequest{Coupon: &coupon, Carts: []dtos.Cart{cartOf(100)}})
+	if err != nil {
+		t.Fatalf("PreviewCoupon() error = %v", err)
+	}
+	if response.Results[0].NotApplicable == "" {
+		t.Errorf("result = %+v, want not applicable before the coupon starts", response.Results[0])
+	}
+
+	// Priced at a time the coupon runs
+	at := startsAt.Add(time.Hour)
+	response, err = service.PreviewCoupon(testContext(testEditor), dtos.PreviewRequest{Coupon: &coupon, Carts: []dtos.Cart{cartOf(100)}, At: &at})
+	if err != nil {
+		t.Fatalf("PreviewCoupon() error = %v", err)
+	}
+	if response.Results[0].UpdatedCart == nil {
+		t.Fatalf("result = %+v, want the updated cart", response.Results[0])
+	}
+	assertMoney(t, "discount", response.Results[0].UpdatedCart.TotalDiscount, 10)
+}
+
+func TestPreviewValidation(t *testing.T) {
+	service, _ := newTestService(t)
+	tooMany := make([]dtos.Cart, maxPreviewCarts+1)
+	for i := range tooMany {
+		tooMany[i] = cartOf(10)
+	}
+
+	tests := []struct {
+		name  string
+		req   dtos.PreviewRequest
+		field string
+	}{
+		{"no coupon", dtos.PreviewRequest{Carts: []dtos.Cart{cartOf(10)}}, "coupon"},
+		{"invalid coupon", dtos.PreviewRequest{Coupon: &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 150}}, Carts: []dtos.Cart{cartOf(10)}}, "coupon.details.discount"},
+		{"no carts", dtos.PreviewRequest{Coupon: &tenPercentOff}, "carts"},
+		{"too many carts", dtos.PreviewRequest{Coupon: &tenPercentOff, Carts: tooMany}, "carts"},
+		{"item without quantity", dtos.PreviewRequest{Coupon: &tenPercentOff, Carts: []dtos.Cart{cartOf(10), {Items: []dtos.CartItem{{ProductId: "A", Price: 10}}}}}, "carts[1].items[0].quantity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.PreviewCoupon(testContext(testEditor), tt.req)
+			if errorCode(err) != errors.CodeValidation {
+				t.Fatalf("PreviewCoupon() error = %v, want a validation error", err)
+			}
+			problems, _ := errors.From(err).Details.([]dtos.FieldError)
+			if len(problems) == 0 || problems[0].Field != tt.field {
+				t.Errorf("problems = %+v, want one on %s", problems, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+)
+
+// maxPromotionProducts bounds the products of a single promotions lookup
+const maxPromotionProducts = 100
+
+// Scopes of a promotion
+const (
+	promotionScopeProduct = "product"
+	promotionScopeCart    = "cart"
+)
+
+// GetProductPromotions returns the active coupons that could touch each of
+// the products, with a badge for product pages. Shopper eligibility and
+// conditions are not checked, as there is no cart or shopper yet.
+func (c *CouponService) GetProductPromotions(ctx *context.Context, productIds []string) (*dtos.PromotionsResponse, error) {
+	v := &validator{}
+	if len(productIds) == 0 {
+		v.report("product_ids", "must not be empty")
+	}
+	if len(productIds) > maxPromotionProducts {
+		v.report("product_ids", "must have at most %d items", maxPromotionProducts)
+	}
+	each(required())(v, "product_ids", productIds)
+	if err := v.err("invalid promotions request"); err != nil {
+		return nil, err
+	}
+
+	index, degraded, err := c.pricingIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	response := &dtos.PromotionsResponse{
+		Products: make([]*dtos.ProductPromotions, 0, len(productIds)),
+		Degraded: degraded,
+	}
+	for _, productId := range productIds {
+		products := &dtos.ProductPromotions{
+			ProductId:  productId,
+			Promotions: []dtos.Promotion{},
+		}
+		for _, rules := range index.ForProduct(productId) {
+			coupon := rules.Coupon
+			if !coupon.IsActive || coupon.ValidityState(now) != models.CouponLive || campaignProblem(rules.Campaign, now) != nil {
+				continue
+			}
+			promotion, ok := productPromotion(rules, productId)
+			if translation, tag := couponText(rules.Translations, ctx.Locales); ok && translation != nil {
+				promotion.Name = translation.Name
+				if translation.Badge != "" {
+					promotion.Badge = translation.Badge
+				}
+				promotion.Locale = tag
+			}
+			if ok {
+				products.Promotions = append(products.Promotions, promotion)
+			}
+		}
+		response.Products = append(response.Products, products)
+	}
+
+	return response, nil
+}
+
+// productPromotion describes a coupon as seen from the page of a product
+func productPromotion(rules *models.CouponRules, productId string) (dtos.Promotion, bool) {
+	coupon := rules.Coupon
+	promotion := dtos.Promotion{
+		CouponId: coupon.Id,
+		Type:     coupon.Type,
+		Code:     coupon.Code,
+		Scope:    promotionScopeProduct,
+		EndsAt:   coupon.EndsAt,
+	}
+
+	switch coupon.Type {
+	case "product-wise":
+		if rules.ProductWise == nil {
+			return promotion, false
+		}
+		promotion.Badge = fmt.Sprintf("%s%% off", formatPercent(rules.ProductWise.Discount))
+
+	case "bxgy":
+		var buy, get int
+		var buys bool
+		for _, buyProduct := range rules.BuyProducts {
+			buy += buyProduct.Quantity
+			buys = buys || buyProduct.ProductID == productId
+		}
+		for _, getProduct := range rules.GetProducts {
+			get += getProduct.Quantity
+		}
+		if buys {
+			promotion.Badge = fmt.Sprintf("Buy %d get %d free", buy, get)
+		} else {
+			promotion.Badge = fmt.Sprintf("Free when you buy %d", buy)
+		}
+
+	case "volume":
+		if len(rules.VolumeBands) == 0 {
+			return promotion, false
+		}
+		first, best := rules.VolumeBands[0], rules.VolumeBands[0]
+		for _, band := range rules.VolumeBands {
+			if band.MinQuantity < first.MinQuantity {
+				first = band
+			}
+			if band.Discount > best.Discount {
+				best = band
+			}
+		}
+		if first == best {
+			promotion.Badge = fmt.Sprintf("Buy %d+, save %s%%", first.MinQuantity, formatPercent(first.Discount))
+		} else {
+			promotion.Badge = fmt.Sprintf("Buy %d+, save up to %s%%", first.MinQuantity, formatPercent(best.Discount))
+		}
+
+	case "fixed-price":
+		for _, product := range rules.FixedPriceProducts {
+			if product.ProductID == productId {
+				promotion.Badge = "Now " + formatAmount(product.Price)
+			}
+		}
+		if promotion.Badge == "" {
+			return promotion, false
+		}
+
+	case "cart-wise":
+		if rules.CartWise == nil {
+			return promotion, false
+		}
+		promotion.Scope = promotionScopeCart
+		promotion.Badge = fmt.Sprintf("%s%% off your order", formatPercent(rules.CartWise.Discount))
+		if rules.CartWise.Threshold > 0 {
+			promotion.Badge += " over " + formatAmount(rules.CartWise.Threshold)
+		}
+
+	case "shipping":
+		if rules.Shipping == nil {
+			return promotion, false
+		}
+		promotion.Scope = promotionScopeCart
+		if rules.Shipping.Discount >= 100 {
+			promotion.Badge = "Free shipping"
+		} else {
+			promotion.Badge = fmt.Sprintf("%s%% off shipping", formatPercent(rules.Shipping.Discount))
+		}
+		if rules.Shipping.Threshold > 0 {
+			promotion.Badge += " over " + formatAmount(rules.Shipping.Threshold)
+		}
+
+	default:
+		return promotion, false
+	}
+
+	return promotion, true
+}
+
+// formatAmount formats an amount for a badge, without decimals when it is whole
+func formatAmount(amount float64) string {
+	formatted := strconv.FormatFloat(amount, 'f', 2, 64)
+	return strings.TrimSuffix(formatted, ".00")
+}
+
+// formatPercent formats a percentage for a badge with as few decimals as needed
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(math.Round(percent*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// productPromotions returns the promotions of a single product
+func productPromotions(t *testing.T, service *CouponService, locales []string, productId string) []dtos.Promotion {
+	t.Helper()
+	ctx := testContext("")
+	ctx.Locales = locales
+	response, err := service.GetProductPromotions(ctx, []string{productId})
+	if err != nil {
+		t.Fatalf("GetProductPromotions() error = %v", err)
+	}
+	if len(response.Products) != 1 || response.Products[0].ProductId != productId {
+		t.Fatalf("products = %+v, want only %s", response.Products, productId)
+	}
+	return response.Products[0].Promotions
+}
+
+func TestPromotionBadges(t *testing.T) {
+	bxgy := dtos.CouponDetails{
+		BuyProducts:   []dtos.ProductQuantityDetails{{ProductId: "A", Quantity: 2}},
+		GetProducts:   []dtos.ProductQuantityDetails{{ProductId: "B", Quantity: 1}},
+		RepitionLimit: 1,
+	}
+
+	tests := []struct {
+		name      string
+		coupon    dtos.Coupon
+		productId string
+		scope     string
+		badge     string
+	}{
+		{"product-wise", dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 10}}, "A", "product", "10% off"},
+		{"bxgy buy product", dtos.Coupon{Type: "bxgy", Details: bxgy}, "A", "product", "Buy 2 get 1 free"},
+		{"bxgy get product", dtos.Coupon{Type: "bxgy", Details: bxgy}, "B", "product", "Free when you buy 2"},
+		{"volume with one band", dtos.Coupon{Type: "volume", Details: dtos.CouponDetails{
+			ProductIds: []string{"A"},
+			Bands:      []dtos.QuantityBand{{MinQuantity: 3, Discount: 5}},
+		}}, "A", "product", "Buy 3+, save 5%"},
+		{"volume with several bands", dtos.Coupon{Type: "volume", Details: dtos.CouponDetails{
+			ProductIds: []string{"A"},
+			Bands:      []dtos.QuantityBand{{MinQuantity: 10, Discount: 12.5}, {MinQuantity: 3, Discount: 5}},
+		}}, "A", "product", "Buy 3+, save up to 12.5%"},
+		{"fixed-price", dtos.Coupon{Type: "fixed-price", Details: dtos.CouponDetails{
+			FixedPrices: []dtos.ProductPriceDetails{{ProductId: "A", Price: 9.5}},
+		}}, "A", "product", "Now 9.50"},
+		{"cart-wise", dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}}, "A", "cart", "10% off your order"},
+		{"cart-wise over a threshold", dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Threshold: 100, Discount: 10}}, "A", "cart", "10% off your order over 100"},
+		{"free shipping", dtos.Coupon{Type: "shipping", Details: dtos.CouponDetails{Discount: 100}}, "A", "cart", "Free shipping"},
+		{"shipping over a threshold", dtos.Coupon{Type: "shipping", Details: dtos.CouponDetails{Threshold: 50, Discount: 50}}, "A", "cart", "50% off shipping over 50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			couponId := createLiveCoupon(t, service, tt.coupon)
+
+			promotions := productPromotions(t, service, nil, tt.productId)
+			if len(promotions) != 1 {
+				t.Fatalf("promotions = %+v, want one", promotions)
+			}
+			promotion := promotions[0]
+			if promotion.CouponId != couponId || promotion.Type != tt.coupon.Type {
+				t.Errorf("promotion = %+v, want coupon %s", promotion, couponId)
+			}
+			if promotion.Scope != tt.scope {
+				t.Errorf("scope = %q, want %q", promotion.Scope, tt.scope)
+			}
+			if promotion.Badge != tt.badge {
+				t.Errorf("badge = %q, want %q", promotion.Badge, tt.badge)
+			}
+		})
+	}
+}
+
+func TestPromotionsOfOtherProducts(t *testing.T) {
+	service, _ := newTestService(t)
+	createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 10}})
+	createLiveCoupon(t, service, dtos.Coupon{Type: "fixed-price", Details: dtos.CouponDetails{
+		FixedPrices: []dtos.ProductPriceDetails{{ProductId: "A", Price: 5}},
+	}})
+
+	if promotions := productPromotions(t, service, nil, "C"); len(promotions) != 0 {
+		t.Errorf("promotions of C = %+v, want none", promotions)
+	}
+}
+
+func TestPromotionsLeaveOutInactiveCoupons(t *testing.T) {
+	service, repositories := newTestService(t)
+	liveId := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 10}})
+	createDraft(t, service, 20)
+	endedId := createLiveCoupon(t, service, dtos.Coupon{Type: "product-wise", Details: dtos.CouponDetails{ProductId: "A", Discount: 30}})
+	endedAt := time.Now().Add(-time.Hour)
+	setValidity(t, repositories, endedId, nil, &endedAt)
+
+	promotions := productPromotions(t, service, nil, "A")
+	if len(promotions) != 1 || promotions[0].CouponId != liveId {
+		t.Errorf("promotions = %+v, want only %s", promotions, liveId)
+	}
+}
+
+func TestPromotionText(t *testing.T) {
+	service, _ := newTestService(t)
+	createLiveCoupon(t, service, dtos.Coupon{
+		Type:    "product-wise",
+		Name:    "Summer sale",
+		Details: dtos.CouponDetails{ProductId: "A", Discount: 10},
+		Translations: map[string]dtos.CouponText{
+			"fr": {Name: "Soldes d'été", Badge: "-10 %"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		locales []string
+		text    string
+		badge   string
+		locale  string
+	}{
+		{"translated badge", []string{"fr-CA"}, "Soldes d'été", "-10 %", "fr"},
+		// The default text has no badge, so the generated one is kept
+		{"generated badge", []string{"en"}, "Summer sale", "10% off", "en"},
+		{"no matching locale", []string{"de"}, "Summer sale", "10% off", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotions := productPromotions(t, service, tt.locales, "A")
+			if len(promotions) != 1 {
+				t.Fatalf("promotions = %+v, want one", promotions)
+			}
+			promotion := promotions[0]
+			if promotion.Name != tt.text || promotion.Badge != tt.badge || promotion.Locale != tt.locale {
+				t.Errorf("promotion = %q, %q in %q, want %q, %q in %q", promotion.Name, promotion.Badge, promotion.Locale, tt.text, tt.badge, tt.locale)
+			}
+		})
+	}
+}
+
+func TestProductPromotionsValidation(t *testing.T) {
+	service, _ := newTestService(t)
+	tooMany := make([]string, maxPromotionProducts+1)
+	for i := range tooMany {
+		tooMany[i] = "A"
+	}
+
+	tests := []struct {
+		name       string
+		productIds []string
+	}{
+		{"no products", nil},
+		{"empty product ID", []string{"A", ""}},
+		{"too many products", tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.GetProductPromotions(testContext(""), tt.productIds)
+			if errorCode(err) != errors.CodeValidation {
+				t.Errorf("GetProductPromotions() error = %v, want a validation error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	stderrors "errors"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/db"
+	"monk-commerce-assignment/utils/errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type RedemptionService struct {
+	db      daos.ICustomer
+	coupons ICouponService
+}
+
+func NewRedemptionService(customers daos.ICustomer, coupons ICouponService) IRedemptionService {
+	return &RedemptionService{
+		db:      customers,
+		coupons: coupons,
+	}
+}
+
+type IRedemptionService interface {
+	RedeemCoupon(ctx *context.Context, couponId string, req *dtos.RedemptionRequest) (*dtos.Redemption, error)
+	RecordOrder(ctx *context.Context, req *dtos.OrderRequest) error
+}
+
+func (r *RedemptionService) RedeemCoupon(ctx *context.Context, couponId string, req *dtos.RedemptionRequest) (*dtos.Redemption, error) {
+	if req.OrderId == "" {
+		return nil, errors.Validation("order id is required")
+	}
+	if req.Customer == nil || req.Customer.Id == "" {
+		return nil, errors.Validation("customer id is required")
+	}
+
+	// Redemptions are written to the database, refuse them while it is unreachable
+	if !db.Available() {
+		return nil, db.ErrUnavailable
+	}
+
+	// Price the order with the coupon, exactly like the apply endpoint does
+	updatedCart, err := r.coupons.ApplyCoupon(ctx, couponId, req.Cart, req.Customer)
+	if err != nil {
+		return nil, err
+	}
+	if updatedCart.Degraded {
+		return nil, db.ErrUnavailable
+	}
+	discount := updatedCart.TotalDiscount + updatedCart.ShippingDiscount
+	if discount <= 0 {
+		return nil, errors.NotApplicable("coupon is not applicable to this cart")
+	}
+
+	now := time.Now()
+	redemption := models.Redemption{
+		Id:         uuid.New().String(),
+		CouponID:   couponId,
+		OrderID:    req.OrderId,
+		CustomerID: req.Customer.Id,
+		Discount:   discount,
+		CreatedAt:  now,
+	}
+	// Feed the order into the customer's order history
+	order := models.CustomerOrder{
+		Id:         uuid.New().String(),
+		CustomerID: req.Customer.Id,
+		OrderID:    req.OrderId,
+		CouponID:   &couponId,
+		Total:      updatedCart.FinalPrice + updatedCart.FinalShipping,
+		Discount:   discount,
+		CreatedAt:  now,
+	}
+
+	// Draw the discount from the budget of the coupon's campaign in the
+	// transaction that stores the redemption
+	err = r.coupons.SpendCampaignBudget(ctx, couponId, discount, func(tx *context.Context, campaignId string) error {
+		if campaignId != "" {
+			redemption.CampaignID = &campaignId
+		}
+		err := r.db.PersistRedemption(tx, &redemption)
+		if err != nil {
+			tx.Log.Error("failed to persist redemption", zap.Error(err))
+			return err
+		}
+		err = r.db.PersistCustomerOrder(tx, &order)
+		if err != nil {
+			tx.Log.Error("failed to persist customer order", zap.Error(err))
+			return orderOfAnotherCustomer(req.OrderId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.Redemption{
+		Id:          redemption.Id,
+		CouponId:    couponId,
+		OrderId:     req.OrderId,
+		CustomerId:  req.Customer.Id,
+		Discount:    discount,
+		UpdatedCart: *updatedCart,
+	}, nil
+}
+
+func (r *RedemptionService) RecordOrder(ctx *context.Context, req *dtos.OrderRequest) error {
+	if req.OrderId == "" || req.CustomerId == "" {
+		return errors.Validation("order id and customer id are required")
+	}
+	if !db.Available() {
+		return db.ErrUnavailable
+	}
+
+	order := models.CustomerOrder{
+		Id:         uuid.New().String(),
+		CustomerID: req.CustomerId,
+		OrderID:    req.OrderId,
+		Total:      req.Total,
+		CreatedAt:  time.Now(),
+	}
+	err := daos.WithTx(ctx, r.db, func(tx *context.Context) error {
+		err := r.db.PersistCustomerOrder(tx, &order)
+		if err != nil {
+			tx.Log.Error("failed to persist customer order", zap.Error(err))
+		}
+		return err
+	})
+	if err != nil {
+		return orderOfAnotherCustomer(req.OrderId, err)
+	}
+
+	return nil
+}
+
+// orderOfAnotherCustomer reports an order ID that is recorded for another
+// customer, other errors are returned as they are
+func orderOfAnotherCustomer(orderId string, err error) error {
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Conflict("order %s is recorded for another customer", orderId).Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	stderrors "errors"
+	"time"
+
+	"monk-commerce-assignment/constants"
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	couponPurgeInterval = time.Hour
+	// Coupons purged in one transaction each, looked up this many at a time
+	couponPurgeBatchSize = 100
+)
+
+// PurgeDeletedCoupons removes for good the coupons deleted longer than the
+// retention period ago. Coupons that were redeemed are kept, their
+// redemptions are needed for accounting. It returns how many were purged.
+func (c *CouponService) PurgeDeletedCoupons(ctx *context.Context, retention time.Duration) (int, error) {
+	deletedBefore := time.Now().Add(-retention)
+
+	purged := 0
+	for {
+		coupons, err := c.db.ListPurgeableCoupons(ctx, deletedBefore, couponPurgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		batch := 0
+		for _, coupon := range coupons {
+			ok, err := c.purgeCoupon(ctx, coupon, deletedBefore)
+			if err != nil {
+				return purged, err
+			}
+			if ok {
+				batch++
+			}
+		}
+		purged += batch
+
+		// Stop on the last batch, or when the coupons listed could not be
+		// purged so the same ones would be listed again
+		if len(coupons) < couponPurgeBatchSize || batch == 0 {
+			return purged, nil
+		}
+	}
+}
+
+// purgeCoupon removes a deleted coupon, its details and translations for
+// good, and records it in the audit log. It reports false if the coupon was
+// restored or redeemed since it was listed.
+func (c *CouponService) purgeCoupon(ctx *context.Context, coupon *models.Coupon, deletedBefore time.Time) (bool, error) {
+	couponId := coupon.Id
+	ruleSet, err := c.db.LoadRuleSet(ctx, []*models.Coupon{coupon})
+	if err != nil {
+		return false, err
+	}
+	before, err := toCouponDto(ruleSet.Get(couponId))
+	if err != nil {
+		return false, err
+	}
+
+	err = daos.WithTx(ctx, c.db, func(tx *context.Context) error {
+		err := c.db.LockPurgeableCoupon(tx, couponId, deletedBefore)
+		if err != nil {
+			return err
+		}
+		err = c.removeCoupon(tx, coupon)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(tx, couponId, models.AuditPurged, before, nil)
+	})
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	c.couponChanged(ctx, couponId)
+	ctx.Log.Info("purged deleted coupon", zap.String("coupon_id", couponId))
+	return true, nil
+}
+
+// StartCouponPurge purges the coupons deleted longer than retentionDays ago,
+// once at startup and then every hour. Deleted coupons are kept forever when
+// retentionDays is not positive.
+func StartCouponPurge(repositories daos.Repositories, retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	service := &CouponService{
+		db:        daos.NewCachedCoupon(repositories.Coupons),
+		customers: repositories.Customers,
+	}
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	go purgeCoupons(service, retention)
+}
+
+func purgeCoupons(service *CouponService, retention time.Duration) {
+	ticker := time.NewTicker(couponPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx := context.Background("coupon-purge", constants.Logger)
+		purged, err := service.PurgeDeletedCoupons(ctx, retention)
+		if err != nil {
+			ctx.Log.Error("unable to purge deleted coupons", zap.Error(err))
+		} else if purged > 0 {
+			ctx.Log.Info("purged deleted coupons", zap.Int("count", purged))
+		}
+		<-ticker.C
+	}
+}
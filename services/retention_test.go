@@ -0,0 +1,185 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// couponHistory returns the actions recorded for a coupon, oldest first
+func couponHistory(t *testing.T, service *CouponService, couponId string) []string {
+	t.Helper()
+	page, err := service.GetCouponHistory(testContext(testEditor), couponId, dtos.AuditQuery{})
+	if err != nil {
+		t.Fatalf("GetCouponHistory() error = %v", err)
+	}
+	var actions []string
+	for _, entry := range page.Entries {
+		actions = append(actions, entry.Action)
+	}
+	slices.Reverse(actions)
+	return actions
+}
+
+func TestDeleteCoupon(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext(testEditor)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+
+	if err := service.DeleteCoupon(ctx, couponId); err != nil {
+		t.Fatalf("DeleteCoupon() error = %v", err)
+	}
+	coupon, err := service.GetCouponById(ctx, couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	if coupon.DeletedAt == nil {
+		t.Errorf("deleted_at is not set")
+	}
+	if _, err := service.ApplyCoupon(testContext(""), couponId, cartOf(200), nil); errorCode(err) != errors.CodeNotFound {
+		t.Errorf("ApplyCoupon() error = %v, want not found", err)
+	}
+
+	if err := service.DeleteCoupon(ctx, couponId); errorCode(err) != errors.CodeConflict {
+		t.Errorf("DeleteCoupon() again error = %v, want a conflict", err)
+	}
+	if err := service.DeleteCoupon(ctx, "missing"); errorCode(err) != errors.CodeNotFound {
+		t.Errorf("DeleteCoupon() of a missing coupon error = %v, want not found", err)
+	}
+	if got := couponHistory(t, service, couponId); got[len(got)-1] != models.AuditDeleted || slices.Index(got, models.AuditDeleted) != len(got)-1 {
+		t.Errorf("actions = %q, want a single %s last", got, models.AuditDeleted)
+	}
+}
+
+func TestRestoreCoupon(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := testContext(testEditor)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+
+	if _, err := service.RestoreCoupon(ctx, couponId); errorCode(err) != errors.CodeConflict {
+		t.Errorf("RestoreCoupon() of a coupon that is not deleted error = %v, want a conflict", err)
+	}
+
+	if err := service.DeleteCoupon(ctx, couponId); err != nil {
+		t.Fatalf("DeleteCoupon() error = %v", err)
+	}
+	restored, err := service.RestoreCoupon(ctx, couponId)
+	if err != nil {
+		t.Fatalf("RestoreCoupon() error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("deleted_at = %v after a restore, want none", restored.DeletedAt)
+	}
+	assertMoney(t, "discount", applyCoupon(t, service, couponId, cartOf(200)).TotalDiscount, 20)
+
+	got := couponHistory(t, service, couponId)
+	if want := []string{models.AuditDeleted, models.AuditRestored}; !slices.Equal(got[len(got)-2:], want) {
+		t.Errorf("actions = %q, want them to end with %q", got, want)
+	}
+}
+
+func TestDeleteCouponDeletedMeanwhile(t *testing.T) {
+	service, repositories := newTestService(t)
+	ctx := testContext(testEditor)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	actions := couponHistory(t, service, couponId)
+	if _, err := service.GetCouponById(ctx, couponId); err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+
+	// Another instance deletes the coupon, and this one has not heard yet
+	now := time.Now()
+	if err := repositories.Coupons.UpdateCouponDeletedAt(ctx, couponId, &now, now); err != nil {
+		t.Fatalf("UpdateCouponDeletedAt() error = %v", err)
+	}
+	if coupon, err := service.GetCouponById(ctx, couponId); err != nil || coupon.DeletedAt != nil {
+		t.Fatalf("GetCouponById() = %+v, %v, want the cached coupon that is not deleted", coupon, err)
+	}
+
+	if err := service.DeleteCoupon(ctx, couponId); errorCode(err) != errors.CodeConflict {
+		t.Errorf("DeleteCoupon() error = %v, want a conflict", err)
+	}
+	if got := couponHistory(t, service, couponId); !slices.Equal(got, actions) {
+		t.Errorf("actions = %q, want nothing recorded after %q", got, actions)
+	}
+
+	// Same for a restore the other instance got to first
+	if err := repositories.Coupons.UpdateCouponDeletedAt(ctx, couponId, nil, now); err != nil {
+		t.Fatalf("UpdateCouponDeletedAt() error = %v", err)
+	}
+	if _, err := service.RestoreCoupon(ctx, couponId); errorCode(err) != errors.CodeConflict {
+		t.Errorf("RestoreCoupon() error = %v, want a conflict", err)
+	}
+	if got := couponHistory(t, service, couponId); !slices.Equal(got, actions) {
+		t.Errorf("actions = %q, want nothing recorded after %q", got, actions)
+	}
+}
+
+func TestPurgeDeletedCoupons(t *testing.T) {
+	service, repositories := newTestService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	ctx := testContext(testEditor)
+	deletedId := createLiveCoupon(t, service, tenPercentOff)
+	redeemedId := createLiveCoupon(t, service, tenPercentOff)
+	restoredId := createLiveCoupon(t, service, tenPercentOff)
+	liveId := createLiveCoupon(t, service, tenPercentOff)
+
+	if _, err := redeem(redemptions, redeemedId, "order-1", "customer-1", cartOf(200)); err != nil {
+		t.Fatalf("RedeemCoupon() error = %v", err)
+	}
+	for _, couponId := range []string{deletedId, redeemedId, restoredId} {
+		if err := service.DeleteCoupon(ctx, couponId); err != nil {
+			t.Fatalf("DeleteCoupon() error = %v", err)
+		}
+	}
+	if _, err := service.RestoreCoupon(ctx, restoredId); err != nil {
+		t.Fatalf("RestoreCoupon() error = %v", err)
+	}
+
+	// Deleted within the retention period
+	purged, err := service.PurgeDeletedCoupons(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeDeletedCoupons() error = %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("%d coupons purged within the retention period, want none", purged)
+	}
+
+	purged, err = service.PurgeDeletedCoupons(ctx, 0)
+	if err != nil {
+		t.Fatalf("PurgeDeletedCoupons() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("%d coupons purged, want 1", purged)
+	}
+	if _, err := service.GetCouponById(ctx, deletedId); errorCode(err) != errors.CodeNotFound {
+		t.Errorf("GetCouponById() of a purged coupon error = %v, want not found", err)
+	}
+	if got := couponHistory(t, service, deletedId); got[len(got)-1] != models.AuditPurged {
+		t.Errorf("actions = %q, want %s last", got, models.AuditPurged)
+	}
+
+	// The redeemed coupon is kept for its redemptions
+	coupon, err := service.GetCouponById(ctx, redeemedId)
+	if err != nil {
+		t.Fatalf("GetCouponById() of a redeemed coupon error = %v", err)
+	}
+	if coupon.DeletedAt == nil {
+		t.Errorf("redeemed coupon is no longer deleted")
+	}
+	for _, couponId := range []string{restoredId, liveId} {
+		if _, err := service.GetCouponById(ctx, couponId); err != nil {
+			t.Errorf("GetCouponById(%s) error = %v", couponId, err)
+		}
+	}
+
+	// Nothing is left to purge
+	purged, err = service.PurgeDeletedCoupons(ctx, 0)
+	if err != nil || purged != 0 {
+		t.Errorf("PurgeDeletedCoupons() = %d, %v, want none purged", purged, err)
+	}
+}
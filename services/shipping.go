@@ -0,0 +1,54 @@
+package services
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+// shippingCouponDetails transforms a shipping coupon into the DTO format
+func shippingCouponDetails(rules *models.CouponRules) dtos.CouponDetails {
+	var shippingMethods []string
+	for _, method := range rules.ShippingMethods {
+		shippingMethods = append(shippingMethods, method.Method)
+	}
+
+	return dtos.CouponDetails{
+		Threshold:       int(rules.Shipping.Threshold),
+		Discount:        int(rules.Shipping.Discount),
+		MaxDiscount:     rules.Shipping.MaxDiscount,
+		ShippingMethods: shippingMethods,
+	}
+}
+
+// shippingDiscount works out the discount a shipping coupon gives on the cart's
+// shipping fee. The coupon applies when the cart total reaches the threshold
+// and, if the coupon is restricted to some shipping methods, the cart uses one of them.
+// A discount of 100 percent makes shipping free.
+func shippingDiscount(shippingCoupon *models.ShippingCoupon, methods []*models.ShippingCouponMethod, cart dtos.Cart, cartTotal float64) (float64, bool) {
+	if cart.ShippingFee <= 0 || cartTotal < shippingCoupon.Threshold {
+		return 0, false
+	}
+
+	if len(methods) > 0 {
+		allowed := false
+		for _, method := range methods {
+			if method.Method == cart.ShippingMethod {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return 0, false
+		}
+	}
+
+	discount := (shippingCoupon.Discount / 100) * cart.ShippingFee
+	if shippingCoupon.MaxDiscount > 0 && discount > shippingCoupon.MaxDiscount {
+		discount = shippingCoupon.MaxDiscount
+	}
+	if discount > cart.ShippingFee {
+		discount = cart.ShippingFee
+	}
+
+	return discount, discount > 0
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestApplyShippingCoupon(t *testing.T) {
+	items := []dtos.CartItem{{ProductId: "A", Quantity: 2, Price: 50}}
+
+	tests := []struct {
+		name             string
+		details          dtos.CouponDetails
+		cart             dtos.Cart
+		shippingDiscount float64
+	}{
+		{
+			name:             "free shipping",
+			details:          dtos.CouponDetails{Discount: 100},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12},
+			shippingDiscount: 12,
+		},
+		{
+			name:             "percentage of the fee",
+			details:          dtos.CouponDetails{Discount: 50},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12},
+			shippingDiscount: 6,
+		},
+		{
+			name:             "discount is capped",
+			details:          dtos.CouponDetails{Discount: 100, MaxDiscount: 5},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12},
+			shippingDiscount: 5,
+		},
+		{
+			name:             "cart below the threshold",
+			details:          dtos.CouponDetails{Discount: 100, Threshold: 150},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12},
+			shippingDiscount: 0,
+		},
+		{
+			name:             "allowed shipping method",
+			details:          dtos.CouponDetails{Discount: 100, ShippingMethods: []string{"standard"}},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12, ShippingMethod: "standard"},
+			shippingDiscount: 12,
+		},
+		{
+			name:             "other shipping method",
+			details:          dtos.CouponDetails{Discount: 100, ShippingMethods: []string{"standard"}},
+			cart:             dtos.Cart{Items: items, ShippingFee: 12, ShippingMethod: "express"},
+			shippingDiscount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "shipping", Details: tt.details})
+
+			updatedCart := applyCoupon(t, service, couponId, tt.cart)
+
+			assertMoney(t, "shipping discount", updatedCart.ShippingDiscount, tt.shippingDiscount)
+			assertMoney(t, "final shipping", updatedCart.FinalShipping, tt.cart.ShippingFee-tt.shippingDiscount)
+			// Shipping coupons never touch the price of the items
+			assertMoney(t, "total discount", updatedCart.TotalDiscount, 0)
+		})
+	}
+}
+
+func TestApplicableShippingCouponNeedsAFee(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "shipping", Details: dtos.CouponDetails{Discount: 100}})
+	items := []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 10}}
+
+	response, err := service.GetApplicableCoupons(testContext(""), dtos.Cart{Items: items}, nil)
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if len(response.ApplicableCoupons) != 0 {
+		t.Errorf("applicable coupons = %v, want none without a shipping fee", response.ApplicableCoupons)
+	}
+
+	response, err = service.GetApplicableCoupons(testContext(""), dtos.Cart{Items: items, ShippingFee: 4}, nil)
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if len(response.ApplicableCoupons) != 1 || response.ApplicableCoupons[0].CouponID != couponId {
+		t.Fatalf("applicable coupons = %v, want %s", response.ApplicableCoupons, couponId)
+	}
+	assertMoney(t, "discount", response.ApplicableCoupons[0].Discount, 4)
+}
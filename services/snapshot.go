@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/db"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// The last index built from the database. Pricing falls back to it while
+	// the database cannot be reached.
+	couponSnapshot atomic.Pointer[CouponIndex]
+	// File the snapshot is saved to, empty to keep it in memory only
+	snapshotPath string
+)
+
+// InitCouponSnapshot sets the file the coupon snapshot is saved to and loads
+// the snapshot a previous run left there, so carts can be priced even if the
+// database is down at startup. An empty path keeps the snapshot in memory only.
+func InitCouponSnapshot(path string) error {
+	snapshotPath = path
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var couponRules []*models.CouponRules
+	err = json.Unmarshal(data, &couponRules)
+	if err != nil {
+		return err
+	}
+
+	couponSnapshot.CompareAndSwap(nil, NewCouponIndex(models.NewRuleSetFromRules(couponRules)))
+	return nil
+}
+
+// storeCouponSnapshot keeps a freshly built index as the snapshot and saves
+// its active coupons to disk
+func storeCouponSnapshot(ctx *context.Context, index *CouponIndex) {
+	couponSnapshot.Store(index)
+	if snapshotPath == "" {
+		return
+	}
+
+	var active []*models.CouponRules
+	for _, rules := range index.RuleSet().Coupons {
+		if rules.Coupon.IsActive {
+			active = append(active, rules)
+		}
+	}
+
+	err := writeFileAtomic(snapshotPath, active)
+	if err != nil {
+		ctx.Log.Warn("failed to save coupon snapshot", zap.Error(err))
+	}
+}
+
+// writeFileAtomic writes v as JSON next to path and renames it into place,
+// so a crash never leaves a half-written snapshot behind
+func writeFileAtomic(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// pricingIndex returns the coupon index to price carts with. When the
+// database cannot be reached it falls back to the snapshot and reports that
+// the result is degraded.
+func (c *CouponService) pricingIndex(ctx *context.Context) (*CouponIndex, bool, error) {
+	index, err := c.getCouponIndex(ctx)
+	if err == nil {
+		// Changes made elsewhere cannot reach us while the database is down
+		return index, !db.Available(), nil
+	}
+	if !db.IsUnavailable(err) {
+		return nil, false, err
+	}
+
+	snapshot := couponSnapshot.Load()
+	if snapshot == nil {
+		return nil, false, err
+	}
+	ctx.Log.Warn("database unavailable, pricing from coupon snapshot", zap.Error(err))
+	return snapshot, true, nil
+}
+
+// pricingRules returns the rules of a single coupon, falling back to the
+// snapshot when the database cannot be reached
+func (c *CouponService) pricingRules(ctx *context.Context, couponId string) (*models.CouponRules, bool, error) {
+	rules, err := c.getCouponRules(ctx, couponId)
+	if err == nil {
+		return rules, !db.Available(), nil
+	}
+	if !db.IsUnavailable(err) {
+		return nil, false, err
+	}
+
+	snapshot := couponSnapshot.Load()
+	if snapshot == nil {
+		return nil, false, err
+	}
+	rules = snapshot.RuleSet().Get(couponId)
+	if rules == nil {
+		return nil, false, err
+	}
+	ctx.Log.Warn("database unavailable, pricing from coupon snapshot", zap.Error(err))
+	return rules, true, nil
+}
+
+// pricingCustomer resolves the customer context. When the order history
+// cannot be read it goes on with what the client sent and reports that the
+// result is degraded.
+func (c *CouponService) pricingCustomer(ctx *context.Context, customer *dtos.Customer) (*dtos.Customer, bool, error) {
+	resolved, err := c.resolveCustomer(ctx, customer)
+	if err == nil {
+		return resolved, false, nil
+	}
+	if !db.IsUnavailable(err) {
+		return nil, false, err
+	}
+
+	ctx.Log.Warn("database unavailable, using customer context from the request", zap.Error(err))
+	return customer, true, nil
+}
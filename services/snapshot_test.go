@@ -0,0 +1,224 @@
+package services
+
+import (
+	stderrors "errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/breaker"
+	"monk-commerce-assignment/utils/context"
+	"monk-commerce-assignment/utils/db"
+)
+
+// unreachableCoupons fails coupon reads the way the database does while the
+// breaker is open
+type unreachableCoupons struct {
+	daos.ICoupon
+}
+
+func (u unreachableCoupons) GetAllCoupons(ctx *context.Context) ([]*models.Coupon, error) {
+	if err := db.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return u.ICoupon.GetAllCoupons(ctx)
+}
+
+func (u unreachableCoupons) GetCouponById(ctx *context.Context, id string) (*models.Coupon, error) {
+	if err := db.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return u.ICoupon.GetCouponById(ctx, id)
+}
+
+// unreachableCustomers fails order history reads the way the database does
+// while the breaker is open
+type unreachableCustomers struct {
+	daos.ICustomer
+}
+
+func (u unreachableCustomers) GetCustomerHistory(ctx *context.Context, customerId string) (*models.CustomerHistory, error) {
+	if err := db.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+	return u.ICustomer.GetCustomerHistory(ctx, customerId)
+}
+
+// newDegradableService returns a coupon service on a fresh in-memory store
+// whose reads fail while db.Breaker is open. The breaker and the snapshot are
+// restored afterwards.
+func newDegradableService(t *testing.T) (*CouponService, daos.Repositories) {
+	t.Helper()
+	repositories := daos.NewMemoryRepositories()
+	ResetCouponIndex()
+
+	previous := db.Breaker
+	db.Breaker = breaker.New(1, time.Hour)
+	couponSnapshot.Store(nil)
+	t.Cleanup(func() {
+		db.Breaker = previous
+		couponSnapshot.Store(nil)
+		ResetCouponIndex()
+	})
+
+	service := NewCouponService(unreachableCoupons{repositories.Coupons}, unreachableCustomers{repositories.Customers}).(*CouponService)
+	return service, repositories
+}
+
+// buildSnapshot lists the coupons applicable to a cart while the database is
+// up, which builds the coupon index and keeps it as the snapshot
+func buildSnapshot(t *testing.T, service *CouponService) {
+	t.Helper()
+	if _, err := service.GetApplicableCoupons(testContext(""), cartOf(200), nil); err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+}
+
+// openBreaker forces db.Breaker open and drops the coupon index and cache, so
+// pricing has to read the database again
+func openBreaker() {
+	db.Breaker.Failure()
+	ResetCouponIndex()
+}
+
+func TestPricingFromSnapshot(t *testing.T) {
+	service, _ := newDegradableService(t)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	cart := cartOf(200)
+
+	applicable, err := service.GetApplicableCoupons(testContext(""), cart, nil)
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if applicable.Degraded {
+		t.Errorf("degraded = true with the database up")
+	}
+
+	openBreaker()
+
+	applicable, err = service.GetApplicableCoupons(testContext(""), cart, nil)
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if !applicable.Degraded {
+		t.Errorf("degraded = false while pricing from the snapshot")
+	}
+	if len(applicable.ApplicableCoupons) != 1 || applicable.ApplicableCoupons[0].CouponID != couponId {
+		t.Errorf("applicable coupons = %+v, want %s from the snapshot", applicable.ApplicableCoupons, couponId)
+	}
+
+	updatedCart, err := service.ApplyCoupon(testContext(""), couponId, cart, nil)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	if !updatedCart.Degraded {
+		t.Errorf("degraded = false while pricing from the snapshot")
+	}
+	assertMoney(t, "discount", updatedCart.TotalDiscount, 20)
+}
+
+func TestPricingWithoutSnapshot(t *testing.T) {
+	service, _ := newDegradableService(t)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	openBreaker()
+
+	// Nothing was priced before the database went down, so there is no snapshot
+	_, err := service.GetApplicableCoupons(testContext(""), cartOf(200), nil)
+	if !stderrors.Is(err, breaker.ErrOpen) {
+		t.Errorf("GetApplicableCoupons() error = %v, want %v", err, breaker.ErrOpen)
+	}
+	_, err = service.ApplyCoupon(testContext(""), couponId, cartOf(200), nil)
+	if !stderrors.Is(err, breaker.ErrOpen) {
+		t.Errorf("ApplyCoupon() error = %v, want %v", err, breaker.ErrOpen)
+	}
+}
+
+func TestPricingIsDegradedWhileBreakerIsOpen(t *testing.T) {
+	service, _ := newDegradableService(t)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	cart := cartOf(200)
+	buildSnapshot(t, service)
+
+	// The index is still in memory, but changes made elsewhere cannot reach it
+	db.Breaker.Failure()
+	updatedCart, err := service.ApplyCoupon(testContext(""), couponId, cart, nil)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	if !updatedCart.Degraded {
+		t.Errorf("degraded = false with the breaker open")
+	}
+
+	// The customer history cannot be read either
+	applicable, err := service.GetApplicableCoupons(testContext(""), cart, &dtos.Customer{Id: "customer-1"})
+	if err != nil {
+		t.Fatalf("GetApplicableCoupons() error = %v", err)
+	}
+	if !applicable.Degraded {
+		t.Errorf("degraded = false without the customer history")
+	}
+}
+
+func TestRedemptionRefusedWhileDegraded(t *testing.T) {
+	service, repositories := newDegradableService(t)
+	redemptions := NewRedemptionService(repositories.Customers, service)
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	buildSnapshot(t, service)
+	openBreaker()
+
+	_, err := redeem(redemptions, couponId, "order-1", "customer-1", cartOf(200))
+	if !stderrors.Is(err, db.ErrUnavailable) {
+		t.Errorf("RedeemCoupon() error = %v, want %v", err, db.ErrUnavailable)
+	}
+	err = redemptions.RecordOrder(testContext(""), &dtos.OrderRequest{OrderId: "order-2", CustomerId: "customer-1", Total: 100})
+	if !stderrors.Is(err, db.ErrUnavailable) {
+		t.Errorf("RecordOrder() error = %v, want %v", err, db.ErrUnavailable)
+	}
+
+	// Once the database is back redemptions go through again
+	db.Breaker.Success()
+	redemption, err := redeem(redemptions, couponId, "order-1", "customer-1", cartOf(200))
+	if err != nil {
+		t.Fatalf("RedeemCoupon() error = %v", err)
+	}
+	if redemption.UpdatedCart.Degraded {
+		t.Errorf("degraded = true after the breaker closed")
+	}
+	history, err := repositories.Customers.GetCustomerHistory(testContext(""), "customer-1")
+	if err != nil {
+		t.Fatalf("GetCustomerHistory() error = %v", err)
+	}
+	if history.OrderCount != 1 {
+		t.Errorf("%d orders are recorded, want only the one redeemed after the breaker closed", history.OrderCount)
+	}
+}
+
+func TestSnapshotSurvivesRestart(t *testing.T) {
+	service, _ := newDegradableService(t)
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	previousPath := snapshotPath
+	t.Cleanup(func() { snapshotPath = previousPath })
+	if err := InitCouponSnapshot(path); err != nil {
+		t.Fatalf("InitCouponSnapshot() error = %v", err)
+	}
+	couponId := createLiveCoupon(t, service, tenPercentOff)
+	buildSnapshot(t, service)
+
+	// A new process starts with the database down and only the file
+	couponSnapshot.Store(nil)
+	openBreaker()
+	if err := InitCouponSnapshot(path); err != nil {
+		t.Fatalf("InitCouponSnapshot() error = %v", err)
+	}
+	updatedCart, err := service.ApplyCoupon(testContext(""), couponId, cartOf(200), nil)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	if !updatedCart.Degraded {
+		t.Errorf("degraded = false while pricing from the snapshot")
+	}
+	assertMoney(t, "discount", updatedCart.TotalDiscount, 20)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"time"
+
+	"monk-commerce-assignment/constants"
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/utils/context"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCouponRefreshInterval = time.Minute
+	// How long changes announced by other instances are collected before
+	// they are applied to the coupon index in one go
+	couponChangeDelay = 100 * time.Millisecond
+)
+
+// couponChanged drops a changed coupon from the cache of this instance and
+// patches it into the coupon index. Other instances do the same when the
+// change notification reaches them.
+func (c *CouponService) couponChanged(ctx *context.Context, couponId string) {
+	c.couponsChanged(ctx, []string{couponId})
+}
+
+// couponsChanged is couponChanged for many coupons, with a single update of
+// the coupon index
+func (c *CouponService) couponsChanged(ctx *context.Context, couponIds []string) {
+	if len(couponIds) == 0 {
+		return
+	}
+	for _, couponId := range couponIds {
+		daos.InvalidateCoupon(couponId)
+	}
+	c.patchCouponIndex(ctx, couponIds)
+}
+
+// StartCouponSync keeps the coupon cache and index of this instance in step
+// with the database. It listens for the changes other instances announce on
+// daos.CouponChangesChannel, skipping the ones this instance made, and, as a
+// fallback for missed notifications, reloads every coupon at the given interval.
+func StartCouponSync(repositories daos.Repositories, databaseURL string, refreshInterval time.Duration) {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultCouponRefreshInterval
+	}
+
+	listener := pq.NewListener(databaseURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			constants.Logger.Warn("coupon change listener error", zap.Error(err))
+		}
+	})
+	go func() {
+		// Listen blocks until the listener is connected, which can take a
+		// while if the database is down at startup
+		err := listener.Listen(daos.CouponChangesChannel)
+		if err != nil {
+			constants.Logger.Error("unable to listen for coupon changes", zap.Error(err))
+		}
+	}()
+
+	service := &CouponService{
+		db:        daos.NewCachedCoupon(repositories.Coupons),
+		customers: repositories.Customers,
+	}
+	go syncCoupons(listener, refreshInterval, service)
+}
+
+func syncCoupons(listener *pq.Listener, refreshInterval time.Duration, service *CouponService) {
+	ctx := context.Background("coupon-sync", constants.Logger)
+
+	refresh := time.NewTicker(refreshInterval)
+	defer refresh.Stop()
+
+	// Changes made by other instances are applied a little later, together
+	// with the ones that arrive in the meantime
+	var changes couponChangeBatch
+	apply := time.NewTimer(couponChangeDelay)
+	apply.Stop()
+
+	reload := func() {
+		changes.take()
+		daos.InvalidateCoupons()
+		service.refreshCouponIndex(ctx)
+	}
+
+	for {
+		select {
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// The connection was re-established and notifications may
+				// have been lost in between
+				ctx.Log.Info("coupon change listener reconnected, reloading coupons")
+				reload()
+				continue
+			}
+			change := daos.ParseCouponChange(notification.Extra)
+			if change.Instance == daos.InstanceID {
+				// This instance applied the change when it made it
+				continue
+			}
+			ctx.Log.Debug("coupon changed", zap.String("coupon_id", change.CouponID))
+			if changes.add(change.CouponID) {
+				apply.Reset(couponChangeDelay)
+			}
+
+		case <-apply.C:
+			service.couponsChanged(ctx, changes.take())
+
+		case <-refresh.C:
+			reload()
+			// Check the listener connection is still alive
+			go listener.Ping()
+		}
+	}
+}
+
+// couponChangeBatch collects the coupons changed since the last update of
+// the coupon index, each once
+type couponChangeBatch struct {
+	couponIds []string
+	seen      map[string]bool
+}
+
+// add records a changed coupon. It reports whether the batch was empty, in
+// which case the caller schedules the update.
+func (b *couponChangeBatch) add(couponId string) bool {
+	if b.seen == nil {
+		b.seen = make(map[string]bool)
+	}
+	if b.seen[couponId] {
+		return false
+	}
+	b.seen[couponId] = true
+	b.couponIds = append(b.couponIds, couponId)
+	return len(b.couponIds) == 1
+}
+
+// take empties the batch and returns the coupons it held
+func (b *couponChangeBatch) take() []string {
+	couponIds := b.couponIds
+	b.couponIds = nil
+	b.seen = nil
+	return couponIds
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"monk-commerce-assignment/daos"
+)
+
+func TestCouponChangeBatch(t *testing.T) {
+	var batch couponChangeBatch
+
+	if !batch.add("a") {
+		t.Error("add() to an empty batch = false, want true")
+	}
+	if batch.add("b") || batch.add("a") {
+		t.Error("add() to a batch with changes = true, want false")
+	}
+	if got, want := batch.take(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("take() = %v, want %v", got, want)
+	}
+
+	// The batch starts over once taken
+	if got := batch.take(); got != nil {
+		t.Errorf("take() of an empty batch = %v, want nil", got)
+	}
+	if !batch.add("a") {
+		t.Error("add() after take() = false, want true")
+	}
+}
+
+func TestParseCouponChange(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    daos.CouponChange
+	}{
+		{`{"instance":"i-1","coupon_id":"c-1"}`, daos.CouponChange{Instance: "i-1", CouponID: "c-1"}},
+		// Sent by instances that predate the instance ID
+		{"c-1", daos.CouponChange{CouponID: "c-1"}},
+	}
+
+	for _, tt := range tests {
+		if got := daos.ParseCouponChange(tt.payload); got != tt.want {
+			t.Errorf("ParseCouponChange(%q) = %+v, want %+v", tt.payload, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"sync"
+	"testing"
+
+	"monk-commerce-assignment/daos"
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+	"monk-commerce-assignment/utils/errors"
+)
+
+func TestConcurrentSubmitsOfACoupon(t *testing.T) {
+	service, repositories := newTestService(t)
+	ctx := testContext(testEditor)
+	coupon, err := service.CreateCoupon(ctx, &dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+
+	// Submits that overlap conflict when they commit and are retried, the
+	// retry finds the coupon submitted already
+	const submits = 8
+	errs := make([]error, submits)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = service.SubmitCoupon(testContext(testEditor), coupon.Id)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errorCode(err) != errors.CodeConflict:
+			t.Errorf("SubmitCoupon() error = %v, want a conflict", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d submits succeeded, want 1", succeeded)
+	}
+
+	entries, err := repositories.Coupons.ListCouponAuditEntries(ctx, &daos.AuditQuery{CouponId: coupon.Id, Action: models.AuditSubmitted, Limit: submits})
+	if err != nil {
+		t.Fatalf("ListCouponAuditEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d submits are audited, want 1", len(entries))
+	}
+}
+
+func TestFailedWriteLeavesNothingBehind(t *testing.T) {
+	service, repositories := newTestService(t)
+	ctx := testContext(testEditor)
+	req := dtos.Coupon{Type: "cart-wise", Code: "SAVE10", Details: dtos.CouponDetails{Discount: 10}}
+	if _, err := service.CreateCoupon(ctx, &req); err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+
+	_, err := service.CreateCoupon(ctx, &req)
+	if errorCode(err) != errors.CodeConflict {
+		t.Fatalf("CreateCoupon() with a taken code error = %v, want a conflict", err)
+	}
+
+	coupons, err := repositories.Coupons.GetAllCoupons(ctx)
+	if err != nil {
+		t.Fatalf("GetAllCoupons() error = %v", err)
+	}
+	if len(coupons) != 1 {
+		t.Errorf("%d coupons are stored, want 1", len(coupons))
+	}
+	entries, err := repositories.Coupons.ListCouponAuditEntries(ctx, &daos.AuditQuery{Action: models.AuditCreated, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListCouponAuditEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d creations are audited, want 1", len(entries))
+	}
+}
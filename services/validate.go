@@ -0,0 +1,236 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// validator collects the problems found in a request, each with the path of
+// the field it is about
+type validator struct {
+	problems []dtos.FieldError
+}
+
+func (v *validator) report(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, dtos.FieldError{
+		Field:   path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns a validation error listing every problem, or nil if there are none
+func (v *validator) err(message string) error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	problems := make([]string, len(v.problems))
+	for i, problem := range v.problems {
+		problems[i] = problem.Field + " " + problem.Message
+	}
+	return errors.Validation("%s: %s", message, strings.Join(problems, "; ")).WithDetails(v.problems)
+}
+
+// rule checks a value found at path
+type rule[T any] func(v *validator, path string, value T)
+
+func joinPath(path, name string) string {
+	if path == "" || name == "" {
+		return path + name
+	}
+	return path + "." + name
+}
+
+// all runs every rule on the value
+func all[T any](rules ...rule[T]) rule[T] {
+	return func(v *validator, path string, value T) {
+		for _, r := range rules {
+			r(v, path, value)
+		}
+	}
+}
+
+// field runs rules on a field of the value
+func field[T, F any](name string, get func(T) F, rules ...rule[F]) rule[T] {
+	return func(v *validator, path string, value T) {
+		all(rules...)(v, joinPath(path, name), get(value))
+	}
+}
+
+// each runs rules on every element of a list
+func each[T any](rules ...rule[T]) rule[[]T] {
+	return func(v *validator, path string, values []T) {
+		for i, value := range values {
+			all(rules...)(v, fmt.Sprintf("%s[%d]", path, i), value)
+		}
+	}
+}
+
+type number interface {
+	~int | ~float64
+}
+
+func greaterThan[N number](min N) rule[N] {
+	return func(v *validator, path string, value N) {
+		if value <= min {
+			v.report(path, "must be > %v", min)
+		}
+	}
+}
+
+func atLeast[N number](min N) rule[N] {
+	return func(v *validator, path string, value N) {
+		if value < min {
+			v.report(path, "must be >= %v", min)
+		}
+	}
+}
+
+func required() rule[string] {
+	return func(v *validator, path string, value string) {
+		if strings.TrimSpace(value) == "" {
+			v.report(path, "is required")
+		}
+	}
+}
+
+func oneOf(values ...string) rule[string] {
+	return func(v *validator, path string, value string) {
+		for _, allowed := range values {
+			if value == allowed {
+				return
+			}
+		}
+		v.report(path, "must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+// unique reports elements of a list that repeat the key of an earlier one
+func unique[T any](name string, key func(T) string) rule[[]T] {
+	return func(v *validator, path string, values []T) {
+		seen := make(map[string]int)
+		for i, value := range values {
+			k := key(value)
+			if first, ok := seen[k]; ok {
+				v.report(joinPath(fmt.Sprintf("%s[%d]", path, i), name), "duplicates %s[%d]", path, first)
+				continue
+			}
+			seen[k] = i
+		}
+	}
+}
+
+var eligibilityRules = all(
+	field("segments", func(e dtos.Eligibility) []string { return e.Segments }, each(required())),
+	field("min_order_count", func(e dtos.Eligibility) int { return e.MinOrderCount }, atLeast(0)),
+	field("min_lifetime_spend", func(e dtos.Eligibility) float64 { return e.MinLifetimeSpend }, atLeast(0.0)),
+	field("lapsed_days", func(e dtos.Eligibility) int { return e.LapsedDays }, atLeast(0)),
+	field("max_days_since_signup", func(e dtos.Eligibility) int { return e.MaxDaysSinceSignup }, atLeast(0)),
+)
+
+var cartRules = all(
+	field("items", func(c dtos.Cart) []dtos.CartItem { return c.Items }, each(
+		field("product_id", func(i dtos.CartItem) string { return i.ProductId }, required()),
+		field("quantity", func(i dtos.CartItem) int { return i.Quantity }, greaterThan(0)),
+		field("price", func(i dtos.CartItem) float64 { return i.Price }, atLeast(0.0)),
+	)),
+	field("shipping_fee", func(c dtos.Cart) float64 { return c.ShippingFee }, atLeast(0.0)),
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validateCoupon checks a coupon definition before it is stored
+func validateCoupon(req *dtos.Coupon) error {
+	v := &validator{}
+
+	if couponType := lookupCouponType(req.Type); couponType != nil {
+		validateDetails(v, couponType, req)
+	} else {
+		oneOf(couponTypeNames()...)(v, "type", req.Type)
+	}
+
+	if req.Code != "" && !couponCodePattern.MatchString(strings.TrimSpace(req.Code)) {
+		v.report("code", "must be 1 to 64 letters, digits, dashes or underscores")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		v.report("ends_at", "must be after starts_at")
+	}
+
+	validateDisplay(v, req)
+
+	if req.Eligibility != nil {
+		eligibilityRules(v, "eligibility", *req.Eligibility)
+	}
+
+	// Reject conditions that do not parse or type-check
+	if req.Condition != "" {
+		if _, err := compileCondition(req.Condition); err != nil {
+			v.report("condition", "is invalid: %v", err)
+		}
+	}
+
+	return v.err("invalid coupon")
+}
+
+// validateDetails checks the details of a coupon against the schema of its
+// type, then against the rules the schema cannot express
+func validateDetails(v *validator, couponType *couponType, req *dtos.Coupon) {
+	details, err := detailsDocument(req)
+	if err != nil {
+		v.report("details", "is invalid: %v", err)
+		return
+	}
+	problems := couponType.details.Validate("details", details)
+	for _, problem := range problems {
+		v.report(problem.Path, "%s", problem.Message)
+	}
+
+	if len(problems) == 0 && couponType.rules != nil {
+		couponType.rules(v, "details", req.Details)
+	}
+}
+
+// detailsDocument returns the details of a coupon as a decoded JSON document.
+// Coupons built in code rather than decoded from a request have no raw
+// details; their zero fields are left out, as a client would leave them out.
+func detailsDocument(req *dtos.Coupon) (interface{}, error) {
+	raw := req.RawDetails
+	if raw == nil {
+		var err error
+		raw, err = json.Marshal(req.Details)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var details interface{}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return nil, err
+	}
+	if req.RawDetails == nil {
+		document := details.(map[string]interface{})
+		for name, value := range document {
+			if value == nil || reflect.ValueOf(value).IsZero() || isEmptyList(value) {
+				delete(document, name)
+			}
+		}
+	}
+	return details, nil
+}
+
+func isEmptyList(value interface{}) bool {
+	list, ok := value.([]interface{})
+	return ok && len(list) == 0
+}
+
+// validateCart checks a cart sent to be priced
+func validateCart(cart dtos.Cart) error {
+	v := &validator{}
+	cartRules(v, "cart", cart)
+	return v.err("invalid cart")
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/utils/errors"
+)
+
+// problems returns the field errors of a validation error, nil when err is nil
+func problems(t *testing.T, err error) []dtos.FieldError {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var domainErr *errors.Error
+	if !stderrors.As(err, &domainErr) || domainErr.Code != errors.CodeValidation {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	return domainErr.Details.([]dtos.FieldError)
+}
+
+func TestValidateCoupon(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		problems []dtos.FieldError
+	}{
+		{
+			name: "valid",
+			src:  `{"type": "cart-wise", "details": {"threshold": 100, "discount": 10}}`,
+		},
+		{
+			name:     "unknown type",
+			src:      `{"type": "percent", "details": {}}`,
+			problems: []dtos.FieldError{{Field: "type", Message: "must be one of cart-wise, product-wise, bxgy, volume, fixed-price, shipping"}},
+		},
+		{
+			name:     "missing detail",
+			src:      `{"type": "cart-wise", "details": {"threshold": 100}}`,
+			problems: []dtos.FieldError{{Field: "details.discount", Message: "is required"}},
+		},
+		{
+			name: "details out of bounds",
+			src:  `{"type": "cart-wise", "details": {"threshold": -1, "discount": 101}}`,
+			problems: []dtos.FieldError{
+				{Field: "details.discount", Message: "must be <= 100"},
+				{Field: "details.threshold", Message: "must be >= 0"},
+			},
+		},
+		{
+			name:     "unknown detail",
+			src:      `{"type": "cart-wise", "details": {"discount": 10, "extra": 1}}`,
+			problems: []dtos.FieldError{{Field: "details.extra", Message: "is not a known field"}},
+		},
+		{
+			name: "invalid product lists",
+			src:  `{"type": "bxgy", "details": {"buy_products": [], "get_products": [{"product_id": "", "quantity": 0}], "repitition_limit": 1}}`,
+			problems: []dtos.FieldError{
+				{Field: "details.buy_products", Message: "must not be empty"},
+				{Field: "details.get_products[0].product_id", Message: "must not be blank"},
+				{Field: "details.get_products[0].quantity", Message: "must be > 0"},
+			},
+		},
+		{
+			name:     "product named twice",
+			src:      `{"type": "bxgy", "details": {"buy_products": [{"product_id": "X", "quantity": 1}, {"product_id": "X", "quantity": 2}], "get_products": [{"product_id": "Y", "quantity": 1}], "repitition_limit": 1}}`,
+			problems: []dtos.FieldError{{Field: "details.buy_products[1].product_id", Message: "duplicates details.buy_products[0]"}},
+		},
+		{
+			name:     "invalid code",
+			src:      `{"type": "cart-wise", "code": "no spaces!", "details": {"discount": 10}}`,
+			problems: []dtos.FieldError{{Field: "code", Message: "must be 1 to 64 letters, digits, dashes or underscores"}},
+		},
+		{
+			name:     "validity window ends before it starts",
+			src:      `{"type": "cart-wise", "starts_at": "2026-02-01T00:00:00Z", "ends_at": "2026-01-01T00:00:00Z", "details": {"discount": 10}}`,
+			problems: []dtos.FieldError{{Field: "ends_at", Message: "must be after starts_at"}},
+		},
+		{
+			name:     "invalid condition",
+			src:      `{"type": "cart-wise", "condition": "cart.subtotal >", "details": {"discount": 10}}`,
+			problems: []dtos.FieldError{{Field: "condition", Message: "is invalid: unexpected end of expression at position 16"}},
+		},
+		{
+			name: "invalid eligibility",
+			src:  `{"type": "cart-wise", "eligibility": {"segments": [""], "min_order_count": -1}, "details": {"discount": 10}}`,
+			problems: []dtos.FieldError{
+				{Field: "eligibility.segments[0]", Message: "is required"},
+				{Field: "eligibility.min_order_count", Message: "must be >= 0"},
+			},
+		},
+		{
+			name: "invalid locale and metadata",
+			src:  `{"type": "cart-wise", "translations": {"xx_!": {"name": "a"}}, "metadata": [1], "details": {"discount": 10}}`,
+			problems: []dtos.FieldError{
+				{Field: "translations.xx_!", Message: "is not a valid locale, e.g. en or pt-BR"},
+				{Field: "metadata", Message: "must be a JSON object"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req dtos.Coupon
+			if err := json.Unmarshal([]byte(tt.src), &req); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			got := problems(t, validateCoupon(&req))
+			if !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("validateCoupon() problems = %+v, want %+v", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidateCouponBuiltInCode(t *testing.T) {
+	// Zero details are left out like a client would, so the threshold takes
+	// its default instead of being reported
+	req := dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}}
+	if err := validateCoupon(&req); err != nil {
+		t.Errorf("validateCoupon() error = %v", err)
+	}
+
+	req = dtos.Coupon{Type: "cart-wise"}
+	want := []dtos.FieldError{{Field: "details.discount", Message: "is required"}}
+	if got := problems(t, validateCoupon(&req)); !reflect.DeepEqual(got, want) {
+		t.Errorf("validateCoupon() problems = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateCart(t *testing.T) {
+	tests := []struct {
+		name     string
+		cart     dtos.Cart
+		problems []dtos.FieldError
+	}{
+		{
+			name: "valid",
+			cart: dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 1, Price: 0}}, ShippingFee: 5},
+		},
+		{
+			name: "invalid items",
+			cart: dtos.Cart{Items: []dtos.CartItem{
+				{ProductId: "A", Quantity: 1, Price: 10},
+				{ProductId: " ", Quantity: 0, Price: -1},
+			}},
+			problems: []dtos.FieldError{
+				{Field: "cart.items[1].product_id", Message: "is required"},
+				{Field: "cart.items[1].quantity", Message: "must be > 0"},
+				{Field: "cart.items[1].price", Message: "must be >= 0"},
+			},
+		},
+		{
+			name:     "negative shipping fee",
+			cart:     dtos.Cart{ShippingFee: -5},
+			problems: []dtos.FieldError{{Field: "cart.shipping_fee", Message: "must be >= 0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := problems(t, validateCart(tt.cart))
+			if !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("validateCart() problems = %+v, want %+v", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidationErrorListsEveryProblem(t *testing.T) {
+	err := validateCart(dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: 0}}, ShippingFee: -1})
+	want := "invalid cart: cart.items[0].quantity must be > 0; cart.shipping_fee must be >= 0"
+	if err == nil || err.Error() != want {
+		t.Errorf("validateCart() error = %v, want %q", err, want)
+	}
+}
+
+func TestApplyRejectsAnInvalidCart(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{Type: "cart-wise", Details: dtos.CouponDetails{Discount: 10}})
+
+	_, err := service.ApplyCoupon(testContext(""), couponId, dtos.Cart{Items: []dtos.CartItem{{ProductId: "A", Quantity: -1, Price: 10}}}, nil)
+	if got := problems(t, err); len(got) != 1 || got[0].Field != "cart.items[0].quantity" {
+		t.Errorf("ApplyCoupon() problems = %+v, want one about the quantity", got)
+	}
+}
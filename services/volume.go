@@ -0,0 +1,97 @@
+package services
+
+import (
+	"monk-commerce-assignment/dtos"
+	"monk-commerce-assignment/models"
+)
+
+const (
+	// Each cart line reaches a quantity band on its own quantity
+	volumeScopeLine = "line"
+	// The quantities of all lines in the product set are added up
+	// before looking up the band
+	volumeScopeSet = "set"
+)
+
+// volumeLine is the outcome of a volume pricing coupon for a single cart line
+type volumeLine struct {
+	Band         *models.VolumePricingBand
+	UnitDiscount float64
+	Discount     float64
+}
+
+// volumeCouponDetails transforms a volume pricing coupon into the DTO format
+func volumeCouponDetails(rules *models.CouponRules) dtos.CouponDetails {
+	var productIds []string
+	for _, product := range rules.VolumeProducts {
+		productIds = append(productIds, product.ProductID)
+	}
+	var bandsDto []dtos.QuantityBand
+	for _, band := range rules.VolumeBands {
+		bandsDto = append(bandsDto, dtos.QuantityBand{
+			MinQuantity: band.MinQuantity,
+			Discount:    band.Discount,
+		})
+	}
+
+	return dtos.CouponDetails{
+		ProductIds: productIds,
+		Scope:      rules.Volume.Scope,
+		Bands:      bandsDto,
+	}
+}
+
+// volumeLines works out the quantity band and discount reached by each cart line.
+// Lines outside the coupon's product set get an empty result.
+func volumeLines(volumeCoupon *models.VolumePricingCoupon, products []*models.VolumePricingProduct, bands []*models.VolumePricingBand, cartItems []dtos.CartItem) []volumeLine {
+	lines := make([]volumeLine, len(cartItems))
+
+	inSet := make(map[string]bool, len(products))
+	for _, product := range products {
+		inSet[product.ProductID] = true
+	}
+
+	// For set scope every line is priced on the combined quantity of the set
+	setQuantity := 0
+	for _, item := range cartItems {
+		if inSet[item.ProductId] {
+			setQuantity += item.Quantity
+		}
+	}
+
+	for i, item := range cartItems {
+		if !inSet[item.ProductId] {
+			continue
+		}
+
+		quantity := item.Quantity
+		if volumeCoupon.Scope == volumeScopeSet {
+			quantity = setQuantity
+		}
+
+		band := volumeBand(bands, quantity)
+		if band == nil {
+			continue
+		}
+
+		unitDiscount := (band.Discount / 100) * item.Price
+		lines[i] = volumeLine{
+			Band:         band,
+			UnitDiscount: unitDiscount,
+			Discount:     unitDiscount * float64(item.Quantity),
+		}
+	}
+
+	return lines
+}
+
+// volumeBand returns the highest band reached by the given quantity, or nil if none is reached
+func volumeBand(bands []*models.VolumePricingBand, quantity int) *models.VolumePricingBand {
+	var best *models.VolumePricingBand
+	for _, band := range bands {
+		if quantity >= band.MinQuantity && (best == nil || band.MinQuantity > best.MinQuantity) {
+			best = band
+		}
+	}
+	return best
+}
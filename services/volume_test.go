@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	"monk-commerce-assignment/dtos"
+)
+
+func TestApplyVolumeCoupon(t *testing.T) {
+	bands := []dtos.QuantityBand{
+		{MinQuantity: 5, Discount: 10},
+		{MinQuantity: 10, Discount: 20},
+	}
+
+	tests := []struct {
+		name  string
+		scope string
+		items []dtos.CartItem
+		// Discount and band reached by each line, a zero band for none
+		lineDiscounts []float64
+		lineBands     []int
+	}{
+		{
+			name:          "line below the lowest band",
+			scope:         volumeScopeLine,
+			items:         []dtos.CartItem{{ProductId: "A", Quantity: 4, Price: 10}},
+			lineDiscounts: []float64{0},
+			lineBands:     []int{0},
+		},
+		{
+			name:          "line reaches the lowest band",
+			scope:         volumeScopeLine,
+			items:         []dtos.CartItem{{ProductId: "A", Quantity: 5, Price: 10}},
+			lineDiscounts: []float64{5},
+			lineBands:     []int{5},
+		},
+		{
+			name:          "line reaches the highest band",
+			scope:         volumeScopeLine,
+			items:         []dtos.CartItem{{ProductId: "A", Quantity: 12, Price: 10}},
+			lineDiscounts: []float64{24},
+			lineBands:     []int{10},
+		},
+		{
+			name:  "line scope prices each line on its own quantity",
+			scope: volumeScopeLine,
+			items: []dtos.CartItem{
+				{ProductId: "A", Quantity: 3, Price: 10},
+				{ProductId: "B", Quantity: 3, Price: 20},
+			},
+			lineDiscounts: []float64{0, 0},
+			lineBands:     []int{0, 0},
+		},
+		{
+			name:  "set scope adds up the quantities of the set",
+			scope: volumeScopeSet,
+			items: []dtos.CartItem{
+				{ProductId: "A", Quantity: 3, Price: 10},
+				{ProductId: "B", Quantity: 3, Price: 20},
+				{ProductId: "C", Quantity: 10, Price: 5},
+			},
+			lineDiscounts: []float64{3, 6, 0},
+			lineBands:     []int{5, 5, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			couponId := createLiveCoupon(t, service, dtos.Coupon{
+				Type: "volume",
+				Details: dtos.CouponDetails{
+					ProductIds: []string{"A", "B"},
+					Scope:      tt.scope,
+					Bands:      bands,
+				},
+			})
+
+			updatedCart := applyCoupon(t, service, couponId, dtos.Cart{Items: tt.items})
+
+			var totalDiscount float64
+			for i, item := range updatedCart.Items {
+				assertMoney(t, item.ProductId+" discount", item.TotalDiscount, tt.lineDiscounts[i])
+				totalDiscount += tt.lineDiscounts[i]
+
+				minQuantity := 0
+				if item.Band != nil {
+					minQuantity = item.Band.MinQuantity
+				}
+				if minQuantity != tt.lineBands[i] {
+					t.Errorf("%s band = %d, want %d", item.ProductId, minQuantity, tt.lineBands[i])
+				}
+			}
+			assertMoney(t, "total discount", updatedCart.TotalDiscount, totalDiscount)
+		})
+	}
+}
+
+func TestCreateVolumeCouponDefaultsToLineScope(t *testing.T) {
+	service, _ := newTestService(t)
+	couponId := createLiveCoupon(t, service, dtos.Coupon{
+		Type: "volume",
+		Details: dtos.CouponDetails{
+			ProductIds: []string{"A"},
+			Bands:      []dtos.QuantityBand{{MinQuantity: 2, Discount: 50}},
+		},
+	})
+
+	coupon, err := service.GetCouponById(testContext(""), couponId)
+	if err != nil {
+		t.Fatalf("GetCouponById() error = %v", err)
+	}
+	if coupon.Details.Scope != volumeScopeLine {
+		t.Errorf("scope = %q, want %q", coupon.Details.Scope, volumeScopeLine)
+	}
+}
@@ -0,0 +1,83 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testCooldown = 20 * time.Millisecond
+
+func assertState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.State(); got != want {
+		t.Fatalf("State() = %v, want %v", got, want)
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := New(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		b.Failure()
+	}
+	assertState(t, b, Closed)
+
+	// A success resets the count of consecutive failures
+	b.Success()
+	for i := 0; i < 2; i++ {
+		b.Failure()
+	}
+	assertState(t, b, Closed)
+
+	b.Failure()
+	assertState(t, b, Open)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow() error = %v, want %v", err, ErrOpen)
+	}
+}
+
+func TestBreakerCloses(t *testing.T) {
+	b := New(1, testCooldown)
+	b.Failure()
+	assertState(t, b, Open)
+
+	time.Sleep(testCooldown)
+	assertState(t, b, HalfOpen)
+
+	// A single trial call goes through
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("second Allow() error = %v, want %v", err, ErrOpen)
+	}
+
+	b.Success()
+	assertState(t, b, Closed)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() error = %v", err)
+	}
+}
+
+func TestBreakerReopens(t *testing.T) {
+	b := New(5, testCooldown)
+	for i := 0; i < 5; i++ {
+		b.Failure()
+	}
+
+	time.Sleep(testCooldown)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	// A failed trial call opens it for another cooldown, whatever the threshold
+	b.Failure()
+	assertState(t, b, Open)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow() error = %v, want %v", err, ErrOpen)
+	}
+}
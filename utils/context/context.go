@@ -1,27 +1,65 @@
+// Package context holds the request context passed to services and DAOs. It
+// does not depend on any transport: HTTP handlers, CLI commands and
+// background jobs all build one the same way.
 package context
 
 import (
+	stdcontext "context"
+
 	"monk-commerce-assignment/utils/db"
 	"monk-commerce-assignment/utils/log"
 
-	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type Context struct {
-	Log          log.Logger `json:"log"`
-	DB           *db.DBConn `json:"db"`
-	RefID        string     `json:"ref_id"`
-	Transaction  *gorm.DB
-	*gin.Context `json:"context"`
+	// Carries the deadline and cancellation of the request. Database calls
+	// made through DB are cancelled with it.
+	stdcontext.Context `json:"-"`
+	Log                log.Logger `json:"log"`
+	DB                 *db.DBConn `json:"db"`
+	RefID              string     `json:"ref_id"`
+	// Who the request is made by, recorded in the audit log
+	Actor string `json:"actor"`
+	// Roles of the actor, e.g. approver
+	Roles       []string `json:"roles"`
+	Transaction *gorm.DB
+	// Locales the caller prefers for display text, most preferred first
+	Locales []string `json:"locales"`
+}
+
+// New builds a request context. conn is bound to parent, so queries are
+// cancelled when parent is, e.g. when an HTTP client disconnects.
+func New(parent stdcontext.Context, refID string, logger log.Logger, conn *db.DBConn) *Context {
+	if conn != nil && conn.DB != nil {
+		conn = &db.DBConn{
+			DB: conn.WithContext(parent),
+		}
+	}
+
+	return &Context{
+		Context: parent,
+		Log:     logger,
+		DB:      conn,
+		RefID:   refID,
+	}
+}
+
+// Background builds a context for work that does not belong to a request,
+// such as jobs and CLI commands
+func Background(refID string, logger log.Logger) *Context {
+	return New(stdcontext.Background(), refID, logger, db.New())
 }
 
 func (c *Context) Copy() *Context {
 	return &Context{
+		Context:     c.Context,
 		Log:         c.Log,
 		DB:          c.DB,
 		RefID:       c.RefID,
+		Actor:       c.Actor,
+		Roles:       c.Roles,
 		Transaction: c.Transaction,
-		Context:     c.Context.Copy(),
+		Locales:     c.Locales,
 	}
 }
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"monk-commerce-assignment/utils/breaker"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 5 * time.Second
+)
+
+// ErrUnavailable is returned by callers that refuse to go ahead while the
+// database is unreachable
+var ErrUnavailable = errors.New("database is unavailable, try again later")
+
+// Breaker guards every query made through DB. While it is open queries fail
+// right away with breaker.ErrOpen instead of waiting on the network.
+var Breaker = breaker.New(defaultBreakerThreshold, defaultBreakerCooldown)
+
+// Available reports whether the database is believed to be reachable
+func Available() bool {
+	return Breaker.State() != breaker.Open
+}
+
+// IsUnavailable reports whether err means the database could not be reached,
+// as opposed to the query itself failing
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	// The caller gave up, which says nothing about the database
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, breaker.ErrOpen) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exception; 57P01 to 57P03 are raised
+		// while the server shuts down or starts up
+		switch {
+		case pqErr.Code.Class() == "08",
+			pqErr.Code == "57P01",
+			pqErr.Code == "57P02",
+			pqErr.Code == "57P03":
+			return true
+		}
+	}
+
+	return false
+}
+
+// registerBreaker wraps every gorm operation with Breaker
+func registerBreaker(gormDB *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		if err := Breaker.Allow(); err != nil {
+			tx.AddError(err)
+		}
+	}
+	after := func(tx *gorm.DB) {
+		switch {
+		case errors.Is(tx.Error, breaker.ErrOpen):
+			// Rejected by the breaker, the database was never asked
+		case IsUnavailable(tx.Error):
+			Breaker.Failure()
+		default:
+			Breaker.Success()
+		}
+	}
+
+	callbacks := gormDB.Callback()
+	for name, err := range map[string]error{
+		"create:before": callbacks.Create().Before("*").Register("breaker:before_create", before),
+		"create:after":  callbacks.Create().After("*").Register("breaker:after_create", after),
+		"query:before":  callbacks.Query().Before("*").Register("breaker:before_query", before),
+		"query:after":   callbacks.Query().After("*").Register("breaker:after_query", after),
+		"update:before": callbacks.Update().Before("*").Register("breaker:before_update", before),
+		"update:after":  callbacks.Update().After("*").Register("breaker:after_update", after),
+		"delete:before": callbacks.Delete().Before("*").Register("breaker:before_delete", before),
+		"delete:after":  callbacks.Delete().After("*").Register("breaker:after_delete", after),
+		"row:before":    callbacks.Row().Before("*").Register("breaker:before_row", before),
+		"row:after":     callbacks.Row().After("*").Register("breaker:after_row", after),
+		"raw:before":    callbacks.Raw().Before("*").Register("breaker:before_raw", before),
+		"raw:after":     callbacks.Raw().After("*").Register("breaker:after_raw", after),
+	} {
+		if err != nil {
+			log.Println("Unable to register", name, "circuit breaker callback. Err:", err)
+			return err
+		}
+	}
+
+	return nil
+}
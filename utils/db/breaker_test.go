@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"monk-commerce-assignment/utils/breaker"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no error", nil, false},
+		{"refused while unavailable", ErrUnavailable, true},
+		{"breaker open", fmt.Errorf("query: %w", breaker.ErrOpen), true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"connection closed", io.ErrUnexpectedEOF, true},
+		{"connection exception", &pq.Error{Code: "08006"}, true},
+		{"server shutting down", &pq.Error{Code: "57P01"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"record not found", gorm.ErrRecordNotFound, false},
+		{"caller gave up", context.Canceled, false},
+		{"caller timed out", context.DeadlineExceeded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnavailable(tt.err); got != tt.want {
+				t.Errorf("IsUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	previous := Breaker
+	t.Cleanup(func() { Breaker = previous })
+
+	Breaker = breaker.New(1, time.Hour)
+	if !Available() {
+		t.Errorf("Available() = false with the breaker closed")
+	}
+	Breaker.Failure()
+	if Available() {
+		t.Errorf("Available() = true with the breaker open")
+	}
+	if err := Breaker.Allow(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("Allow() error = %v, want %v", err, breaker.ErrOpen)
+	}
+}
@@ -27,14 +27,28 @@ func Init(config *Config) error {
 
 		sqlDB.SetConnMaxLifetime(time.Hour)
 
+		// The connection is checked below instead of by gorm, so the service
+		// can start while the database is down and price from its snapshot
 		DB, err = gorm.Open(postgres.New(postgres.Config{
 			Conn: sqlDB,
-		}), &gorm.Config{})
+		}), &gorm.Config{DisableAutomaticPing: true})
 		if err != nil {
 			log.Println("Unable to open postges gorm connection. Err:", err)
 			return err
 		}
 
+		err = registerBreaker(DB)
+		if err != nil {
+			return err
+		}
+
+		err = sqlDB.Ping()
+		if err != nil {
+			log.Println("Unable to reach postgres, starting in degraded mode. Err:", err)
+			Breaker.Failure()
+			return nil
+		}
+
 		log.Println("Successfully established database connection")
 	}
 
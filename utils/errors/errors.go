@@ -0,0 +1,157 @@
+// Package errors defines the domain errors services return. Each error has a
+// code that tells the transport how to report it, e.g. which HTTP status to
+// answer with, and a message that is safe to show to clients.
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"monk-commerce-assignment/utils/db"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type Code string
+
+const (
+	CodeNotFound      Code = "not_found"
+	CodeValidation    Code = "validation_failed"
+	CodeForbidden     Code = "forbidden"
+	CodeConflict      Code = "conflict"
+	CodeNotApplicable Code = "not_applicable"
+	CodeUnavailable   Code = "unavailable"
+	CodeInternal      Code = "internal"
+)
+
+// Status returns the HTTP status code errors with the code are reported with
+func (c Code) Status() int {
+	switch c {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeNotApplicable:
+		return http.StatusUnprocessableEntity
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+type Error struct {
+	Code    Code
+	Message string
+	// Extra data for the client, e.g. the fields that failed validation
+	Details interface{}
+	// The error that caused this one, logged but not shown to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of the error carrying details for the client
+func (e *Error) WithDetails(details interface{}) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// Wrap returns a copy of the error caused by err
+func (e *Error) Wrap(err error) *Error {
+	clone := *e
+	clone.Err = err
+	return &clone
+}
+
+func newError(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// NotFound reports that the requested resource does not exist
+func NotFound(format string, args ...interface{}) *Error {
+	return newError(CodeNotFound, format, args...)
+}
+
+// Validation reports that the request is malformed or breaks a rule
+func Validation(format string, args ...interface{}) *Error {
+	return newError(CodeValidation, format, args...)
+}
+
+// Forbidden reports that the caller may not make the request, e.g. because
+// it lacks a role
+func Forbidden(format string, args ...interface{}) *Error {
+	return newError(CodeForbidden, format, args...)
+}
+
+// Conflict reports that the request clashes with the current state, e.g. a
+// resource that already exists
+func Conflict(format string, args ...interface{}) *Error {
+	return newError(CodeConflict, format, args...)
+}
+
+// NotApplicable reports that a coupon cannot be used for the cart or customer
+func NotApplicable(format string, args ...interface{}) *Error {
+	return newError(CodeNotApplicable, format, args...)
+}
+
+// Internal reports an unexpected failure. Its cause is not shown to clients.
+func Internal(err error) *Error {
+	return &Error{
+		Code:    CodeInternal,
+		Message: "internal server error",
+		Err:     err,
+	}
+}
+
+// From turns any error into a domain error. Domain errors are returned as
+// they are, storage errors are mapped to the matching code and anything else
+// is an internal error.
+func From(err error) *Error {
+	var domainErr *Error
+	if stderrors.As(err, &domainErr) {
+		return domainErr
+	}
+
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound("resource not found").Wrap(err)
+	}
+	if db.IsUnavailable(err) {
+		return &Error{
+			Code:    CodeUnavailable,
+			Message: db.ErrUnavailable.Error(),
+			Err:     err,
+		}
+	}
+
+	var pqErr *pq.Error
+	if stderrors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return Conflict("resource already exists").Wrap(err)
+		case "foreign_key_violation":
+			return Validation("referenced resource does not exist").Wrap(err)
+		}
+	}
+
+	return Internal(err)
+}
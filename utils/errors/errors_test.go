@@ -0,0 +1,100 @@
+package errors
+
+import (
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"monk-commerce-assignment/utils/db"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code Code
+	}{
+		{"domain error", Forbidden("no"), CodeForbidden},
+		{"record not found", gorm.ErrRecordNotFound, CodeNotFound},
+		{"wrapped record not found", fmt.Errorf("loading: %w", gorm.ErrRecordNotFound), CodeNotFound},
+		{"database unavailable", db.ErrUnavailable, CodeUnavailable},
+		{"bad connection", driver.ErrBadConn, CodeUnavailable},
+		{"connection exception", &pq.Error{Code: "08006"}, CodeUnavailable},
+		{"unique violation", &pq.Error{Code: "23505"}, CodeConflict},
+		{"foreign key violation", &pq.Error{Code: "23503"}, CodeValidation},
+		{"other Postgres error", &pq.Error{Code: "22P02"}, CodeInternal},
+		{"anything else", stderrors.New("boom"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Code != tt.code {
+				t.Errorf("From().Code = %v, want %v", got.Code, tt.code)
+			}
+			// The cause is kept for logging
+			if !stderrors.Is(got, tt.err) {
+				t.Errorf("From() = %v does not wrap %v", got, tt.err)
+			}
+		})
+	}
+}
+
+func TestFromKeepsDomainErrors(t *testing.T) {
+	err := Validation("bad request").WithDetails([]string{"field"})
+	if got := From(fmt.Errorf("wrapped: %w", err)); got != err {
+		t.Errorf("From() = %v, want the domain error itself", got)
+	}
+}
+
+func TestInternalHidesTheCause(t *testing.T) {
+	err := From(stderrors.New("password=secret"))
+	if err.Message != "internal server error" {
+		t.Errorf("Message = %q, want a generic message", err.Message)
+	}
+}
+
+func TestCodeStatus(t *testing.T) {
+	tests := []struct {
+		code Code
+		want int
+	}{
+		{CodeNotFound, http.StatusNotFound},
+		{CodeValidation, http.StatusBadRequest},
+		{CodeForbidden, http.StatusForbidden},
+		{CodeConflict, http.StatusConflict},
+		{CodeNotApplicable, http.StatusUnprocessableEntity},
+		{CodeUnavailable, http.StatusServiceUnavailable},
+		{CodeInternal, http.StatusInternalServerError},
+		{Code("unknown"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.Status(); got != tt.want {
+			t.Errorf("%s.Status() = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestWrapAndWithDetailsCopy(t *testing.T) {
+	base := NotFound("coupon %s not found", "c1")
+	cause := stderrors.New("cause")
+
+	wrapped := base.Wrap(cause)
+	detailed := base.WithDetails("details")
+
+	if base.Err != nil || base.Details != nil {
+		t.Errorf("base error changed to %+v", base)
+	}
+	if wrapped.Error() != "coupon c1 not found: cause" || !stderrors.Is(wrapped, cause) {
+		t.Errorf("Wrap() = %v", wrapped)
+	}
+	if detailed.Details != "details" || detailed.Message != base.Message {
+		t.Errorf("WithDetails() = %+v", detailed)
+	}
+}
@@ -0,0 +1,530 @@
+// Package expr implements a small, sandboxed expression language for coupon
+// conditions, e.g.
+//
+//	cart.subtotal >= 500 && "electronics" in cart.categories && customer.segment == "vip"
+//
+// Expressions can only read the variables declared in their Env and call a
+// fixed set of pure functions (len, lower, upper, contains, starts_with,
+// ends_with, min, max). They are type-checked when compiled and every
+// evaluation is charged against a cost budget, so a condition can never loop,
+// allocate without bound or reach anything outside of its variables.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Type of a value in an expression
+type Type int
+
+const (
+	Invalid Type = iota
+	Number
+	String
+	Bool
+	StringList
+	NumberList
+)
+
+func (t Type) String() string {
+	switch t {
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case StringList:
+		return "list of strings"
+	case NumberList:
+		return "list of numbers"
+	default:
+		return "invalid"
+	}
+}
+
+// elem returns the element type of a list type
+func (t Type) elem() Type {
+	switch t {
+	case StringList:
+		return String
+	case NumberList:
+		return Number
+	default:
+		return Invalid
+	}
+}
+
+// Env declares the variables an expression may refer to and their types.
+// Nothing outside of it is reachable from an expression.
+type Env map[string]Type
+
+// Vars holds the values of the variables for one evaluation. Values are
+// float64, string, bool, []string or []float64 according to the declared type;
+// missing variables evaluate to the zero value of their type.
+type Vars map[string]any
+
+// Limits bound the size of an expression and the work done by each evaluation
+type Limits struct {
+	MaxLength int
+	MaxDepth  int
+	MaxNodes  int
+	MaxCost   int
+}
+
+var DefaultLimits = Limits{
+	MaxLength: 2048,
+	MaxDepth:  32,
+	MaxNodes:  256,
+	MaxCost:   10000,
+}
+
+var (
+	ErrCostLimit      = errors.New("expr: evaluation exceeded its cost limit")
+	ErrDivisionByZero = errors.New("expr: division by zero")
+)
+
+// Program is a compiled, type-checked boolean expression. It is safe for concurrent use.
+type Program struct {
+	source  string
+	eval    evalFn
+	maxCost int
+}
+
+type evalFn func(s *state) any
+
+// state of a single evaluation
+type state struct {
+	vars    Vars
+	cost    int
+	maxCost int
+}
+
+// evalError aborts an evaluation, it is recovered by Program.Eval
+type evalError struct {
+	err error
+}
+
+func (s *state) charge(cost int) {
+	s.cost += cost
+	if s.cost > s.maxCost {
+		panic(evalError{ErrCostLimit})
+	}
+}
+
+// Compile parses and type-checks an expression against the environment.
+// The expression has to evaluate to a bool.
+func Compile(src string, env Env, limits Limits) (*Program, error) {
+	tree, err := parse(src, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	fn, typ, err := compile(tree, env)
+	if err != nil {
+		return nil, err
+	}
+	if typ != Bool {
+		return nil, errorAt(tree.position(), "expression must be a bool, not a %s", typ)
+	}
+
+	return &Program{source: src, eval: fn, maxCost: limits.MaxCost}, nil
+}
+
+// Source returns the text the program was compiled from
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Eval runs the program against the given variables
+func (p *Program) Eval(vars Vars) (result bool, err error) {
+	s := &state{vars: vars, maxCost: p.maxCost}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(evalError)
+			if !ok {
+				panic(r)
+			}
+			result, err = false, e.err
+		}
+	}()
+
+	return p.eval(s).(bool), nil
+}
+
+func compile(n node, env Env) (evalFn, Type, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		value := n.value
+		return func(s *state) any {
+			s.charge(1)
+			return value
+		}, n.typ, nil
+
+	case *identNode:
+		typ, ok := env[n.name]
+		if !ok {
+			return nil, Invalid, errorAt(n.pos, "unknown variable %q", n.name)
+		}
+		name := n.name
+		zero := zeroValue(typ)
+		return func(s *state) any {
+			s.charge(1)
+			v, ok := s.vars[name]
+			if !ok || v == nil {
+				return zero
+			}
+			v, ok = coerce(v, typ)
+			if !ok {
+				panic(evalError{fmt.Errorf("expr: variable %q is not a %s", name, typ)})
+			}
+			return v
+		}, typ, nil
+
+	case *listNode:
+		return compileList(n, env)
+
+	case *unaryNode:
+		x, typ, err := compile(n.x, env)
+		if err != nil {
+			return nil, Invalid, err
+		}
+		switch {
+		case n.op == "!" && typ == Bool:
+			return func(s *state) any {
+				s.charge(1)
+				return !x(s).(bool)
+			}, Bool, nil
+		case n.op == "-" && typ == Number:
+			return func(s *state) any {
+				s.charge(1)
+				return -x(s).(float64)
+			}, Number, nil
+		}
+		return nil, Invalid, errorAt(n.pos, "operator %q is not defined on %s", n.op, typ)
+
+	case *binaryNode:
+		return compileBinary(n, env)
+
+	case *callNode:
+		return compileCall(n, env)
+	}
+
+	return nil, Invalid, errorAt(n.position(), "unsupported expression")
+}
+
+func compileList(n *listNode, env Env) (evalFn, Type, error) {
+	if len(n.elems) == 0 {
+		return nil, Invalid, errorAt(n.pos, "empty lists are not supported")
+	}
+
+	elems := make([]evalFn, len(n.elems))
+	var elemType Type
+	for i, e := range n.elems {
+		fn, typ, err := compile(e, env)
+		if err != nil {
+			return nil, Invalid, err
+		}
+		if i == 0 {
+			elemType = typ
+		}
+		if typ != elemType || (typ != String && typ != Number) {
+			return nil, Invalid, errorAt(e.position(), "list elements must all be strings or all be numbers")
+		}
+		elems[i] = fn
+	}
+
+	if elemType == String {
+		return func(s *state) any {
+			s.charge(len(elems))
+			list := make([]string, len(elems))
+			for i, fn := range elems {
+				list[i] = fn(s).(string)
+			}
+			return list
+		}, StringList, nil
+	}
+	return func(s *state) any {
+		s.charge(len(elems))
+		list := make([]float64, len(elems))
+		for i, fn := range elems {
+			list[i] = fn(s).(float64)
+		}
+		return list
+	}, NumberList, nil
+}
+
+func compileBinary(n *binaryNode, env Env) (evalFn, Type, error) {
+	x, xt, err := compile(n.x, env)
+	if err != nil {
+		return nil, Invalid, err
+	}
+	y, yt, err := compile(n.y, env)
+	if err != nil {
+		return nil, Invalid, err
+	}
+	mismatch := errorAt(n.pos, "operator %q is not defined on %s and %s", n.op, xt, yt)
+
+	switch n.op {
+	case "&&", "||":
+		if xt != Bool || yt != Bool {
+			return nil, Invalid, mismatch
+		}
+		if n.op == "&&" {
+			return func(s *state) any {
+				s.charge(1)
+				return x(s).(bool) && y(s).(bool)
+			}, Bool, nil
+		}
+		return func(s *state) any {
+			s.charge(1)
+			return x(s).(bool) || y(s).(bool)
+		}, Bool, nil
+
+	case "==", "!=":
+		if xt != yt || (xt != Number && xt != String && xt != Bool) {
+			return nil, Invalid, mismatch
+		}
+		equal := n.op == "=="
+		return func(s *state) any {
+			s.charge(1)
+			return (x(s) == y(s)) == equal
+		}, Bool, nil
+
+	case "<", "<=", ">", ">=":
+		if xt != yt || (xt != Number && xt != String) {
+			return nil, Invalid, mismatch
+		}
+		op := n.op
+		return func(s *state) any {
+			s.charge(1)
+			return compare(op, x(s), y(s))
+		}, Bool, nil
+
+	case "in":
+		if yt.elem() == Invalid || yt.elem() != xt {
+			return nil, Invalid, mismatch
+		}
+		return func(s *state) any {
+			s.charge(1)
+			needle := x(s)
+			switch list := y(s).(type) {
+			case []string:
+				s.charge(len(list))
+				for _, v := range list {
+					if v == needle {
+						return true
+					}
+				}
+			case []float64:
+				s.charge(len(list))
+				for _, v := range list {
+					if v == needle {
+						return true
+					}
+				}
+			}
+			return false
+		}, Bool, nil
+
+	case "+", "-", "*", "/", "%":
+		if xt != Number || yt != Number {
+			return nil, Invalid, mismatch
+		}
+		op := n.op
+		return func(s *state) any {
+			s.charge(1)
+			return arithmetic(op, x(s).(float64), y(s).(float64))
+		}, Number, nil
+	}
+
+	return nil, Invalid, errorAt(n.pos, "unknown operator %q", n.op)
+}
+
+func compare(op string, x, y any) bool {
+	var c int
+	switch x := x.(type) {
+	case float64:
+		c = cmp(x, y.(float64))
+	case string:
+		c = strings.Compare(x, y.(string))
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func cmp(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func arithmetic(op string, x, y float64) float64 {
+	switch op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		if y == 0 {
+			panic(evalError{ErrDivisionByZero})
+		}
+		return x / y
+	default:
+		if y == 0 {
+			panic(evalError{ErrDivisionByZero})
+		}
+		return math.Mod(x, y)
+	}
+}
+
+// Functions available to expressions
+func compileCall(n *callNode, env Env) (evalFn, Type, error) {
+	args := make([]evalFn, len(n.args))
+	types := make([]Type, len(n.args))
+	for i, arg := range n.args {
+		fn, typ, err := compile(arg, env)
+		if err != nil {
+			return nil, Invalid, err
+		}
+		args[i], types[i] = fn, typ
+	}
+
+	signature := func(want ...Type) error {
+		if len(types) != len(want) {
+			return errorAt(n.pos, "%s expects %d arguments, got %d", n.fn, len(want), len(types))
+		}
+		for i := range want {
+			if types[i] != want[i] {
+				return errorAt(n.args[i].position(), "argument %d of %s must be a %s, not a %s", i+1, n.fn, want[i], types[i])
+			}
+		}
+		return nil
+	}
+
+	switch n.fn {
+	case "len":
+		if len(types) != 1 || (types[0] != String && types[0].elem() == Invalid) {
+			return nil, Invalid, errorAt(n.pos, "len expects a string or a list")
+		}
+		return func(s *state) any {
+			s.charge(1)
+			switch v := args[0](s).(type) {
+			case string:
+				return float64(len(v))
+			case []string:
+				return float64(len(v))
+			case []float64:
+				return float64(len(v))
+			}
+			return float64(0)
+		}, Number, nil
+
+	case "lower", "upper":
+		if err := signature(String); err != nil {
+			return nil, Invalid, err
+		}
+		transform := strings.ToLower
+		if n.fn == "upper" {
+			transform = strings.ToUpper
+		}
+		return func(s *state) any {
+			v := args[0](s).(string)
+			s.charge(1 + len(v))
+			return transform(v)
+		}, String, nil
+
+	case "contains", "starts_with", "ends_with":
+		if err := signature(String, String); err != nil {
+			return nil, Invalid, err
+		}
+		match := strings.Contains
+		switch n.fn {
+		case "starts_with":
+			match = strings.HasPrefix
+		case "ends_with":
+			match = strings.HasSuffix
+		}
+		return func(s *state) any {
+			v, sub := args[0](s).(string), args[1](s).(string)
+			s.charge(1 + len(v))
+			return match(v, sub)
+		}, Bool, nil
+
+	case "min", "max":
+		if err := signature(Number, Number); err != nil {
+			return nil, Invalid, err
+		}
+		pick := math.Min
+		if n.fn == "max" {
+			pick = math.Max
+		}
+		return func(s *state) any {
+			s.charge(1)
+			return pick(args[0](s).(float64), args[1](s).(float64))
+		}, Number, nil
+	}
+
+	return nil, Invalid, errorAt(n.pos, "unknown function %q", n.fn)
+}
+
+// coerce checks a variable's value against its declared type, widening integers to numbers
+func coerce(v any, t Type) (any, bool) {
+	switch t {
+	case Number:
+		switch n := v.(type) {
+		case float64:
+			return n, true
+		case int:
+			return float64(n), true
+		}
+	case String:
+		_, ok := v.(string)
+		return v, ok
+	case Bool:
+		_, ok := v.(bool)
+		return v, ok
+	case StringList:
+		_, ok := v.([]string)
+		return v, ok
+	case NumberList:
+		_, ok := v.([]float64)
+		return v, ok
+	}
+	return nil, false
+}
+
+func zeroValue(t Type) any {
+	switch t {
+	case Number:
+		return float64(0)
+	case String:
+		return ""
+	case Bool:
+		return false
+	case StringList:
+		return []string(nil)
+	case NumberList:
+		return []float64(nil)
+	}
+	return nil
+}
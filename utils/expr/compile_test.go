@@ -0,0 +1,215 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testEnv = Env{
+	"n":       Number,
+	"m":       Number,
+	"s":       String,
+	"b":       Bool,
+	"strings": StringList,
+	"numbers": NumberList,
+}
+
+func TestEval(t *testing.T) {
+	vars := Vars{
+		"n":       10.0,
+		"m":       3,
+		"s":       "Electronics",
+		"b":       true,
+		"strings": []string{"vip", "staff"},
+		"numbers": []float64{1, 2.5},
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"true", true},
+		{"n == 10", true},
+		{"n + m * 2 == 16", true},
+		{"(n + m) * 2 == 26", true},
+		{"n / 4 == 2.5", true},
+		{"n % m == 1", true},
+		{"-n < 0", true},
+		{"m == 3", true},
+		{`s == "Electronics"`, true},
+		{`s != "electronics"`, true},
+		{`"a" < "b"`, true},
+		{`lower(s) == "electronics"`, true},
+		{`upper(s) == "ELECTRONICS"`, true},
+		{`contains(s, "tron")`, true},
+		{`starts_with(s, "Elec") && ends_with(s, "ics")`, true},
+		{`len(s) == 11 && len(strings) == 2 && len(numbers) == 2`, true},
+		{"min(n, m) == 3 && max(n, m) == 10", true},
+		{`"vip" in strings`, true},
+		{`"new" in strings`, false},
+		{"2.5 in numbers", true},
+		{`s in ["Books", "Electronics"]`, true},
+		{"n in [1, 2, 3]", false},
+		{"!b || n > 100", false},
+		{"b && !(n < 5)", true},
+		{"b == true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, err := Compile(tt.src, testEnv, DefaultLimits)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := program.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalMissingVariables(t *testing.T) {
+	// Missing variables evaluate to the zero value of their type
+	for _, src := range []string{"n == 0", `s == ""`, "!b", "len(strings) == 0", `!("x" in strings)`} {
+		program, err := Compile(src, testEnv, DefaultLimits)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", src, err)
+		}
+		got, err := program.Eval(Vars{"n": nil})
+		if err != nil || !got {
+			t.Errorf("Eval(%q) = %v, %v, want true", src, got, err)
+		}
+	}
+}
+
+func TestEvalShortCircuits(t *testing.T) {
+	// The division is never evaluated
+	program, err := Compile("n > 0 && 1 / n > 0 || n == 0", testEnv, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	got, err := program.Eval(Vars{"n": 0.0})
+	if err != nil || !got {
+		t.Errorf("Eval() = %v, %v, want true", got, err)
+	}
+}
+
+func TestCompileTypeErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		msg string
+	}{
+		{"n", "expression must be a bool, not a number"},
+		{`lower(s)`, "expression must be a bool, not a string"},
+		{"x > 1", `unknown variable "x"`},
+		{"cart.subtotal > 1", `unknown variable "cart.subtotal"`},
+		{`n + "a" > 1`, `operator "+" is not defined on number and string`},
+		{"b && n", `operator "&&" is not defined on bool and number`},
+		{`n == "10"`, `operator "==" is not defined on number and string`},
+		{"strings == strings", `operator "==" is not defined on list of strings and list of strings`},
+		{"b < true", `operator "<" is not defined on bool and bool`},
+		{`n in strings`, `operator "in" is not defined on number and list of strings`},
+		{`"a" in s`, `operator "in" is not defined on string and string`},
+		{"!n", `operator "!" is not defined on number`},
+		{`-s == s`, `operator "-" is not defined on string`},
+		{"[] == n", "empty lists are not supported"},
+		{`n in [1, "a"]`, "list elements must all be strings or all be numbers"},
+		{`b in [true]`, "list elements must all be strings or all be numbers"},
+		{"len(n) > 0", "len expects a string or a list"},
+		{"lower(n) == s", "argument 1 of lower must be a string, not a number"},
+		{`contains(s) `, "contains expects 2 arguments, got 1"},
+		{"min(n, s) > 0", "argument 2 of min must be a number, not a string"},
+		{"eval(s)", `unknown function "eval"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src, testEnv, DefaultLimits)
+			var exprErr *Error
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("Compile() error = %v, want an *Error", err)
+			}
+			if exprErr.Msg != tt.msg {
+				t.Errorf("Compile() error = %q, want %q", exprErr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		vars Vars
+		want error
+	}{
+		{"division by zero", "n / m > 0", Vars{"n": 1.0, "m": 0.0}, ErrDivisionByZero},
+		{"modulo by zero", "n % m > 0", Vars{"n": 1.0, "m": 0.0}, ErrDivisionByZero},
+		{"long list", `"x" in strings`, Vars{"strings": make([]string, DefaultLimits.MaxCost)}, ErrCostLimit},
+		{"long string", `contains(upper(s), "X")`, Vars{"s": strings.Repeat("a", DefaultLimits.MaxCost)}, ErrCostLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.src, testEnv, DefaultLimits)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := program.Eval(tt.vars)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Eval() error = %v, want %v", err, tt.want)
+			}
+			if got {
+				t.Error("Eval() = true on error, want false")
+			}
+		})
+	}
+}
+
+func TestEvalVariableOfTheWrongType(t *testing.T) {
+	program, err := Compile(`s == "1"`, testEnv, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	_, err = program.Eval(Vars{"s": 1.0})
+	if err == nil || !strings.Contains(err.Error(), `variable "s" is not a string`) {
+		t.Errorf("Eval() error = %v, want a type error", err)
+	}
+}
+
+func TestEvalCostLimit(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxCost = 5
+
+	// Each literal, variable and operator costs one
+	program, err := Compile("n + 1 == 2", testEnv, limits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err := program.Eval(Vars{"n": 1.0}); err != nil {
+		t.Errorf("Eval() within the budget error = %v", err)
+	}
+
+	program, err = Compile("n + 1 + 1 == 3", testEnv, limits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err := program.Eval(Vars{"n": 1.0}); !errors.Is(err, ErrCostLimit) {
+		t.Errorf("Eval() over the budget error = %v, want %v", err, ErrCostLimit)
+	}
+}
+
+func TestProgramSource(t *testing.T) {
+	program, err := Compile("b", testEnv, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if program.Source() != "b" {
+		t.Errorf("Source() = %q, want %q", program.Source(), "b")
+	}
+}
@@ -0,0 +1,97 @@
+package expr
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src   string
+		kinds []tokenKind
+		texts []string
+	}{
+		{"", []tokenKind{tokenEOF}, []string{""}},
+		{"  42  ", []tokenKind{tokenNumber, tokenEOF}, []string{"42", ""}},
+		{"1.5 .5", []tokenKind{tokenNumber, tokenNumber, tokenEOF}, []string{"1.5", ".5", ""}},
+		{`"a b" 'c'`, []tokenKind{tokenString, tokenString, tokenEOF}, []string{"a b", "c", ""}},
+		{`"say \"hi\""`, []tokenKind{tokenString, tokenEOF}, []string{`say "hi"`, ""}},
+		{"cart.subtotal _x y2", []tokenKind{tokenIdent, tokenIdent, tokenIdent, tokenEOF}, []string{"cart.subtotal", "_x", "y2", ""}},
+		{
+			"a>=1&&b!=2||!c",
+			[]tokenKind{tokenIdent, tokenOperator, tokenNumber, tokenOperator, tokenIdent, tokenOperator, tokenNumber, tokenOperator, tokenOperator, tokenIdent, tokenEOF},
+			[]string{"a", ">=", "1", "&&", "b", "!=", "2", "||", "!", "c", ""},
+		},
+		{
+			`x in ["a",1]`,
+			[]tokenKind{tokenIdent, tokenIdent, tokenOperator, tokenString, tokenOperator, tokenNumber, tokenOperator, tokenEOF},
+			[]string{"x", "in", "[", "a", ",", "1", "]", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			tokens, err := lex(tt.src)
+			if err != nil {
+				t.Fatalf("lex() error = %v", err)
+			}
+			var kinds []tokenKind
+			var texts []string
+			for _, tok := range tokens {
+				kinds = append(kinds, tok.kind)
+				texts = append(texts, tok.text)
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("kinds = %v, want %v", kinds, tt.kinds)
+			}
+			if !reflect.DeepEqual(texts, tt.texts) {
+				t.Errorf("texts = %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
+
+func TestLexPositions(t *testing.T) {
+	tokens, err := lex(`a <= "b"`)
+	if err != nil {
+		t.Fatalf("lex() error = %v", err)
+	}
+	var positions []int
+	for _, tok := range tokens {
+		positions = append(positions, tok.pos)
+	}
+	if want := []int{0, 2, 5, 8}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{`"open`, 0, "unterminated string"},
+		{`a == 'x`, 5, "unterminated string"},
+		{"1.2.3", 0, `invalid number "1.2.3"`},
+		{"cart.", 0, `invalid identifier "cart."`},
+		{"cart..subtotal", 0, `invalid identifier "cart..subtotal"`},
+		{"a = b", 2, `unexpected character '='`},
+		{"a & b", 2, `unexpected character '&'`},
+		{"$x", 0, `unexpected character '$'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := lex(tt.src)
+			var exprErr *Error
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("lex() error = %v, want an *Error", err)
+			}
+			if exprErr.Pos != tt.pos || exprErr.Msg != tt.msg {
+				t.Errorf("lex() error = %q at %d, want %q at %d", exprErr.Msg, exprErr.Pos, tt.msg, tt.pos)
+			}
+		})
+	}
+}
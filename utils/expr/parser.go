@@ -0,0 +1,259 @@
+package expr
+
+type node interface {
+	position() int
+}
+
+type literalNode struct {
+	pos   int
+	typ   Type
+	value any
+}
+
+type identNode struct {
+	pos  int
+	name string
+}
+
+type unaryNode struct {
+	pos int
+	op  string
+	x   node
+}
+
+type binaryNode struct {
+	pos int
+	op  string
+	x   node
+	y   node
+}
+
+type callNode struct {
+	pos  int
+	fn   string
+	args []node
+}
+
+type listNode struct {
+	pos   int
+	elems []node
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *identNode) position() int   { return n.pos }
+func (n *unaryNode) position() int   { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+
+// Binary operators by precedence, from the loosest to the tightest binding
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4, "in": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	nodes  int
+	limits Limits
+}
+
+// parse builds the syntax tree of an expression, enforcing the size limits
+func parse(src string, limits Limits) (node, error) {
+	if len(src) > limits.MaxLength {
+		return nil, errorAt(limits.MaxLength, "expression is longer than %d characters", limits.MaxLength)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, limits: limits}
+	n, err := p.parseBinary(1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) expect(op string) error {
+	tok := p.next()
+	if tok.kind != tokenOperator || tok.text != op {
+		if tok.kind == tokenEOF {
+			return errorAt(tok.pos, "expected %q, found end of expression", op)
+		}
+		return errorAt(tok.pos, "expected %q, found %q", op, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) newNode(pos int, depth int) error {
+	p.nodes++
+	if p.nodes > p.limits.MaxNodes {
+		return errorAt(pos, "expression has more than %d nodes", p.limits.MaxNodes)
+	}
+	if depth > p.limits.MaxDepth {
+		return errorAt(pos, "expression is nested deeper than %d levels", p.limits.MaxDepth)
+	}
+	return nil
+}
+
+// binaryOp returns the binary operator at the current token, if any
+func (p *parser) binaryOp() (string, bool) {
+	tok := p.peek()
+	if tok.kind == tokenOperator || (tok.kind == tokenIdent && tok.text == "in") {
+		_, ok := precedence[tok.text]
+		return tok.text, ok
+	}
+	return "", false
+}
+
+// parseBinary parses operators binding at least as tightly as minPrec (precedence climbing)
+func (p *parser) parseBinary(minPrec int, depth int) (node, error) {
+	x, err := p.parseUnary(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.binaryOp()
+		if !ok || precedence[op] < minPrec {
+			return x, nil
+		}
+		tok := p.next()
+		if err := p.newNode(tok.pos, depth); err != nil {
+			return nil, err
+		}
+
+		y, err := p.parseBinary(precedence[op]+1, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{pos: tok.pos, op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	tok := p.peek()
+	if tok.kind == tokenOperator && (tok.text == "!" || tok.text == "-") {
+		p.next()
+		if err := p.newNode(tok.pos, depth); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: tok.text, x: x}, nil
+	}
+
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	tok := p.next()
+	if err := p.newNode(tok.pos, depth); err != nil {
+		return nil, err
+	}
+
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{pos: tok.pos, typ: Number, value: tok.num}, nil
+
+	case tokenString:
+		return &literalNode{pos: tok.pos, typ: String, value: tok.text}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{pos: tok.pos, typ: Bool, value: true}, nil
+		case "false":
+			return &literalNode{pos: tok.pos, typ: Bool, value: false}, nil
+		case "in":
+			return nil, errorAt(tok.pos, "unexpected \"in\"")
+		}
+
+		// A name followed by an opening parenthesis is a function call
+		if next := p.peek(); next.kind == tokenOperator && next.text == "(" {
+			p.next()
+			args, err := p.parseList(")", depth)
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{pos: tok.pos, fn: tok.text, args: args}, nil
+		}
+		return &identNode{pos: tok.pos, name: tok.text}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			x, err := p.parseBinary(1, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+
+		case "[":
+			elems, err := p.parseList("]", depth)
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{pos: tok.pos, elems: elems}, nil
+		}
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+
+	return nil, errorAt(tok.pos, "unexpected end of expression")
+}
+
+// parseList parses comma separated expressions up to the closing token
+func (p *parser) parseList(closing string, depth int) ([]node, error) {
+	var elems []node
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == closing {
+		p.next()
+		return elems, nil
+	}
+
+	for {
+		elem, err := p.parseBinary(1, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+
+		tok := p.peek()
+		if tok.kind == tokenOperator && tok.text == "," {
+			p.next()
+			continue
+		}
+		if err := p.expect(closing); err != nil {
+			return nil, err
+		}
+		return elems, nil
+	}
+}
@@ -0,0 +1,141 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sexpr prints a syntax tree with explicit grouping, e.g. (+ 1 (* 2 3))
+func sexpr(n node) string {
+	switch n := n.(type) {
+	case *literalNode:
+		if n.typ == String {
+			return fmt.Sprintf("%q", n.value)
+		}
+		return fmt.Sprint(n.value)
+	case *identNode:
+		return n.name
+	case *unaryNode:
+		return fmt.Sprintf("(%s %s)", n.op, sexpr(n.x))
+	case *binaryNode:
+		return fmt.Sprintf("(%s %s %s)", n.op, sexpr(n.x), sexpr(n.y))
+	case *callNode:
+		parts := []string{n.fn}
+		for _, arg := range n.args {
+			parts = append(parts, sexpr(arg))
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	case *listNode:
+		var parts []string
+		for _, elem := range n.elems {
+			parts = append(parts, sexpr(elem))
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+	return "?"
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"(1 + 2) * 3", "(* (+ 1 2) 3)"},
+		{"1 - 2 - 3", "(- (- 1 2) 3)"},
+		{"8 / 4 % 3", "(% (/ 8 4) 3)"},
+		{"a || b && c", "(|| a (&& b c))"},
+		{"a && b || c", "(|| (&& a b) c)"},
+		{"!a && b", "(&& (! a) b)"},
+		{"!!a", "(! (! a))"},
+		{"-x * 2", "(* (- x) 2)"},
+		{"a + 1 > b * 2", "(> (+ a 1) (* b 2))"},
+		{"a < b == c >= d", "(== (< a b) (>= c d))"},
+		{`"x" in list == true`, `(== (in "x" list) true)`},
+		{`x in ["a", "b"] && y`, `(&& (in x ["a" "b"]) y)`},
+		{"max(a, b + 1) > min(1, 2)", "(> (max a (+ b 1)) (min 1 2))"},
+		{"len(cart.product_ids) >= 2", "(>= (len cart.product_ids) 2)"},
+		{"f()", "(f)"},
+		{"true != false", "(!= true false)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			tree, err := parse(tt.src, DefaultLimits)
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if got := sexpr(tree); got != tt.want {
+				t.Errorf("parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		msg string
+	}{
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")", found end of expression`},
+		{"f(1, 2", `expected ")", found end of expression`},
+		{"[1, 2", `expected "]", found end of expression`},
+		{"f(1 2)", `expected ")", found "2"`},
+		{"1 2", `unexpected "2"`},
+		{")", `unexpected ")"`},
+		{"a in", "unexpected end of expression"},
+		{"in [1]", `unexpected "in"`},
+		{"* 2", `unexpected "*"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := parse(tt.src, DefaultLimits)
+			var exprErr *Error
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("parse() error = %v, want an *Error", err)
+			}
+			if exprErr.Msg != tt.msg {
+				t.Errorf("parse() error = %q, want %q", exprErr.Msg, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits := Limits{MaxLength: 100, MaxDepth: 8, MaxNodes: 10, MaxCost: 100}
+
+	tests := []struct {
+		name string
+		src  string
+		msg  string
+	}{
+		{"too long", strings.Repeat("a", 101), "expression is longer than 100 characters"},
+		{"too many nodes", "1" + strings.Repeat(" + 1", 5), "expression has more than 10 nodes"},
+		{"parentheses nested too deep", strings.Repeat("(", 5) + "1" + strings.Repeat(")", 5), "expression is nested deeper than 8 levels"},
+		{"negations nested too deep", strings.Repeat("!", 9) + "a", "expression is nested deeper than 8 levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src, limits)
+			var exprErr *Error
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("parse() error = %v, want an *Error", err)
+			}
+			if exprErr.Msg != tt.msg {
+				t.Errorf("parse() error = %q, want %q", exprErr.Msg, tt.msg)
+			}
+		})
+	}
+
+	// Right at the limits
+	for _, src := range []string{strings.Repeat("a", 100), "1 + 1 + 1 + 1 + 1", strings.Repeat("!", 7) + "a"} {
+		if _, err := parse(src, limits); err != nil {
+			t.Errorf("parse(%q) error = %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package jsondiff lists the differences between two JSON documents, to show
+// what a change did.
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is a value that differs between two documents. Path is the dotted
+// path of the value, e.g. "details.discount"; Before or After is empty when
+// the value is missing from that document.
+type Change struct {
+	Path   string          `json:"path"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Diff returns the changes from before to after, ordered by path. Objects are
+// compared member by member, lists and other values as a whole. An empty
+// document counts as an empty object, so every member of a new document is a
+// change.
+func Diff(before, after []byte) ([]Change, error) {
+	b, err := decode(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := decode(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	diff("", b, a, &changes)
+	return changes, nil
+}
+
+func decode(document []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(document)) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func diff(path string, before, after interface{}, changes *[]Change) {
+	beforeObject, beforeIsObject := before.(map[string]interface{})
+	afterObject, afterIsObject := after.(map[string]interface{})
+	if (beforeIsObject || before == nil) && (afterIsObject || after == nil) && (beforeIsObject || afterIsObject) {
+		keys := make([]string, 0, len(beforeObject)+len(afterObject))
+		for key := range beforeObject {
+			keys = append(keys, key)
+		}
+		for key := range afterObject {
+			if _, ok := beforeObject[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diff(join(path, key), beforeObject[key], afterObject[key], changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	*changes = append(*changes, Change{
+		Path:   path,
+		Before: encode(before),
+		After:  encode(after),
+	})
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + strings.ReplaceAll(key, ".", `\.`)
+}
+
+func encode(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
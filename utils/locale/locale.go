@@ -0,0 +1,124 @@
+// Package locale picks the language of responses. Locales are BCP 47 language
+// tags such as "en", "fr" or "pt-BR"; clients list the ones they prefer in the
+// Accept-Language header.
+package locale
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Valid tells whether s is a well-formed language tag
+func Valid(s string) bool {
+	return tagPattern.MatchString(s)
+}
+
+// Canonical writes a language tag the usual way: the language in lower case,
+// a region in upper case and a script in title case, e.g. "zh-Hant-TW"
+func Canonical(tag string) string {
+	parts := strings.Split(tag, "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Parse returns the locales of an Accept-Language header, most preferred
+// first. Wildcards, malformed tags and tags with q=0 are left out.
+func Parse(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if !Valid(tag) {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: Canonical(tag), q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	locales := make([]string, len(tags))
+	for i, tag := range tags {
+		locales[i] = tag.tag
+	}
+	return locales
+}
+
+// Match picks the available locale that best fits the preferred ones. Each
+// preferred locale is tried as it is, then without its subtags ("fr-CA"
+// matches "fr"). Failing that, any available locale in a preferred language
+// is used ("de" matches "de-DE"), then fallback, then the first available
+// locale. It returns "" only when nothing is available.
+func Match(preferred []string, available []string, fallback string) string {
+	find := func(tag string) string {
+		for _, locale := range available {
+			if strings.EqualFold(locale, tag) {
+				return locale
+			}
+		}
+		return ""
+	}
+
+	for _, tag := range preferred {
+		for tag != "" {
+			if locale := find(tag); locale != "" {
+				return locale
+			}
+			cut := strings.LastIndex(tag, "-")
+			if cut < 0 {
+				break
+			}
+			tag = tag[:cut]
+		}
+	}
+
+	for _, tag := range preferred {
+		language, _, _ := strings.Cut(tag, "-")
+		for _, locale := range available {
+			if available, _, _ := strings.Cut(locale, "-"); strings.EqualFold(available, language) {
+				return locale
+			}
+		}
+	}
+
+	if locale := find(fallback); locale != "" {
+		return locale
+	}
+	if len(available) > 0 {
+		return available[0]
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package locale
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{"empty", "", []string{}},
+		{"single", "fr", []string{"fr"}},
+		{"ordered by weight", "en;q=0.5, fr-ca, de;q=0.8", []string{"fr-CA", "de", "en"}},
+		{"equal weights keep their order", "de, fr", []string{"de", "fr"}},
+		{"canonical case", "ZH-hant-tw", []string{"zh-Hant-TW"}},
+		{"wildcard and malformed tags", "*, en_US, 12, fr", []string{"fr"}},
+		{"refused with q=0", "fr;q=0, de", []string{"de"}},
+		{"malformed weight", "fr;q=high, de", []string{"de"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header); !slices.Equal(got, tt.want) {
+				t.Errorf("Parse(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred []string
+		available []string
+		fallback  string
+		want      string
+	}{
+		{"exact", []string{"fr"}, []string{"en", "fr"}, "en", "fr"},
+		{"case insensitive", []string{"pt-BR"}, []string{"en", "pt-br"}, "en", "pt-br"},
+		{"without region", []string{"fr-CA"}, []string{"en", "fr"}, "en", "fr"},
+		{"without script and region", []string{"zh-Hant-TW"}, []string{"en", "zh"}, "en", "zh"},
+		{"in order of preference", []string{"de", "fr"}, []string{"fr", "de"}, "en", "de"},
+		{"subtags before later preferences", []string{"fr-CA", "de"}, []string{"de", "fr"}, "en", "fr"},
+		{"other region of the language", []string{"de"}, []string{"en", "de-DE"}, "en", "de-DE"},
+		{"fallback", []string{"ja"}, []string{"fr", "en"}, "en", "en"},
+		{"fallback without preferences", nil, []string{"fr", "en"}, "en", "en"},
+		{"first available without the fallback", []string{"ja"}, []string{"fr", "de"}, "en", "fr"},
+		{"nothing available", []string{"fr"}, nil, "en", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.preferred, tt.available, tt.fallback); got != tt.want {
+				t.Errorf("Match(%q, %q, %q) = %q, want %q", tt.preferred, tt.available, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package schema implements the subset of JSON Schema used to describe
+// request bodies: types, required and known properties, numeric bounds,
+// string lengths and patterns, list lengths, unique items and enums.
+// Schemas marshal to standard JSON Schema, so clients can use them to build
+// forms.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+// Object describes an object with the given properties. Other properties are
+// rejected; the required ones are listed with Require.
+func Object(properties map[string]*Schema) *Schema {
+	closed := false
+	return &Schema{
+		Type:                 TypeObject,
+		Properties:           properties,
+		AdditionalProperties: &closed,
+	}
+}
+
+func Array(items *Schema) *Schema {
+	return &Schema{Type: TypeArray, Items: items}
+}
+
+func String() *Schema {
+	return &Schema{Type: TypeString}
+}
+
+func Number() *Schema {
+	return &Schema{Type: TypeNumber}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: TypeInteger}
+}
+
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+func (s *Schema) Require(properties ...string) *Schema {
+	s.Required = append(s.Required, properties...)
+	return s
+}
+
+func (s *Schema) Min(min float64) *Schema {
+	s.Minimum = &min
+	return s
+}
+
+func (s *Schema) ExclusiveMin(min float64) *Schema {
+	s.ExclusiveMinimum = &min
+	return s
+}
+
+func (s *Schema) Max(max float64) *Schema {
+	s.Maximum = &max
+	return s
+}
+
+func (s *Schema) MinLen(n int) *Schema {
+	s.MinLength = &n
+	return s
+}
+
+func (s *Schema) NonEmpty() *Schema {
+	n := 1
+	s.MinItems = &n
+	return s
+}
+
+func (s *Schema) Match(pattern string) *Schema {
+	s.Pattern = pattern
+	return s
+}
+
+func (s *Schema) Unique() *Schema {
+	s.UniqueItems = true
+	return s
+}
+
+func (s *Schema) OneOf(values ...interface{}) *Schema {
+	s.Enum = values
+	return s
+}
+
+func (s *Schema) WithDefault(value interface{}) *Schema {
+	s.Default = value
+	return s
+}
+
+// Problem is a value that does not match its schema
+type Problem struct {
+	// Path of the value, e.g. details.buy_products[0].quantity
+	Path    string
+	Message string
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into an
+// interface{}) against the schema. path names the value in the problems.
+func (s *Schema) Validate(path string, value interface{}) []Problem {
+	var problems []Problem
+	s.validate(path, value, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, value interface{}, problems *[]Problem) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case TypeObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("must be an object")
+			return
+		}
+		s.validateObject(path, object, problems)
+
+	case TypeArray:
+		list, ok := value.([]interface{})
+		if !ok {
+			report("must be a list")
+			return
+		}
+		if s.MinItems != nil && len(list) < *s.MinItems {
+			if *s.MinItems == 1 {
+				report("must not be empty")
+			} else {
+				report("must have at least %d items", *s.MinItems)
+			}
+		}
+		for i, item := range list {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if s.UniqueItems {
+				for j := 0; j < i; j++ {
+					if equal(list[j], item) {
+						*problems = append(*problems, Problem{Path: itemPath, Message: fmt.Sprintf("duplicates %s[%d]", path, j)})
+						break
+					}
+				}
+			}
+			if s.Items != nil {
+				s.Items.validate(itemPath, item, problems)
+			}
+		}
+
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			report("must be a string")
+			return
+		}
+		if s.MinLength != nil && len(strings.TrimSpace(str)) < *s.MinLength {
+			if *s.MinLength == 1 {
+				report("must not be blank")
+			} else {
+				report("must be at least %d characters long", *s.MinLength)
+			}
+		}
+		if s.Pattern != "" {
+			if matched, err := regexp.MatchString(s.Pattern, str); err != nil || !matched {
+				report("must match %s", s.Pattern)
+			}
+		}
+
+	case TypeNumber, TypeInteger:
+		number, ok := value.(float64)
+		if !ok {
+			report("must be a number")
+			return
+		}
+		if s.Type == TypeInteger && number != math.Trunc(number) {
+			report("must be an integer")
+			return
+		}
+		if s.ExclusiveMinimum != nil && number <= *s.ExclusiveMinimum {
+			report("must be > %v", *s.ExclusiveMinimum)
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			report("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			report("must be <= %v", *s.Maximum)
+		}
+
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			report("must be a boolean")
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if equal(allowed, value) {
+				return
+			}
+		}
+		values := make([]string, len(s.Enum))
+		for i, allowed := range s.Enum {
+			values[i] = fmt.Sprint(allowed)
+		}
+		report("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}, problems *[]Problem) {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			*problems = append(*problems, Problem{Path: join(path, name), Message: "is required"})
+		}
+	}
+
+	// Walk the properties in a stable order so problems are reported consistently
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*problems = append(*problems, Problem{Path: join(path, name), Message: "is not a known field"})
+			}
+			continue
+		}
+		property.validate(join(path, name), object[name], problems)
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// equal compares decoded JSON values
+func equal(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, src string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(src), &value); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", src, err)
+	}
+	return value
+}
+
+var productSchema = Object(map[string]*Schema{
+	"id":       String().MinLen(1),
+	"sku":      String().Match(`^[A-Z]{3}-\d+$`),
+	"quantity": Integer().ExclusiveMin(0),
+	"price":    Number().Min(0).Max(1000),
+	"tags":     Array(String().MinLen(1)).NonEmpty().Unique(),
+	"codes":    Array(Integer()).Unique(),
+	"parts":    Array(Object(map[string]*Schema{"id": String()}).Require("id")),
+	"color":    String().OneOf("red", "blue"),
+	"size":     Integer().OneOf(1.0, 2.0),
+	"bundle":   Array(String()).NonEmpty(),
+	"lines":    Array(Integer()),
+	"notes":    String().MinLen(3),
+	"gift":     &Schema{Type: TypeBoolean},
+}).Require("id", "quantity")
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		problems []Problem
+	}{
+		{"valid", `{"id": "p1", "quantity": 2, "price": 9.5, "tags": ["a", "b"], "color": "red", "gift": true}`, nil},
+		{"required fields", `{}`, []Problem{{"item.id", "is required"}, {"item.quantity", "is required"}}},
+		{"unknown field", `{"id": "p1", "quantity": 1, "extra": 1}`, []Problem{{"item.extra", "is not a known field"}}},
+		{"not an object", `[1]`, []Problem{{"item", "must be an object"}}},
+		{"wrong types", `{"id": 1, "quantity": "1", "tags": "a", "gift": "yes"}`, []Problem{
+			{"item.gift", "must be a boolean"},
+			{"item.id", "must be a string"},
+			{"item.quantity", "must be a number"},
+			{"item.tags", "must be a list"},
+		}},
+		{"integer", `{"id": "p1", "quantity": 1.5}`, []Problem{{"item.quantity", "must be an integer"}}},
+		{"exclusive minimum", `{"id": "p1", "quantity": 0}`, []Problem{{"item.quantity", "must be > 0"}}},
+		{"minimum", `{"id": "p1", "quantity": 1, "price": -0.01}`, []Problem{{"item.price", "must be >= 0"}}},
+		{"maximum", `{"id": "p1", "quantity": 1, "price": 1000.5}`, []Problem{{"item.price", "must be <= 1000"}}},
+		{"bounds are inclusive", `{"id": "p1", "quantity": 1, "price": 1000}`, nil},
+		{"blank string", `{"id": "  ", "quantity": 1}`, []Problem{{"item.id", "must not be blank"}}},
+		{"minimum length", `{"id": "p1", "quantity": 1, "notes": "ab"}`, []Problem{{"item.notes", "must be at least 3 characters long"}}},
+		{"pattern", `{"id": "p1", "quantity": 1, "sku": "abc-1"}`, []Problem{{"item.sku", `must match ^[A-Z]{3}-\d+$`}}},
+		{"empty list", `{"id": "p1", "quantity": 1, "tags": [], "bundle": []}`, []Problem{
+			{"item.bundle", "must not be empty"},
+			{"item.tags", "must not be empty"},
+		}},
+		{"unique items", `{"id": "p1", "quantity": 1, "tags": ["a", "b", "a"], "codes": [1, 2, 1, 1]}`, []Problem{
+			{"item.codes[2]", "duplicates item.codes[0]"},
+			{"item.codes[3]", "duplicates item.codes[0]"},
+			{"item.tags[2]", "duplicates item.tags[0]"},
+		}},
+		{"items", `{"id": "p1", "quantity": 1, "tags": ["a", ""], "lines": [1, "2"]}`, []Problem{
+			{"item.lines[1]", "must be a number"},
+			{"item.tags[1]", "must not be blank"},
+		}},
+		{"nested objects", `{"id": "p1", "quantity": 1, "parts": [{"id": "a"}, {}]}`, []Problem{{"item.parts[1].id", "is required"}}},
+		{"enum", `{"id": "p1", "quantity": 1, "color": "green", "size": 3}`, []Problem{
+			{"item.color", "must be one of red, blue"},
+			{"item.size", "must be one of 1, 2"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := productSchema.Validate("item", decode(t, tt.src))
+			if !reflect.DeepEqual(got, tt.problems) {
+				t.Errorf("Validate() = %v, want %v", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidateWithoutPath(t *testing.T) {
+	got := Object(map[string]*Schema{}).Require("id").Validate("", decode(t, `{}`))
+	if want := []Problem{{"id", "is required"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %v, want %v", got, want)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	s := Object(map[string]*Schema{
+		"product_ids": Array(String().MinLen(1)).NonEmpty().Unique().Describe("Products"),
+		"discount":    Number().ExclusiveMin(0).Max(100),
+		"threshold":   Integer().Min(0).WithDefault(0),
+		"scope":       String().OneOf("line", "set").WithDefault("line"),
+	}).Require("product_ids", "discount")
+
+	got, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	// Zero bounds and defaults are kept, unset keywords are left out
+	want := `{"type":"object","properties":{` +
+		`"discount":{"type":"number","exclusiveMinimum":0,"maximum":100},` +
+		`"product_ids":{"type":"array","description":"Products","items":{"type":"string","minLength":1},"minItems":1,"uniqueItems":true},` +
+		`"scope":{"type":"string","enum":["line","set"],"default":"line"},` +
+		`"threshold":{"type":"integer","minimum":0,"default":0}},` +
+		`"required":["product_ids","discount"],"additionalProperties":false}`
+	if string(got) != want {
+		t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}